@@ -39,12 +39,17 @@ type runner struct {
 	highMem     core.ObservedMemory
 	ioStats     *core.IoStatsBuilder
 	metadata    *core.Metadata
+	status      *core.StatusClient
 	runType     string
 	jobInfo     *core.JobInfo
 	monitoring  bool
 	start       time.Time
 	isDone      chan struct{}
 	perfDone    <-chan struct{}
+
+	// The modification time of the progress file when it was last
+	// reported.
+	progressTime time.Time
 }
 
 func main() {
@@ -80,6 +85,13 @@ func main() {
 		util.LogTeeWriter(log)
 		defer run.log.Close()
 	}
+	if client, err := core.DialStatusServer(metadataPath, fqname); err != nil {
+		util.PrintError(err, "monitor",
+			"Could not connect to mrp for status updates.  Using the journal instead.")
+	} else if client != nil {
+		run.status = client
+		run.metadata.SetStatusClient(client)
+	}
 	run.metadata.UpdateJournal(core.StdOut)
 	run.metadata.UpdateJournal(core.StdErr)
 
@@ -561,10 +573,18 @@ func (self *runner) WaitLoop() {
 		// about very short stages.
 		timer := time.NewTimer(time.Millisecond * 500)
 		defer timer.Stop()
+		var timeout <-chan time.Time
+		if self.jobInfo.Timeout > 0 {
+			deadline := time.NewTimer(time.Duration(self.jobInfo.Timeout) * time.Second)
+			defer deadline.Stop()
+			timeout = deadline.C
+		}
 		for {
 			select {
 			case err := <-wait:
 				return err
+			case <-timeout:
+				return self.killTimedOut()
 			case <-timer.C:
 				// Minimize parent process impact on memory stats, and
 				// prevent mrjob from using too many resources for polling.
@@ -636,6 +656,61 @@ func (self *runner) logProcessTree() {
 	}
 }
 
+// Kill the job and any processes it started.
+func (self *runner) killProcessTree() {
+	proc := self.job.Process
+	if proc == nil {
+		return
+	}
+	tree, err := core.GetProcessTreeMemoryList(proc.Pid)
+	if err != nil {
+		util.LogError(err, "monitor", "Could not list the job's child processes.")
+	}
+	proc.Kill()
+	for _, p := range tree {
+		if p.Pid == proc.Pid {
+			continue
+		}
+		if child, err := os.FindProcess(p.Pid); err == nil {
+			child.Kill()
+		}
+	}
+}
+
+// Kill a job which ran for longer than its timeout.  The returned error
+// begins with core.TimeoutError, to distinguish it from other failures.
+func (self *runner) killTimedOut() error {
+	limit := time.Duration(self.jobInfo.Timeout) * time.Second
+	util.PrintInfo("monitor",
+		"Stage exceeded its time limit of %v.  Killing it.", limit)
+	if proc := self.job.Process; proc != nil {
+		if tree, _ := core.GetProcessTreeMemoryList(proc.Pid); len(tree) > 0 {
+			util.LogInfo("monitor", "Process tree:\n%s",
+				tree.Format("       "))
+		}
+	}
+	self.killProcessTree()
+	return fmt.Errorf("%s of %v (ran for %v)",
+		core.TimeoutError, limit,
+		time.Since(self.start).Round(time.Second))
+}
+
+// When sending status updates directly to mrp, journal entries written by the
+// stage code adapter are only read occasionally, so forward progress updates.
+func (self *runner) forwardProgress() {
+	if self.status == nil {
+		return
+	}
+	info, err := os.Stat(self.metadata.MetadataFilePath(core.ProgressFile))
+	if err != nil || !info.ModTime().After(self.progressTime) {
+		return
+	}
+	self.progressTime = info.ModTime()
+	if err := self.metadata.UpdateJournal(core.ProgressFile); err != nil {
+		util.LogError(err, "monitor", "Could not report progress.")
+	}
+}
+
 func (self *runner) monitor(lastHeartbeat *time.Time) error {
 	if rss, vmem := self.getChildMemGB(); rss > float64(self.jobInfo.MemGB) {
 		self.logProcessTree()
@@ -669,6 +744,7 @@ func (self *runner) monitor(lastHeartbeat *time.Time) error {
 				vmem, self.jobInfo.VMemGB)
 		}
 	}
+	self.forwardProgress()
 	if time.Since(*lastHeartbeat) > HeartbeatInterval {
 		if err := self.metadata.UpdateJournal(core.Heartbeat); err != nil {
 			util.PrintError(err, "monitor", "Could not write heartbeat.")
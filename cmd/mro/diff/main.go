@@ -0,0 +1,78 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+// Package diff implements the command line interface for comparing two
+// pipestances.
+package diff
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/martian-lang/martian/martian/core"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+const usage = "Usage: mro diff [options] <pipestance|metadata.zip> <pipestance|metadata.zip>"
+
+func Main(argv []string) int {
+	util.SetPrintLogger(os.Stderr)
+	var flags flag.FlagSet
+	flags.Init("mro diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(),
+			"Compares the _args, _outs, forks, chunks, and _versions of two\n"+
+				"pipestances, given either as pipestance directories or as\n"+
+				"metadata zip archives.  Exits with status 1 if there are\n"+
+				"differences, or 2 on error.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	asJson := flags.Bool("json", false, "Write the differences as json.")
+	checksums := flags.Bool("checksums", false,
+		"Also compare the checksums of output files.  "+
+			"Requires pipestance directories.")
+	if err := flags.Parse(argv); err != nil {
+		panic(err)
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	diffs, err := core.DiffPipestances(flags.Arg(0), flags.Arg(1), *checksums)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	out := bufio.NewWriter(os.Stdout)
+	if *asJson {
+		if diffs == nil {
+			diffs = []*core.PipestanceDifference{}
+		}
+		b, err := json.MarshalIndent(diffs, "", "    ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		out.Write(b)
+		out.WriteByte('\n')
+	} else if len(diffs) > 0 {
+		fmt.Fprintln(out, "a:", flags.Arg(0))
+		fmt.Fprintln(out, "b:", flags.Arg(1))
+		for _, d := range diffs {
+			fmt.Fprintln(out, d.String())
+		}
+	}
+	if err := out.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(diffs) > 0 {
+		return 1
+	}
+	return 0
+}
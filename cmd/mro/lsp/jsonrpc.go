@@ -0,0 +1,107 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// conn reads and writes json-rpc messages using the base protocol framing
+// from the language server protocol, which is a set of http-like headers
+// followed by the message content.
+type conn struct {
+	reader *textproto.Reader
+	buf    *bufio.Reader
+	writer io.Writer
+	lock   sync.Mutex
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	buf := bufio.NewReader(r)
+	return &conn{
+		reader: textproto.NewReader(buf),
+		buf:    buf,
+		writer: w,
+	}
+}
+
+// read returns the next message from the stream.
+func (c *conn) read() (*message, error) {
+	header, err := c.reader.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(c.buf, b); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return nil, &responseError{
+			Code:    codeParseError,
+			Message: err.Error(),
+		}
+	}
+	return &msg, nil
+}
+
+func (c *conn) write(msg *message) error {
+	msg.Jsonrpc = "2.0"
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, err := fmt.Fprintf(c.writer,
+		"Content-Length: %d\r\n\r\n", len(b)); err != nil {
+		return err
+	}
+	_, err = c.writer.Write(b)
+	return err
+}
+
+// reply sends the response to a request.
+func (c *conn) reply(id *json.RawMessage, result interface{}, err error) error {
+	msg := message{ID: id}
+	if err != nil {
+		if rerr, ok := err.(*responseError); ok {
+			msg.Error = rerr
+		} else {
+			msg.Error = &responseError{
+				Code:    codeInternalError,
+				Message: err.Error(),
+			}
+		}
+	} else if b, err := json.Marshal(result); err != nil {
+		msg.Error = &responseError{
+			Code:    codeInternalError,
+			Message: err.Error(),
+		}
+	} else {
+		msg.Result = b
+	}
+	return c.write(&msg)
+}
+
+// notify sends a notification to the client.
+func (c *conn) notify(method string, params interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{
+		Method: method,
+		Params: b,
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+// Package lsp implements a language server for mro files.
+//
+// The server speaks the Language Server Protocol over standard input and
+// output, and supports diagnostics, go-to-definition, hover, completion,
+// and rename.  Editors should be configured to launch it as
+//
+//	mro lsp
+//
+// Include paths are taken from MROPATH, if set, or else from the
+// "mropath" initialization option or the workspace root.  Included files
+// are always read from disk, so unsaved changes to an included file are not
+// seen by files which include it until it is saved.
+package lsp
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/martian-lang/martian/martian/util"
+)
+
+func Main(argv []string) int {
+	// Standard output is reserved for protocol messages.
+	util.SetPrintLogger(os.Stderr)
+
+	var flags flag.FlagSet
+	flags.Init("mro lsp", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mro lsp [options]")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(),
+			"Runs a language server on standard input and output.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	// Many clients pass --stdio to specify the transport, which is the
+	// only one supported.
+	flags.Bool("stdio", true, "Communicate over standard input and output.")
+	version := flags.Bool("v", false, "Print the version and exit.")
+	if err := flags.Parse(argv); err != nil {
+		panic(err)
+	}
+	if *version {
+		fmt.Println(util.GetVersion())
+		return 0
+	}
+
+	var mroPaths []string
+	if value := os.Getenv("MROPATH"); len(value) > 0 {
+		mroPaths = util.ParseMroPath(value)
+	}
+	return newServer(os.Stdin, os.Stdout, mroPaths).run()
+}
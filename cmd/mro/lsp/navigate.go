@@ -0,0 +1,565 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/martian-lang/martian/martian/syntax"
+)
+
+type symbolKind int
+
+const (
+	symCallable symbolKind = iota
+	symStruct
+	symFileType
+	symEnum
+	symCall
+	symInParam
+	symOutParam
+	symMember
+)
+
+// A symbol which was found at a cursor location.
+type symbol struct {
+	kind symbolKind
+
+	// The declaration of the symbol.
+	node syntax.NamedNode
+
+	// For callables, calls, and parameters, the callable object.
+	callable syntax.Callable
+
+	// The type of a parameter or struct member.
+	tname syntax.TypeId
+
+	// Help text for a parameter.
+	help string
+}
+
+// The scope which encloses a cursor location.
+type scope struct {
+	// The stage or pipeline declaration, if any.
+	callable syntax.Callable
+
+	// The call statement, if any.  This may be the top-level call.
+	call *syntax.CallStm
+
+	// True if the cursor is in the return statement of a pipeline.
+	inReturn bool
+}
+
+func getCallable(ast *syntax.Ast, id string) syntax.Callable {
+	if ast.Callables == nil {
+		return nil
+	}
+	if ast.Callables.Table != nil {
+		return ast.Callables.Table[id]
+	}
+	for _, c := range ast.Callables.List {
+		if c.GetId() == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func getStruct(ast *syntax.Ast, id string) *syntax.StructType {
+	for _, st := range ast.StructTypes {
+		if st.Id == id {
+			return st
+		}
+	}
+	return nil
+}
+
+func getFileType(ast *syntax.Ast, id string) *syntax.UserType {
+	for _, ut := range ast.UserTypes {
+		if ut.Id == id {
+			return ut
+		}
+	}
+	return nil
+}
+
+func getEnum(ast *syntax.Ast, id string) *syntax.EnumType {
+	for _, et := range ast.EnumTypes {
+		if et.Id == id {
+			return et
+		}
+	}
+	return nil
+}
+
+func getCall(pipe *syntax.Pipeline, id string) *syntax.CallStm {
+	for _, call := range pipe.Calls {
+		if call.Id == id {
+			return call
+		}
+	}
+	return nil
+}
+
+// findScope returns the callable and call which enclose the given
+// one-based line of the file.
+//
+// Since the ast does not record where nodes end, the enclosing node is
+// the last one which starts before the line.
+func findScope(ast *syntax.Ast, path string, line int) scope {
+	var result scope
+	start := 0
+	for _, c := range ast.Callables.List {
+		if c.File() != nil && c.File().FullPath == path &&
+			c.Line() <= line && c.Line() > start {
+			result.callable = c
+			start = c.Line()
+		}
+	}
+	if call := ast.Call; call != nil && call.File() != nil &&
+		call.File().FullPath == path && call.Line() <= line &&
+		call.Line() > start {
+		return scope{call: call}
+	}
+	if pipe, ok := result.callable.(*syntax.Pipeline); ok {
+		start = 0
+		for _, call := range pipe.Calls {
+			if call.Line() <= line && call.Line() > start {
+				result.call = call
+				start = call.Line()
+			}
+		}
+		if pipe.Ret != nil && pipe.Ret.Node.Loc.Line <= line &&
+			pipe.Ret.Node.Loc.Line > start {
+			result.call = nil
+			result.inReturn = true
+		}
+	}
+	return result
+}
+
+func callableSymbol(c syntax.Callable) *symbol {
+	return &symbol{
+		kind:     symCallable,
+		node:     c,
+		callable: c,
+	}
+}
+
+func inParamSymbol(c syntax.Callable, id string) *symbol {
+	if c == nil || c.GetInParams() == nil {
+		return nil
+	}
+	for _, param := range c.GetInParams().List {
+		if param.Id == id {
+			return &symbol{
+				kind:     symInParam,
+				node:     param,
+				callable: c,
+				tname:    param.Tname,
+				help:     param.Help,
+			}
+		}
+	}
+	return nil
+}
+
+func outParamSymbol(c syntax.Callable, id string) *symbol {
+	if c == nil || c.GetOutParams() == nil {
+		return nil
+	}
+	for _, param := range c.GetOutParams().List {
+		if param.Id == id {
+			return &symbol{
+				kind:     symOutParam,
+				node:     param,
+				callable: c,
+				tname:    param.Tname,
+				help:     param.Help,
+			}
+		}
+	}
+	return nil
+}
+
+// memberSymbol returns the symbol for a member of a struct-typed symbol.
+func memberSymbol(ast *syntax.Ast, parent *symbol, id string) *symbol {
+	st := getStruct(ast, parent.tname.Tname)
+	if st == nil {
+		// Calls to pipelines have implicit struct types for their outputs.
+		if c := getCallable(ast, parent.tname.Tname); c != nil {
+			return outParamSymbol(c, id)
+		}
+		return nil
+	}
+	for _, member := range st.Members {
+		if member.Id == id {
+			return &symbol{
+				kind:  symMember,
+				node:  member,
+				tname: member.Tname,
+				help:  member.Help,
+			}
+		}
+	}
+	return nil
+}
+
+// resolveReference finds the symbol referred to by a dotted reference
+// within a pipeline, e.g. self.input or CALL.output.member.
+func resolveReference(ast *syntax.Ast, sc scope, parts []string) *symbol {
+	pipe, _ := sc.callable.(*syntax.Pipeline)
+	if pipe == nil || len(parts) < 2 {
+		return nil
+	}
+	var sym *symbol
+	if parts[0] == "self" {
+		sym = inParamSymbol(pipe, parts[1])
+	} else if call := getCall(pipe, parts[0]); call != nil {
+		sym = outParamSymbol(getCallable(ast, call.DecId), parts[1])
+	}
+	for _, part := range parts[2:] {
+		if sym == nil {
+			return nil
+		}
+		sym = memberSymbol(ast, sym, part)
+	}
+	return sym
+}
+
+// isBindingName returns true if the cursor is on the left hand side of a
+// binding statement.
+func isBindingName(c *cursor) bool {
+	after := strings.TrimSpace(c.after())
+	return strings.TrimSpace(c.before()) == "" &&
+		strings.HasPrefix(after, "=") &&
+		!strings.HasPrefix(after, "==")
+}
+
+// resolve finds the symbol at the given cursor location.
+func resolve(ast *syntax.Ast, path string, c *cursor) *symbol {
+	if ast == nil || ast.Callables == nil {
+		return nil
+	}
+	word := c.word()
+	if word == "" {
+		return nil
+	}
+	parts := strings.Split(word, ".")
+	sc := findScope(ast, path, c.line+1)
+	if len(parts) > 1 {
+		return resolveReference(ast, sc, parts)
+	}
+	id := parts[0]
+	if isBindingName(c) {
+		if sc.call != nil {
+			return inParamSymbol(getCallable(ast, sc.call.DecId), id)
+		} else if sc.inReturn {
+			return outParamSymbol(sc.callable, id)
+		}
+	}
+	if pipe, ok := sc.callable.(*syntax.Pipeline); ok &&
+		strings.HasPrefix(c.after(), ".") {
+		if call := getCall(pipe, id); call != nil {
+			return &symbol{
+				kind:     symCall,
+				node:     call,
+				callable: getCallable(ast, call.DecId),
+			}
+		}
+	}
+	if callable := getCallable(ast, id); callable != nil {
+		return callableSymbol(callable)
+	}
+	if st := getStruct(ast, id); st != nil {
+		return &symbol{
+			kind: symStruct,
+			node: st,
+		}
+	}
+	if ut := getFileType(ast, id); ut != nil {
+		return &symbol{
+			kind: symFileType,
+			node: ut,
+		}
+	}
+	if et := getEnum(ast, id); et != nil {
+		return &symbol{
+			kind: symEnum,
+			node: et,
+		}
+	}
+	if sc.callable != nil && sc.call == nil && !sc.inReturn {
+		// Parameter declarations.
+		if sym := inParamSymbol(sc.callable, id); sym != nil &&
+			sym.node.Line() == c.line+1 {
+			return sym
+		}
+		if sym := outParamSymbol(sc.callable, id); sym != nil &&
+			sym.node.Line() == c.line+1 {
+			return sym
+		}
+	}
+	if pipe, ok := sc.callable.(*syntax.Pipeline); ok {
+		// A call alias.
+		if call := getCall(pipe, id); call != nil {
+			return &symbol{
+				kind:     symCall,
+				node:     call,
+				callable: getCallable(ast, call.DecId),
+			}
+		}
+	}
+	return nil
+}
+
+// declarationLocation returns the location of the name of a declaration.
+func (s *server) declarationLocation(node syntax.NamedNode) *location {
+	file := node.File()
+	if file == nil || file.FullPath == "" {
+		return nil
+	}
+	line := node.Line() - 1
+	text := lineText(s.fileText(file.FullPath), line)
+	loc := location{
+		URI: pathToUri(file.FullPath),
+	}
+	id := node.GetId()
+	if i := findWord(text, id, 0); i >= 0 {
+		loc.Range = wordRange(line, text, i, i+len(id))
+	} else {
+		loc.Range.Start.Line = line
+		loc.Range.End.Line = line
+	}
+	return &loc
+}
+
+func (s *server) definition(params *textDocumentPositionParams) []location {
+	doc := s.docs[params.TextDocument.URI]
+	if doc == nil {
+		return nil
+	}
+	sym := resolve(doc.ast, doc.path, cursorAt(doc.text, params.Position))
+	if sym == nil {
+		return nil
+	}
+	if loc := s.declarationLocation(sym.node); loc != nil {
+		return []location{*loc}
+	}
+	return nil
+}
+
+func (s *server) hover(params *textDocumentPositionParams) *hover {
+	doc := s.docs[params.TextDocument.URI]
+	if doc == nil {
+		return nil
+	}
+	c := cursorAt(doc.text, params.Position)
+	sym := resolve(doc.ast, doc.path, c)
+	if sym == nil {
+		return nil
+	}
+	var buf strings.Builder
+	buf.WriteString("```mro\n")
+	switch sym.kind {
+	case symCallable, symCall:
+		if sym.kind == symCall {
+			fmt.Fprintf(&buf, "call %s\n\n", sym.node.GetId())
+		}
+		if sym.callable != nil {
+			writeSignature(&buf, sym.callable)
+		}
+	case symStruct:
+		writeStruct(&buf, sym.node.(*syntax.StructType))
+	case symFileType:
+		fmt.Fprintf(&buf, "filetype %s;\n", sym.node.GetId())
+	case symEnum:
+		writeEnum(&buf, sym.node.(*syntax.EnumType))
+	case symInParam:
+		fmt.Fprintf(&buf, "in  %s %s\n",
+			sym.tname.String(), sym.node.GetId())
+	case symOutParam, symMember:
+		fmt.Fprintf(&buf, "out %s %s\n",
+			sym.tname.String(), sym.node.GetId())
+	}
+	buf.WriteString("```")
+	if sym.help != "" {
+		buf.WriteString("\n\n")
+		buf.WriteString(sym.help)
+	}
+	if sym.callable != nil && (sym.kind == symInParam || sym.kind == symOutParam) {
+		fmt.Fprintf(&buf, "\n\nParameter of %s %s.",
+			sym.callable.Type(), sym.callable.GetId())
+	}
+	r := wordRange(c.line, c.text,
+		c.start+strings.LastIndexByte(c.prefix(), '.')+1, c.end)
+	return &hover{
+		Contents: markupContent{
+			Kind:  "markdown",
+			Value: buf.String(),
+		},
+		Range: &r,
+	}
+}
+
+// writeSignature writes the inputs and outputs of a callable, without the
+// body of a pipeline or the implementation details of a stage.
+func writeSignature(buf *strings.Builder, c syntax.Callable) {
+	fmt.Fprintf(buf, "%s %s(\n", c.Type(), c.GetId())
+	if ins := c.GetInParams(); ins != nil {
+		for _, param := range ins.List {
+			fmt.Fprintf(buf, "    in  %s %s,\n", paramType(param), param.Id)
+		}
+	}
+	if outs := c.GetOutParams(); outs != nil {
+		for _, param := range outs.List {
+			fmt.Fprintf(buf, "    out %s %s,\n", paramType(param), param.Id)
+		}
+	}
+	buf.WriteString(")\n")
+}
+
+// paramType returns the type of a parameter as it would be declared,
+// including the ! for non-null parameters.
+func paramType(param syntax.StructMemberLike) string {
+	t := param.GetTname()
+	if param.IsNonNull() {
+		return t.String() + "!"
+	}
+	return t.String()
+}
+
+func writeStruct(buf *strings.Builder, st *syntax.StructType) {
+	fmt.Fprintf(buf, "struct %s(\n", st.Id)
+	for _, member := range st.Members {
+		fmt.Fprintf(buf, "    %s %s,\n", paramType(member), member.Id)
+	}
+	buf.WriteString(")\n")
+}
+
+func writeEnum(buf *strings.Builder, et *syntax.EnumType) {
+	fmt.Fprintf(buf, "enum %s(\n", et.Id)
+	for _, v := range et.Values {
+		fmt.Fprintf(buf, "    %q,\n", v.Value)
+	}
+	buf.WriteString(")\n")
+}
+
+func (s *server) completion(params *textDocumentPositionParams) []completionItem {
+	items := []completionItem{}
+	doc := s.docs[params.TextDocument.URI]
+	if doc == nil || doc.ast == nil || doc.ast.Callables == nil {
+		return items
+	}
+	ast := doc.ast
+	c := cursorAt(doc.text, params.Position)
+	sc := findScope(ast, doc.path, c.line+1)
+	prefix := c.prefix()
+	if i := strings.LastIndexByte(prefix, '.'); i >= 0 {
+		// Members of a reference.
+		parts := strings.Split(prefix[:i], ".")
+		var outs []syntax.StructMemberLike
+		if len(parts) == 1 && parts[0] == "self" {
+			if sc.callable != nil {
+				for _, param := range sc.callable.GetInParams().List {
+					outs = append(outs, param)
+				}
+			}
+		} else if len(parts) == 1 {
+			if pipe, ok := sc.callable.(*syntax.Pipeline); ok {
+				if call := getCall(pipe, parts[0]); call != nil {
+					if callable := getCallable(ast, call.DecId); callable != nil {
+						for _, param := range callable.GetOutParams().List {
+							outs = append(outs, param)
+						}
+					}
+				}
+			}
+		} else if sym := resolveReference(ast, sc, parts); sym != nil {
+			if st := getStruct(ast, sym.tname.Tname); st != nil {
+				for _, member := range st.Members {
+					outs = append(outs, member)
+				}
+			}
+		}
+		for _, param := range outs {
+			tname := param.GetTname()
+			items = append(items, completionItem{
+				Label:         param.GetId(),
+				Kind:          completionKindField,
+				Detail:        tname.String(),
+				Documentation: param.GetHelp(),
+			})
+		}
+		return items
+	}
+	before := strings.TrimSpace(c.before())
+	if f := strings.Fields(before); len(f) > 0 && f[len(f)-1] == "call" {
+		// Callable names.
+		for _, callable := range ast.Callables.List {
+			items = append(items, completionItem{
+				Label:  callable.GetId(),
+				Kind:   completionKindFunction,
+				Detail: callable.Type(),
+			})
+		}
+		return items
+	}
+	if sc.call != nil && before == "" {
+		// Parameters which have not yet been bound.
+		if callable := getCallable(ast, sc.call.DecId); callable != nil {
+			for _, param := range callable.GetInParams().List {
+				if sc.call.Bindings != nil && sc.call.Bindings.Table != nil {
+					if _, ok := sc.call.Bindings.Table[param.Id]; ok {
+						continue
+					}
+				}
+				items = append(items, completionItem{
+					Label:         param.Id,
+					Kind:          completionKindVariable,
+					Detail:        param.Tname.String(),
+					Documentation: param.Help,
+					InsertText:    param.Id + " = ",
+				})
+			}
+		}
+		return items
+	}
+	if pipe, ok := sc.callable.(*syntax.Pipeline); ok &&
+		(sc.call != nil || sc.inReturn) {
+		// The start of a reference expression.
+		items = append(items, completionItem{
+			Label: "self",
+			Kind:  completionKindModule,
+		})
+		for _, call := range pipe.Calls {
+			items = append(items, completionItem{
+				Label:  call.Id,
+				Kind:   completionKindModule,
+				Detail: call.DecId,
+			})
+		}
+		return items
+	}
+	// Type names, for parameter declarations.
+	for _, st := range ast.StructTypes {
+		items = append(items, completionItem{
+			Label: st.Id,
+			Kind:  completionKindClass,
+		})
+	}
+	for _, ut := range ast.UserTypes {
+		items = append(items, completionItem{
+			Label: ut.Id,
+			Kind:  completionKindClass,
+		})
+	}
+	for _, et := range ast.EnumTypes {
+		items = append(items, completionItem{
+			Label: et.Id,
+			Kind:  completionKindClass,
+		})
+	}
+	return items
+}
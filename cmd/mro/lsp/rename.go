@@ -0,0 +1,141 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/martian-lang/martian/martian/syntax"
+	"github.com/martian-lang/martian/martian/syntax/refactoring"
+)
+
+func validIdentifier(id string) bool {
+	if id == "" || id[0] >= '0' && id[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if !isIdentByte(id[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// renameConfig returns the refactoring configuration for renaming the
+// given symbol.
+func renameConfig(sym *symbol, newName string) (refactoring.RefactorConfig, error) {
+	var conf refactoring.RefactorConfig
+	switch sym.kind {
+	case symCall:
+		if sym.callable == nil || sym.node.GetId() != sym.callable.GetId() {
+			return conf, fmt.Errorf("renaming call aliases is not supported")
+		}
+		fallthrough
+	case symCallable:
+		conf.Rename = []refactoring.Rename{{
+			Callable: sym.callable.GetId(),
+			NewName:  newName,
+		}}
+	case symInParam:
+		conf.RenameInParam = []refactoring.RenameParam{{
+			CallableParam: refactoring.CallableParam{
+				Callable: sym.callable.GetId(),
+				Param:    sym.node.GetId(),
+			},
+			NewName: newName,
+		}}
+	case symOutParam:
+		conf.RenameOutParam = []refactoring.RenameParam{{
+			CallableParam: refactoring.CallableParam{
+				Callable: sym.callable.GetId(),
+				Param:    sym.node.GetId(),
+			},
+			NewName: newName,
+		}}
+	default:
+		return conf, fmt.Errorf("%s cannot be renamed", sym.node.GetId())
+	}
+	return conf, nil
+}
+
+// rename computes the edits required to rename the symbol at the cursor.
+//
+// The rename is applied to every open document and to every file which
+// those documents include, but not to files which are not open and include
+// the renamed symbol.
+func (s *server) rename(params *renameParams) (*workspaceEdit, error) {
+	doc := s.docs[params.TextDocument.URI]
+	if doc == nil {
+		return nil, &responseError{
+			Code:    codeInvalidParams,
+			Message: "document is not open",
+		}
+	}
+	if !validIdentifier(params.NewName) {
+		return nil, &responseError{
+			Code:    codeInvalidParams,
+			Message: params.NewName + " is not a valid identifier",
+		}
+	}
+	if _, err := s.parse(doc); err != nil {
+		return nil, fmt.Errorf("cannot rename in a file with errors: %w", err)
+	}
+	sym := resolve(doc.ast, doc.path, cursorAt(doc.text, params.Position))
+	if sym == nil {
+		return nil, fmt.Errorf("no symbol found to rename")
+	}
+	conf, err := renameConfig(sym, params.NewName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refactor modifies the asts it is given, so use fresh copies rather
+	// than the cached ones.
+	asts := make([]*syntax.Ast, 0, len(s.docs))
+	files := make(map[string]struct{})
+	for _, d := range s.docs {
+		if ast, err := s.parse(d); err == nil && ast != nil {
+			asts = append(asts, ast)
+			for f := range ast.Files {
+				files[f] = struct{}{}
+			}
+		}
+	}
+	edit, err := refactoring.Refactor(asts, conf)
+	if err != nil {
+		return nil, err
+	}
+	result := workspaceEdit{
+		Changes: make(map[string][]textEdit),
+	}
+	var parser syntax.Parser
+	for f := range files {
+		text := s.fileText(f)
+		ast, err := parser.UncheckedParse(text, f)
+		if err != nil {
+			return nil, err
+		}
+		if count, err := edit.Apply(ast); err != nil {
+			return nil, err
+		} else if count == 0 {
+			continue
+		}
+		result.Changes[pathToUri(f)] = []textEdit{{
+			Range:   fullRange(text),
+			NewText: ast.Format(),
+		}}
+	}
+	return &result, nil
+}
+
+// fullRange returns the range covering the entire text.
+func fullRange(text []byte) lspRange {
+	lines := bytes.Count(text, []byte{'\n'})
+	return lspRange{
+		End: position{
+			Line:      lines,
+			Character: utf16Len(lineText(text, lines)),
+		},
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/martian-lang/martian/martian/syntax"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+// An open text document.
+type document struct {
+	uri     string
+	path    string
+	text    []byte
+	version int
+
+	// The most recent successfully parsed ast for the document.  This is
+	// kept even if the document subsequently fails to parse, so that
+	// navigation continues to work while the user is typing.
+	ast *syntax.Ast
+}
+
+type server struct {
+	conn     *conn
+	mroPaths []string
+	docs     map[string]*document
+	shutdown bool
+}
+
+func newServer(r io.Reader, w io.Writer, mroPaths []string) *server {
+	return &server{
+		conn:     newConn(r, w),
+		mroPaths: mroPaths,
+		docs:     make(map[string]*document),
+	}
+}
+
+// run processes messages until the client sends an exit notification or
+// closes the stream, and returns the process exit code.
+func (s *server) run() int {
+	for {
+		msg, err := s.conn.read()
+		if err != nil {
+			var rerr *responseError
+			if errors.As(err, &rerr) {
+				if err := s.conn.reply(nil, nil, rerr); err != nil {
+					return 1
+				}
+				continue
+			}
+			if err == io.EOF && s.shutdown {
+				return 0
+			}
+			return 1
+		}
+		if msg.Method == "exit" {
+			if s.shutdown {
+				return 0
+			}
+			return 1
+		}
+		if msg.ID != nil {
+			result, err := s.handleRequest(msg)
+			if err := s.conn.reply(msg.ID, result, err); err != nil {
+				return 1
+			}
+		} else {
+			s.handleNotification(msg)
+		}
+	}
+}
+
+func (s *server) handleRequest(msg *message) (interface{}, error) {
+	switch msg.Method {
+	case "initialize":
+		var params initializeParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.initialize(&params), nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(&params), nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(&params), nil
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(&params), nil
+	case "textDocument/rename":
+		var params renameParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.rename(&params)
+	default:
+		return nil, &responseError{
+			Code:    codeMethodNotFound,
+			Message: "method not supported: " + msg.Method,
+		}
+	}
+}
+
+func (s *server) handleNotification(msg *message) {
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if unmarshalParams(msg, &params) == nil {
+			s.didOpen(&params)
+		}
+	case "textDocument/didChange":
+		var params didChangeParams
+		if unmarshalParams(msg, &params) == nil {
+			s.didChange(&params)
+		}
+	case "textDocument/didClose":
+		var params didCloseParams
+		if unmarshalParams(msg, &params) == nil {
+			s.didClose(&params)
+		}
+	case "textDocument/didSave":
+		// Other open documents may include the saved file.
+		for _, doc := range s.docs {
+			s.analyze(doc)
+		}
+	}
+}
+
+func unmarshalParams(msg *message, v interface{}) error {
+	if err := json.Unmarshal(msg.Params, v); err != nil {
+		return &responseError{
+			Code:    codeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (s *server) initialize(params *initializeParams) *initializeResult {
+	if opts := params.InitializationOptions; opts != nil && opts.MroPath != "" {
+		s.mroPaths = util.ParseMroPath(opts.MroPath)
+	} else if len(s.mroPaths) == 0 && params.RootURI != "" {
+		if p := uriToPath(params.RootURI); p != "" {
+			s.mroPaths = []string{p}
+		}
+	}
+	var result initializeResult
+	result.ServerInfo.Name = "mro lsp"
+	result.ServerInfo.Version = util.GetVersion()
+	result.Capabilities.TextDocumentSync = 1
+	result.Capabilities.DefinitionProvider = true
+	result.Capabilities.HoverProvider = true
+	result.Capabilities.RenameProvider = true
+	result.Capabilities.CompletionProvider = &struct {
+		TriggerCharacters []string `json:"triggerCharacters"`
+	}{
+		TriggerCharacters: []string{"."},
+	}
+	return &result
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.Clean(u.Path)
+}
+
+func pathToUri(p string) string {
+	u := url.URL{
+		Scheme: "file",
+		Path:   p,
+	}
+	return u.String()
+}
+
+func (s *server) didOpen(params *didOpenParams) {
+	doc := &document{
+		uri:     params.TextDocument.URI,
+		path:    uriToPath(params.TextDocument.URI),
+		text:    []byte(params.TextDocument.Text),
+		version: params.TextDocument.Version,
+	}
+	if doc.path == "" {
+		return
+	}
+	s.docs[doc.uri] = doc
+	s.analyze(doc)
+}
+
+func (s *server) didChange(params *didChangeParams) {
+	doc := s.docs[params.TextDocument.URI]
+	if doc == nil || len(params.ContentChanges) == 0 {
+		return
+	}
+	doc.text = []byte(params.ContentChanges[len(params.ContentChanges)-1].Text)
+	doc.version = params.TextDocument.Version
+	s.analyze(doc)
+}
+
+func (s *server) didClose(params *didCloseParams) {
+	delete(s.docs, params.TextDocument.URI)
+	// Clear any diagnostics for the document.
+	_ = s.conn.notify("textDocument/publishDiagnostics",
+		&publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+}
+
+// parse compiles the document, returning the resulting ast, if any, and
+// any errors.
+func (s *server) parse(doc *document) (*syntax.Ast, error) {
+	var parser syntax.Parser
+	_, _, ast, err := parser.ParseSourceBytes(doc.text, doc.path,
+		s.mroPaths, false)
+	return ast, err
+}
+
+// analyze compiles the document and publishes the diagnostics.
+func (s *server) analyze(doc *document) {
+	ast, err := s.parse(doc)
+	if ast != nil {
+		doc.ast = ast
+	}
+	diags := []diagnostic{}
+	for _, err := range flattenErrors(err, nil) {
+		diags = append(diags, s.makeDiagnostic(doc, err))
+	}
+	version := doc.version
+	_ = s.conn.notify("textDocument/publishDiagnostics",
+		&publishDiagnosticsParams{
+			URI:         doc.uri,
+			Version:     &version,
+			Diagnostics: diags,
+		})
+}
+
+// flattenErrors splits an ErrorList into its component errors.
+func flattenErrors(err error, errs []error) []error {
+	if err == nil {
+		return errs
+	}
+	if list, ok := err.(syntax.ErrorList); ok {
+		for _, err := range list {
+			errs = flattenErrors(err, errs)
+		}
+		return errs
+	}
+	return append(errs, err)
+}
+
+func (s *server) makeDiagnostic(doc *document, err error) diagnostic {
+	d := diagnostic{
+		Severity: severityError,
+		Source:   "mro",
+		Message:  errorMessage(err),
+	}
+	loc, ok := syntax.ErrorLocation(err)
+	if !ok {
+		return d
+	}
+	// If the error is in an included file, report it on the include
+	// directive which lead to that file.
+	for loc.File != nil && loc.File.FullPath != doc.path &&
+		len(loc.File.IncludedFrom) > 0 {
+		if loc.File.FileName != "" {
+			d.Message = "In included file " + loc.File.FileName +
+				": " + d.Message
+		}
+		loc = *loc.File.IncludedFrom[0]
+	}
+	if loc.File != nil && loc.File.FullPath != doc.path || loc.Line < 1 {
+		return d
+	}
+	line := lineText(doc.text, loc.Line-1)
+	col := loc.Col - 1
+	if col < 0 || col > len(line) {
+		col = 0
+	}
+	d.Range = lspRange{
+		Start: position{
+			Line:      loc.Line - 1,
+			Character: utf16Len(line[:col]),
+		},
+		End: position{
+			Line:      loc.Line - 1,
+			Character: utf16Len(line),
+		},
+	}
+	return d
+}
+
+// errorMessage strips the location information from an error message, since
+// that is conveyed by the diagnostic range instead.
+func errorMessage(err error) string {
+	msg := strings.TrimPrefix(err.Error(), "MRO ")
+	msg, _, _ = strings.Cut(msg, "\n    at ")
+	msg = strings.TrimSuffix(msg, " at ")
+	// Parse errors include the source line with a caret under the error.
+	if strings.HasSuffix(msg, "^") {
+		for i := 0; i < 2; i++ {
+			if j := strings.LastIndexByte(msg, '\n'); j >= 0 {
+				msg = msg[:j]
+			}
+		}
+	}
+	return msg
+}
+
+// fileText returns the content of a file, using the content of the open
+// document for it if there is one.
+func (s *server) fileText(p string) []byte {
+	if doc := s.docs[pathToUri(p)]; doc != nil {
+		return doc.text
+	}
+	for _, doc := range s.docs {
+		if doc.path == p {
+			return doc.text
+		}
+	}
+	b, _ := os.ReadFile(p)
+	return b
+}
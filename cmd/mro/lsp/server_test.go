@@ -0,0 +1,298 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testStagesMro = `struct RESULT(
+    int count,
+)
+
+stage COUNT(
+    in  int    value "the value to count",
+    out RESULT result,
+    src py     "stages/count",
+)
+`
+
+const testPipelineMro = `@include "stages.mro"
+
+pipeline PIPE(
+    in  int    value,
+    out RESULT result,
+)
+{
+    call COUNT(
+        value = self.value,
+    )
+
+    return (
+        result = COUNT.result,
+    )
+}
+`
+
+func setupServer(t *testing.T) (*server, *bytes.Buffer, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "stages.mro"),
+		[]byte(testStagesMro), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pipe := filepath.Join(dir, "pipeline.mro")
+	if err := os.WriteFile(pipe, []byte(testPipelineMro), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	s := newServer(strings.NewReader(""), &out, []string{dir})
+	s.didOpen(&didOpenParams{TextDocument: textDocumentItem{
+		URI:     pathToUri(pipe),
+		Version: 1,
+		Text:    testPipelineMro,
+	}})
+	return s, &out, pipe
+}
+
+// Returns the last diagnostics which were published.
+func lastDiagnostics(t *testing.T, out *bytes.Buffer) []diagnostic {
+	t.Helper()
+	var result []diagnostic
+	s := newConn(bytes.NewReader(out.Bytes()), nil)
+	for {
+		msg, err := s.read()
+		if err != nil {
+			return result
+		}
+		if msg.Method == "textDocument/publishDiagnostics" {
+			var params publishDiagnosticsParams
+			if err := json.Unmarshal(msg.Params, &params); err != nil {
+				t.Fatal(err)
+			}
+			result = params.Diagnostics
+		}
+	}
+}
+
+// Returns the position of the nth occurrence of word in text.
+func positionOf(text, word string, n int) position {
+	for i, line := range strings.Split(text, "\n") {
+		for j := findWord(line, word, 0); j >= 0; j = findWord(line, word, j+1) {
+			if n == 0 {
+				return position{Line: i, Character: j}
+			}
+			n--
+		}
+	}
+	panic(word + " not found")
+}
+
+func TestDiagnostics(t *testing.T) {
+	s, out, pipe := setupServer(t)
+	if diags := lastDiagnostics(t, out); len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+	broken := strings.Replace(testPipelineMro,
+		"value = self.value", "value = self.missing", 1)
+	s.didChange(&didChangeParams{
+		TextDocument: versionedTextDocumentIdentifier{
+			URI:     pathToUri(pipe),
+			Version: 2,
+		},
+		ContentChanges: []textDocumentContentChangeEvent{{Text: broken}},
+	})
+	diags := lastDiagnostics(t, out)
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %v", diags)
+	}
+	if diags[0].Range.Start.Line != 8 {
+		t.Errorf("expected error on line 8, got %d", diags[0].Range.Start.Line)
+	}
+	if !strings.Contains(diags[0].Message, "missing") {
+		t.Errorf("unexpected message %q", diags[0].Message)
+	}
+	if strings.Contains(diags[0].Message, pipe) {
+		t.Errorf("message should not include location: %q", diags[0].Message)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	s, _, pipe := setupServer(t)
+	uri := pathToUri(pipe)
+	check := func(word string, n int, file string, line, char int) {
+		t.Helper()
+		locs := s.definition(&textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     positionOf(testPipelineMro, word, n),
+		})
+		if len(locs) != 1 {
+			t.Errorf("expected definition for %s", word)
+			return
+		}
+		loc := locs[0]
+		if !strings.HasSuffix(loc.URI, file) {
+			t.Errorf("%s: expected %s, got %s", word, file, loc.URI)
+		}
+		if loc.Range.Start.Line != line || loc.Range.Start.Character != char {
+			t.Errorf("%s: expected %d:%d, got %d:%d", word, line, char,
+				loc.Range.Start.Line, loc.Range.Start.Character)
+		}
+	}
+	// The callable, in the call statement.
+	check("COUNT", 0, "stages.mro", 4, 6)
+	// The struct type.
+	check("RESULT", 0, "stages.mro", 0, 7)
+	// A bound parameter name.
+	check("value", 1, "stages.mro", 5, 15)
+	// A reference to a pipeline input.
+	check("value", 2, "pipeline.mro", 3, 15)
+	// A reference to a call output.
+	check("result", 2, "stages.mro", 6, 15)
+}
+
+func TestHover(t *testing.T) {
+	s, _, pipe := setupServer(t)
+	h := s.hover(&textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: pathToUri(pipe)},
+		Position:     positionOf(testPipelineMro, "value", 1),
+	})
+	if h == nil {
+		t.Fatal("expected hover")
+	}
+	if !strings.Contains(h.Contents.Value, "in  int value") ||
+		!strings.Contains(h.Contents.Value, "the value to count") {
+		t.Errorf("unexpected hover content %q", h.Contents.Value)
+	}
+	h = s.hover(&textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: pathToUri(pipe)},
+		Position:     positionOf(testPipelineMro, "COUNT", 0),
+	})
+	if h == nil {
+		t.Fatal("expected hover")
+	}
+	if !strings.Contains(h.Contents.Value, "stage COUNT(") ||
+		!strings.Contains(h.Contents.Value, "out RESULT result") {
+		t.Errorf("unexpected hover content %q", h.Contents.Value)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	s, _, pipe := setupServer(t)
+	uri := pathToUri(pipe)
+	text := strings.Replace(testPipelineMro,
+		"        value = self.value,\n", "        \n", 1)
+	s.didChange(&didChangeParams{
+		TextDocument: versionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []textDocumentContentChangeEvent{{
+			Text: testPipelineMro,
+		}, {
+			Text: text,
+		}},
+	})
+	labels := func(items []completionItem) string {
+		l := make([]string, len(items))
+		for i, item := range items {
+			l[i] = item.Label
+		}
+		return strings.Join(l, ",")
+	}
+	// The modified text doesn't compile, so completion uses the ast from
+	// before the change, in which value was already bound.
+	items := s.completion(&textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Position:     position{Line: 8, Character: 8},
+	})
+	if l := labels(items); l != "" && l != "value" {
+		t.Errorf("unexpected completions %s", l)
+	}
+	items = s.completion(&textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Position:     position{Line: 12, Character: 23},
+	})
+	if l := labels(items); l != "result" {
+		t.Errorf("expected output completions, got %s", l)
+	}
+}
+
+func TestRename(t *testing.T) {
+	s, _, pipe := setupServer(t)
+	edit, err := s.rename(&renameParams{
+		textDocumentPositionParams: textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: pathToUri(pipe)},
+			Position:     positionOf(testPipelineMro, "COUNT", 0),
+		},
+		NewName: "TALLY",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edit.Changes) != 2 {
+		t.Fatalf("expected changes to 2 files, got %d", len(edit.Changes))
+	}
+	for uri, edits := range edit.Changes {
+		if len(edits) != 1 {
+			t.Errorf("expected 1 edit for %s, got %d", uri, len(edits))
+			continue
+		}
+		if strings.Contains(edits[0].NewText, "COUNT") ||
+			!strings.Contains(edits[0].NewText, "TALLY") {
+			t.Errorf("incorrect rename in %s:\n%s", uri, edits[0].NewText)
+		}
+	}
+	if _, err := s.rename(&renameParams{
+		textDocumentPositionParams: textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: pathToUri(pipe)},
+			Position:     positionOf(testPipelineMro, "COUNT", 0),
+		},
+		NewName: "not valid",
+	}); err == nil {
+		t.Error("expected error for invalid name")
+	}
+}
+
+func TestProtocol(t *testing.T) {
+	var in bytes.Buffer
+	write := func(msg string) {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	write(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	write(`{"jsonrpc":"2.0","method":"initialized","params":{}}`)
+	write(`{"jsonrpc":"2.0","id":2,"method":"unknown/method","params":{}}`)
+	write(`{"jsonrpc":"2.0","id":3,"method":"shutdown"}`)
+	write(`{"jsonrpc":"2.0","method":"exit"}`)
+	var out bytes.Buffer
+	if code := newServer(&in, &out, nil).run(); code != 0 {
+		t.Errorf("expected clean exit, got %d", code)
+	}
+	c := newConn(&out, nil)
+	var responses []*message
+	for {
+		msg, err := c.read()
+		if err != nil {
+			break
+		}
+		responses = append(responses, msg)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("expected 3 responses, got %d", len(responses))
+	}
+	var init initializeResult
+	if err := json.Unmarshal(responses[0].Result, &init); err != nil {
+		t.Error(err)
+	} else if !init.Capabilities.DefinitionProvider {
+		t.Error("expected definition support")
+	}
+	if responses[1].Error == nil || responses[1].Error.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got %v", responses[1].Error)
+	}
+	if string(responses[2].Result) != "null" {
+		t.Errorf("expected null shutdown result, got %s", responses[2].Result)
+	}
+}
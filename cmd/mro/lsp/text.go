@@ -0,0 +1,144 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package lsp
+
+import (
+	"bytes"
+	"unicode/utf16"
+)
+
+// lineText returns the given zero-based line of text, without the trailing
+// newline.
+func lineText(text []byte, line int) string {
+	for ; line > 0; line-- {
+		i := bytes.IndexByte(text, '\n')
+		if i < 0 {
+			return ""
+		}
+		text = text[i+1:]
+	}
+	if i := bytes.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	return string(bytes.TrimSuffix(text, []byte{'\r'}))
+}
+
+// utf16Len returns the length of s in UTF-16 code units, which is how the
+// language server protocol measures positions within a line.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// byteOffset converts a UTF-16 offset within a line into a byte offset.
+func byteOffset(line string, character int) int {
+	for i, r := range line {
+		if character <= 0 {
+			return i
+		}
+		character -= utf16.RuneLen(r)
+	}
+	return len(line)
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' ||
+		'a' <= b && b <= 'z' ||
+		'A' <= b && b <= 'Z' ||
+		'0' <= b && b <= '9'
+}
+
+// findWord returns the byte offset of the first occurrence of word in line
+// at or after start which is not part of a longer identifier, or -1.
+func findWord(line, word string, start int) int {
+	for start >= 0 && start < len(line) {
+		i := bytes.Index([]byte(line[start:]), []byte(word))
+		if i < 0 {
+			return -1
+		}
+		i += start
+		end := i + len(word)
+		if (i == 0 || !isIdentByte(line[i-1])) &&
+			(end >= len(line) || !isIdentByte(line[end])) {
+			return i
+		}
+		start = end
+	}
+	return -1
+}
+
+// The identifier under the cursor, which may be a dotted reference
+// such as CALL.output.member.
+type cursor struct {
+	// Zero-based line number.
+	line int
+	// The text of the line.
+	text string
+	// Byte offset of the cursor in the line.
+	offset int
+	// Byte offsets of the start and end of the word in the line.
+	start, end int
+}
+
+func (c *cursor) word() string {
+	return c.text[c.start:c.end]
+}
+
+// prefix returns the part of the word before the cursor.
+func (c *cursor) prefix() string {
+	return c.text[c.start:c.offset]
+}
+
+// segment returns the index of the dot-separated component of the word
+// which the cursor is in.
+func (c *cursor) segment() int {
+	return bytes.Count([]byte(c.prefix()), []byte{'.'})
+}
+
+// before returns the text on the line before the word.
+func (c *cursor) before() string {
+	return c.text[:c.start]
+}
+
+// after returns the text on the line after the word.
+func (c *cursor) after() string {
+	return c.text[c.end:]
+}
+
+func cursorAt(text []byte, pos position) *cursor {
+	c := cursor{
+		line: pos.Line,
+		text: lineText(text, pos.Line),
+	}
+	c.offset = byteOffset(c.text, pos.Character)
+	c.start = c.offset
+	for c.start > 0 && (isIdentByte(c.text[c.start-1]) || c.text[c.start-1] == '.') {
+		c.start--
+	}
+	c.end = c.offset
+	for c.end < len(c.text) && isIdentByte(c.text[c.end]) {
+		c.end++
+	}
+	// Don't include leading dots.
+	for c.start < c.offset && c.text[c.start] == '.' {
+		c.start++
+	}
+	return &c
+}
+
+// wordRange returns the range of the identifier at the given byte offset.
+func wordRange(line int, text string, start, end int) lspRange {
+	return lspRange{
+		Start: position{
+			Line:      line,
+			Character: utf16Len(text[:start]),
+		},
+		End: position{
+			Line:      line,
+			Character: utf16Len(text[:end]),
+		},
+	}
+}
@@ -12,13 +12,18 @@ import (
 	"strings"
 
 	"github.com/martian-lang/martian/cmd/mro/check"
+	"github.com/martian-lang/martian/cmd/mro/diff"
 	"github.com/martian-lang/martian/cmd/mro/edit"
 	"github.com/martian-lang/martian/cmd/mro/format"
 	"github.com/martian-lang/martian/cmd/mro/graph"
+	"github.com/martian-lang/martian/cmd/mro/lsp"
+	"github.com/martian-lang/martian/cmd/mro/perf"
+	"github.com/martian-lang/martian/cmd/mro/rocrate"
+	"github.com/martian-lang/martian/cmd/mro/verify"
 	"github.com/martian-lang/martian/martian/util"
 )
 
-const usage = "Usage: mro [help] [check | edit | format | graph] ..."
+const usage = "Usage: mro [help] [check | diff | edit | format | graph | lsp | perf | rocrate | verify] ..."
 
 func main() {
 	if len(os.Args) < 2 {
@@ -42,6 +47,9 @@ func main() {
 	check:
 		Perform static analysis tasks.
 
+	diff:
+		Compare the metadata and outputs of two pipestances.
+
 	edit:
 		Perform various refactoring tasks.
 
@@ -51,6 +59,18 @@ func main() {
 	graph:
 		Render a call graph, or query information about it.
 
+	lsp:
+		Run a language server for editor integration.
+
+	perf:
+		Suggest resource requests from completed pipestances.
+
+	rocrate:
+		Export the provenance of a pipestance as RO-Crate metadata.
+
+	verify:
+		Check the output files of a pipestance against its checksum manifest.
+
 	version:
 		Print the version and exit.`)
 		} else {
@@ -68,12 +88,22 @@ func delegateMain(argv []string) int {
 	switch argv[0] {
 	case "check":
 		return check.Main(argv[1:])
+	case "diff":
+		return diff.Main(argv[1:])
 	case "edit":
 		return edit.Main(argv[1:])
 	case "format":
 		return format.Main(argv[1:])
 	case "graph":
 		return graph.Main(argv[1:])
+	case "lsp":
+		return lsp.Main(argv[1:])
+	case "perf":
+		return perf.Main(argv[1:])
+	case "rocrate":
+		return rocrate.Main(argv[1:])
+	case "verify":
+		return verify.Main(argv[1:])
 	case "-cpuprofile":
 		return cpuProfile(argv[1], argv[2:])
 	case "-memprofile":
@@ -0,0 +1,113 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+// Package perf implements the command line interface for analyzing the
+// performance data recorded by completed pipestances.
+package perf
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/martian-lang/martian/martian/core"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+const usage = "Usage: mro perf suggest [options] <pipestance|_perf>..."
+
+func Main(argv []string) int {
+	util.SetPrintLogger(os.Stderr)
+	if len(argv) > 0 {
+		switch argv[0] {
+		case "suggest":
+			return suggest(argv[1:])
+		case "-h", "--help":
+			// suggest is currently the only action.
+			return suggest(argv)
+		}
+	}
+	fmt.Fprintln(os.Stderr, usage)
+	return 1
+}
+
+func suggest(argv []string) int {
+	var flags flag.FlagSet
+	flags.Init("mro perf suggest", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(),
+			"Generates a resource profile for mrp --resource-profile, "+
+				"or a\npipestance overrides file, with per-stage chunk "+
+				"memory and thread\nrequests based on the _perf files of "+
+				"completed pipestances.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	percentile := flags.Float64("percentile", 95,
+		"The `PERCENTILE` of observed chunk usage to request.")
+	headroom := flags.Float64("headroom", 0.2,
+		"Request this `FRACTION` more than the observed usage.")
+	output := flags.String("o", "",
+		"Write the result to `FILE` instead of standard output.")
+	if err := flags.Parse(argv); err != nil {
+		panic(err)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 1
+	}
+	if *percentile <= 0 || *percentile > 100 {
+		fmt.Fprintln(os.Stderr, "percentile must be in the range (0, 100]")
+		return 1
+	}
+	if *headroom < 0 {
+		fmt.Fprintln(os.Stderr, "headroom cannot be negative")
+		return 1
+	}
+	perfs := make([][]*core.NodePerfInfo, 0, flags.NArg())
+	for _, p := range flags.Args() {
+		perf, err := readPerf(p)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading", p+":", err)
+			return 1
+		}
+		perfs = append(perfs, perf)
+	}
+	b, err := json.MarshalIndent(
+		core.SuggestOverrides(perfs, *percentile, *headroom),
+		"", "    ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	b = append(b, '\n')
+	if *output == "" {
+		_, err = os.Stdout.Write(b)
+	} else {
+		err = os.WriteFile(*output, b, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// readPerf reads the performance data for a pipestance, given either the
+// path to the pipestance directory or to its _perf file.
+func readPerf(p string) ([]*core.NodePerfInfo, error) {
+	if info, err := os.Stat(p); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		p = filepath.Join(p, core.Perf.FileName())
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var perf []*core.NodePerfInfo
+	return perf, json.Unmarshal(b, &perf)
+}
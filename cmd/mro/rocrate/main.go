@@ -0,0 +1,79 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+// Package rocrate implements the command line interface for exporting the
+// provenance of a pipestance as RO-Crate metadata.
+package rocrate
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/martian-lang/martian/martian/core"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+const usage = "Usage: mro rocrate [options] <pipestance | metadata.zip>"
+
+func Main(argv []string) int {
+	util.SetPrintLogger(os.Stderr)
+	var flags flag.FlagSet
+	flags.Init("mro rocrate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(),
+			"Describes a completed pipestance, and each stage which ran in it,\n"+
+				"as RO-Crate JSON-LD metadata, which makes the pipestance\n"+
+				"directory an RO-Crate.  This is the same metadata which\n"+
+				"mrp --rocrate writes when a pipestance completes.  The\n"+
+				"argument may also be the metadata zip archive written by\n"+
+				"mrp --zip.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	output := flags.String("o", "",
+		"Write the result to `FILE`, or - for standard output.  "+
+			"By default it is written to\n"+core.ROCrateMetadataFile+
+			" in the pipestance directory, or in the directory\n"+
+			"containing the metadata zip archive.")
+	if err := flags.Parse(argv); err != nil {
+		panic(err)
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+	crate, err := core.ExportROCrate(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	b, err := json.MarshalIndent(crate, "", "    ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	b = append(b, '\n')
+	switch *output {
+	case "-":
+		_, err = os.Stdout.Write(b)
+	case "":
+		dir := flags.Arg(0)
+		if info, serr := os.Stat(dir); serr == nil && !info.IsDir() {
+			// The metadata zip archive is in the pipestance directory.
+			dir = filepath.Dir(dir)
+		}
+		err = os.WriteFile(filepath.Join(dir,
+			core.ROCrateMetadataFile), b, 0644)
+	default:
+		err = os.WriteFile(*output, b, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
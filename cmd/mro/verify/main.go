@@ -0,0 +1,69 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+// Package verify implements the command line interface for checking the
+// output files of a pipestance against its checksum manifest.
+package verify
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/martian-lang/martian/martian/core"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+const usage = "Usage: mro verify [options] <pipestance>"
+
+func Main(argv []string) int {
+	util.SetPrintLogger(os.Stderr)
+	var flags flag.FlagSet
+	flags.Init("mro verify", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(),
+			"Checks the output files of a completed pipestance against the\n"+
+				"sha256 checksums which mrp recorded in its _manifest file.\n"+
+				"Exits with status 1 if any files are missing, modified, or\n"+
+				"were added to the outs directory, or 2 on error.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	asJson := flags.Bool("json", false, "Write the mismatched files as json.")
+	if err := flags.Parse(argv); err != nil {
+		panic(err)
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	count, mismatches, err := core.VerifyOutputManifest(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *asJson {
+		if mismatches == nil {
+			mismatches = []*core.ManifestMismatch{}
+		}
+		b, err := json.MarshalIndent(mismatches, "", "    ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		fmt.Println(string(b))
+	} else {
+		for _, m := range mismatches {
+			fmt.Println(m.String())
+		}
+		if len(mismatches) == 0 {
+			fmt.Fprintf(os.Stderr, "Verified %d files.\n", count)
+		}
+	}
+	if len(mismatches) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -14,40 +14,45 @@ import (
 	"github.com/martian-lang/martian/martian/syntax"
 )
 
-func getParamStructs(ast *syntax.Ast, param syntax.StructMemberLike,
-	structs []*syntax.StructType,
-	structSet map[string]struct{}) []*syntax.StructType {
-	t := ast.TypeTable.Get(syntax.TypeId{Tname: param.GetTname().Tname})
-	if s, ok := t.(*syntax.StructType); ok {
-		if _, ok := structSet[s.Id]; !ok {
-			structSet[s.Id] = struct{}{}
+func getParamTypes(ast *syntax.Ast, param syntax.StructMemberLike,
+	structs []*syntax.StructType, enums []*syntax.EnumType,
+	structSet map[string]struct{}) ([]*syntax.StructType, []*syntax.EnumType) {
+	switch t := ast.TypeTable.Get(syntax.TypeId{Tname: param.GetTname().Tname}).(type) {
+	case *syntax.StructType:
+		if _, ok := structSet[t.Id]; !ok {
+			structSet[t.Id] = struct{}{}
 			// Recursively get struct types
-			for _, m := range s.Members {
-				structs = getParamStructs(ast, m, structs, structSet)
+			for _, m := range t.Members {
+				structs, enums = getParamTypes(ast, m, structs, enums, structSet)
 			}
-			structs = append(structs, s)
+			structs = append(structs, t)
+		}
+	case *syntax.EnumType:
+		if _, ok := structSet[t.Id]; !ok {
+			structSet[t.Id] = struct{}{}
+			enums = append(enums, t)
 		}
 	}
-	return structs
+	return structs, enums
 }
 
-func getStructs(ast *syntax.Ast, callable syntax.Callable,
+func getTypes(ast *syntax.Ast, callable syntax.Callable,
 	onlyIns bool,
-	structs []*syntax.StructType,
-	structSet map[string]struct{}) []*syntax.StructType {
+	structs []*syntax.StructType, enums []*syntax.EnumType,
+	structSet map[string]struct{}) ([]*syntax.StructType, []*syntax.EnumType) {
 	if ins := callable.GetInParams(); ins != nil {
 		for _, arg := range ins.List {
-			structs = getParamStructs(ast, arg, structs, structSet)
+			structs, enums = getParamTypes(ast, arg, structs, enums, structSet)
 		}
 	}
 	if !onlyIns {
 		if outs := callable.GetOutParams(); outs != nil {
 			for _, arg := range outs.List {
-				structs = getParamStructs(ast, arg, structs, structSet)
+				structs, enums = getParamTypes(ast, arg, structs, enums, structSet)
 			}
 		}
 	}
-	return structs
+	return structs, enums
 }
 
 func anySplit(callables []syntax.Callable) (bool, bool) {
@@ -109,6 +114,7 @@ func makeCallableGoRaw(ast *syntax.Ast, pkg, mroName string, stageNames []string
 	callables := getCallables(ast, mroName, stageNames, pipeline)
 
 	var structs []*syntax.StructType
+	var enums []*syntax.EnumType
 	if pkg != "" {
 		buffer.WriteString("package ")
 		buffer.WriteString(pkg)
@@ -116,7 +122,8 @@ func makeCallableGoRaw(ast *syntax.Ast, pkg, mroName string, stageNames []string
 
 		if seenStructs != nil {
 			for _, c := range callables {
-				structs = getStructs(ast, c, onlyIns, structs, seenStructs)
+				structs, enums = getTypes(ast, c, onlyIns,
+					structs, enums, seenStructs)
 			}
 		}
 		if split, chunkOuts := anySplit(callables); split {
@@ -143,9 +150,13 @@ import (
 		}
 	} else if seenStructs != nil {
 		for _, c := range callables {
-			structs = getStructs(ast, c, onlyIns, structs, seenStructs)
+			structs, enums = getTypes(ast, c, onlyIns,
+				structs, enums, seenStructs)
 		}
 	}
+	for _, e := range enums {
+		writeEnum(&buffer, e)
+	}
 	for _, s := range structs {
 		writeStruct(&buffer, &ast.TypeTable, s)
 	}
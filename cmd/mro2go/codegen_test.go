@@ -33,6 +33,7 @@ func ExampleGoName() {
 		"param_name",
 		"_paramName",
 		"_param_name",
+		"lib.STAGE_NAME",
 	} {
 		fmt.Println(n, "->", GoName(n))
 	}
@@ -47,6 +48,7 @@ func ExampleGoName() {
 	// param_name -> ParamName
 	// _paramName -> ParamName
 	// _param_name -> ParamName
+	// lib.STAGE_NAME -> LibStageName
 }
 
 // Test that the go output is the same as what is being tested
@@ -115,6 +117,109 @@ func TestStructPipelineMroToGo(t *testing.T) {
 	}
 }
 
+// Test that default values for inputs are documented.
+func TestDefaultMroToGo(t *testing.T) {
+	const mrosrc = `stage THREADED(
+    in  int threads = 4 "The number of threads",
+    src py  "stages/threaded",
+)
+`
+	var dest bytes.Buffer
+	if err := MroToGo(&dest,
+		[]byte(mrosrc), "threaded.mro", nil,
+		nil,
+		"main", "threaded.go", false, false,
+		make(map[string]struct{})); err != nil {
+		t.Fatal(err)
+	}
+	const expect = "\t// The number of threads\n" +
+		"\t//\n" +
+		"\t// Default: 4\n" +
+		"\tThreads int `json:\"threads\"`\n"
+	if goSrc := dest.String(); !strings.Contains(goSrc, expect) {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expect, goSrc)
+	}
+}
+
+// Test that enum types generate typed constants.
+func TestEnumMroToGo(t *testing.T) {
+	const mrosrc = `# The library chemistry.
+enum CHEMISTRY(
+    "SC3Pv2",
+    # Detect the chemistry from the reads.
+    "auto-detect",
+)
+
+stage COUNT(
+    in  CHEMISTRY   chemistry,
+    in  CHEMISTRY[] chemistries,
+    src py          "stages/count",
+)
+`
+	var dest bytes.Buffer
+	if err := MroToGo(&dest,
+		[]byte(mrosrc), "count.mro", nil,
+		nil,
+		"main", "count.go", false, false,
+		make(map[string]struct{})); err != nil {
+		t.Fatal(err)
+	}
+	goSrc := dest.String()
+	for _, expect := range []string{
+		"// The library chemistry.\n" +
+			"type Chemistry string\n\n" +
+			"const (\n" +
+			"\tChemistrySc3pv2 Chemistry = \"SC3Pv2\"\n" +
+			"\t// Detect the chemistry from the reads.\n" +
+			"\tChemistryAutoDetect Chemistry = \"auto-detect\"\n" +
+			")\n",
+		"\tChemistry   Chemistry   `json:\"chemistry\"`\n",
+		"\tChemistries []Chemistry `json:\"chemistries\"`\n",
+	} {
+		if !strings.Contains(goSrc, expect) {
+			t.Errorf("Expected:\n%s\n\nGot:\n%s", expect, goSrc)
+		}
+	}
+}
+
+// Test that non-null struct parameters are not generated as pointers.
+func TestNonNullMroToGo(t *testing.T) {
+	const mrosrc = `struct BAM(
+    bam  bam,
+    bam! index,
+)
+
+filetype bam;
+
+stage ALIGN(
+    in  BAM!   reads,
+    in  BAM    maybe_reads,
+    in  BAM[]! read_list,
+    out BAM!   aligned,
+    src py     "stages/align",
+)
+`
+	var dest bytes.Buffer
+	if err := MroToGo(&dest,
+		[]byte(mrosrc), "align.mro", nil,
+		nil,
+		"main", "align.go", false, false,
+		make(map[string]struct{})); err != nil {
+		t.Fatal(err)
+	}
+	goSrc := dest.String()
+	for _, expect := range []string{
+		"\tReads      Bam    `json:\"reads\"`\n",
+		"\tMaybeReads *Bam   `json:\"maybe_reads\"`\n",
+		"\tReadList   []*Bam `json:\"read_list\"`\n",
+		"\tAligned Bam `json:\"aligned\"`\n",
+	} {
+		if !strings.Contains(goSrc, expect) {
+			t.Errorf("Expected:\n%s\n\nGot:\n%s", expect, goSrc)
+		}
+	}
+}
+
 func serialize(t *testing.T, obj interface{}, expected string) {
 	t.Helper()
 	if b, err := json.MarshalIndent(obj, "\t", "\t"); err != nil {
@@ -65,9 +65,57 @@ func writeStruct(buffer *bytes.Buffer, lookup *syntax.TypeLookup, s *syntax.Stru
 	buffer.WriteString("}\n\n")
 }
 
+func writeEnum(buffer *bytes.Buffer, e *syntax.EnumType) {
+	prefix := GoName(e.Id)
+
+	if len(e.Node.Comments) > 0 {
+		for _, c := range e.Node.Comments {
+			buffer.WriteString("// ")
+			buffer.WriteString(strings.TrimSpace(strings.TrimLeft(c, "#")))
+			buffer.WriteRune('\n')
+		}
+	} else {
+		fmt.Fprintf(buffer,
+			"// The legal values for the %s enum.\n",
+			e.Id)
+	}
+	fmt.Fprintf(buffer,
+		"type %s string\n\nconst (\n",
+		prefix)
+	for _, v := range e.Values {
+		for _, c := range v.Node.Comments {
+			fmt.Fprintf(buffer,
+				"\t//%s\n",
+				c[1:])
+		}
+		fmt.Fprintf(buffer,
+			"\t%s%s %s = %s\n",
+			prefix, enumValueGoName(v.Value), prefix,
+			strconv.Quote(v.Value))
+	}
+	buffer.WriteString(")\n\n")
+}
+
+// Convert an enum value into a suffix for the name of its go constant.
+func enumValueGoName(value string) string {
+	name := GoName(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, value))
+	if name == "" {
+		return "Empty"
+	}
+	return name
+}
+
 // Convert mro stage and variable names into appropriate exported go names.
 func GoName(stageName string) string {
-	parts := strings.Split(stageName, "_")
+	// Names from namespaced includes, e.g. lib.STAGE, become LibStage.
+	parts := strings.FieldsFunc(stageName, func(r rune) bool {
+		return r == '_' || r == '.'
+	})
 	var result bytes.Buffer
 	for _, p := range parts {
 		for i, r := range p {
@@ -142,6 +190,15 @@ func writeParam(buffer *bytes.Buffer, lookup *syntax.TypeLookup, param syntax.St
 		} else {
 			buffer.WriteRune('\n')
 		}
+		spacer = true
+	}
+	if p, ok := param.(*syntax.InParam); ok && p.Default != nil {
+		if spacer {
+			buffer.WriteString("\t//\n")
+		}
+		buffer.WriteString("\t// Default: ")
+		buffer.WriteString(syntax.FormatExp(p.Default, "\t// "))
+		buffer.WriteRune('\n')
 	}
 	tid := param.GetTname()
 	buffer.WriteRune('\t')
@@ -166,12 +223,17 @@ func writeParam(buffer *bytes.Buffer, lookup *syntax.TypeLookup, param syntax.St
 	case syntax.KindString, syntax.KindFile, syntax.KindPath:
 		buffer.WriteString("string")
 	default:
-		if _, ok := lookup.Get(syntax.TypeId{
-			Tname: tid.Tname}).(*syntax.StructType); ok {
-			// Struct type
-			buffer.WriteRune('*')
+		switch lookup.Get(syntax.TypeId{Tname: tid.Tname}).(type) {
+		case *syntax.StructType:
+			// Struct type.  Non-null struct values don't need a pointer,
+			// though elements of collections still might be null.
+			if !param.IsNonNull() || tid.ArrayDim > 0 || tid.MapDim > 0 {
+				buffer.WriteRune('*')
+			}
 			buffer.WriteString(GoName(tid.Tname))
-		} else {
+		case *syntax.EnumType:
+			buffer.WriteString(GoName(tid.Tname))
+		default:
 			buffer.WriteString("string")
 		}
 	}
@@ -37,8 +37,11 @@ type mrpConfiguration struct {
 	mroVersion     string
 	uiport         string
 	authKey        string
+	statusChannel  string
+	rerun          string
 	requireAuth    bool
 	noExit         bool
+	dryRun         bool
 	cert           *tls.Config
 }
 
@@ -102,6 +105,8 @@ Options:
     --jobmode=MODE      Job manager to use. Valid options:
                             local (default)
                             A cluster job mode listed such as sge, lsf, or slurm
+                            kubernetes, using kubernetes.template
+                            A job manager registered in this build of mrp
                             A file <jobmode>.template
     --localcores=NUM    Set max cores the pipeline may request at one time.
                             Only applies to local jobs.
@@ -109,6 +114,12 @@ Options:
                             Only applies to local jobs.
     --localvmem=NUM     Set max virtual address space in GB for the pipeline.
                             Only applies to local jobs.
+    --localresource=NAME:NUM[,NAME:NUM...]
+                        Limit the number of a named resource, such as a
+                        license or database connection, which local jobs may
+                        use at one time.  Jobs request these through the
+                        special resource, e.g. special = "db_conn:1".
+                            Only applies to local jobs.
     --mempercore=NUM    Reserve enough threads for each job to ensure enough
                         memory will be available, assuming each core on your
                         cluster has at least this much memory available.
@@ -122,6 +133,11 @@ Options:
 
     --vdrmode=MODE      Enables Volatile Data Removal. Valid options:
                             post, rolling (default), strict, or disable
+    --manifest=MODE     Record sha256 checksums of output files on completion,
+                        for use with mro verify.  Valid options:
+                            outs (default), all, or disable
+                        all includes stage output files which remain after
+                        volatile data removal.
 
     --nopreflight       Skips preflight stages.
     --strict=MODE       Determines how mrp reports cases where it needs to fall
@@ -134,6 +150,12 @@ Options:
                         if --uiport is not set).
     --auth-key=KEY      Set the authentication key required for accessing the
                             web UI.
+    --status-channel=NET
+                        Have jobs report status updates to mrp over a socket,
+                        authenticated with a per-job token, in addition to
+                        writing files in the pipestance.  Allowed values:
+                            unix, for jobs running on this host only
+                            tcp
     --https-cert=FILE   Set path to a file containing the TLS certificate to use
                         for the user interface.
                             If set, https-key must also be provided.
@@ -143,6 +165,8 @@ Options:
     --noexit            Keep UI running after pipestance completes or fails.
     --onfinish=EXEC     Run this when pipeline finishes, success or fail.
     --zip               Zip metadata files after pipestance completes.
+    --rocrate           Write RO-Crate provenance metadata after pipestance
+                            completes.
     --tags=TAGS         Tag pipestance with comma-separated key:value pairs.
 
     --profile=MODE      Enables stage performance profiling.  Configurable.
@@ -151,15 +175,29 @@ Options:
     --stackvars         Print local variables in stage code stack trace.
     --monitor           Kill jobs that exceed requested memory resources.
     --inspect           Inspect pipestance without resetting failed stages.
+    --rerun=NAME        When reattaching, discard the results of the given
+                        stage or pipeline, and everything downstream of it,
+                        so that they run again.
+    --dry-run           Print the stages which would run, with their resource
+                        requests, without running anything or creating the
+                        pipestance directory.
     --debug             Enable debug logging for local job manager.
     --stest             Substitute real stages with stress-testing stage.
     --autoretry=NUM     Automatically retry failed runs up to NUM times.
     --retry-wait=SECS   Wait SECS seconds after a failure before attempting
                         automatic retry.  Defaults to 1 second.
     --overrides=JSON    JSON file supplying custom run conditions per stage.
+    --resource-profile=JSON
+                        Overrides file, such as generated by mro perf suggest,
+                        supplying resource requests for stages.  Values set
+                        in --overrides take precedence.
     --psdir=PATH        The path to the pipestance directory.  The default is
                         to use <pipestance_name>.
     --never-local       Ignore 'local' modifiers on non-preflight stages.
+    --stage-cache=PATH  Reuse outputs of stages which were previously run
+                        with identical inputs, from a cache directory which
+                        may be shared between pipestances.  Caching may be
+                        disabled for specific stages with overrides.
 
     -h --help           Show this message.
     --version           Show version.`
@@ -216,6 +254,17 @@ Options:
 			os.Exit(1)
 		}
 	}
+	if value := opts["--localresource"]; value != nil {
+		if value, err := core.ParseLocalResources(value.(string)); err == nil {
+			config.LocalResources = value
+			util.LogInfo("options", "--localresource=%s",
+				core.FormatLocalResources(config.LocalResources))
+		} else {
+			util.PrintError(err, "options",
+				"Could not parse --localresource value \"%s\"", opts["--localresource"].(string))
+			os.Exit(1)
+		}
+	}
 	if value := opts["--mempercore"]; value != nil {
 		if value, err := strconv.Atoi(value.(string)); err == nil {
 			config.MemPerCore = value
@@ -300,6 +349,13 @@ Options:
 	util.LogInfo("options", "--vdrmode=%s", config.VdrMode)
 	core.VerifyVDRMode(config.VdrMode)
 
+	// Compute manifest mode.
+	if value := opts["--manifest"]; value != nil {
+		config.ManifestMode = core.ManifestMode(value.(string))
+	}
+	util.LogInfo("options", "--manifest=%s", config.ManifestMode)
+	core.VerifyManifestMode(config.ManifestMode)
+
 	// Compute onfinish
 	if value := opts["--onfinish"]; value != nil {
 		config.OnFinishHandler = value.(string)
@@ -336,6 +392,10 @@ Options:
 		c.requireAuth = true
 		util.LogInfo("options", "--require-auth")
 	}
+	if value := opts["--status-channel"]; value != nil {
+		c.statusChannel = value.(string)
+		util.LogInfo("options", "--status-channel=%s", c.statusChannel)
+	}
 	if value := opts["--auth-key"]; value != nil {
 		c.authKey = value.(string)
 		util.LogInfo("options", "--auth-key=%s", c.authKey)
@@ -369,6 +429,29 @@ Options:
 
 		}
 	}
+	if v := opts["--resource-profile"]; v != nil {
+		profilePath := v.(string)
+		profile, err := core.ReadOverrides(profilePath)
+		if err != nil {
+			util.PrintError(err, "startup", "Failed to parse resource profile")
+			os.Exit(1)
+		}
+		if config.Overrides == nil {
+			config.Overrides = new(core.PipestanceOverrides)
+		}
+		config.Overrides.SetResourceProfile(profile)
+		util.LogInfo("options", "--resource-profile=%s", profilePath)
+	}
+
+	if value := opts["--stage-cache"]; value != nil {
+		if p, ok := value.(string); ok && p != "" {
+			if abs, err := filepath.Abs(p); err == nil {
+				p = abs
+			}
+			config.StageCache = p
+			util.LogInfo("options", "--stage-cache=%s", config.StageCache)
+		}
+	}
 
 	// Compute stackVars flag.
 	config.StackVars = opts["--stackvars"].(bool)
@@ -376,6 +459,8 @@ Options:
 
 	config.Zip = opts["--zip"].(bool)
 	util.LogInfo("options", "--zip=%v", config.Zip)
+	config.ROCrate = opts["--rocrate"].(bool)
+	util.LogInfo("options", "--rocrate=%v", config.ROCrate)
 
 	config.LimitLoadavg = opts["--limit-loadavg"].(bool)
 	util.LogInfo("options", "--limit-loadavg=%v", config.LimitLoadavg)
@@ -401,6 +486,15 @@ Options:
 	}
 	config.Monitor = opts["--monitor"].(bool)
 	c.readOnly = opts["--inspect"].(bool)
+	c.dryRun = opts["--dry-run"].(bool)
+	if value := opts["--rerun"]; value != nil {
+		c.rerun = value.(string)
+		util.LogInfo("options", "--rerun=%s", c.rerun)
+		if c.readOnly {
+			util.Println("\nWARNING: ignoring --rerun because --inspect was given.\n")
+			c.rerun = ""
+		}
+	}
 	config.Debug = opts["--debug"].(bool)
 	config.StressTest = opts["--stest"].(bool)
 	if value := opts["--autoretry"]; value != nil {
@@ -0,0 +1,101 @@
+//
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+//
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/martian-lang/martian/martian/core"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+// dryRun prints the execution plan for the pipestance without running
+// anything, and returns the exit code for mrp.
+func dryRun(c *mrpConfiguration, invocationSrc string) int {
+	rt, err := c.config.NewRuntime()
+	if err != nil {
+		util.PrintError(err, "jobmngr", "Could not configure the job manager.")
+		return 1
+	}
+	plan, err := rt.PlanPipeline(invocationSrc, c.invocationPath, c.psid,
+		c.pipestancePath, c.mroPaths, c.mroVersion, nil)
+	if err != nil {
+		util.PrintError(err, "runtime", "Could not plan the pipestance.")
+		return 1
+	}
+	w := bufio.NewWriter(os.Stdout)
+	writePlan(w, plan)
+	if err := w.Flush(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+var planPhases = [...]string{
+	core.STAGE_TYPE_SPLIT,
+	core.STAGE_TYPE_CHUNK,
+	core.STAGE_TYPE_JOIN,
+}
+
+var computedResourceNames = [...]string{
+	"threads",
+	"mem_gb",
+	"vmem_gb",
+}
+
+func writePlan(w io.Writer, plan *core.ExecutionPlan) {
+	fmt.Fprintf(w, "Execution plan for %s (pipestance %s, jobmode %s, vdrmode %s)\n",
+		plan.Pipeline, plan.Psid, plan.JobMode, plan.VdrMode)
+	for _, f := range plan.Stages {
+		fmt.Fprintf(w, "\n%s %s: %s", f.Fqname, f.Fork, f.State)
+		if len(f.DisabledBy) > 0 {
+			fmt.Fprintf(w, " (disabled by %s)", strings.Join(f.DisabledBy, ", "))
+		}
+		var flags []string
+		if f.Preflight {
+			flags = append(flags, "preflight")
+		}
+		if f.Local {
+			flags = append(flags, "local")
+		}
+		if f.Undetermined {
+			flags = append(flags, "forks determined at runtime")
+		}
+		if len(flags) > 0 {
+			fmt.Fprintf(w, " [%s]", strings.Join(flags, ", "))
+		}
+		io.WriteString(w, "\n")
+		for _, phase := range planPhases {
+			if res, ok := f.Resources[phase]; ok {
+				fmt.Fprintf(w, "    %-5s threads=%g mem_gb=%g vmem_gb=%g",
+					phase, res.Threads, res.MemGB, res.VMemGB)
+				if res.Special != "" {
+					fmt.Fprintf(w, " special=%s", res.Special)
+				}
+				if res.Timeout != 0 {
+					fmt.Fprintf(w, " timeout=%d", res.Timeout)
+				}
+				io.WriteString(w, "\n")
+			}
+		}
+		for _, name := range computedResourceNames {
+			if exp, ok := f.ComputedResources[name]; ok {
+				fmt.Fprintf(w, "    using %s = %s (computed at runtime)\n",
+					name, exp)
+			}
+		}
+		if len(f.VolatileOutputs) > 0 {
+			fmt.Fprintf(w, "    volatile outputs: %s\n",
+				strings.Join(f.VolatileOutputs, ", "))
+		}
+	}
+	run, disabled, conditional := plan.Summary()
+	fmt.Fprintf(w, "\n%d stage fork%s would run, %d disabled, %d conditional.\n",
+		run, util.Pluralize(run), disabled, conditional)
+}
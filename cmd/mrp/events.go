@@ -0,0 +1,205 @@
+//
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+//
+
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/martian-lang/martian/martian/api"
+	"github.com/martian-lang/martian/martian/core"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+// The number of events which can be buffered for a client before it is
+// considered to be too slow and disconnected.
+const eventBufferSize = 1024
+
+type forkKey struct {
+	fqname string
+	index  int
+}
+
+// eventBroker tracks the state transitions observed by the run loop and
+// distributes them, along with log messages and alarms, to clients of the
+// event stream API.
+type eventBroker struct {
+	subscribers map[chan *api.Event]struct{}
+	forkStates  map[forkKey]core.MetadataState
+	state       core.MetadataState
+	lock        sync.Mutex
+}
+
+// subscribe registers a new client.  It returns the channel on which events
+// will be sent, along with a snapshot of the current state of every fork.
+//
+// If the client does not keep up with the events, the channel is closed.
+func (self *eventBroker) subscribe() (chan *api.Event, []*api.Event) {
+	ch := make(chan *api.Event, eventBufferSize)
+	now := util.Timestamp()
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.subscribers == nil {
+		self.subscribers = make(map[chan *api.Event]struct{})
+	}
+	self.subscribers[ch] = struct{}{}
+	snapshot := make([]*api.Event, 0, len(self.forkStates)+1)
+	if self.state != "" {
+		snapshot = append(snapshot, &api.Event{
+			Type:  api.EventPipestance,
+			Time:  now,
+			State: self.state,
+		})
+	}
+	forks := snapshot[len(snapshot):]
+	for key, state := range self.forkStates {
+		forks = append(forks, &api.Event{
+			Type:   api.EventState,
+			Time:   now,
+			Fqname: key.fqname,
+			Fork:   key.index,
+			State:  state,
+		})
+	}
+	sort.Slice(forks, func(i, j int) bool {
+		if forks[i].Fqname != forks[j].Fqname {
+			return forks[i].Fqname < forks[j].Fqname
+		}
+		return forks[i].Fork < forks[j].Fork
+	})
+	return ch, append(snapshot, forks...)
+}
+
+// unsubscribe removes a client, if it has not already been removed.
+func (self *eventBroker) unsubscribe(ch chan *api.Event) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if _, ok := self.subscribers[ch]; ok {
+		delete(self.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Send an event to all subscribers.  The lock must be held.
+func (self *eventBroker) publishNoLock(ev *api.Event) {
+	for ch := range self.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// Rather than silently dropping events, disconnect the client.
+			// It can reconnect to get a fresh snapshot.
+			delete(self.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// updateState records a change in the overall pipestance state.
+func (self *eventBroker) updateState(state core.MetadataState) {
+	now := util.Timestamp()
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.state == state {
+		return
+	}
+	self.publishNoLock(&api.Event{
+		Type:     api.EventPipestance,
+		Time:     now,
+		State:    state,
+		OldState: self.state,
+	})
+	self.state = state
+}
+
+// observe compares the current fork states against the states seen the last
+// time it was called, and publishes any transitions.
+func (self *eventBroker) observe(states []core.ForkStateInfo) {
+	now := util.Timestamp()
+	var finished []*core.ForkStateInfo
+	self.lock.Lock()
+	if self.forkStates == nil {
+		self.forkStates = make(map[forkKey]core.MetadataState, len(states))
+	}
+	for i := range states {
+		st := &states[i]
+		key := forkKey{fqname: st.Fqname, index: st.Index}
+		old := self.forkStates[key]
+		if old == st.State {
+			continue
+		}
+		self.forkStates[key] = st.State
+		self.publishNoLock(&api.Event{
+			Type:     api.EventState,
+			Time:     now,
+			Fqname:   st.Fqname,
+			Fork:     st.Index,
+			State:    st.State,
+			OldState: old,
+		})
+		if len(self.subscribers) > 0 &&
+			(st.State == core.Complete || st.State == core.Failed) {
+			finished = append(finished, st)
+		}
+	}
+	self.lock.Unlock()
+
+	// Don't hold the lock while reading files.
+	for _, st := range finished {
+		if alarms := st.Alarms(); alarms != "" {
+			self.lock.Lock()
+			self.publishNoLock(&api.Event{
+				Type:    api.EventAlarm,
+				Time:    now,
+				Fqname:  st.Fqname,
+				Fork:    st.Index,
+				Message: alarms,
+			})
+			self.lock.Unlock()
+		}
+	}
+}
+
+// stateCounts returns the number of forks last observed in each state.
+func (self *eventBroker) stateCounts() map[core.MetadataState]int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	counts := make(map[core.MetadataState]int)
+	for _, state := range self.forkStates {
+		counts[state]++
+	}
+	return counts
+}
+
+// Write implements io.Writer so that the broker can be registered with
+// util.LogHook to forward log messages.
+func (self *eventBroker) Write(msg []byte) (int, error) {
+	if trimmed := bytes.TrimSpace(msg); len(trimmed) > 0 {
+		self.publishLog(string(trimmed))
+	}
+	return len(msg), nil
+}
+
+// WriteString implements util.StringWriter.
+func (self *eventBroker) WriteString(msg string) (int, error) {
+	if trimmed := strings.TrimSpace(msg); len(trimmed) > 0 {
+		self.publishLog(trimmed)
+	}
+	return len(msg), nil
+}
+
+func (self *eventBroker) publishLog(msg string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if len(self.subscribers) == 0 {
+		return
+	}
+	self.publishNoLock(&api.Event{
+		Type:    api.EventLog,
+		Time:    util.Timestamp(),
+		Message: msg,
+	})
+}
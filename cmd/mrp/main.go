@@ -39,6 +39,7 @@ type pipestanceHolder struct {
 	info             *api.PipestanceInfo
 	maxRetries       int
 	remainingRetries int
+	retriesConsumed  int64
 	authKey          string
 	enableUI         bool
 	showedFailed     bool
@@ -49,7 +50,14 @@ type pipestanceHolder struct {
 	https            bool
 	retryWait        time.Duration
 	server           *http.Server
+	statusServer     *core.StatusServer
+	logCheckOnce     sync.Once
 	lastLogCheck     time.Time
+	events           eventBroker
+
+	// Signaled when the pipestance is rerun, so that the run loop can
+	// resume after a completed pipestance is rerun with --noexit.
+	rerunDone chan struct{}
 }
 
 func (self *pipestanceHolder) getPipestance() *core.Pipestance {
@@ -70,10 +78,19 @@ func (self *pipestanceHolder) consumeRetry() bool {
 		return false
 	} else {
 		self.remainingRetries--
+		self.retriesConsumed++
 		return true
 	}
 }
 
+// Returns the total number of automatic retries used, including those used
+// before the retry count was last reset.
+func (self *pipestanceHolder) getRetriesConsumed() int64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.retriesConsumed
+}
+
 // Restart the pipestance and set remaining retries back to maximum.
 func (self *pipestanceHolder) reset(ctx context.Context) error {
 	self.lock.Lock()
@@ -85,6 +102,27 @@ func (self *pipestanceHolder) reset(ctx context.Context) error {
 
 // Restart the pipestance.
 func (self *pipestanceHolder) restart(outerCtx context.Context) error {
+	return self.reattach(outerCtx, "")
+}
+
+// Restart the pipestance after invalidating the given node and everything
+// downstream of it, and set remaining retries back to maximum.
+func (self *pipestanceHolder) rerun(ctx context.Context, fqname string) error {
+	self.lock.Lock()
+	self.remainingRetries = self.maxRetries
+	self.showedFailed = false
+	self.lock.Unlock()
+	if err := self.reattach(ctx, fqname); err != nil {
+		return err
+	}
+	select {
+	case self.rerunDone <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (self *pipestanceHolder) reattach(outerCtx context.Context, rerun string) error {
 	ctx, task := trace.NewTask(outerCtx, "restart")
 	defer task.End()
 	if self.readOnly {
@@ -94,7 +132,12 @@ func (self *pipestanceHolder) restart(outerCtx context.Context) error {
 	defer self.lock.Unlock()
 	ps, err := self.factory.ReattachToPipestance(ctx)
 	if err == nil {
-		err = ps.Reset()
+		if rerun != "" {
+			err = ps.Rerun(rerun)
+		}
+		if err == nil {
+			err = ps.Reset()
+		}
 		if err != nil {
 			ps.Unlock()
 			return err
@@ -108,6 +151,7 @@ func (self *pipestanceHolder) restart(outerCtx context.Context) error {
 func (self *pipestanceHolder) UpdateState(state core.MetadataState) chan struct{} {
 	oldState := self.info.State
 	self.info.State = state
+	self.events.updateState(state)
 	if oldState != state || time.Since(self.lastRegister) > 10*time.Minute {
 		return self.Register(false)
 	}
@@ -192,6 +236,10 @@ func (pipestanceBox *pipestanceHolder) Configure(c *mrpConfiguration, invocation
 	// Configure Martian runtime.
 	//=========================================================================
 	rt, configErr := c.config.NewRuntime()
+	if configErr == nil && c.statusChannel != "" && !c.readOnly {
+		rt.StatusServer, configErr = core.NewStatusServer(c.statusChannel)
+		pipestanceBox.statusServer = rt.StatusServer
+	}
 
 	factory := core.NewRuntimePipestanceFactory(rt,
 		invocationSrc, c.invocationPath, c.psid, c.mroPaths, c.pipestancePath, c.mroVersion,
@@ -240,6 +288,16 @@ func (pipestanceBox *pipestanceHolder) Configure(c *mrpConfiguration, invocation
 	return reattaching, rt
 }
 
+// closeStatusServer stops listening for job status updates, if a status
+// server was started, and removes its socket.
+func (self *pipestanceHolder) closeStatusServer() {
+	if self.statusServer != nil {
+		if err := self.statusServer.Close(); err != nil {
+			util.LogError(err, "runtime", "Error closing status server.")
+		}
+	}
+}
+
 // reportAndDieIf is shortand for reportConfigFailure followed by util.DieIf.
 func (pipestanceBox *pipestanceHolder) reportAndDieIf(err error) {
 	if err == nil {
@@ -408,6 +466,7 @@ func (c *mrpConfiguration) getListener(hostname string,
 			util.PrintError(err, "webserv", "Cannot open port %s", c.uiport)
 			if dieWithoutUi {
 				pipestanceBox.reportConfigFailure(err)
+				pipestanceBox.closeStatusServer()
 				os.Exit(1)
 			} else {
 				util.PrintError(err, "webserv", "UI disabled")
@@ -511,9 +570,14 @@ func main() {
 	util.DieIf(err)
 	invocationSrc := string(data)
 
+	if c.dryRun {
+		os.Exit(dryRun(&c, invocationSrc))
+	}
+
 	// Attempt to reattach to the pipestance.
 	cwd, _ := os.Getwd()
 	pipestanceBox := pipestanceHolder{
+		rerunDone: make(chan struct{}, 1),
 		info: &api.PipestanceInfo{
 			Hostname:     hostname,
 			Username:     username,
@@ -550,19 +614,32 @@ func main() {
 	if reattaching {
 		// If it already exists, try to reattach to it.
 		if !c.readOnly {
-			if err = pipestance.Reset(); err == nil {
+			if c.rerun != "" {
+				err = pipestance.Rerun(c.rerun)
+			}
+			if err == nil {
+				err = pipestance.Reset()
+			}
+			if err == nil {
 				err = pipestance.RestartLocalJobs(c.config.JobMode)
 			}
 			pipestanceBox.reportAndDieIf(err)
 		}
-	} else if !c.config.SkipPreflight && !c.readOnly {
-		util.Println("Running preflight checks (please wait)...")
+	} else {
+		if c.rerun != "" {
+			util.PrintInfo("runtime",
+				"Ignoring --rerun=%s for a new pipestance.", c.rerun)
+		}
+		if !c.config.SkipPreflight && !c.readOnly {
+			util.Println("Running preflight checks (please wait)...")
+		}
 	}
 
 	//=========================================================================
 	// Start web server.
 	//=========================================================================
 	if listener != nil {
+		util.LogHook(&pipestanceBox.events)
 		go runWebServer(listener, rt, &pipestanceBox, c.requireAuth)
 	}
 
@@ -0,0 +1,135 @@
+//
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+//
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/martian-lang/martian/martian/core"
+)
+
+// Serves runtime metrics in the Prometheus text exposition format.
+func (self *mrpWebServer) getMetrics(w http.ResponseWriter, req *http.Request) {
+	if self.readAuth && !self.verifyAuth(w, req) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	buf := bufio.NewWriter(w)
+	self.writeMetrics(buf)
+	buf.Flush()
+}
+
+func (self *mrpWebServer) writeMetrics(w io.Writer) {
+	writeForkMetrics(w, self.pipestanceBox.events.stateCounts())
+
+	if local := self.rt.LocalJobManager; local != nil {
+		threads := local.ThreadUsage()
+		writeMetric(w, "martian_local_threads_in_use", "gauge",
+			"Threads in use by local jobs, including unaccounted usage.",
+			float64(threads.InUse)/100)
+		writeMetric(w, "martian_local_threads_reserved", "gauge",
+			"Threads reserved by running local jobs.",
+			float64(threads.Reserved)/100)
+		writeMetric(w, "martian_local_threads_available", "gauge",
+			"Threads available to start new local jobs.",
+			float64(threads.Available)/100)
+		mem := local.MemoryUsage()
+		writeMetric(w, "martian_local_memory_in_use_bytes", "gauge",
+			"Memory in use by local jobs, including unaccounted usage.",
+			float64(mem.InUse)*1024*1024)
+		writeMetric(w, "martian_local_memory_reserved_bytes", "gauge",
+			"Memory reserved by running local jobs.",
+			float64(mem.Reserved)*1024*1024)
+		writeMetric(w, "martian_local_memory_available_bytes", "gauge",
+			"Memory available to start new local jobs.",
+			float64(mem.Available)*1024*1024)
+		writeLocalResourceMetrics(w, local.LocalResourceUsage())
+	}
+	if remote, ok := self.rt.JobManager.(*core.RemoteJobManager); ok {
+		running, waiting, limit := remote.QueueStats()
+		writeMetric(w, "martian_remote_jobs_running", "gauge",
+			"Jobs submitted to the cluster which have not yet finished.",
+			float64(running))
+		writeMetric(w, "martian_remote_jobs_waiting", "gauge",
+			"Jobs waiting for a slot under --maxjobs before submission.",
+			float64(waiting))
+		writeMetric(w, "martian_remote_jobs_limit", "gauge",
+			"The maximum number of jobs which may be submitted at once, "+
+				"or 0 if unlimited.",
+			float64(limit))
+	}
+
+	counters := core.GetRuntimeCounters()
+	writeMetric(w, "martian_job_submit_failures_total", "counter",
+		"Cluster job submissions which returned an error.",
+		float64(counters.JobSubmitFailures))
+	writeMetric(w, "martian_retries_total", "counter",
+		"Automatic pipestance retries consumed.",
+		float64(self.pipestanceBox.getRetriesConsumed()))
+	writeMetric(w, "martian_vdr_freed_bytes_total", "counter",
+		"Bytes freed by volatile data removal.",
+		float64(counters.VdrBytesFreed))
+	writeMetric(w, "martian_heartbeat_failures_total", "counter",
+		"Jobs which failed due to a missing heartbeat.",
+		float64(counters.HeartbeatFailures))
+}
+
+func writeForkMetrics(w io.Writer, counts map[core.MetadataState]int) {
+	byLabel := make(map[string]int, len(counts))
+	for state, count := range counts {
+		if state == core.Waiting {
+			state = core.ForkWaiting
+		}
+		byLabel[string(state)] += count
+	}
+	labels := make([]string, 0, len(byLabel))
+	for label := range byLabel {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	io.WriteString(w, "# HELP martian_forks Stage forks in each state.\n"+
+		"# TYPE martian_forks gauge\n")
+	for _, label := range labels {
+		fmt.Fprintf(w, "martian_forks{state=%q} %d\n", label, byLabel[label])
+	}
+}
+
+func writeLocalResourceMetrics(w io.Writer, stats []core.LocalResourceStats) {
+	if len(stats) == 0 {
+		return
+	}
+	for _, m := range [...]struct {
+		name, help string
+		value      func(*core.LocalResourceStats) float64
+	}{
+		{
+			"martian_local_resource_capacity", "The amount of each named local resource.",
+			func(s *core.LocalResourceStats) float64 { return float64(s.Capacity) },
+		},
+		{
+			"martian_local_resource_reserved", "The amount of each named local resource reserved by running jobs.",
+			func(s *core.LocalResourceStats) float64 { return float64(s.Reserved) },
+		},
+		{
+			"martian_local_resource_waiting", "Local jobs waiting for each named local resource.",
+			func(s *core.LocalResourceStats) float64 { return float64(s.Waiting) },
+		},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for i := range stats {
+			fmt.Fprintf(w, "%s{resource=%q} %g\n",
+				m.name, stats[i].Name, m.value(&stats[i]))
+		}
+	}
+}
+
+func writeMetric(w io.Writer, name, kind, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n",
+		name, help, name, kind, name, value)
+}
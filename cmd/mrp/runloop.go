@@ -79,12 +79,18 @@ func loopBody(pipestanceBox *pipestanceHolder,
 	ctx, task := trace.NewTask(context.Background(), "update")
 	defer task.End()
 	pipestance.RefreshState(ctx)
+	pipestanceBox.events.observe(pipestance.ForkStates())
 
 	// Check for completion states.
 	state := pipestance.GetState(ctx)
 	if state == core.Complete || state == core.DisabledState {
 		pipestanceBox.UpdateState(state.Prefixed(core.CleanupPrefix))
-		cleanupCompleted(pipestance, pipestanceBox, vdrMode, noExit, ctx)
+		flushChannel(pipestanceBox.rerunDone)
+		if cleanupCompleted(pipestance, pipestanceBox, vdrMode, noExit, ctx) {
+			// Don't return until the pipestance is rerun; otherwise we'll
+			// repeatedly try to clean up.
+			<-pipestanceBox.rerunDone
+		}
 		return false
 	} else if state == core.Failed {
 		if pipestanceBox.showedFailed {
@@ -164,14 +170,16 @@ func attemptRetry(pipestance *core.Pipestance, pipestanceBox *pipestanceHolder,
 	return canRetry
 }
 
+// cleanupCompleted finalizes a completed pipestance and exits, unless
+// --noexit was given, in which case it returns true.
 func cleanupCompleted(pipestance *core.Pipestance, pipestanceBox *pipestanceHolder,
-	vdrMode core.VdrMode, noExit bool, ctx context.Context) {
+	vdrMode core.VdrMode, noExit bool, ctx context.Context) bool {
 	r := trace.StartRegion(ctx, "cleanupCompleted")
 	defer r.End()
 	if pipestanceBox.readOnly {
 		pipestanceBox.UpdateState(core.Complete)
 		util.Println("Pipestance completed successfully, staying alive because --inspect given.\n")
-		return
+		return false
 	}
 	pipestanceBox.cleanupLock.Lock()
 	defer pipestanceBox.cleanupLock.Unlock()
@@ -189,9 +197,8 @@ func cleanupCompleted(pipestance *core.Pipestance, pipestanceBox *pipestanceHold
 	if noExit {
 		util.Println("Pipestance completed successfully, staying alive because --noexit given.\n")
 		runtime.GC()
-		// Don't return; otherwise we'll repeatedly try to clean up.
-		go completedLogCheck()
-		runtime.Goexit()
+		pipestanceBox.logCheckOnce.Do(func() { go completedLogCheck() })
+		return true
 	} else {
 		if pipestanceBox.enableUI {
 			// Give time for web ui client to get last update.
@@ -202,7 +209,9 @@ func cleanupCompleted(pipestance *core.Pipestance, pipestanceBox *pipestanceHold
 		if updateComplete != nil {
 			<-updateComplete
 		}
+		pipestanceBox.closeStatusServer()
 		util.Suicide(true)
+		return false
 	}
 }
 
@@ -252,6 +261,7 @@ func cleanupFailed(pipestance *core.Pipestance, pipestanceBox *pipestanceHolder,
 			if serverUpdate != nil {
 				<-serverUpdate
 			}
+			pipestanceBox.closeStatusServer()
 			util.Suicide(false)
 		} else if len(errPaths) > 0 {
 			// Build relative path to _errors file
@@ -298,6 +308,7 @@ Log message:
 		if serverUpdate != nil {
 			<-serverUpdate
 		}
+		pipestanceBox.closeStatusServer()
 		util.Suicide(false)
 	}
 }
@@ -13,6 +13,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -219,12 +220,17 @@ func (self *mrpWebServer) handleApi(sm *http.ServeMux) {
 	sm.HandleFunc(api.QueryGetInfo+"/", self.getInfo)
 	sm.HandleFunc(api.QueryGetState, self.getState)
 	sm.HandleFunc(api.QueryGetState+"/", self.getState)
+	sm.HandleFunc(api.QueryEvents, self.getEvents)
+	sm.HandleFunc(api.QueryEvents+"/", self.getEvents)
+	sm.HandleFunc(api.QueryMetrics, self.getMetrics)
 	sm.HandleFunc(api.QueryGetPerf, self.getPerf)
 	sm.HandleFunc(api.QueryGetPerf+"/", self.getPerf)
 	sm.HandleFunc(api.QueryGetMetadata, self.getMetadata)
 	sm.HandleFunc(api.QueryGetMetadata+"/", self.getMetadata)
 	sm.HandleFunc(api.QueryRestart, self.restart)
 	sm.HandleFunc(api.QueryRestart+"/", self.restart)
+	sm.HandleFunc(api.QueryRerun, self.rerun)
+	sm.HandleFunc(api.QueryRerun+"/", self.rerun)
 	p := self.pipestanceBox.getPipestance().GetPath()
 	sm.Handle(api.QueryGetMetadataTop, self.authorize(pathToMetadata(
 		http.FileServer(http.Dir(p)))))
@@ -340,6 +346,80 @@ func (self *mrpWebServer) getState(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// The interval at which comments are sent on the event stream to keep
+// proxies from closing idle connections.
+const eventKeepAlive = 30 * time.Second
+
+// Stream node and fork state transitions, log messages, and alarms as
+// server-sent events.  On connection, the current state of every fork is
+// sent first.
+func (self *mrpWebServer) getEvents(w http.ResponseWriter, req *http.Request) {
+	if self.readAuth && !self.verifyAuth(w, req) {
+		return
+	}
+	rc := http.NewResponseController(w)
+	// The server write timeout is meant for ordinary requests.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	events, snapshot := self.pipestanceBox.events.subscribe()
+	defer self.pipestanceBox.events.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for _, ev := range snapshot {
+		if err := writeEvent(w, ev); err != nil {
+			return
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ":\n\n"); err != nil {
+				return
+			}
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+			// Send along anything else that's already waiting before
+			// flushing.
+			for pending := len(events); pending > 0; pending-- {
+				if ev, ok := <-events; !ok {
+					return
+				} else if err := writeEvent(w, ev); err != nil {
+					return
+				}
+			}
+		}
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+// Writes an event in server-sent event format.
+func writeEvent(w io.Writer, ev *api.Event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, b)
+	return err
+}
+
 // Get pipestance performance data: disable API endpoint for release
 func (self *mrpWebServer) getPerf(w http.ResponseWriter, req *http.Request) {
 	if self.readAuth && !self.verifyAuth(w, req) {
@@ -458,6 +538,34 @@ func (self *mrpWebServer) restart(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// Rerun a stage and everything downstream of it.
+func (self *mrpWebServer) rerun(w http.ResponseWriter, req *http.Request) {
+	if !self.verifyAuth(w, req) {
+		return
+	}
+	if self.pipestanceBox.readOnly {
+		http.Error(w, "mrp is in read-only mode.", http.StatusBadRequest)
+		return
+	}
+	fqname := req.FormValue("fqname")
+	if fqname == "" {
+		http.Error(w, "fqname is required.", http.StatusBadRequest)
+		return
+	}
+	self.pipestanceBox.cleanupLock.Lock()
+	defer self.pipestanceBox.cleanupLock.Unlock()
+	if st := self.pipestanceBox.getPipestance().GetState(req.Context()); st != core.Failed &&
+		st != core.Complete {
+		http.Error(w, "Only failed or completed pipestances can be rerun.",
+			http.StatusBadRequest)
+		return
+	}
+	util.LogInfo("webserv", "Got API request to rerun %s.", fqname)
+	if err := self.pipestanceBox.rerun(req.Context(), fqname); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
 // Kill the pipestance.
 func (self *mrpWebServer) kill(w http.ResponseWriter, req *http.Request) {
 	if !self.verifyAuth(w, req) {
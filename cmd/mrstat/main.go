@@ -15,10 +15,18 @@ The --stop option allows users to terminate the pipestance.  For running
 pipestances, this forces the pipestance into a failed state, and mrp to
 terminate.  For completed mrp instances launched with the --noexit option,
 it causes mrp to terminate.
+
+The --rerun option restarts a failed pipestance after discarding the results
+of the given stage or pipeline, and everything downstream of it.
+
+The --follow option streams state changes, log messages, and alarms from the
+pipestance as they happen, until the pipestance completes or fails.
 */
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -52,6 +60,12 @@ Options:
                 If the pipestance is running, this will cause it to fail.
     --restart   If mrp was launched with --noexit, and the pipeline failed,
                 attempt to retry the run.
+    --rerun=NAME
+                If mrp was launched with --noexit, and the pipeline failed,
+                discard the results of the given stage or pipeline, and
+                everything downstream of it, and retry the run.
+    --follow    Print stage state changes, log messages, and alarms as
+                they happen, until the pipestance completes or fails.
 
     -h --help   Show this message.
     --version   Show version.`
@@ -60,6 +74,8 @@ Options:
 
 	stop := (opts["--stop"] != nil && opts["--stop"].(bool))
 	restart := (opts["--restart"] != nil && opts["--restart"].(bool))
+	follow := (opts["--follow"] != nil && opts["--follow"].(bool))
+	rerun, _ := opts["--rerun"].(string)
 
 	psid := opts["<pipestance_name>"].(string)
 
@@ -87,6 +103,10 @@ Options:
 		sendStop(psid, mrpUrl)
 	} else if restart {
 		sendRestart(psid, mrpUrl)
+	} else if rerun != "" {
+		sendRerun(psid, mrpUrl, rerun)
+	} else if follow {
+		followEvents(mrpUrl)
 	} else {
 		status(psid, mrpUrl)
 	}
@@ -134,6 +154,29 @@ func sendRestart(psid string, mrpUrl *url.URL) {
 	os.Exit(0)
 }
 
+func sendRerun(psid string, mrpUrl *url.URL, fqname string) {
+	mrpUrl.Path = api.QueryRerun
+	form := mrpUrl.Query()
+	form.Set("fqname", fqname)
+	fmt.Println("Sending rerun command for", fqname, "to", psid)
+	if resp, err := http.PostForm(mrpUrl.String(), form); err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot connect to", mrpUrl)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(5)
+	} else {
+		if resp.StatusCode != http.StatusOK {
+			fmt.Fprintln(os.Stderr, "Response:", resp.Status)
+			io.Copy(os.Stderr, resp.Body)
+			resp.Body.Close()
+			os.Exit(6)
+		} else {
+			fmt.Println("Rerun request for", psid, "accepted")
+		}
+		resp.Body.Close()
+	}
+	os.Exit(0)
+}
+
 func status(psid string, mrpUrl *url.URL) {
 	mrpUrl.Path = api.QueryGetInfo + "/" + psid
 	if resp, err := http.Get(mrpUrl.String()); err != nil {
@@ -172,3 +215,71 @@ func status(psid string, mrpUrl *url.URL) {
 		os.Exit(0)
 	}
 }
+
+func followEvents(mrpUrl *url.URL) {
+	mrpUrl.Path = api.QueryEvents
+	resp, err := http.Get(mrpUrl.String())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot connect to", mrpUrl)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(5)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintln(os.Stderr, "Response:", resp.Status)
+		io.Copy(os.Stderr, resp.Body)
+		resp.Body.Close()
+		os.Exit(6)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	dataPrefix := []byte("data: ")
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !bytes.HasPrefix(line, dataPrefix) {
+			// Event names, keep-alive comments, and separators carry no
+			// information which isn't also in the data.
+			continue
+		}
+		var ev api.Event
+		if err := json.Unmarshal(line[len(dataPrefix):], &ev); err != nil {
+			fmt.Fprintln(os.Stderr, "Can't parse event: ", err)
+			continue
+		}
+		printEvent(&ev)
+		if ev.Type == api.EventPipestance {
+			switch ev.State {
+			case core.Complete:
+				os.Exit(0)
+			case core.Failed:
+				os.Exit(1)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading events:", err)
+		os.Exit(7)
+	}
+	// The server closed the connection, most likely because mrp exited.
+	os.Exit(0)
+}
+
+func printEvent(ev *api.Event) {
+	switch ev.Type {
+	case api.EventPipestance:
+		fmt.Printf("%s [pipestance] %s\n", ev.Time, ev.State)
+	case api.EventState:
+		if ev.OldState == "" {
+			fmt.Printf("%s [state] %s.fork%d: %s\n",
+				ev.Time, ev.Fqname, ev.Fork, ev.State)
+		} else {
+			fmt.Printf("%s [state] %s.fork%d: %s -> %s\n",
+				ev.Time, ev.Fqname, ev.Fork, ev.OldState, ev.State)
+		}
+	case api.EventAlarm:
+		fmt.Printf("%s [alarm] %s.fork%d:\n%s\n",
+			ev.Time, ev.Fqname, ev.Fork, ev.Message)
+	case api.EventLog:
+		fmt.Println(ev.Message)
+	}
+}
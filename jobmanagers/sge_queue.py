@@ -52,10 +52,32 @@ def parse_output(out):
 
 
 def list_jobs(jobs):
-    """Gets the list of jobs from a job_list."""
+    """Gets the list of jobs from a job_list.  Tasks of array jobs are listed
+    as jobid.taskid."""
     for item in jobs.findall("job_list"):
         if not "E" in item.find("state").text:
-            yield item.find("JB_job_number").text
+            jobid = item.find("JB_job_number").text
+            tasks = item.find("tasks")
+            if tasks is None or not tasks.text:
+                yield jobid
+            else:
+                for task in expand_tasks(tasks.text):
+                    yield "%s.%d" % (jobid, task)
+
+
+def expand_tasks(tasks):
+    """Expands a task range such as 1-9:2,12 into the list of task ids."""
+    for part in tasks.split(","):
+        step = 1
+        if ":" in part:
+            part, step = part.split(":", 1)
+            step = int(step)
+        if "-" in part:
+            start, end = part.split("-", 1)
+            for task in range(int(start), int(end) + 1, step):
+                yield task
+        else:
+            yield int(part)
 
 
 def main():
@@ -22,7 +22,10 @@ def mkopts(ids):
     """Gets the command line for qstat."""
     if not ids:
         sys.exit(0)
-    return ["squeue", "-o", r"%A %t", "-j", ",".join(ids)]
+    # Array task ids are of the form jobid_taskid.  Query the whole array, and
+    # report each task separately.
+    jobs = sorted(set(jobid.split("_", 1)[0] for jobid in ids))
+    return ["squeue", "-r", "-o", r"%i %t", "-j", ",".join(jobs)]
 
 
 def execute(cmd):
@@ -12,15 +12,27 @@ const (
 	// Gets top-level information about a pipestance and all of its nodes.
 	QueryGetState = "/api/get-state"
 
+	// Streams state transitions, log messages, and alarms for a pipestance
+	// as server-sent events.
+	QueryEvents = "/api/events"
+
 	// Gets information about a pipestance's performance.
 	QueryGetPerf = "/api/get-perf"
 
+	// Gets runtime metrics in the Prometheus text exposition format.
+	QueryMetrics = "/metrics"
+
 	// Get the contents of a specific metadata file.
 	QueryGetMetadata = "/api/get-metadata"
 
 	// Restarts a failed pipestance.
 	QueryRestart = "/api/restart"
 
+	// Restarts a failed or completed pipestance after invalidating the stage
+	// or pipeline given by the fqname form value, and everything downstream
+	// of it.
+	QueryRerun = "/api/rerun"
+
 	// Get the contents of a pipestance's top-level metadata.
 	QueryGetMetadataTop = "/api/get-metadata-top/"
 
@@ -0,0 +1,54 @@
+//
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+//
+
+package api
+
+import (
+	"github.com/martian-lang/martian/martian/core"
+)
+
+// The kinds of events which can be sent on the QueryEvents stream.
+type EventType string
+
+const (
+	// The overall state of the pipestance changed.
+	EventPipestance EventType = "pipestance"
+
+	// The state of a fork of a stage or pipeline changed.
+	EventState EventType = "state"
+
+	// A message was written to the pipestance log.
+	EventLog EventType = "log"
+
+	// A stage fork which completed or failed raised one or more alarms.
+	EventAlarm EventType = "alarm"
+)
+
+// A single event sent by mrp on the QueryEvents stream.
+//
+// Each event is sent as a server-sent event, with the event type as the
+// event name and the json-encoded Event object as the data.
+type Event struct {
+	Type EventType `json:"type"`
+
+	// The time the event was observed, formatted as by util.Timestamp.
+	Time string `json:"time"`
+
+	// The fully-qualified name of the node, for state and alarm events.
+	Fqname string `json:"fqname,omitempty"`
+
+	// The index of the fork, for state and alarm events.
+	Fork int `json:"fork"`
+
+	// The new state, for state and pipestance events.
+	State core.MetadataState `json:"state,omitempty"`
+
+	// The previous state, for state and pipestance events.  This is empty
+	// for events which are sent as part of the initial snapshot when a
+	// client first connects.
+	OldState core.MetadataState `json:"old_state,omitempty"`
+
+	// The log or alarm text, for log and alarm events.
+	Message string `json:"message,omitempty"`
+}
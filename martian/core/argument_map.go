@@ -48,7 +48,7 @@ var nullBytes = []byte(syntax.KindNull)
 
 // Validate that all of the arguments in the map are declared parameters, and
 // that all declared parameters are set in the arguments to a value of the
-// correct type, or null.
+// correct type, or null if the parameter was not declared non-null.
 //
 // Hard errors are returned as the first parameter.  "soft" error messages
 // are returned in the second.
@@ -78,7 +78,12 @@ func (self LazyArgumentMap) ValidateInputs(types *syntax.TypeLookup,
 			fmt.Fprintf(&result, "Missing input parameter '%s'\n", param.GetId())
 			continue
 		} else if len(val) == 0 || bytes.Equal(val, nullBytes) {
-			// Allow for null output parameters
+			// Allow for null parameters, unless they were declared non-null.
+			if param.IsNonNull() {
+				fmt.Fprintf(&result,
+					"Null value for non-null %s input parameter '%s'\n",
+					tname(param), param.GetId())
+			}
 			continue
 		} else if err := checkJsonType(types,
 			val,
@@ -123,7 +128,7 @@ func (self LazyArgumentMap) ValidateInputs(types *syntax.TypeLookup,
 
 // Validate that all of the arguments in the map are declared parameters, and
 // that all declared parameters are set in the arguments to a value of the
-// correct type, or null.
+// correct type, or null if the parameter was not declared non-null.
 //
 // Hard errors are returned as the first parameter.  "soft" error messages
 // are returned in the second.
@@ -153,7 +158,12 @@ func (self LazyArgumentMap) ValidateOutputs(types *syntax.TypeLookup,
 			fmt.Fprintf(&result, "Missing output value '%s'\n", param.GetId())
 			continue
 		} else if len(val) == 0 || bytes.Equal(val, nullBytes) {
-			// Allow for null output parameters
+			// Allow for null parameters, unless they were declared non-null.
+			if param.IsNonNull() {
+				fmt.Fprintf(&result,
+					"Null value for non-null %s output value '%s'\n",
+					tname(param), param.GetId())
+			}
 			continue
 		} else if err := checkJsonType(types,
 			val,
@@ -197,7 +207,7 @@ func (self LazyArgumentMap) ValidateOutputs(types *syntax.TypeLookup,
 }
 
 // Validate that all of the arguments in the map are a value of the
-// correct type, or null.
+// correct type, or null if the parameter was not declared non-null.
 //
 // Hard errors are returned as the first parameter.  "soft" error messages
 // are returned in the second.
@@ -220,7 +230,13 @@ func (self MarshalerMap) ValidatePipelineOutputs(types *syntax.TypeLookup,
 			switch val := val.(type) {
 			case json.RawMessage:
 				if len(val) == 0 || bytes.Equal(val, nullBytes) {
-					// Allow for null output parameters
+					// Allow for null output parameters, unless they were
+					// declared non-null.
+					if param.NonNull {
+						fmt.Fprintf(&result,
+							"Null value for non-null %s output value '%s'\n",
+							tname(param), param.Id)
+					}
 					continue
 				} else if err := checkJsonType(types,
 					val,
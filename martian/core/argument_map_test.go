@@ -109,6 +109,87 @@ func TestArgumentMapValidateInputs(t *testing.T) {
 	}
 }
 
+func TestArgumentMapValidateEnumInputs(t *testing.T) {
+	lookup := syntax.NewTypeLookup()
+	if err := lookup.AddEnumType(&syntax.EnumType{
+		Id: "CHEMISTRY",
+		Values: []*syntax.EnumValue{
+			{Value: "SC3Pv2"},
+			{Value: "auto"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	param := &syntax.InParam{
+		Id:    "chemistry",
+		Tname: syntax.TypeId{Tname: "CHEMISTRY"},
+	}
+	params := syntax.InParams{
+		Table: map[string]*syntax.InParam{param.Id: param},
+		List:  []*syntax.InParam{param},
+	}
+	for _, good := range []string{`"auto"`, `null`} {
+		args := LazyArgumentMap{"chemistry": json.RawMessage(good)}
+		if err, msg := args.ValidateInputs(lookup, &params); err != nil {
+			t.Errorf("Validation error for %s: %v", good, err)
+		} else if msg != "" {
+			t.Errorf("Didn't expect a soft error message, got %s", msg)
+		}
+	}
+	args := LazyArgumentMap{"chemistry": json.RawMessage(`"SC5P"`)}
+	if err, _ := args.ValidateInputs(lookup, &params); err == nil {
+		t.Error("Expected error from unknown enum value, got none.")
+	} else if e := `Expected CHEMISTRY input parameter 'chemistry' ` +
+		`"SC5P" is not a legal value for enum CHEMISTRY ` +
+		`(expected one of "SC3Pv2", "auto")`; strings.TrimSpace(err.Error()) != e {
+		t.Errorf("Validation error: expected\n%s\ngot\n%v", e, err)
+	}
+}
+
+func TestArgumentMapValidateNonNull(t *testing.T) {
+	lookup := syntax.NewTypeLookup()
+	inParam := &syntax.InParam{
+		Id:      "count",
+		Tname:   syntax.TypeId{Tname: syntax.KindInt},
+		NonNull: true,
+	}
+	inParams := syntax.InParams{
+		Table: map[string]*syntax.InParam{inParam.Id: inParam},
+		List:  []*syntax.InParam{inParam},
+	}
+	outParam := &syntax.OutParam{
+		StructMember: syntax.StructMember{
+			Id:      "total",
+			Tname:   syntax.TypeId{Tname: syntax.KindInt, ArrayDim: 1},
+			NonNull: true,
+		},
+	}
+	outParams := syntax.OutParams{
+		Table: map[string]*syntax.OutParam{outParam.Id: outParam},
+		List:  []*syntax.OutParam{outParam},
+	}
+	args := LazyArgumentMap{"count": json.RawMessage(`3`)}
+	if err, _ := args.ValidateInputs(lookup, &inParams); err != nil {
+		t.Errorf("Validation error: %v", err)
+	}
+	args = LazyArgumentMap{"count": json.RawMessage(`null`)}
+	if err, _ := args.ValidateInputs(lookup, &inParams); err == nil {
+		t.Error("Expected error from null input, got none.")
+	} else if e := "Null value for non-null int input parameter 'count'"; strings.TrimSpace(err.Error()) != e {
+		t.Errorf("Validation error: expected\n%s\ngot\n%v", e, err)
+	}
+	outs := LazyArgumentMap{"total": json.RawMessage(`[]`)}
+	if err, _ := outs.ValidateOutputs(lookup, &outParams); err != nil {
+		t.Errorf("Validation error: %v", err)
+	}
+	outs = LazyArgumentMap{"total": json.RawMessage(`null`)}
+	if err, _ := outs.ValidateOutputs(lookup, &outParams); err == nil {
+		t.Error("Expected error from null output, got none.")
+	} else if e := "Null value for non-null int[] output value 'total'"; strings.TrimSpace(err.Error()) != e {
+		t.Errorf("Validation error: expected\n%s\ngot\n%v", e, err)
+	}
+}
+
 func TestArgumentMapValidateOutputs(t *testing.T) {
 	var def ChunkDef
 	if err := json.Unmarshal([]byte(`{
@@ -21,7 +21,8 @@ func (self *RuntimeError) Error() string {
 }
 
 // PipestanceInvocationError is returned when attempting to reattach to a
-// pipestance directory if the mro source code has changed.
+// pipestance directory if the top-level call in the mro source code has
+// changed.
 type PipestanceInvocationError struct {
 	Psid           string
 	InvocationPath string
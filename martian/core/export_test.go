@@ -0,0 +1,13 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+// Hooks for tests in the core_test package.
+
+// UnregisterJobManager removes a job manager registered by a test, so that
+// the test may be run more than once in the same process.
+func UnregisterJobManager(jobMode string) {
+	jobManagerRegistryMu.Lock()
+	defer jobManagerRegistryMu.Unlock()
+	delete(jobManagerRegistry, jobMode)
+}
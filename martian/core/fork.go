@@ -1386,6 +1386,19 @@ func (self *Fork) getDisabledSource(exp *syntax.DisabledExp) (syntax.Exp, error)
 	}
 }
 
+func (self *Fork) getCondSource(exp *syntax.CondExp) (syntax.Exp, error) {
+	ready, result, err := self.node.top.resolve(exp.Condition,
+		self.node.top.types.Get(syntax.TypeId{Tname: syntax.KindBool}),
+		self.forkId, readSizeLimit)
+	if err != nil {
+		return nil, err
+	}
+	if !ready {
+		panic("condition " + exp.Condition.GoString() + " was not ready")
+	}
+	return selectCondBranch(exp, result)
+}
+
 func (self *Fork) expandForkPart(must bool,
 	i int, part *ForkSourcePart,
 	split *syntax.SplitExp, result []ForkId) ([]ForkId, error) {
@@ -1468,6 +1481,12 @@ func (self *Fork) expandForkPartFromExp(must bool, i int, part *ForkSourcePart,
 			return nil, err
 		}
 		return self.expandForkPartFromExp(must, i, part, split, ee, result)
+	case *syntax.CondExp:
+		ee, err := self.getCondSource(exp)
+		if err != nil {
+			return nil, err
+		}
+		return self.expandForkPartFromExp(must, i, part, split, ee, result)
 	case *syntax.ArrayExp:
 		return self.expandForkFromObj(i, part, split, exp, exp, result)
 	case *syntax.MapExp:
@@ -1541,6 +1560,12 @@ func (self *Fork) expandForkSplitInnerPart(
 		}
 		return self.expandForkSplitInnerPart(
 			split, exp.Value, index)
+	case *syntax.CondExp:
+		ee, err := self.getCondSource(exp)
+		if err != nil {
+			return nil, err
+		}
+		return self.expandForkSplitInnerPart(split, ee, index)
 	case *syntax.SplitExp:
 		for _, id := range self.forkId {
 			if id.Split.Call == exp.Call {
@@ -20,6 +20,9 @@ type JobResources struct {
 	Threads float64 `json:"__threads,omitempty"`
 	MemGB   float64 `json:"__mem_gb,omitempty"`
 	VMemGB  float64 `json:"__vmem_gb,omitempty"`
+
+	// The wall-clock time limit for the job, in seconds.
+	Timeout int64 `json:"__timeout,omitempty"`
 }
 
 func (self *JobResources) ToLazyMap() LazyArgumentMap {
@@ -62,6 +65,9 @@ func (self *JobResources) ToLazyMap() LazyArgumentMap {
 	if self.Special != "" {
 		r["__special"], _ = json.Marshal(self.Special)
 	}
+	if self.Timeout != 0 {
+		r["__timeout"] = strconv.AppendInt(nil, self.Timeout, 10)
+	}
 	return r
 }
 
@@ -96,6 +102,12 @@ func (self *JobResources) updateFromLazyArgs(args LazyArgumentMap) error {
 		}
 		delete(args, "__special")
 	}
+	if v, ok := args["__timeout"]; ok {
+		if err := json.Unmarshal(v, &self.Timeout); err != nil {
+			return err
+		}
+		delete(args, "__timeout")
+	}
 	return nil
 }
 
@@ -177,7 +189,8 @@ func (self *ChunkDef) UnmarshalJSON(b []byte) error {
 		if err := res.updateFromLazyArgs(self.Args); err != nil {
 			return err
 		}
-		if res.Threads != 0 || res.MemGB != 0 || res.VMemGB != 0 || res.Special != "" {
+		if res.Threads != 0 || res.MemGB != 0 || res.VMemGB != 0 ||
+			res.Special != "" || res.Timeout != 0 {
 			self.Resources = &res
 		}
 	}
@@ -59,6 +59,7 @@ func TestChunkDefUnmarshal(t *testing.T) {
 	if err := json.Unmarshal([]byte(`{
 		"__threads": 4,
 		"__mem_gb": 3,
+		"__timeout": 600,
 		"foo": 12,
 		"bar": 1.2
 	}`), &def); err != nil {
@@ -75,6 +76,10 @@ func TestChunkDefUnmarshal(t *testing.T) {
 			t.Errorf("Incorrect mem_gb: expected 3, got %g",
 				def.Resources.MemGB)
 		}
+		if def.Resources.Timeout != 600 {
+			t.Errorf("Incorrect timeout: expected 600, got %d",
+				def.Resources.Timeout)
+		}
 	}
 	if len(def.Args) != 2 {
 		t.Errorf("Incorrect number of args: expected 4, got %d", len(def.Args))
@@ -20,49 +20,79 @@ import (
 )
 
 // JobManager provides an interface for executing jobs.
+//
+// In addition to the built-in job managers, implementations may be added
+// with RegisterJobManager, making them available to mrp through --jobmode.
+//
+// A job manager is handed a command line to run for each job, along with the
+// Metadata object for the job.  It must arrange for the command to be run
+// with the given environment, in the working directory given by
+// Metadata.FilesPath, with its standard output and error going to the
+// StdOut and StdErr metadata files.  The job itself (mrjob) takes care of
+// reporting progress and completion through the metadata files.  The job
+// manager is only responsible for reporting failures to start the job,
+// which it should do with Metadata.WriteErrorString.
 type JobManager interface {
-	execJob(shellCmd string,
+	// ExecJob starts or submits a job.  It should not block waiting for the
+	// job to start.
+	//
+	// The shellName is one of "split", "main", or "join".  Jobs for
+	// preflight stages which must run before anything else have preflight
+	// set to true.
+	//
+	// Once the job has been handed off, implementations should call
+	// Metadata.ClearQueuedLocally, and if the job was assigned an ID which
+	// can be passed to CheckQueue, write it to the JobId metadata file.
+	ExecJob(shellCmd string,
 		args []string,
 		env map[string]string,
 		md *Metadata,
 		res *JobResources,
 		fqname, shellName string,
 		preflight bool)
-	endJob(*Metadata)
+
+	// EndJob is called when a job started by ExecJob is no longer running,
+	// successfully or otherwise.
+	EndJob(*Metadata)
 
 	// Given a list of candidate job IDs, returns a list of jobIds which may be
 	// still queued or running, as well as the stderr output of the queue check.
 	// If this job manager doesn't know how to check the queue or the query
 	// fails, it simply returns the list it was given.
-	checkQueue([]string, context.Context) ([]string, string)
-	// Returns true if checkQueue does something useful.
-	hasQueueCheck() bool
+	CheckQueue([]string, context.Context) ([]string, string)
+	// Returns true if CheckQueue does something useful.
+	HasQueueCheck() bool
 	// Returns the amount of time to wait, after a job is found to be unknown
 	// to the job manager, before declaring the job dead.  This is to protect
 	// against races between NFS caching in the directories Martian watches and
 	// whatever the queue manager uses to syncronize state.
-	queueCheckGrace() time.Duration
+	QueueCheckGrace() time.Duration
 
 	// Update resource availability.
 	//
 	// For local mode, this means free memory and possibly loadavg.
 	//
 	// For remote job managers, this means maxjobs.
-	refreshResources(localMode bool) error
+	RefreshResources(localMode bool) error
+
+	// Get the resources which would actually be reserved for a job which
+	// requested the given resources, after applying defaults and limits.
 	GetSystemReqs(*JobResources) JobResources
 	GetMaxCores() int
 	GetMaxMemGB() int
 	GetSettings() *JobManagerSettings
 
 	// Reset the max jobs semaphore.
-	resetMaxJobs()
-	// Re-add a job to the max jobs semaphore.
-	reattach(*Metadata)
+	ResetMaxJobs()
+	// Re-add a job to the max jobs semaphore, when reattaching to a
+	// pipestance in which the job was already queued or running.
+	Reattach(*Metadata)
 }
 
-// Set environment variables which control thread count.  Do not override
-// envs from above.
-func threadEnvs(self JobManager, threads int,
+// ThreadEnvs returns the given environment with the addition of the
+// variables, configured in the job manager settings, which control thread
+// count.  Variables which are already set in envs are not overridden.
+func ThreadEnvs(self JobManager, threads int,
 	envs map[string]string) map[string]string {
 	thr := strconv.Itoa(threads)
 	newEnvs := make(map[string]string)
@@ -92,6 +122,36 @@ type JobModeJson struct {
 	JobEnvs         []*JobModeEnv `json:"envs"`
 	QueueQueryGrace int           `json:"queue_query_grace_secs,omitempty"`
 	AlwaysVmem      bool          `json:"mem_is_vmem,omitempty"`
+
+	// If set, chunks of the same fork with identical resource requirements
+	// may be submitted together as an array job.
+	Array *JobModeArrayJson `json:"array,omitempty"`
+}
+
+// JobModeArrayJson describes how to submit and track array jobs for a
+// cluster job mode.
+type JobModeArrayJson struct {
+	// The environment variable which is set to the (1-based) index of the
+	// task within the array, e.g. SLURM_ARRAY_TASK_ID.
+	TaskEnv string `json:"task_env"`
+
+	// The string which the cluster replaces with the task index in the
+	// output and error file paths, e.g. %a for slurm.
+	TaskPattern string `json:"task_pattern"`
+
+	// A format string which produces the job ID of a task from the job ID
+	// of the array and the task index, e.g. "%s_%d" for slurm.
+	TaskId string `json:"task_id"`
+
+	// If set, a format string which produces the job name for an array job
+	// from the name and number of tasks, for clusters like LSF where the
+	// array is specified as part of the job name.  Otherwise, array
+	// submission is only enabled if the job template contains
+	// __MRO_ARRAY_SIZE__.
+	JobName string `json:"job_name,omitempty"`
+
+	// The maximum number of tasks to submit in a single array job.
+	MaxSize int `json:"max_size,omitempty"`
 }
 
 type JobManagerSettings struct {
@@ -114,6 +174,7 @@ type jobManagerConfig struct {
 	jobTemplate      string
 	jobCmd           string
 	jobCmdArgs       []string
+	array            *JobModeArrayJson
 	queueQueryGrace  time.Duration
 	alwaysVmem       bool
 	threadingEnabled bool
@@ -255,6 +316,14 @@ Please consult the documentation for details.
 `)
 	}
 
+	// Check if array jobs are enabled.
+	var array *JobModeArrayJson
+	if a := jobModeJson.Array; a != nil && a.TaskEnv != "" && a.TaskId != "" &&
+		(a.JobName != "" || strings.Contains(jobTemplate, "__MRO_ARRAY_SIZE__")) {
+		array = a
+		util.LogInfo("jobmngr", "Array job submission enabled.")
+	}
+
 	// Verify job command exists
 	if _, err := exec.LookPath(jobCmd); err != nil {
 		return jobManagerConfig{}, fmt.Errorf(
@@ -283,6 +352,7 @@ Please consult the documentation for details.
 		jobCmd:           jobCmd,
 		jobCmdArgs:       jobModeJson.Args,
 		alwaysVmem:       jobModeJson.AlwaysVmem,
+		array:            array,
 		queueQueryCmd:    jobModeJson.QueueQuery,
 		queueQueryGrace:  queueGrace,
 		jobResourcesOpt:  jobResourcesOpt,
@@ -0,0 +1,724 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+// Job manager which runs jobs as Kubernetes batch/v1 Jobs.
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martian-lang/martian/martian/util"
+)
+
+const (
+	kubernetesMode = "kubernetes"
+
+	// The label applied to jobs and pods created by martian, with the job
+	// name as the value.
+	kubernetesJobLabel = "martian.job-name"
+
+	// Location of in-cluster service account credentials.
+	kubernetesServiceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	// How long finished jobs are kept around before kubernetes cleans them
+	// up, unless the template specifies otherwise.
+	kubernetesJobTtlSecs = 600
+
+	// Default queue check grace period if none is configured.
+	kubernetesQueueGrace = 5 * time.Minute
+)
+
+// KubernetesJobManager submits jobs to a Kubernetes cluster through its
+// REST API.
+//
+// Each job is created from the template in jobmanagers/kubernetes.template,
+// which is a batch/v1 Job manifest in json format.  The first container in
+// the pod template is used to run the job, with its command, working
+// directory, environment, and resource requests filled in by martian.  The
+// container image must have the pipestance directory and martian
+// installation mounted at the same paths as they are for mrp.
+//
+// The API server and credentials are taken from the in-cluster service
+// account, unless MRO_KUBERNETES_API is set, in which case that url is used
+// directly (for example, with kubectl proxy).  The namespace may be
+// overridden with MRO_KUBERNETES_NAMESPACE.
+//
+// Stages with a __special resource request are scheduled using the node
+// selector mapped to it in MRO_JOBRESOURCES, for example
+// MRO_JOBRESOURCES="highmem:pool=highmem,disk=ssd".
+type KubernetesJobManager struct {
+	config               jobManagerConfig
+	jobResourcesMappings map[string]string
+	jobSem               *MaxJobsSemaphore
+	limiter              *time.Ticker
+	client               *http.Client
+	template             map[string]interface{}
+	apiUrl               string
+	namespace            string
+	token                string
+	memGBPerCore         int
+	maxJobs              int
+	jobFreqMillis        int
+	queueMutex           sync.Mutex
+	debug                bool
+}
+
+func init() {
+	RegisterJobManager(kubernetesMode, func(_ string, c *RuntimeOptions,
+		jobConfig *JobManagerJson) (JobManager, error) {
+		jm, err := NewKubernetesJobManager(c.MemPerCore, c.MaxJobs,
+			c.JobFreqMillis, c.ResourceSpecial, jobConfig, c.Debug)
+		if err != nil {
+			return nil, err
+		}
+		return jm, nil
+	})
+}
+
+func NewKubernetesJobManager(memGBPerCore int, maxJobs int, jobFreqMillis int,
+	jobResources string, config *JobManagerJson, debug bool) (*KubernetesJobManager, error) {
+	jobPath := util.RelPath(path.Join("..", "jobmanagers"))
+	templateFile := path.Join(jobPath, kubernetesMode+".template")
+	b, err := os.ReadFile(templateFile)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf(`Job manager template file %s does not exist.
+
+To set up a job manager template, please follow instructions in %s.`,
+			templateFile, templateFile+".example")
+	} else if err != nil {
+		return nil, err
+	}
+	util.LogInfo("jobmngr", "Job template = %s", templateFile)
+
+	apiUrl := os.Getenv("MRO_KUBERNETES_API")
+	client := &http.Client{Timeout: time.Minute}
+	var token string
+	if apiUrl == "" {
+		host := os.Getenv("KUBERNETES_SERVICE_HOST")
+		port := os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf(
+				"kubernetes job mode requires either running inside a " +
+					"cluster or setting MRO_KUBERNETES_API")
+		}
+		apiUrl = "https://" + net.JoinHostPort(host, port)
+		if b, err := os.ReadFile(path.Join(
+			kubernetesServiceAccountPath, "token")); err == nil {
+			token = string(bytes.TrimSpace(b))
+		}
+		if ca, err := os.ReadFile(path.Join(
+			kubernetesServiceAccountPath, "ca.crt")); err == nil {
+			pool := x509.NewCertPool()
+			if pool.AppendCertsFromPEM(ca) {
+				client.Transport = &http.Transport{
+					Proxy:           http.ProxyFromEnvironment,
+					TLSClientConfig: &tls.Config{RootCAs: pool},
+				}
+			}
+		}
+	}
+	namespace := os.Getenv("MRO_KUBERNETES_NAMESPACE")
+	if namespace == "" {
+		if b, err := os.ReadFile(path.Join(
+			kubernetesServiceAccountPath, "namespace")); err == nil {
+			namespace = string(bytes.TrimSpace(b))
+		} else {
+			namespace = "default"
+		}
+	}
+	util.LogInfo("jobmngr", "Kubernetes API = %s, namespace = %s",
+		apiUrl, namespace)
+	grace := kubernetesQueueGrace
+	if jm := config.JobModes[kubernetesMode]; jm != nil && jm.QueueQueryGrace > 0 {
+		grace = time.Duration(jm.QueueQueryGrace) * time.Second
+	}
+	return newKubernetesJobManager(b, apiUrl, namespace, token, client,
+		memGBPerCore, maxJobs, jobFreqMillis,
+		jobResources, config.JobSettings, grace, debug)
+}
+
+func newKubernetesJobManager(template []byte,
+	apiUrl, namespace, token string, client *http.Client,
+	memGBPerCore, maxJobs, jobFreqMillis int,
+	jobResources string, settings *JobManagerSettings,
+	queueGrace time.Duration, debug bool) (*KubernetesJobManager, error) {
+	self := &KubernetesJobManager{
+		config: jobManagerConfig{
+			jobSettings:      settings,
+			queueQueryGrace:  queueGrace,
+			threadingEnabled: true,
+		},
+		client:        client,
+		apiUrl:        strings.TrimSuffix(apiUrl, "/"),
+		namespace:     namespace,
+		token:         token,
+		memGBPerCore:  memGBPerCore,
+		maxJobs:       maxJobs,
+		jobFreqMillis: jobFreqMillis,
+		debug:         debug,
+	}
+	if err := json.Unmarshal(stripTemplateComments(template),
+		&self.template); err != nil {
+		return self, fmt.Errorf("parsing kubernetes job template: %w", err)
+	}
+	if _, err := podContainer(self.template); err != nil {
+		return self, fmt.Errorf("invalid kubernetes job template: %w", err)
+	}
+	self.jobResourcesMappings = parseJobResourcesMappings(jobResources)
+	if self.maxJobs > 0 {
+		self.jobSem = NewMaxJobsSemaphore(self.maxJobs)
+	}
+	if self.jobFreqMillis > 0 {
+		self.limiter = time.NewTicker(time.Millisecond * time.Duration(self.jobFreqMillis))
+	}
+	return self, nil
+}
+
+// Remove lines starting with '#', so that the template can contain usage
+// instructions like the other job templates.
+func stripTemplateComments(template []byte) []byte {
+	lines := bytes.Split(template, []byte("\n"))
+	result := lines[:0]
+	for _, line := range lines {
+		if !bytes.HasPrefix(bytes.TrimSpace(line), []byte("#")) {
+			result = append(result, line)
+		}
+	}
+	return bytes.Join(result, []byte("\n"))
+}
+
+// Get a json object member, creating it if it does not exist.
+func jsonObject(parent map[string]interface{}, key string) (map[string]interface{}, error) {
+	switch v := parent[key].(type) {
+	case nil:
+		m := make(map[string]interface{})
+		parent[key] = m
+		return m, nil
+	case map[string]interface{}:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s must be an object", key)
+	}
+}
+
+// Get the container which will run the job from the job manifest.
+func podContainer(job map[string]interface{}) (map[string]interface{}, error) {
+	spec, err := jsonObject(job, "spec")
+	if err != nil {
+		return nil, err
+	}
+	template, err := jsonObject(spec, "template")
+	if err != nil {
+		return nil, err
+	}
+	podSpec, err := jsonObject(template, "spec")
+	if err != nil {
+		return nil, err
+	}
+	containers, ok := podSpec["containers"].([]interface{})
+	if !ok || len(containers) == 0 {
+		return nil, fmt.Errorf("spec.template.spec.containers must be a non-empty list")
+	}
+	container, ok := containers[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("spec.template.spec.containers[0] must be an object")
+	}
+	return container, nil
+}
+
+// Make a deep copy of the job template.
+func (self *KubernetesJobManager) newJobObject() map[string]interface{} {
+	b, err := json.Marshal(self.template)
+	if err != nil {
+		panic(err)
+	}
+	var job map[string]interface{}
+	if err := json.Unmarshal(b, &job); err != nil {
+		panic(err)
+	}
+	return job
+}
+
+func (self *KubernetesJobManager) RefreshResources(bool) error {
+	if self.jobSem != nil {
+		self.jobSem.FindDone()
+	}
+	return nil
+}
+
+func (self *KubernetesJobManager) GetMaxCores() int {
+	return 0
+}
+
+func (self *KubernetesJobManager) GetMaxMemGB() int {
+	return 0
+}
+
+func (self *KubernetesJobManager) GetSettings() *JobManagerSettings {
+	return self.config.jobSettings
+}
+
+func (self *KubernetesJobManager) GetSystemReqs(resRequest *JobResources) JobResources {
+	res := *resRequest
+	if res.Threads == 0 {
+		res.Threads = float64(self.config.jobSettings.ThreadsPerJob)
+	} else if res.Threads < 0 {
+		res.Threads = -res.Threads
+	}
+	if res.MemGB < 0 {
+		res.MemGB = -res.MemGB
+	}
+	if res.MemGB == 0 {
+		res.MemGB = float64(self.config.jobSettings.MemGBPerJob)
+	}
+	if res.VMemGB < 1 {
+		res.VMemGB = res.MemGB + float64(self.config.jobSettings.ExtraVmemGB)
+	}
+	if self.memGBPerCore > 0 {
+		if threadsForMemory := res.MemGB /
+			float64(self.memGBPerCore); threadsForMemory > res.Threads {
+			res.Threads = threadsForMemory
+		}
+	}
+	// Unlike most cluster managers, kubernetes supports fractional cpu
+	// requests, so the thread count is not rounded up.
+	return res
+}
+
+var kubernetesNameInvalid = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Generate a name for the job which is valid as a kubernetes object name
+// and label value, and unique to this submission.
+func kubernetesJobName(fqname, shellName string) string {
+	h := sha256.Sum256([]byte(fqname + "." + shellName + "." +
+		strconv.FormatInt(time.Now().UnixNano(), 36)))
+	base := kubernetesNameInvalid.ReplaceAllString(
+		strings.ToLower(strings.TrimPrefix(fqname, "ID.")+"-"+shellName), "-")
+	const maxBase = 63 - len("mro--") - 10
+	if len(base) > maxBase {
+		base = base[len(base)-maxBase:]
+	}
+	return "mro-" + strings.Trim(base, "-") + "-" + hex.EncodeToString(h[:5])
+}
+
+func (self *KubernetesJobManager) jobManifest(shellCmd string, argv []string,
+	envs map[string]string, metadata *Metadata, resRequest *JobResources,
+	name string) (map[string]interface{}, error) {
+	res := self.GetSystemReqs(resRequest)
+	threads := int(math.Ceil(res.Threads))
+
+	job := self.newJobObject()
+	job["apiVersion"] = "batch/v1"
+	job["kind"] = "Job"
+	meta, err := jsonObject(job, "metadata")
+	if err != nil {
+		return nil, err
+	}
+	meta["name"] = name
+	meta["namespace"] = self.namespace
+	labels, err := jsonObject(meta, "labels")
+	if err != nil {
+		return nil, err
+	}
+	labels[kubernetesJobLabel] = name
+
+	spec, err := jsonObject(job, "spec")
+	if err != nil {
+		return nil, err
+	}
+	// Martian handles retries itself.
+	spec["backoffLimit"] = 0
+	if _, ok := spec["ttlSecondsAfterFinished"]; !ok {
+		spec["ttlSecondsAfterFinished"] = kubernetesJobTtlSecs
+	}
+	podTemplate, err := jsonObject(spec, "template")
+	if err != nil {
+		return nil, err
+	}
+	podMeta, err := jsonObject(podTemplate, "metadata")
+	if err != nil {
+		return nil, err
+	}
+	podLabels, err := jsonObject(podMeta, "labels")
+	if err != nil {
+		return nil, err
+	}
+	podLabels[kubernetesJobLabel] = name
+	podSpec, err := jsonObject(podTemplate, "spec")
+	if err != nil {
+		return nil, err
+	}
+	podSpec["restartPolicy"] = "Never"
+	if len(res.Special) > 0 {
+		if selector, ok := self.jobResourcesMappings[res.Special]; ok {
+			nodeSelector, err := jsonObject(podSpec, "nodeSelector")
+			if err != nil {
+				return nil, err
+			}
+			for _, kv := range strings.Split(selector, ",") {
+				if k, v, ok := strings.Cut(kv, "="); ok {
+					nodeSelector[strings.TrimSpace(k)] = strings.TrimSpace(v)
+				} else if kv != "" {
+					util.LogInfo("jobmngr",
+						"Could not parse node selector %s", kv)
+				}
+			}
+		}
+	}
+
+	container, err := podContainer(job)
+	if err != nil {
+		return nil, err
+	}
+	// Run through the shell in order to redirect output to the metadata
+	// directory, where mrp expects to find it.
+	cmdline := append(make([]string, 0, len(argv)+1), shellCmd)
+	cmdline = append(cmdline, argv...)
+	var script strings.Builder
+	script.WriteString("exec")
+	for _, arg := range cmdline {
+		script.WriteByte(' ')
+		script.WriteString(shellSafeQuote(arg))
+	}
+	script.WriteString(" > ")
+	script.WriteString(shellSafeQuote(metadata.MetadataFilePath("stdout")))
+	script.WriteString(" 2> ")
+	script.WriteString(shellSafeQuote(metadata.MetadataFilePath("stderr")))
+	container["command"] = []string{"/bin/sh", "-c", script.String()}
+	delete(container, "args")
+	container["workingDir"] = metadata.FilesPath()
+
+	jobEnvs := ThreadEnvs(self, threads, envs)
+	envKeys := make([]string, 0, len(jobEnvs))
+	for k := range jobEnvs {
+		envKeys = append(envKeys, k)
+	}
+	sort.Strings(envKeys)
+	envList, _ := container["env"].([]interface{})
+	for _, k := range envKeys {
+		envList = append(envList, map[string]interface{}{
+			"name":  k,
+			"value": jobEnvs[k],
+		})
+	}
+	container["env"] = envList
+
+	resources, err := jsonObject(container, "resources")
+	if err != nil {
+		return nil, err
+	}
+	requests, err := jsonObject(resources, "requests")
+	if err != nil {
+		return nil, err
+	}
+	limits, err := jsonObject(resources, "limits")
+	if err != nil {
+		return nil, err
+	}
+	cpu := strconv.Itoa(int(math.Ceil(res.Threads*1000))) + "m"
+	mem := strconv.Itoa(int(math.Ceil(res.MemGB*1024))) + "Mi"
+	requests["cpu"] = cpu
+	requests["memory"] = mem
+	limits["memory"] = mem
+	return job, nil
+}
+
+func (self *KubernetesJobManager) ExecJob(shellCmd string, argv []string,
+	envs map[string]string, metadata *Metadata, resRequest *JobResources,
+	fqname string, shellName string, preflight bool) {
+	ctx, task := trace.NewTask(context.Background(), "queueKubernetes")
+	if self.maxJobs <= 0 {
+		defer task.End()
+		self.sendJob(shellCmd, argv, envs, metadata, resRequest,
+			fqname, shellName, ctx)
+		return
+	}
+	go func(ctx context.Context, task *trace.Task, jobSem *MaxJobsSemaphore) {
+		defer task.End()
+		if self.debug {
+			util.LogInfo("jobmngr", "Waiting for job: %s", fqname)
+		}
+		if success := jobSem.Acquire(metadata, false); !success {
+			if self.debug {
+				util.LogInfo("jobmngr",
+					"Wait for job %s canceled.",
+					fqname)
+			}
+			return
+		}
+		if self.debug {
+			util.LogInfo("jobmngr", "Job sent: %s", fqname)
+		}
+		self.sendJob(shellCmd, argv, envs, metadata, resRequest,
+			fqname, shellName, ctx)
+	}(ctx, task, self.jobSem)
+}
+
+func (self *KubernetesJobManager) EndJob(metadata *Metadata) {
+	if self.jobSem != nil {
+		self.jobSem.Release(metadata)
+	}
+}
+
+func (self *KubernetesJobManager) newRequest(ctx context.Context,
+	method, p string, query url.Values, body []byte) (*http.Request, error) {
+	u := self.apiUrl + p
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if self.token != "" {
+		req.Header.Set("Authorization", "Bearer "+self.token)
+	}
+	return req, nil
+}
+
+// Send a request to the API server and decode the response into target.
+func (self *KubernetesJobManager) do(req *http.Request, target interface{}) error {
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		var status struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &status) == nil && status.Message != "" {
+			return fmt.Errorf("%s: %s", resp.Status, status.Message)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	if target != nil {
+		return json.Unmarshal(body, target)
+	}
+	return nil
+}
+
+func (self *KubernetesJobManager) sendJob(shellCmd string, argv []string,
+	envs map[string]string, metadata *Metadata, resRequest *JobResources,
+	fqname, shellName string, ctx context.Context) {
+	name := kubernetesJobName(fqname, shellName)
+	job, err := self.jobManifest(shellCmd, argv, envs, metadata,
+		resRequest, name)
+	if err != nil {
+		metadata.WriteErrorString("kubernetes job error: " + err.Error())
+		return
+	}
+	body, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		metadata.WriteErrorString("kubernetes job error: " + err.Error())
+		return
+	}
+	if err := metadata.WriteRawBytes("jobscript", body); err != nil {
+		util.LogError(err, "jobmngr", "Could not write job script.")
+	}
+	req, err := self.newRequest(ctx, http.MethodPost,
+		"/apis/batch/v1/namespaces/"+url.PathEscape(self.namespace)+"/jobs",
+		nil, body)
+	if err != nil {
+		metadata.WriteErrorString("kubernetes job error: " + err.Error())
+		return
+	}
+
+	// As with RemoteJobManager, only allow one pending submission at a time.
+	self.queueMutex.Lock()
+	defer self.queueMutex.Unlock()
+	if self.limiter != nil {
+		<-(self.limiter.C)
+		if self.debug {
+			util.LogInfo("jobmngr", "Job rate-limit released: %s", fqname)
+		}
+	}
+
+	util.EnterCriticalSection()
+	defer util.ExitCriticalSection()
+	if err := metadata.ClearQueuedLocally(); err != nil {
+		util.LogError(err, "jobmngr", "Error removing queue sentinel file.")
+	}
+	if err := self.do(req, nil); err != nil {
+		metadata.WriteErrorString("kubernetes job submission error:\n" + err.Error())
+		return
+	}
+	if err := metadata.WriteRaw(JobId, name); err != nil {
+		util.LogError(err, "jobmngr", "Could not write job id file.")
+	}
+}
+
+// Returns the subset of the given job names which are pending or running.
+//
+// A job is considered to be alive until kubernetes marks it as complete or
+// failed, even if it does not have a pod yet, for example because it is
+// waiting for quota or for a node to be scheduled on.  For jobs which are
+// not alive, the state of their pods, if any, is returned for diagnostic
+// purposes.
+func (self *KubernetesJobManager) CheckQueue(ids []string, ctx context.Context) ([]string, string) {
+	if len(ids) == 0 {
+		return ids, ""
+	}
+	req, err := self.newRequest(ctx, http.MethodGet,
+		"/apis/batch/v1/namespaces/"+url.PathEscape(self.namespace)+"/jobs",
+		kubernetesLabelQuery(ids), nil)
+	if err != nil {
+		return ids, err.Error()
+	}
+	var jobs struct {
+		Items []struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Status struct {
+				Active     int `json:"active"`
+				Succeeded  int `json:"succeeded"`
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := self.do(req, &jobs); err != nil {
+		return ids, err.Error()
+	}
+	alive := make(map[string]struct{}, len(jobs.Items))
+	for _, job := range jobs.Items {
+		// A job with no active pods and a successful one is finished,
+		// even if its condition has not been updated yet.
+		finished := job.Status.Active == 0 && job.Status.Succeeded > 0
+		for _, cond := range job.Status.Conditions {
+			if (cond.Type == "Complete" || cond.Type == "Failed") &&
+				cond.Status == "True" {
+				finished = true
+			}
+		}
+		if !finished {
+			alive[job.Metadata.Labels[kubernetesJobLabel]] = struct{}{}
+		}
+	}
+	result := make([]string, 0, len(alive))
+	dead := make([]string, 0, len(ids)-len(alive))
+	for _, id := range ids {
+		if _, ok := alive[id]; ok {
+			result = append(result, id)
+		} else {
+			dead = append(dead, id)
+		}
+	}
+	if len(dead) == 0 {
+		return result, ""
+	}
+	return result, self.describePods(dead, ctx)
+}
+
+// kubernetesLabelQuery returns the query for objects labeled with any of
+// the given job names.
+func kubernetesLabelQuery(ids []string) url.Values {
+	return url.Values{
+		"labelSelector": []string{
+			kubernetesJobLabel + " in (" + strings.Join(ids, ",") + ")",
+		},
+	}
+}
+
+// describePods returns a description of the state of the pods for the
+// given jobs.
+func (self *KubernetesJobManager) describePods(ids []string, ctx context.Context) string {
+	req, err := self.newRequest(ctx, http.MethodGet,
+		"/api/v1/namespaces/"+url.PathEscape(self.namespace)+"/pods",
+		kubernetesLabelQuery(ids), nil)
+	if err != nil {
+		return err.Error()
+	}
+	var pods struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Status struct {
+				Phase   string `json:"phase"`
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := self.do(req, &pods); err != nil {
+		return err.Error()
+	}
+	var buf strings.Builder
+	for _, pod := range pods.Items {
+		fmt.Fprintf(&buf, "%s (job %s): %s",
+			pod.Metadata.Name,
+			pod.Metadata.Labels[kubernetesJobLabel],
+			pod.Status.Phase)
+		if pod.Status.Reason != "" {
+			buf.WriteString(" " + pod.Status.Reason)
+		}
+		if pod.Status.Message != "" {
+			buf.WriteString(": " + pod.Status.Message)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+func (self *KubernetesJobManager) HasQueueCheck() bool {
+	return true
+}
+
+func (self *KubernetesJobManager) QueueCheckGrace() time.Duration {
+	return self.config.queueQueryGrace
+}
+
+// Reset the max jobs semaphore.
+func (self *KubernetesJobManager) ResetMaxJobs() {
+	oldSem := self.jobSem
+	if oldSem != nil {
+		self.jobSem = NewMaxJobsSemaphore(oldSem.Limit)
+		oldSem.Clear()
+	}
+}
+
+// Re-add a job to the max jobs semaphore.
+func (self *KubernetesJobManager) Reattach(md *Metadata) {
+	if self.jobSem == nil {
+		return
+	}
+	self.jobSem.Acquire(md, true)
+}
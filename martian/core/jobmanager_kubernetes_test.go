@@ -0,0 +1,257 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testKubernetesTemplate = `# A comment line.
+{
+  "apiVersion": "batch/v1",
+  "kind": "Job",
+  "spec": {
+    "template": {
+      "spec": {
+        "containers": [
+          {
+            "name": "martian",
+            "image": "example/martian",
+            "env": [{"name": "FOO", "value": "bar"}]
+          }
+        ]
+      }
+    }
+  }
+}
+`
+
+// A minimal stand-in for the kubernetes API server, which records submitted
+// jobs and reports a pod for each of them which is not pending.
+type fakeKubernetesApi struct {
+	jobs      []map[string]interface{}
+	succeeded map[string]bool
+	pending   map[string]bool
+	lock      sync.Mutex
+}
+
+func (f *fakeKubernetesApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	switch {
+	case r.Method == http.MethodPost &&
+		r.URL.Path == "/apis/batch/v1/namespaces/testns/jobs":
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var job map[string]interface{}
+		if err := json.Unmarshal(b, &job); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.jobs = append(f.jobs, job)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(b)
+	case r.Method == http.MethodGet &&
+		r.URL.Path == "/apis/batch/v1/namespaces/testns/jobs":
+		sel := r.URL.Query().Get("labelSelector")
+		if !strings.HasPrefix(sel, kubernetesJobLabel+" in (") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		type condition struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		}
+		type jobStatus struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Status struct {
+				Active     int         `json:"active,omitempty"`
+				Succeeded  int         `json:"succeeded,omitempty"`
+				Conditions []condition `json:"conditions,omitempty"`
+			} `json:"status"`
+		}
+		var result struct {
+			Items []jobStatus `json:"items"`
+		}
+		for _, job := range f.jobs {
+			name := job["metadata"].(map[string]interface{})["name"].(string)
+			if !strings.Contains(sel, name) {
+				continue
+			}
+			var j jobStatus
+			j.Metadata.Labels = map[string]string{kubernetesJobLabel: name}
+			if f.succeeded[name] {
+				j.Status.Succeeded = 1
+				j.Status.Conditions = []condition{{"Complete", "True"}}
+			} else if !f.pending[name] {
+				j.Status.Active = 1
+			}
+			result.Items = append(result.Items, j)
+		}
+		_ = json.NewEncoder(w).Encode(&result)
+	case r.Method == http.MethodGet &&
+		r.URL.Path == "/api/v1/namespaces/testns/pods":
+		sel := r.URL.Query().Get("labelSelector")
+		if !strings.HasPrefix(sel, kubernetesJobLabel+" in (") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		type pod struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Status struct {
+				Phase string `json:"phase"`
+			} `json:"status"`
+		}
+		var result struct {
+			Items []pod `json:"items"`
+		}
+		for _, job := range f.jobs {
+			name := job["metadata"].(map[string]interface{})["name"].(string)
+			if !strings.Contains(sel, name) || f.pending[name] {
+				continue
+			}
+			var p pod
+			p.Metadata.Labels = map[string]string{kubernetesJobLabel: name}
+			if f.succeeded[name] {
+				p.Status.Phase = "Succeeded"
+			} else {
+				p.Status.Phase = "Running"
+			}
+			result.Items = append(result.Items, p)
+		}
+		_ = json.NewEncoder(w).Encode(&result)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestKubernetesJobManager(t *testing.T) {
+	t.Parallel()
+	api := fakeKubernetesApi{
+		succeeded: make(map[string]bool),
+		pending:   make(map[string]bool),
+	}
+	server := httptest.NewServer(&api)
+	defer server.Close()
+
+	jm, err := newKubernetesJobManager([]byte(testKubernetesTemplate),
+		server.URL, "testns", "token", server.Client(),
+		0, 0, 0, "highmem:pool=highmem,disk=ssd",
+		&JobManagerSettings{
+			ThreadsPerJob: 1,
+			MemGBPerJob:   2,
+			ThreadEnvs:    []string{"OMP_NUM_THREADS"},
+		}, time.Minute, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var submitted []string
+	for i, res := range []JobResources{
+		{Threads: 2.5, MemGB: 3, Special: "highmem"},
+		{},
+	} {
+		md := NewMetadata("ID.ps.PIPE.STAGE.fork0.chnk"+string(rune('0'+i)),
+			t.TempDir())
+		md.WriteTime(QueuedLocally)
+		jm.ExecJob("/bin/mrjob", []string{"stage", "main"}, nil,
+			md, &res, md.fqname, "main", false)
+		if !md.exists(JobId) {
+			t.Fatalf("job %d: expected job id, got error %s",
+				i, md.readRaw(Errors))
+		}
+		if md.exists(QueuedLocally) {
+			t.Errorf("job %d: expected queue sentinel to be removed", i)
+		}
+		submitted = append(submitted, md.readRaw(JobId))
+	}
+	if len(api.jobs) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(api.jobs))
+	}
+	if submitted[0] == submitted[1] {
+		t.Error("job names should be unique")
+	}
+
+	job := api.jobs[0]
+	podSpec := job["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	if sel, ok := podSpec["nodeSelector"].(map[string]interface{}); !ok {
+		t.Error("expected node selector")
+	} else if sel["pool"] != "highmem" || sel["disk"] != "ssd" {
+		t.Errorf("incorrect node selector %v", sel)
+	}
+	container := podSpec["containers"].([]interface{})[0].(map[string]interface{})
+	if container["image"] != "example/martian" {
+		t.Errorf("template image not preserved: %v", container["image"])
+	}
+	requests := container["resources"].(map[string]interface{})["requests"].(map[string]interface{})
+	if requests["cpu"] != "2500m" {
+		t.Errorf("expected 2500m cpu, got %v", requests["cpu"])
+	}
+	if requests["memory"] != "3072Mi" {
+		t.Errorf("expected 3072Mi memory, got %v", requests["memory"])
+	}
+	envs := container["env"].([]interface{})
+	if len(envs) != 2 {
+		t.Errorf("expected 2 envs, got %v", envs)
+	} else if e := envs[1].(map[string]interface{}); e["name"] != "OMP_NUM_THREADS" ||
+		e["value"] != "3" {
+		t.Errorf("incorrect thread env %v", e)
+	}
+
+	podSpec = api.jobs[1]["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	if _, ok := podSpec["nodeSelector"]; ok {
+		t.Error("expected no node selector")
+	}
+	container = podSpec["containers"].([]interface{})[0].(map[string]interface{})
+	requests = container["resources"].(map[string]interface{})["requests"].(map[string]interface{})
+	if requests["memory"] != "2048Mi" {
+		t.Errorf("expected default 2048Mi memory, got %v", requests["memory"])
+	}
+
+	api.lock.Lock()
+	api.succeeded[submitted[1]] = true
+	// A job which has no pod yet, e.g. because it is waiting for quota,
+	// is still queued.
+	api.pending[submitted[0]] = true
+	api.lock.Unlock()
+	running, raw := jm.CheckQueue(
+		append(submitted, "mro-unknown"), context.Background())
+	if len(running) != 1 || running[0] != submitted[0] {
+		t.Errorf("expected only %s running, got %v", submitted[0], running)
+	}
+	if !strings.Contains(raw, "(job "+submitted[1]+"): Succeeded") {
+		t.Errorf("expected pod state for %s, got %q", submitted[1], raw)
+	}
+}
+
+func TestKubernetesJobName(t *testing.T) {
+	name := kubernetesJobName(
+		"ID.Some_Pipestance.PIPELINE.SUB_PIPELINE.A_VERY_LONG_STAGE_NAME.fork0.chnk0001",
+		"main")
+	if len(name) > 63 {
+		t.Errorf("name %s is too long", name)
+	}
+	if kubernetesNameInvalid.MatchString(name) {
+		t.Errorf("name %s contains invalid characters", name)
+	}
+	if !strings.HasPrefix(name, "mro-") {
+		t.Errorf("name %s should start with mro-", name)
+	}
+}
@@ -59,6 +59,9 @@ type LocalJobManager struct {
 	debug       bool
 	limitLoad   bool
 	highMem     ObservedMemory
+
+	// Named resources configured with --localresource.
+	localResources map[string]*localResource
 }
 
 func NewLocalJobManager(userMaxCores int,
@@ -293,7 +296,7 @@ func (self *LocalJobManager) GetSettings() *JobManagerSettings {
 	return self.jobSettings
 }
 
-func (self *LocalJobManager) refreshResources(localMode bool) error {
+func (self *LocalJobManager) RefreshResources(localMode bool) error {
 	var sysMem MemInfo
 	if err := sysMem.Get(); err != nil {
 		return err
@@ -488,15 +491,15 @@ func (self *LocalJobManager) GetSystemReqs(request *JobResources) JobResources {
 	return result
 }
 
-func (self *LocalJobManager) checkQueue(ids []string, _ context.Context) ([]string, string) {
+func (self *LocalJobManager) CheckQueue(ids []string, _ context.Context) ([]string, string) {
 	return ids, ""
 }
 
-func (self *LocalJobManager) hasQueueCheck() bool {
+func (self *LocalJobManager) HasQueueCheck() bool {
 	return false
 }
 
-func (self *LocalJobManager) queueCheckGrace() time.Duration {
+func (self *LocalJobManager) QueueCheckGrace() time.Duration {
 	return 0
 }
 
@@ -511,13 +514,25 @@ func (self *LocalJobManager) Enqueue(shellCmd string, argv []string,
 
 		// Exec the shell directly.
 		cmd := exec.Command(shellCmd, argv...)
-		cmd.Dir = metadata.curFilesPath
+		cmd.Dir = metadata.FilesPath()
 		// Make sure jobs don't use more threads than they're supposed to.
-		cmd.Env = util.MergeEnv(threadEnvs(self, int(math.Ceil(res.Threads)), envs))
+		cmd.Env = util.MergeEnv(ThreadEnvs(self, int(math.Ceil(res.Threads)), envs))
 
 		stdoutPath := metadata.MetadataFilePath("stdout")
 		stderrPath := metadata.MetadataFilePath("stderr")
 
+		// Acquire named resources first, so that jobs waiting on them do
+		// not hold on to threads or memory which other jobs could use.
+		releaseResources, err := self.acquireLocalResources(res.Special)
+		if err != nil {
+			util.LogError(err, "jobmngr",
+				"%s requested local resources %q which cannot be satisfied.",
+				metadata.GetFQName(), res.Special)
+			metadata.WriteErrorString(err.Error())
+			return
+		}
+		defer releaseResources()
+
 		// Acquire cores.
 		if self.debug {
 			util.LogInfo("jobmngr",
@@ -529,7 +544,7 @@ func (self *LocalJobManager) Enqueue(shellCmd string, argv []string,
 		if err := self.centcoreSem.Acquire(centiCores); err != nil {
 			util.LogError(err, "jobmngr",
 				"%s requested %g threads, but the job manager was only configured to use %d.",
-				metadata.fqname, res.Threads, self.maxCores)
+				metadata.GetFQName(), res.Threads, self.maxCores)
 			metadata.WriteErrorString(err.Error())
 			return
 		}
@@ -566,7 +581,7 @@ func (self *LocalJobManager) Enqueue(shellCmd string, argv []string,
 		if err := self.memMBSem.Acquire(memMb); err != nil {
 			util.LogError(err, "jobmngr",
 				"%s requested %g GB of memory, but the job manager was only configured to use %d.",
-				metadata.fqname, res.MemGB, self.maxMemGB)
+				metadata.GetFQName(), res.MemGB, self.maxMemGB)
 			metadata.WriteErrorString(err.Error())
 			return
 		}
@@ -594,7 +609,7 @@ func (self *LocalJobManager) Enqueue(shellCmd string, argv []string,
 				util.LogError(err, "jobmngr",
 					"%s requested %d GB of virtual memory, but the "+
 						"job manager was only configured to use %.1f.",
-					metadata.fqname, res.VMemGB, float64(self.maxVmemMB)/1024)
+					metadata.GetFQName(), res.VMemGB, float64(self.maxVmemMB)/1024)
 				metadata.WriteErrorString(err.Error())
 				return
 			}
@@ -630,7 +645,7 @@ func (self *LocalJobManager) Enqueue(shellCmd string, argv []string,
 			if err := self.procsSem.Acquire(procEstimate); err != nil {
 				util.LogError(err, "jobmngr",
 					"%s estimated to require %d processes, but the process ulimit is %d.",
-					metadata.fqname, procEstimate, self.procsSem.CurrentSize())
+					metadata.GetFQName(), procEstimate, self.procsSem.CurrentSize())
 				metadata.WriteErrorString(err.Error())
 				return
 			}
@@ -651,7 +666,7 @@ func (self *LocalJobManager) Enqueue(shellCmd string, argv []string,
 				util.LogInfo("jobmngr", "%d goroutines", runtime.NumGoroutine())
 			}
 		}
-		err := executeLocal(cmd, stdoutPath, stderrPath, localpreflight, metadata)
+		err = executeLocal(cmd, stdoutPath, stderrPath, localpreflight, metadata)
 		// CentOS < 5.5 workaround
 		if err != nil {
 			if strings.Contains(err.Error(), exitCodeString) {
@@ -665,7 +680,7 @@ func (self *LocalJobManager) Enqueue(shellCmd string, argv []string,
 				retries = maxRetries + 1
 			}
 			if retries > maxRetries {
-				if _, err2 := metadata.readRawSafe(Errors); os.IsNotExist(err2) {
+				if _, err2 := os.Stat(metadata.MetadataFilePath(Errors)); os.IsNotExist(err2) {
 					// Only write _errors if the job didn't write one before
 					// failing.  Because this is local mode, we don't need to
 					// worry about nfs data races.
@@ -731,7 +746,7 @@ func executeLocal(cmd *exec.Cmd, stdoutPath, stderrPath string,
 		defer util.ExitCriticalSection()
 		err = cmd.Start()
 		if err == nil {
-			return metadata.remove(QueuedLocally)
+			return metadata.ClearQueuedLocally()
 		}
 		return err
 	}(cmd, stdoutPath, stderrPath,
@@ -741,6 +756,23 @@ func executeLocal(cmd *exec.Cmd, stdoutPath, stderrPath string,
 	return cmd.Wait()
 }
 
+// ThreadUsage returns the current state of the local thread semaphore, in
+// hundredths of a thread.
+func (self *LocalJobManager) ThreadUsage() ResourceUsage {
+	if self.centcoreSem == nil {
+		return ResourceUsage{}
+	}
+	return self.centcoreSem.Usage()
+}
+
+// MemoryUsage returns the current state of the local memory semaphore, in MB.
+func (self *LocalJobManager) MemoryUsage() ResourceUsage {
+	if self.memMBSem == nil {
+		return ResourceUsage{}
+	}
+	return self.memMBSem.Usage()
+}
+
 // Done returns a channel which gets notified when a local job exits.
 func (self *LocalJobManager) Done() <-chan struct{} {
 	return self.jobDone
@@ -758,16 +790,16 @@ func (self *LocalJobManager) GetMaxVMemGB() int {
 	return int(self.maxVmemMB / 1024)
 }
 
-func (self *LocalJobManager) execJob(shellCmd string, argv []string,
+func (self *LocalJobManager) ExecJob(shellCmd string, argv []string,
 	envs map[string]string, metadata *Metadata, resRequest *JobResources,
 	fqname string, shellName string, preflight bool) {
 	self.Enqueue(shellCmd, argv, envs, metadata, resRequest, fqname, 0, 0, preflight)
 }
 
-func (self *LocalJobManager) endJob(*Metadata) {}
+func (self *LocalJobManager) EndJob(*Metadata) {}
 
 // Reset the max jobs semaphore.
-func (self *LocalJobManager) resetMaxJobs() {}
+func (self *LocalJobManager) ResetMaxJobs() {}
 
 // Re-add a job to the max jobs semaphore.
-func (self *LocalJobManager) reattach(*Metadata) {}
+func (self *LocalJobManager) Reattach(*Metadata) {}
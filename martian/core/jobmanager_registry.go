@@ -0,0 +1,80 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+// Registry of job managers which are selectable with --jobmode.
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// A JobManagerFactory creates a JobManager.
+//
+// The jobMode is the name the job manager was registered with.  The options
+// carry the settings from the mrp command line, such as MaxJobs and
+// MemPerCore, and jobConfig is the content of jobmanagers/config.json.
+type JobManagerFactory func(jobMode string,
+	options *RuntimeOptions,
+	jobConfig *JobManagerJson) (JobManager, error)
+
+var (
+	jobManagerRegistry   = make(map[string]JobManagerFactory)
+	jobManagerRegistryMu sync.Mutex
+)
+
+// RegisterJobManager makes a job manager available to be selected by the
+// given --jobmode name.  It is intended to be called from an init function
+// in the package implementing the job manager, which is then linked into a
+// custom build of mrp.
+//
+// Job modes which are not registered are assumed to refer to a template
+// for a cluster job manager in the jobmanagers directory.  The job manager
+// for those is registered as "template".
+//
+// RegisterJobManager panics if the name is already registered, or is
+// "local".
+func RegisterJobManager(jobMode string, factory JobManagerFactory) {
+	if jobMode == localMode {
+		panic("cannot replace the local job manager")
+	}
+	if factory == nil {
+		panic("nil factory for job manager " + jobMode)
+	}
+	jobManagerRegistryMu.Lock()
+	defer jobManagerRegistryMu.Unlock()
+	if _, ok := jobManagerRegistry[jobMode]; ok {
+		panic(fmt.Sprintf("job manager %q registered twice", jobMode))
+	}
+	jobManagerRegistry[jobMode] = factory
+}
+
+// RegisteredJobModes returns the sorted names of the job managers which have
+// been registered.
+func RegisteredJobModes() []string {
+	jobManagerRegistryMu.Lock()
+	defer jobManagerRegistryMu.Unlock()
+	modes := make([]string, 0, len(jobManagerRegistry))
+	for mode := range jobManagerRegistry {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	return modes
+}
+
+// The name under which the factory for job modes which are not otherwise
+// registered is registered.  Such job modes refer to a template for a
+// cluster job manager in the jobmanagers directory.
+const templateMode = "template"
+
+// getJobManagerFactory returns the factory for the given job mode, or the
+// template job manager factory if the mode is not registered.
+func getJobManagerFactory(jobMode string) JobManagerFactory {
+	jobManagerRegistryMu.Lock()
+	defer jobManagerRegistryMu.Unlock()
+	if factory := jobManagerRegistry[jobMode]; factory != nil {
+		return factory
+	}
+	return jobManagerRegistry[templateMode]
+}
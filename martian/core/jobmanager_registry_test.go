@@ -0,0 +1,167 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martian-lang/martian/martian/core"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+// A job manager which records jobs rather than running them, using only
+// the exported core.Metadata API.
+type recordingJobManager struct {
+	settings core.JobManagerSettings
+	jobs     []string
+}
+
+func (jm *recordingJobManager) ExecJob(shellCmd string, args []string,
+	env map[string]string, md *core.Metadata, res *core.JobResources,
+	fqname, shellName string, preflight bool) {
+	if err := md.ClearQueuedLocally(); err != nil {
+		md.WriteErrorString(err.Error())
+		return
+	}
+	env = core.ThreadEnvs(jm, int(jm.GetSystemReqs(res).Threads), env)
+	id := fqname + "." + shellName
+	jm.jobs = append(jm.jobs, id)
+	if err := md.WriteRaw(core.JobId, id); err != nil {
+		md.WriteErrorString(err.Error())
+	}
+}
+
+func (jm *recordingJobManager) EndJob(*core.Metadata) {}
+
+func (jm *recordingJobManager) CheckQueue(ids []string,
+	_ context.Context) ([]string, string) {
+	return ids, ""
+}
+
+func (jm *recordingJobManager) HasQueueCheck() bool { return false }
+
+func (jm *recordingJobManager) QueueCheckGrace() time.Duration { return 0 }
+
+func (jm *recordingJobManager) RefreshResources(bool) error { return nil }
+
+func (jm *recordingJobManager) GetSystemReqs(res *core.JobResources) core.JobResources {
+	result := *res
+	if result.Threads <= 0 {
+		result.Threads = float64(jm.settings.ThreadsPerJob)
+	}
+	if result.MemGB <= 0 {
+		result.MemGB = float64(jm.settings.MemGBPerJob)
+	}
+	return result
+}
+
+func (jm *recordingJobManager) GetMaxCores() int { return 0 }
+
+func (jm *recordingJobManager) GetMaxMemGB() int { return 0 }
+
+func (jm *recordingJobManager) GetSettings() *core.JobManagerSettings {
+	return &jm.settings
+}
+
+func (jm *recordingJobManager) ResetMaxJobs() {}
+
+func (jm *recordingJobManager) Reattach(*core.Metadata) {}
+
+// Ensures that a job manager config file exists where NewRuntime will look
+// for it.  The test binary usually runs from a temporary directory, in which
+// case a minimal config is created.
+func setupJobConfig(t *testing.T) {
+	t.Helper()
+	jobPath := util.RelPath(filepath.Join("..", "jobmanagers"))
+	cfg := filepath.Join(jobPath, "config.json")
+	if _, err := os.Stat(cfg); err == nil {
+		return
+	} else if !os.IsNotExist(err) {
+		t.Skip(err)
+	}
+	if _, err := os.Stat(jobPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(jobPath, 0777); err != nil {
+			t.Skip(err)
+		}
+		t.Cleanup(func() { os.RemoveAll(jobPath) })
+	}
+	if err := os.WriteFile(cfg, []byte(`{
+  "settings": {
+    "threads_per_job": 1,
+    "memGB_per_job": 1,
+    "thread_envs": []
+  },
+  "jobmodes": {}
+}`), 0666); err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { os.Remove(cfg) })
+}
+
+func TestRegisterJobManager(t *testing.T) {
+	jm := &recordingJobManager{
+		settings: core.JobManagerSettings{
+			ThreadsPerJob: 1,
+			MemGBPerJob:   2,
+			ThreadEnvs:    []string{"TEST_THREADS"},
+		},
+	}
+	var factoryMode string
+	factory := func(jobMode string, _ *core.RuntimeOptions,
+		_ *core.JobManagerJson) (core.JobManager, error) {
+		factoryMode = jobMode
+		return jm, nil
+	}
+	core.RegisterJobManager("recording", factory)
+	defer core.UnregisterJobManager("recording")
+	found := false
+	for _, mode := range core.RegisteredJobModes() {
+		if mode == "recording" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("recording not in job modes %v", core.RegisteredJobModes())
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected duplicate registration to panic")
+			}
+		}()
+		core.RegisterJobManager("recording", factory)
+	}()
+
+	setupJobConfig(t)
+	opts := core.DefaultRuntimeOptions()
+	opts.JobMode = "recording"
+	rt, err := opts.NewRuntime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt.JobManager != jm {
+		t.Fatalf("expected the registered job manager, got %T", rt.JobManager)
+	} else if factoryMode != "recording" {
+		t.Errorf("factory called for job mode %q", factoryMode)
+	}
+
+	dir := filepath.Join(t.TempDir(), "chnk0")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	md := core.NewMetadata("ID.ps.PIPE.STAGE.fork0.chnk0", dir)
+	rt.JobManager.ExecJob("mrjob", nil, nil, md, &core.JobResources{},
+		md.GetFQName(), "main", false)
+	if b, err := os.ReadFile(md.MetadataFilePath(core.JobId)); err != nil {
+		t.Error(err)
+	} else if s := string(b); s != "ID.ps.PIPE.STAGE.fork0.chnk0.main" {
+		t.Errorf("incorrect job id %q", s)
+	}
+	if len(jm.jobs) != 1 {
+		t.Errorf("expected 1 job, got %v", jm.jobs)
+	}
+}
@@ -5,6 +5,7 @@ package core
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"math"
 	"os"
 	"os/exec"
@@ -19,6 +20,11 @@ import (
 	"github.com/martian-lang/martian/martian/util"
 )
 
+// The number of seconds added to a job's timeout to get the walltime passed
+// to the cluster, so that mrjob can kill the job and record the timeout
+// before the cluster kills it.
+const walltimeGrace = 5 * 60
+
 type RemoteJobManager struct {
 	jobMode              string
 	jobResourcesMappings map[string]string
@@ -29,9 +35,26 @@ type RemoteJobManager struct {
 	maxJobs              int
 	jobFreqMillis        int
 	queueMutex           sync.Mutex
+	arrayMutex           sync.Mutex
+	arrays               map[arrayKey]*arrayJob
+	arrayTasks           map[*Metadata]*arrayJob
 	debug                bool
 }
 
+func init() {
+	// Job modes which are not otherwise registered refer to a template in
+	// the jobmanagers directory.
+	RegisterJobManager(templateMode, func(jobMode string, c *RuntimeOptions,
+		jobConfig *JobManagerJson) (JobManager, error) {
+		jm, err := NewRemoteJobManager(jobMode, c.MemPerCore, c.MaxJobs,
+			c.JobFreqMillis, c.ResourceSpecial, jobConfig, c.Debug)
+		if err != nil {
+			return nil, err
+		}
+		return jm, nil
+	})
+}
+
 func NewRemoteJobManager(jobMode string, memGBPerCore int, maxJobs int, jobFreqMillis int,
 	jobResources string, config *JobManagerJson, debug bool) (*RemoteJobManager, error) {
 	self := &RemoteJobManager{}
@@ -46,19 +69,7 @@ func NewRemoteJobManager(jobMode string, memGBPerCore int, maxJobs int, jobFreqM
 		return self, err
 	}
 
-	// Parse jobresources mappings
-	self.jobResourcesMappings = map[string]string{}
-	for _, mapping := range strings.Split(jobResources, ";") {
-		if len(mapping) > 0 {
-			parts := strings.Split(mapping, ":")
-			if len(parts) == 2 {
-				self.jobResourcesMappings[parts[0]] = parts[1]
-				util.LogInfo("jobmngr", "Mapping %s to %s", parts[0], parts[1])
-			} else {
-				util.LogInfo("jobmngr", "Could not parse mapping: %s", mapping)
-			}
-		}
-	}
+	self.jobResourcesMappings = parseJobResourcesMappings(jobResources)
 
 	if self.maxJobs > 0 {
 		self.jobSem = NewMaxJobsSemaphore(self.maxJobs)
@@ -72,7 +83,25 @@ func NewRemoteJobManager(jobMode string, memGBPerCore int, maxJobs int, jobFreqM
 	return self, err
 }
 
-func (self *RemoteJobManager) refreshResources(bool) error {
+// Parse jobresources mappings, which are of the form
+// special1:resources1;special2:resources2
+func parseJobResourcesMappings(jobResources string) map[string]string {
+	mappings := map[string]string{}
+	for _, mapping := range strings.Split(jobResources, ";") {
+		if len(mapping) > 0 {
+			parts := strings.Split(mapping, ":")
+			if len(parts) == 2 {
+				mappings[parts[0]] = parts[1]
+				util.LogInfo("jobmngr", "Mapping %s to %s", parts[0], parts[1])
+			} else {
+				util.LogInfo("jobmngr", "Could not parse mapping: %s", mapping)
+			}
+		}
+	}
+	return mappings
+}
+
+func (self *RemoteJobManager) RefreshResources(bool) error {
 	if self.jobSem != nil {
 		self.jobSem.FindDone()
 	}
@@ -132,7 +161,7 @@ func (self *RemoteJobManager) GetSystemReqs(resRequest *JobResources) JobResourc
 	return res
 }
 
-func (self *RemoteJobManager) execJob(shellCmd string, argv []string,
+func (self *RemoteJobManager) ExecJob(shellCmd string, argv []string,
 	envs map[string]string, metadata *Metadata, resRequest *JobResources,
 	fqname string, shellName string, localpreflight bool) {
 	ctx, task := trace.NewTask(context.Background(), "queueRemote")
@@ -140,7 +169,7 @@ func (self *RemoteJobManager) execJob(shellCmd string, argv []string,
 	// no limit, send the job
 	if self.maxJobs <= 0 {
 		defer task.End()
-		self.sendJob(shellCmd, argv, envs,
+		self.queueJob(shellCmd, argv, envs,
 			metadata, resRequest,
 			fqname, shellName, ctx)
 		return
@@ -169,23 +198,52 @@ func (self *RemoteJobManager) execJob(shellCmd string, argv []string,
 		if self.debug {
 			util.LogInfo("jobmngr", "Job sent: %s", fqname)
 		}
-		self.sendJob(shellCmd, argv, envs,
+		self.queueJob(shellCmd, argv, envs,
 			metadata, resRequest,
 			fqname, shellName, ctx)
 	}(ctx, task, self.jobSem)
 }
 
-func (self *RemoteJobManager) endJob(metadata *Metadata) {
+// Send the job, or add it to a pending array job if it is a chunk and the
+// job mode supports array jobs.
+func (self *RemoteJobManager) queueJob(shellCmd string, argv []string,
+	envs map[string]string, metadata *Metadata, resRequest *JobResources,
+	fqname, shellName string, ctx context.Context) {
+	if self.config.array != nil {
+		if fork := chunkForkName(fqname); fork != "" {
+			self.addArrayTask(&arrayTask{
+				shellCmd: shellCmd,
+				argv:     argv,
+				envs:     envs,
+				metadata: metadata,
+				res:      self.GetSystemReqs(resRequest),
+				fqname:   fqname,
+			}, fork, shellName)
+			return
+		}
+	}
+	self.sendJob(shellCmd, argv, envs,
+		metadata, resRequest,
+		fqname, shellName, ctx)
+}
+
+func (self *RemoteJobManager) EndJob(metadata *Metadata) {
 	if self.jobSem != nil {
 		self.jobSem.Release(metadata)
 	}
+	if self.config.array != nil {
+		self.endArrayTask(metadata)
+	}
 }
 
+// Generate the job script from the template.  If array is not nil, the
+// script is for an array job and the metadata is that of the first task.
 func (self *RemoteJobManager) jobScript(
 	shellCmd string, argv []string, envs map[string]string,
 	metadata *Metadata,
 	resRequest *JobResources,
-	fqname, shellName string) string {
+	fqname, shellName string,
+	array *arrayJob) string {
 	res := self.GetSystemReqs(resRequest)
 
 	// figure out per-thread memory requirements for the template.
@@ -217,23 +275,55 @@ func (self *RemoteJobManager) jobScript(
 		}
 	}
 
+	var walltime, walltimeMinutes, walltimeSeconds string
+	if res.Timeout > 0 {
+		seconds := res.Timeout + walltimeGrace
+		walltime = fmt.Sprintf("%d:%02d:%02d",
+			seconds/3600, seconds/60%60, seconds%60)
+		walltimeMinutes = strconv.FormatInt((seconds+59)/60, 10)
+		walltimeSeconds = strconv.FormatInt(seconds, 10)
+	}
+
 	threads := int(math.Ceil(res.Threads))
-	argsStr := formatArgs(threadEnvs(self, threads, envs), shellCmd, argv)
+	jobName := fqname + "." + shellName
+	stdout := shellSafeQuote(metadata.MetadataFilePath("stdout"))
+	stderr := shellSafeQuote(metadata.MetadataFilePath("stderr"))
+	workDir := shellSafeQuote(metadata.FilesPath())
+	var argsStr, arraySize string
+	if array != nil {
+		if self.config.array.JobName != "" {
+			jobName = fmt.Sprintf(self.config.array.JobName,
+				jobName, len(array.tasks))
+		}
+		stdout = arrayTaskPath(array.dir, self.config.array.TaskPattern,
+			metadata.MetadataFilePath("stdout"))
+		stderr = arrayTaskPath(array.dir, self.config.array.TaskPattern,
+			metadata.MetadataFilePath("stderr"))
+		workDir = shellSafeQuote(array.dir)
+		argsStr = "sh " + arrayTaskPath(array.dir,
+			"${"+self.config.array.TaskEnv+"}",
+			metadata.MetadataFilePath(arrayTaskScript))
+		arraySize = strconv.Itoa(len(array.tasks))
+	} else {
+		argsStr = formatArgs(ThreadEnvs(self, threads, envs), shellCmd, argv)
+	}
 	const prefix = "__MRO_"
 	const suffix = "__"
 	params := [...][2]string{
 		{prefix + "JOB_NAME" + suffix,
-			fqname + "." + shellName},
+			jobName},
 		{prefix + "THREADS" + suffix,
 			strconv.Itoa(threads)},
 		{prefix + "STDOUT" + suffix,
-			shellSafeQuote(metadata.MetadataFilePath("stdout"))},
+			stdout},
 		{prefix + "STDERR" + suffix,
-			shellSafeQuote(metadata.MetadataFilePath("stderr"))},
+			stderr},
 		{prefix + "JOB_WORKDIR" + suffix,
-			shellSafeQuote(metadata.curFilesPath)},
+			workDir},
 		{prefix + "CMD" + suffix,
 			argsStr},
+		{prefix + "ARRAY_SIZE" + suffix,
+			arraySize},
 		{prefix + "MEM_GB" + suffix,
 			strconv.Itoa(int(math.Ceil(res.MemGB)))},
 		{prefix + "MEM_MB" + suffix,
@@ -266,6 +356,12 @@ func (self *RemoteJobManager) jobScript(
 			strconv.Itoa(vmemGBPerThread * 1024 * 1024)},
 		{prefix + "VMEM_B_PER_THREAD" + suffix,
 			strconv.Itoa(vmemGBPerThread * 1024 * 1024 * 1024)},
+		{prefix + "WALLTIME_MINUTES" + suffix,
+			walltimeMinutes},
+		{prefix + "WALLTIME_SECONDS" + suffix,
+			walltimeSeconds},
+		{prefix + "WALLTIME" + suffix,
+			walltime},
 		{prefix + "ACCOUNT" + suffix,
 			os.Getenv("MRO_ACCOUNT")},
 		{prefix + "RESOURCES" + suffix,
@@ -329,13 +425,21 @@ func (self *RemoteJobManager) sendJob(shellCmd string, argv []string, envs map[s
 	metadata *Metadata, resRequest *JobResources, fqname string, shellName string,
 	ctx context.Context) {
 	jobscript := self.jobScript(shellCmd, argv, envs, metadata,
-		resRequest, fqname, shellName)
+		resRequest, fqname, shellName, nil)
 	if err := metadata.WriteRaw("jobscript", jobscript); err != nil {
 		util.LogError(err, "jobmngr", "Could not write job script.")
 	}
+	self.submit(jobscript, metadata.FilesPath(), fqname,
+		[]*Metadata{metadata}, ctx)
+}
 
+// Run the job submit command with the given job script.  If there is more
+// than one metadata, the job is an array job, and the job ID for each task is
+// written to the corresponding metadata.
+func (self *RemoteJobManager) submit(jobscript, dir, fqname string,
+	metadatas []*Metadata, ctx context.Context) {
 	cmd := exec.CommandContext(ctx, self.config.jobCmd, self.config.jobCmdArgs...)
-	cmd.Dir = metadata.curFilesPath
+	cmd.Dir = dir
 	cmd.Stdin = strings.NewReader(jobscript)
 
 	// Regardless of the limiter rate, only allow one pending submission to the queue
@@ -353,26 +457,40 @@ func (self *RemoteJobManager) sendJob(shellCmd string, argv []string, envs map[s
 
 	util.EnterCriticalSection()
 	defer util.ExitCriticalSection()
-	if err := metadata.remove(QueuedLocally); err != nil {
-		util.LogError(err, "jobmngr", "Error removing queue sentinel file.")
+	for _, metadata := range metadatas {
+		if err := metadata.ClearQueuedLocally(); err != nil {
+			util.LogError(err, "jobmngr", "Error removing queue sentinel file.")
+		}
 	}
 	if output, err := cmd.CombinedOutput(); err != nil {
-		metadata.WriteErrorString(
-			"jobcmd error (" + err.Error() + "):\n" + string(output))
+		countJobSubmitFailure()
+		for _, metadata := range metadatas {
+			metadata.WriteErrorString(
+				"jobcmd error (" + err.Error() + "):\n" + string(output))
+		}
 	} else {
 		trimmed := bytes.TrimSpace(output)
+		if len(metadatas) > 1 {
+			trimmed = arrayJobId(trimmed)
+		}
 		// jobids should not have spaces in them.  This is the most general way to
 		// check that a string is actually a jobid.
 		if len(trimmed) > 0 && !bytes.ContainsAny(trimmed, " \t\n\r") {
-			if err := metadata.WriteRawBytes("jobid", bytes.TrimSpace(output)); err != nil {
-				util.LogError(err, "jobmngr", "Could not write job id file.")
+			for i, metadata := range metadatas {
+				jobId := trimmed
+				if len(metadatas) > 1 {
+					jobId = []byte(fmt.Sprintf(self.config.array.TaskId,
+						trimmed, i+1))
+				}
+				if err := metadata.WriteRawBytes(JobId, jobId); err != nil {
+					util.LogError(err, "jobmngr", "Could not write job id file.")
+				}
 			}
-			metadata.cache("jobid", metadata.uniquifier)
 		}
 	}
 }
 
-func (self *RemoteJobManager) checkQueue(ids []string, ctx context.Context) ([]string, string) {
+func (self *RemoteJobManager) CheckQueue(ids []string, ctx context.Context) ([]string, string) {
 	if self.config.queueQueryCmd == "" {
 		return ids, ""
 	}
@@ -386,19 +504,34 @@ func (self *RemoteJobManager) checkQueue(ids []string, ctx context.Context) ([]s
 	if err != nil {
 		return ids, stderr.String()
 	}
-	return strings.Split(string(output), "\n"), stderr.String()
+	queued := strings.Split(string(output), "\n")
+	if self.config.array != nil {
+		queued = arrayTasksQueued(self.config.array.TaskId, ids, queued)
+	}
+	return queued, stderr.String()
 }
 
-func (self *RemoteJobManager) hasQueueCheck() bool {
+func (self *RemoteJobManager) HasQueueCheck() bool {
 	return self.config.queueQueryCmd != ""
 }
 
-func (self *RemoteJobManager) queueCheckGrace() time.Duration {
+func (self *RemoteJobManager) QueueCheckGrace() time.Duration {
 	return self.config.queueQueryGrace
 }
 
+// QueueStats returns the number of jobs which are currently submitted, the
+// number waiting for a slot in order to be submitted, and the maximum number
+// of jobs which may be submitted at once.  If there is no limit, the limit is
+// zero and jobs are not tracked.
+func (self *RemoteJobManager) QueueStats() (running, waiting, limit int) {
+	if sem := self.jobSem; sem != nil {
+		return sem.Current(), sem.Waiting(), sem.Limit
+	}
+	return 0, 0, 0
+}
+
 // Reset the max jobs semaphore.
-func (self *RemoteJobManager) resetMaxJobs() {
+func (self *RemoteJobManager) ResetMaxJobs() {
 	oldSem := self.jobSem
 	if oldSem != nil {
 		self.jobSem = NewMaxJobsSemaphore(oldSem.Limit)
@@ -407,7 +540,7 @@ func (self *RemoteJobManager) resetMaxJobs() {
 }
 
 // Re-add a job to the max jobs semaphore.
-func (self *RemoteJobManager) reattach(md *Metadata) {
+func (self *RemoteJobManager) Reattach(md *Metadata) {
 	if self.jobSem == nil {
 		return
 	}
@@ -0,0 +1,284 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+//
+// Submission of chunks as cluster array jobs.
+//
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martian-lang/martian/martian/util"
+)
+
+// How long to wait for more chunks to arrive before submitting an array job.
+// Chunks for a fork are generally all queued in the same pipestance step, so
+// this mostly matters when waiting for --maxjobs slots.
+const arrayBatchDelay = 500 * time.Millisecond
+
+// The metadata file containing the command for a task in an array job.
+const arrayTaskScript MetadataFileName = "taskscript"
+
+// Chunks which can be submitted in the same array job must belong to the
+// same fork and have the same resource requirements.
+type arrayKey struct {
+	fork      string
+	shellName string
+	res       JobResources
+}
+
+type arrayTask struct {
+	shellCmd string
+	argv     []string
+	envs     map[string]string
+	metadata *Metadata
+	res      JobResources
+	fqname   string
+}
+
+// An arrayJob is a set of chunks waiting to be submitted together.
+type arrayJob struct {
+	key   arrayKey
+	tasks []*arrayTask
+	timer *time.Timer
+	sent  bool
+
+	// The directory containing a symlink to the metadata directory for each
+	// task, named by the task index, so that the job template can refer to
+	// per-task files.  It is removed once all of the tasks have ended.
+	dir string
+
+	// The number of tasks which have not yet ended.
+	running int
+}
+
+// Returns the fully qualified name of the fork for a chunk, or an empty
+// string if fqname is not the name of a chunk.
+func chunkForkName(fqname string) string {
+	i := strings.LastIndexByte(fqname, '.')
+	if i < 0 || !strings.HasPrefix(fqname[i+1:], "chnk") {
+		return ""
+	}
+	return fqname[:i]
+}
+
+func (self *RemoteJobManager) addArrayTask(task *arrayTask, fork, shellName string) {
+	key := arrayKey{
+		fork:      fork,
+		shellName: shellName,
+		res:       task.res,
+	}
+	self.arrayMutex.Lock()
+	defer self.arrayMutex.Unlock()
+	if self.arrays == nil {
+		self.arrays = make(map[arrayKey]*arrayJob)
+	}
+	if self.arrayTasks == nil {
+		self.arrayTasks = make(map[*Metadata]*arrayJob)
+	}
+	job := self.arrays[key]
+	if job == nil {
+		job = &arrayJob{key: key}
+		self.arrays[key] = job
+		job.timer = time.AfterFunc(arrayBatchDelay, func() {
+			self.flushArray(job)
+		})
+	}
+	job.tasks = append(job.tasks, task)
+	job.running++
+	self.arrayTasks[task.metadata] = job
+	if max := self.config.array.MaxSize; max > 0 && len(job.tasks) >= max {
+		job.sent = true
+		delete(self.arrays, key)
+		job.timer.Stop()
+		go self.sendArray(job)
+	}
+}
+
+func (self *RemoteJobManager) flushArray(job *arrayJob) {
+	self.arrayMutex.Lock()
+	if job.sent {
+		self.arrayMutex.Unlock()
+		return
+	}
+	job.sent = true
+	if self.arrays[job.key] == job {
+		delete(self.arrays, job.key)
+	}
+	self.arrayMutex.Unlock()
+	self.sendArray(job)
+}
+
+func (self *RemoteJobManager) sendArray(job *arrayJob) {
+	ctx, task := trace.NewTask(context.Background(), "queueRemoteArray")
+	defer task.End()
+	first := job.tasks[0]
+	if len(job.tasks) == 1 {
+		self.sendJob(first.shellCmd, first.argv, first.envs,
+			first.metadata, &first.res,
+			first.fqname, job.key.shellName, ctx)
+		return
+	}
+	metadatas := make([]*Metadata, len(job.tasks))
+	for i, t := range job.tasks {
+		metadatas[i] = t.metadata
+	}
+	if err := self.makeArrayDir(job); err != nil {
+		util.LogError(err, "jobmngr", "Could not set up array job for %s.",
+			job.key.fork)
+		for _, metadata := range metadatas {
+			if err := metadata.ClearQueuedLocally(); err != nil {
+				util.LogError(err, "jobmngr", "Error removing queue sentinel file.")
+			}
+			metadata.WriteErrorString(
+				"Could not set up array job: " + err.Error())
+		}
+		return
+	}
+	jobscript := self.jobScript(first.shellCmd, first.argv, first.envs,
+		first.metadata, &first.res,
+		job.key.fork, job.key.shellName, job)
+	for _, metadata := range metadatas {
+		if err := metadata.WriteRaw("jobscript", jobscript); err != nil {
+			util.LogError(err, "jobmngr", "Could not write job script.")
+		}
+	}
+	util.LogInfo("jobmngr", "Submitting %d chunks of %s as an array job.",
+		len(metadatas), job.key.fork)
+	self.submit(jobscript, job.dir, job.key.fork, metadatas, ctx)
+}
+
+// Create the directory of task symlinks for the array job, and write the
+// command for each task.
+func (self *RemoteJobManager) makeArrayDir(job *arrayJob) error {
+	forkDir := path.Dir(path.Dir(job.tasks[0].metadata.FilesPath()))
+	arraysDir := path.Join(forkDir, "arrays")
+	if err := os.MkdirAll(arraysDir, 0777); err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp(arraysDir, job.key.shellName+"_")
+	if err != nil {
+		return err
+	}
+	self.arrayMutex.Lock()
+	job.dir = dir
+	self.arrayMutex.Unlock()
+	for i, t := range job.tasks {
+		chunkDir := path.Dir(t.metadata.FilesPath())
+		rel, err := filepath.Rel(dir, chunkDir)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(rel, path.Join(dir, strconv.Itoa(i+1))); err != nil {
+			return err
+		}
+		threads := int(math.Ceil(t.res.Threads))
+		if err := t.metadata.WriteRaw(arrayTaskScript,
+			"cd "+shellSafeQuote(t.metadata.FilesPath())+" || exit 1\n"+
+				formatArgs(ThreadEnvs(self, threads, t.envs),
+					t.shellCmd, t.argv)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Called when a task which was added to an array job is no longer running.
+// Once all of the tasks in the array have ended, the array's directory is no
+// longer needed.
+func (self *RemoteJobManager) endArrayTask(metadata *Metadata) {
+	self.arrayMutex.Lock()
+	job := self.arrayTasks[metadata]
+	if job == nil {
+		self.arrayMutex.Unlock()
+		return
+	}
+	delete(self.arrayTasks, metadata)
+	job.running--
+	var dir string
+	if job.running == 0 {
+		dir = job.dir
+	}
+	self.arrayMutex.Unlock()
+	if dir != "" {
+		if err := os.RemoveAll(dir); err != nil {
+			util.LogError(err, "jobmngr",
+				"Could not remove array job directory %s.", dir)
+		}
+	}
+}
+
+// Returns the quoted path to the given file in the metadata directory of a
+// task in an array job, where pattern is expanded by the cluster or shell to
+// the task index.
+func arrayTaskPath(dir, pattern, file string) string {
+	quoted := shellSafeQuote(dir)
+	return quoted[:len(quoted)-1] + "/" + pattern + "/" + path.Base(file) + `"`
+}
+
+// Extract the job ID of the array from the output of the job submit command.
+// For example, SGE reports "12345.1-10:1".
+func arrayJobId(output []byte) []byte {
+	if i := bytes.IndexAny(output, ".;"); i > 0 {
+		return output[:i]
+	}
+	return output
+}
+
+// Parse a task ID generated using the given format, returning the job ID of
+// the array.
+func parseArrayTaskId(format, id string) (string, bool) {
+	i := strings.Index(format, "%s")
+	j := strings.Index(format, "%d")
+	if i < 0 || j < i+2 {
+		return "", false
+	}
+	prefix, sep, suffix := format[:i], format[i+2:j], format[j+2:]
+	if len(id) <= len(prefix)+len(suffix) ||
+		!strings.HasPrefix(id, prefix) || !strings.HasSuffix(id, suffix) {
+		return "", false
+	}
+	id = id[len(prefix) : len(id)-len(suffix)]
+	k := len(id)
+	for k > 0 && id[k-1] >= '0' && id[k-1] <= '9' {
+		k--
+	}
+	if k == len(id) || !strings.HasSuffix(id[:k], sep) {
+		return "", false
+	}
+	if id = id[:k-len(sep)]; id == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// Queue query commands may report array jobs by the ID of the array rather
+// than of individual tasks.  Adds the task IDs from ids for which the array
+// was reported as queued.
+func arrayTasksQueued(format string, ids, queued []string) []string {
+	found := make(map[string]struct{}, len(queued))
+	for _, id := range queued {
+		found[strings.TrimSpace(id)] = struct{}{}
+	}
+	for _, id := range ids {
+		if _, ok := found[id]; ok {
+			continue
+		}
+		if array, ok := parseArrayTaskId(format, id); ok {
+			if _, ok := found[array]; ok {
+				queued = append(queued, id)
+			}
+		}
+	}
+	return queued
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+import (
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseArrayTaskId(t *testing.T) {
+	check := func(format, id, expect string) {
+		t.Helper()
+		if base, ok := parseArrayTaskId(format, id); expect == "" && ok {
+			t.Errorf("expected %q to not parse with %q, got %q",
+				id, format, base)
+		} else if base != expect {
+			t.Errorf("expected %q from %q, got %q", expect, id, base)
+		}
+	}
+	check("%s_%d", "12345_7", "12345")
+	check("%s_%d", "12345_", "")
+	check("%s_%d", "12345", "")
+	check("%s_%d", "_7", "")
+	check("%s.%d", "12345.12", "12345")
+	check("%s[%d]", "12345[3]", "12345")
+	check("%s[%d]", "12345[3", "")
+}
+
+func TestArrayTasksQueued(t *testing.T) {
+	queued := arrayTasksQueued("%s_%d",
+		[]string{"10_1", "10_2", "11_1", "12", "13_1"},
+		[]string{"10_1", " 11", "12", ""})
+	expect := "10_1, 11,12,,11_1"
+	if s := strings.Join(queued, ","); s != expect {
+		t.Errorf("expected %q, got %q", expect, s)
+	}
+}
+
+// A copy of jobmanagers/fake_remote_array.template.
+const fakeArrayTemplate = `#!/usr/bin/env sh
+(
+for MRO_ARRAY_TASK_ID in $(seq 1 __MRO_ARRAY_SIZE__); do
+export MRO_ARRAY_TASK_ID
+/usr/bin/env __MRO_CMD__ > __MRO_STDOUT__ 2> __MRO_STDERR__ &
+done # __MRO_ARRAY_SIZE__ tasks
+wait
+) > /dev/null 2>&1 & echo $!
+`
+
+func TestRemoteArrayJob(t *testing.T) {
+	jm := &RemoteJobManager{
+		config: jobManagerConfig{
+			jobSettings: &JobManagerSettings{
+				ThreadsPerJob: 1,
+				MemGBPerJob:   1,
+			},
+			jobCmd:      "sh",
+			jobTemplate: fakeArrayTemplate,
+			array: &JobModeArrayJson{
+				TaskEnv:     "MRO_ARRAY_TASK_ID",
+				TaskPattern: "${MRO_ARRAY_TASK_ID}",
+				TaskId:      "%s.%d",
+			},
+			threadingEnabled: true,
+		},
+	}
+	forkDir := path.Join(t.TempDir(), "STAGE", "fork0")
+	newMd := func(name string) *Metadata {
+		t.Helper()
+		md := NewMetadata("ID.ps.STAGE.fork0."+name, path.Join(forkDir, name))
+		if err := os.MkdirAll(md.FilesPath(), 0777); err != nil {
+			t.Fatal(err)
+		}
+		return md
+	}
+	chunks := make([]*Metadata, 3)
+	for i := range chunks {
+		chunks[i] = newMd("chnk" + strconv.Itoa(i))
+		jm.ExecJob("echo", []string{"chunk", strconv.Itoa(i)}, nil,
+			chunks[i], &JobResources{}, chunks[i].fqname, "main", false)
+	}
+	// The join is not a chunk, so it is submitted on its own.
+	join := newMd("join")
+	jm.ExecJob("echo", []string{"join"}, nil,
+		join, &JobResources{}, join.fqname, "join", false)
+
+	readFile := func(md *Metadata, name MetadataFileName) string {
+		t.Helper()
+		for start := time.Now(); time.Since(start) < 10*time.Second; {
+			if b, err := os.ReadFile(md.MetadataFilePath(name)); err == nil &&
+				len(b) > 0 {
+				return string(b)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %s of %s", name, md.fqname)
+		return ""
+	}
+	if out := readFile(join, StdOut); out != "join\n" {
+		t.Errorf("incorrect join output %q", out)
+	}
+	if s := readFile(join, "jobscript"); strings.Contains(s, "seq") {
+		t.Errorf("expected join to not be an array job:\n%s", s)
+	}
+	var arrayId string
+	for i, md := range chunks {
+		if out := readFile(md, StdOut); out != "chunk "+strconv.Itoa(i)+"\n" {
+			t.Errorf("incorrect output %q for chunk %d", out, i)
+		}
+		id, ok := parseArrayTaskId("%s.%d", readFile(md, JobId))
+		if !ok {
+			t.Errorf("incorrect job id for chunk %d", i)
+		} else if i == 0 {
+			arrayId = id
+		} else if id != arrayId {
+			t.Errorf("expected chunk %d in array %s, got %s", i, arrayId, id)
+		}
+		if s := readFile(md, JobId); s != arrayId+"."+strconv.Itoa(i+1) {
+			t.Errorf("incorrect task id %s for chunk %d", s, i)
+		}
+		if s := readFile(md, "jobscript"); !strings.Contains(s, "seq 1 3") {
+			t.Errorf("expected array job script, got\n%s", s)
+		}
+	}
+
+	// The array directory is removed once all of the tasks have ended.
+	arraysDir := path.Join(forkDir, "arrays")
+	for i, md := range chunks {
+		if dirs, err := os.ReadDir(arraysDir); err != nil {
+			t.Fatal(err)
+		} else if len(dirs) != 1 {
+			t.Errorf("expected 1 array directory before chunk %d ended, got %d",
+				i, len(dirs))
+		}
+		jm.EndJob(md)
+	}
+	if dirs, err := os.ReadDir(arraysDir); err != nil {
+		t.Error(err)
+	} else if len(dirs) != 0 {
+		t.Errorf("expected array directory to be removed, got %v", dirs)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJobScriptWalltime(t *testing.T) {
+	jm := &RemoteJobManager{
+		config: jobManagerConfig{
+			jobSettings: &JobManagerSettings{
+				ThreadsPerJob: 1,
+				MemGBPerJob:   1,
+			},
+			jobTemplate: "#SBATCH -t __MRO_WALLTIME__\n" +
+				"#BSUB -W __MRO_WALLTIME_MINUTES__\n" +
+				"# __MRO_WALLTIME_SECONDS__ seconds\n" +
+				"__MRO_CMD__\n",
+		},
+	}
+	md := NewMetadata("ID.ps.STAGE.fork0.chnk0", t.TempDir())
+	script := jm.jobScript("echo", nil, nil, md,
+		&JobResources{Timeout: 3600}, md.fqname, "main", nil)
+	if expect := "#SBATCH -t 1:05:00\n" +
+		"#BSUB -W 65\n" +
+		"# 3900 seconds\n" +
+		`"echo"` + "\n"; script != expect {
+		t.Errorf("expected\n%s\ngot\n%s", expect, script)
+	}
+	script = jm.jobScript("echo", nil, nil, md,
+		&JobResources{}, md.fqname, "main", nil)
+	if strings.Contains(script, "MRO") || strings.Contains(script, "SBATCH") {
+		t.Errorf("expected walltime lines to be removed, got\n%s", script)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+// Named, counted resources for the local job manager, such as licenses or
+// database connections.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martian-lang/martian/martian/util"
+)
+
+// ParseLocalResources parses a comma-separated list of name:count pairs,
+// as given to mrp --localresource.
+func ParseLocalResources(s string) (map[string]int, error) {
+	result := make(map[string]int)
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		i := strings.LastIndexByte(term, ':')
+		if i <= 0 {
+			return nil, fmt.Errorf(
+				"local resource %q must be given as NAME:COUNT", term)
+		}
+		name := strings.TrimSpace(term[:i])
+		count, err := strconv.Atoi(strings.TrimSpace(term[i+1:]))
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf(
+				"invalid count for local resource %q", name)
+		}
+		if _, ok := result[name]; ok {
+			return nil, fmt.Errorf(
+				"local resource %q given more than once", name)
+		}
+		result[name] = count
+	}
+	return result, nil
+}
+
+// FormatLocalResources is the inverse of ParseLocalResources.
+func FormatLocalResources(resources map[string]int) string {
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(name)
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(resources[name]))
+	}
+	return buf.String()
+}
+
+// A named resource with a fixed capacity which local jobs can reserve.
+type localResource struct {
+	name string
+	sem  *ResourceSemaphore
+
+	mu   sync.Mutex
+	peak int64
+	jobs int
+	wait time.Duration
+}
+
+// LocalResourceStats summarizes the usage of a named local resource over the
+// lifetime of the job manager.
+type LocalResourceStats struct {
+	Name     string `json:"name"`
+	Capacity int64  `json:"capacity"`
+	Reserved int64  `json:"reserved"`
+	Waiting  int    `json:"waiting"`
+
+	// The largest amount which was reserved at once.
+	Peak int64 `json:"peak"`
+
+	// The number of jobs which reserved the resource.
+	Jobs int `json:"jobs"`
+
+	// The total time jobs spent waiting for the resource to be available.
+	WaitSeconds float64 `json:"wait_seconds"`
+}
+
+func (self *localResource) acquire(n int64) error {
+	start := time.Now()
+	if err := self.sem.Acquire(n); err != nil {
+		return err
+	}
+	wait := time.Since(start)
+	reserved := self.sem.Reserved()
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.jobs++
+	self.wait += wait
+	if reserved > self.peak {
+		self.peak = reserved
+	}
+	return nil
+}
+
+func (self *localResource) stats() LocalResourceStats {
+	usage := self.sem.Usage()
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return LocalResourceStats{
+		Name:        self.name,
+		Capacity:    usage.InUse + usage.Available,
+		Reserved:    usage.Reserved,
+		Waiting:     self.sem.QueueLength(),
+		Peak:        self.peak,
+		Jobs:        self.jobs,
+		WaitSeconds: self.wait.Seconds(),
+	}
+}
+
+func (self *LocalJobManager) setupLocalResources(resources map[string]int) {
+	if len(resources) == 0 {
+		return
+	}
+	self.localResources = make(map[string]*localResource, len(resources))
+	for name, count := range resources {
+		self.localResources[name] = &localResource{
+			name: name,
+			sem: NewResourceSemaphore(int64(count),
+				DefaultResourceFormatter(name)),
+		}
+	}
+	util.LogInfo("jobmngr", "Using local resources %s, per --localresource option.",
+		FormatLocalResources(resources))
+}
+
+// A request for some amount of a named local resource.
+type localResourceClaim struct {
+	resource *localResource
+	count    int64
+}
+
+// localResourceClaims parses the resources a job requested through its
+// special resource string.  Terms are separated by commas and take the form
+// name or name:count.  Terms which do not name a configured local resource
+// are ignored, since special may also be used to select cluster resources.
+// It is an error to request the same local resource more than once.
+//
+// The claims are returned in name order, so that jobs acquire them in a
+// consistent order.
+func (self *LocalJobManager) localResourceClaims(special string) ([]localResourceClaim, error) {
+	if len(self.localResources) == 0 || special == "" {
+		return nil, nil
+	}
+	var claims []localResourceClaim
+	for _, term := range strings.Split(special, ",") {
+		name, count := strings.TrimSpace(term), "1"
+		if i := strings.LastIndexByte(name, ':'); i >= 0 {
+			name, count = strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+1:])
+		}
+		res := self.localResources[name]
+		if res == nil {
+			continue
+		}
+		n, err := strconv.ParseInt(count, 10, 64)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf(
+				"invalid count %q requested for local resource %s",
+				count, name)
+		}
+		// Claiming the same resource twice would deadlock if the job
+		// holds the first claim while waiting for the second.
+		for _, claim := range claims {
+			if claim.resource == res {
+				return nil, fmt.Errorf(
+					"local resource %s requested more than once", name)
+			}
+		}
+		claims = append(claims, localResourceClaim{resource: res, count: n})
+	}
+	sort.Slice(claims, func(i, j int) bool {
+		return claims[i].resource.name < claims[j].resource.name
+	})
+	return claims, nil
+}
+
+// acquireLocalResources blocks until all of the named resources requested by
+// the job are available.  On success, the returned function must be called
+// to release them.
+func (self *LocalJobManager) acquireLocalResources(special string) (func(), error) {
+	claims, err := self.localResourceClaims(special)
+	if err != nil {
+		return nil, err
+	}
+	release := func(claims []localResourceClaim) {
+		for _, claim := range claims {
+			claim.resource.sem.Release(claim.count)
+			if self.debug {
+				util.LogInfo("jobmngr", "Released %s",
+					claim.resource.sem.Formatter(claim.count))
+			}
+		}
+	}
+	for i, claim := range claims {
+		if self.debug {
+			util.LogInfo("jobmngr", "Waiting for %s",
+				claim.resource.sem.Formatter(claim.count))
+		}
+		if err := claim.resource.acquire(claim.count); err != nil {
+			release(claims[:i])
+			return nil, err
+		}
+		if self.debug {
+			util.LogInfo("jobmngr", "Acquired %s (%d/%d in use)",
+				claim.resource.sem.Formatter(claim.count),
+				claim.resource.sem.Reserved(),
+				claim.resource.sem.CurrentSize())
+		}
+	}
+	return func() { release(claims) }, nil
+}
+
+// LocalResourceUsage returns the state of each named local resource, sorted
+// by name.
+func (self *LocalJobManager) LocalResourceUsage() []LocalResourceStats {
+	if len(self.localResources) == 0 {
+		return nil
+	}
+	stats := make([]LocalResourceStats, 0, len(self.localResources))
+	for _, res := range self.localResources {
+		stats = append(stats, res.stats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLocalResources(t *testing.T) {
+	res, err := ParseLocalResources("db_conn:4, license:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res["db_conn"] != 4 || res["license"] != 2 {
+		t.Errorf("incorrect resources %v", res)
+	}
+	if s := FormatLocalResources(res); s != "db_conn:4,license:2" {
+		t.Errorf("incorrect format %q", s)
+	}
+	for _, bad := range []string{"db_conn", "db_conn:0", "db_conn:x", "a:1,a:2"} {
+		if _, err := ParseLocalResources(bad); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestAcquireLocalResources(t *testing.T) {
+	var self LocalJobManager
+	self.setupLocalResources(map[string]int{"db_conn": 2, "ssd": 1})
+	release, err := self.acquireLocalResources("highmem,ssd,db_conn:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := self.acquireLocalResources("db_conn:3"); err == nil {
+		t.Error("expected error requesting more than the capacity")
+	}
+	if _, err := self.acquireLocalResources("ssd:-1"); err == nil {
+		t.Error("expected error requesting a negative amount")
+	}
+	if _, err := self.acquireLocalResources("db_conn, db_conn:1"); err == nil {
+		t.Error("expected error requesting a resource twice")
+	}
+	acquired := make(chan func())
+	go func() {
+		release, err := self.acquireLocalResources("db_conn")
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- release
+	}()
+	for self.localResources["db_conn"].sem.QueueLength() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := self.LocalResourceUsage(); len(stats) != 2 {
+		t.Errorf("expected 2 resources, got %v", stats)
+	} else if s := stats[0]; s.Name != "db_conn" || s.Capacity != 2 ||
+		s.Reserved != 2 || s.Waiting != 1 || s.Peak != 2 || s.Jobs != 1 {
+		t.Errorf("incorrect stats %v", s)
+	}
+	release()
+	(<-acquired)()
+	if stats := self.LocalResourceUsage(); stats[0].Reserved != 0 ||
+		stats[0].Jobs != 2 || stats[1].Reserved != 0 || stats[1].Jobs != 1 {
+		t.Errorf("incorrect stats %v", stats)
+	}
+}
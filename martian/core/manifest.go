@@ -0,0 +1,244 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+// Checksum manifests for pipestance output files, which can be used to
+// verify that the outputs have not been modified since the pipestance
+// completed.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/trace"
+	"sort"
+
+	"github.com/martian-lang/martian/martian/syntax"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+type ManifestMode string
+
+const (
+	// Do not record output file checksums.
+	ManifestDisable ManifestMode = disable
+
+	// Record checksums for the files in the top-level outs directory.
+	ManifestOuts ManifestMode = "outs"
+
+	// Record checksums for the files in the top-level outs directory, as
+	// well as any stage output files which remain after VDR.
+	ManifestAll ManifestMode = "all"
+)
+
+func VerifyManifestMode(mode ManifestMode) {
+	switch mode {
+	case ManifestDisable, ManifestOuts, ManifestAll:
+		return
+	}
+	util.PrintInfo("runtime",
+		"Invalid manifest mode: %s. Valid manifest modes: outs, all, disable",
+		mode)
+	os.Exit(1)
+}
+
+// A ManifestEntry records the checksum of an output file.
+type ManifestEntry struct {
+	Sha256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// An OutputManifest records the checksums of output files, keyed by path
+// relative to the pipestance directory.
+type OutputManifest struct {
+	Files map[string]ManifestEntry `json:"files"`
+}
+
+func checksumFile(p string) (ManifestEntry, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	return ManifestEntry{
+		Sha256: hex.EncodeToString(h.Sum(nil)),
+		Size:   size,
+	}, err
+}
+
+// Walk the given directory, calling fn with the path of each file relative
+// to pipestancePath.  Unlike filepath.WalkDir, symlinks are followed, since
+// the outs directory contains symlinks to files outside the pipestance.
+func walkOutputFiles(pipestancePath, dir string,
+	visited map[string]struct{},
+	fn func(rel, p string) error) error {
+	if real, err := filepath.EvalSymlinks(dir); err != nil {
+		return err
+	} else if _, ok := visited[real]; ok {
+		return nil
+	} else {
+		visited[real] = struct{}{}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, ent := range entries {
+		p := path.Join(dir, ent.Name())
+		info, err := os.Stat(p)
+		if err != nil {
+			// Dangling symlinks are not output files.
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		if info.IsDir() {
+			if err := walkOutputFiles(pipestancePath, p, visited, fn); err != nil {
+				return err
+			}
+		} else if info.Mode().IsRegular() {
+			rel, err := filepath.Rel(pipestancePath, p)
+			if err != nil {
+				return err
+			}
+			if err := fn(filepath.ToSlash(rel), p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Compute the checksums of the files in the given directories.  Directories
+// which do not exist are ignored.
+func computeOutputManifest(pipestancePath string, dirs []string) (*OutputManifest, error) {
+	manifest := &OutputManifest{
+		Files: make(map[string]ManifestEntry),
+	}
+	visited := make(map[string]struct{})
+	var errs syntax.ErrorList
+	for _, dir := range dirs {
+		if err := walkOutputFiles(pipestancePath, dir, visited,
+			func(rel, p string) error {
+				if _, ok := manifest.Files[rel]; ok {
+					return nil
+				}
+				entry, err := checksumFile(p)
+				if err != nil {
+					return err
+				}
+				manifest.Files[rel] = entry
+				return nil
+			}); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return manifest, errs.If()
+}
+
+// Write the checksum manifest for the pipestance's output files.
+func (self *Pipestance) writeManifest(ctx context.Context) error {
+	defer trace.StartRegion(ctx, "writeManifest").End()
+	mode := self.node.top.rt.Config.ManifestMode
+	if mode == ManifestDisable {
+		return nil
+	}
+	pipestancePath := self.GetPath()
+	dirs := []string{path.Join(pipestancePath, "outs")}
+	if mode == ManifestAll {
+		for _, node := range self.allNodes() {
+			if node.call.Kind() != syntax.KindStage {
+				continue
+			}
+			for _, metadata := range node.collectMetadatas() {
+				dirs = append(dirs, metadata.FilesPath())
+			}
+		}
+	}
+	manifest, err := computeOutputManifest(pipestancePath, dirs)
+	if err != nil {
+		return err
+	}
+	util.LogInfo("runtime", "Recorded checksums for %d output files.",
+		len(manifest.Files))
+	return self.metadata.WriteAtomic(Manifest, manifest)
+}
+
+// A ManifestMismatch describes a file which does not match the checksum
+// manifest for a pipestance.
+type ManifestMismatch struct {
+	// The path to the file, relative to the pipestance directory.
+	Path string `json:"path"`
+
+	// One of missing, modified, or unexpected.  Unexpected files are files
+	// in the top-level outs directory which are not in the manifest.
+	Problem string `json:"problem"`
+}
+
+func (self *ManifestMismatch) String() string {
+	return self.Problem + ": " + self.Path
+}
+
+// VerifyOutputManifest checks the output files of a completed pipestance
+// against the checksums recorded when it completed.  Returns the number of
+// files which were checked, and the files which did not match.
+func VerifyOutputManifest(pipestancePath string) (int, []*ManifestMismatch, error) {
+	b, err := os.ReadFile(path.Join(pipestancePath, Manifest.FileName()))
+	if err != nil {
+		return 0, nil, err
+	}
+	var manifest OutputManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return 0, nil, err
+	}
+	names := make([]string, 0, len(manifest.Files))
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var mismatches []*ManifestMismatch
+	for _, name := range names {
+		entry, err := checksumFile(path.Join(pipestancePath, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			mismatches = append(mismatches, &ManifestMismatch{
+				Path:    name,
+				Problem: "missing",
+			})
+		} else if err != nil {
+			return len(names), mismatches, err
+		} else if entry != manifest.Files[name] {
+			mismatches = append(mismatches, &ManifestMismatch{
+				Path:    name,
+				Problem: "modified",
+			})
+		}
+	}
+	var unexpected []*ManifestMismatch
+	if err := walkOutputFiles(pipestancePath,
+		path.Join(pipestancePath, "outs"), make(map[string]struct{}),
+		func(rel, _ string) error {
+			if _, ok := manifest.Files[rel]; !ok {
+				unexpected = append(unexpected, &ManifestMismatch{
+					Path:    rel,
+					Problem: "unexpected",
+				})
+			}
+			return nil
+		}); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return len(names), mismatches, err
+	}
+	sort.Slice(unexpected, func(i, j int) bool {
+		return unexpected[i].Path < unexpected[j].Path
+	})
+	return len(names), append(mismatches, unexpected...), nil
+}
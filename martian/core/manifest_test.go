@@ -0,0 +1,90 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestOutputManifest(t *testing.T) {
+	psPath := t.TempDir()
+	external := path.Join(t.TempDir(), "external.txt")
+	writeTestPipestance(t, psPath, map[string]string{
+		"outs/a.txt":                 "a",
+		"outs/dir/b.txt":             "b",
+		"outs/external.txt":          "->" + external,
+		"outs/dangling.txt":          "->missing.txt",
+		"P/A/fork0/files/stage.txt":  "stage",
+		"P/A/fork0/files/linked.txt": "->../../../../outs/a.txt",
+	})
+	if err := os.WriteFile(external, []byte("external"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := computeOutputManifest(psPath, []string{
+		path.Join(psPath, "outs"),
+		path.Join(psPath, "P/A/fork0/files"),
+		path.Join(psPath, "P/B/fork0/files"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(manifest.Files))
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	for _, name := range []string{
+		"outs/a.txt", "outs/dir/b.txt", "outs/external.txt",
+		"P/A/fork0/files/stage.txt", "P/A/fork0/files/linked.txt",
+	} {
+		if _, ok := manifest.Files[name]; !ok {
+			t.Errorf("expected %s in manifest, got %v", name, names)
+		}
+	}
+	if len(manifest.Files) != 5 {
+		t.Errorf("expected 5 files, got %v", names)
+	}
+	if e := manifest.Files["outs/a.txt"]; e.Size != 1 || e.Sha256 !=
+		"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb" {
+		t.Errorf("incorrect entry %v", e)
+	}
+	md := NewMetadata("", psPath)
+	if err := md.WriteAtomic(Manifest, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(expect ...string) {
+		t.Helper()
+		count, mismatches, err := VerifyOutputManifest(psPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 5 {
+			t.Errorf("expected 5 files checked, got %d", count)
+		}
+		result := make([]string, len(mismatches))
+		for i, m := range mismatches {
+			result[i] = m.String()
+		}
+		if s, e := strings.Join(result, "\n"), strings.Join(expect, "\n"); s != e {
+			t.Errorf("expected\n%s\ngot\n%s", e, s)
+		}
+	}
+	check()
+	if err := os.WriteFile(external, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path.Join(psPath, "P/A/fork0/files/stage.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path.Join(psPath, "outs/c.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	check(
+		"missing: P/A/fork0/files/stage.txt",
+		"modified: outs/external.txt",
+		"unexpected: outs/c.txt",
+	)
+}
@@ -14,6 +14,7 @@ type MaxJobsSemaphore struct {
 	cond    *sync.Cond
 	lock    sync.Mutex
 	Limit   int
+	waiting int
 }
 
 func NewMaxJobsSemaphore(limit int) *MaxJobsSemaphore {
@@ -66,7 +67,9 @@ func (self *MaxJobsSemaphore) Acquire(metadata *Metadata, nonblocking bool) bool
 		if nonblocking {
 			return false
 		}
+		self.waiting++
 		self.cond.Wait()
+		self.waiting--
 	}
 	if st, ok := metadata.getState(); ok && st != Queued && st != Waiting {
 		return false
@@ -131,3 +134,10 @@ func (self *MaxJobsSemaphore) Current() int {
 	defer self.lock.Unlock()
 	return len(self.running)
 }
+
+// Waiting returns the number of jobs blocked waiting to acquire the semaphore.
+func (self *MaxJobsSemaphore) Waiting() int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.waiting
+}
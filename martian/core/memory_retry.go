@@ -0,0 +1,157 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+// Escalation of memory requests for chunks which are retried after
+// running out of memory.
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/martian-lang/martian/martian/util"
+)
+
+// The memory_retry section of retry.json.
+type memoryRetryJson struct {
+	// Regular expressions which, when matched against a line of a job's
+	// errors file, indicate that the job ran out of memory.
+	RetryOn []string `json:"retry_on"`
+
+	// The factor by which to multiply the memory request on each retry.
+	Factor float64 `json:"factor"`
+
+	// The largest memory request, in GB, which escalation will produce.
+	// If zero, there is no cap other than that imposed by the job manager.
+	MaxMemGB float64 `json:"max_mem_gb,omitempty"`
+}
+
+type memoryRetry struct {
+	errors   []*regexp.Regexp
+	factor   float64
+	maxMemGB float64
+}
+
+func (self *memoryRetryJson) compile() *memoryRetry {
+	if self == nil || self.Factor <= 1 || len(self.RetryOn) == 0 {
+		return nil
+	}
+	regexps := make([]*regexp.Regexp, len(self.RetryOn))
+	for i, exp := range self.RetryOn {
+		regexps[i] = regexp.MustCompile(exp)
+	}
+	return &memoryRetry{
+		errors:   regexps,
+		factor:   self.Factor,
+		maxMemGB: self.MaxMemGB,
+	}
+}
+
+// Returns true if the error log indicates the job ran out of memory.
+func (self *memoryRetry) isMemoryError(errlog string) bool {
+	for _, line := range strings.Split(errlog, "\n") {
+		for _, re := range self.errors {
+			if re.MatchString(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the memory request to use for a retry of a job which ran out of
+// memory after requesting memGB, or 0 if the request cannot be increased.
+func (self *memoryRetry) escalate(memGB float64) float64 {
+	if memGB <= 0 {
+		return 0
+	}
+	mem := math.Ceil(memGB * self.factor)
+	if self.maxMemGB > 0 && mem > self.maxMemGB {
+		mem = self.maxMemGB
+	}
+	if mem <= memGB {
+		return 0
+	}
+	return mem
+}
+
+// Returns the escalated memory request for the given failed job metadata,
+// or 0 if the job did not fail due to memory or could not be given more.
+func (self *memoryRetry) escalateFailed(metadata *Metadata) float64 {
+	if self == nil {
+		return 0
+	}
+	errlog, err := metadata.readRawSafe(Errors)
+	if err != nil || !self.isMemoryError(errlog) {
+		return 0
+	}
+	var jobInfo JobInfo
+	if err := metadata.ReadInto(JobInfoFile, &jobInfo); err != nil {
+		return 0
+	}
+	return self.escalate(jobInfo.MemGB)
+}
+
+// Returns the memory request which was persisted by a previous escalation,
+// or 0 if there was none.
+func (self *Chunk) retryMemGB() float64 {
+	var res JobResources
+	if err := self.metadata.ReadInto(RetryResources, &res); err != nil {
+		res.MemGB = 0
+	}
+	if self.fork != nil {
+		if mem := self.fork.retryMem[self.index]; mem > res.MemGB {
+			return mem
+		}
+	}
+	return res.MemGB
+}
+
+// Returns the memory request to use when the chunk is retried, which is
+// the escalated request if the chunk failed due to running out of memory,
+// or otherwise the request from previous escalations, if any.
+func (self *Chunk) escalatedMemGB(memRetry *memoryRetry) float64 {
+	mem := self.retryMemGB()
+	if state, _ := self.metadata.getState(); state != Failed {
+		return mem
+	}
+	if escalated := memRetry.escalateFailed(self.metadata); escalated > mem {
+		mem = escalated
+		util.PrintInfo("runtime",
+			"(retry-memory)    %s: increasing memory request to %g GB",
+			self.fqname, mem)
+	}
+	return mem
+}
+
+// Saves the escalated memory requests for the chunks in the fork, so that
+// they are kept when the chunk metadata is removed by a full reset.
+func (self *Fork) saveRetryMem(memRetry *memoryRetry) {
+	for _, chunk := range self.chunks {
+		if mem := chunk.escalatedMemGB(memRetry); mem > 0 {
+			if self.retryMem == nil {
+				self.retryMem = make(map[int]float64, len(self.chunks))
+			}
+			self.retryMem[chunk.index] = mem
+		}
+	}
+}
+
+// Reset the chunk if it failed.  If it failed due to running out of memory,
+// the escalated memory request is persisted in the chunk metadata so that
+// it will be used when the chunk is resubmitted.  Escalations from previous
+// retries are kept.
+func (self *Chunk) resetPartial(memRetry *memoryRetry) error {
+	if state, _ := self.metadata.getState(); state != Failed {
+		return nil
+	}
+	mem := self.escalatedMemGB(memRetry)
+	if err := self.metadata.checkedReset(); err != nil {
+		return err
+	}
+	if mem > 0 {
+		return self.metadata.Write(RetryResources, &JobResources{MemGB: mem})
+	}
+	return nil
+}
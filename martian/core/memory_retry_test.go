@@ -0,0 +1,131 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryRetryEscalate(t *testing.T) {
+	retry := (&memoryRetryJson{
+		RetryOn:  []string{"^Stage exceeded its memory quota"},
+		Factor:   2,
+		MaxMemGB: 12,
+	}).compile()
+	if retry == nil {
+		t.Fatal("expected memory retry to be configured")
+	}
+	check := func(t *testing.T, mem, expect float64) {
+		t.Helper()
+		if v := retry.escalate(mem); v != expect {
+			t.Errorf("expected %g -> %g, got %g", mem, expect, v)
+		}
+	}
+	check(t, 1.5, 3)
+	check(t, 4, 8)
+	check(t, 8, 12)
+	check(t, 12, 0)
+	check(t, 0, 0)
+	if !retry.isMemoryError("foo\nStage exceeded its memory quota (using 3.1, allowed 2G)") {
+		t.Error("expected memory error")
+	}
+	if retry.isMemoryError("signal: killed") {
+		t.Error("unexpected memory error")
+	}
+	if (&memoryRetryJson{
+		RetryOn: []string{"MemoryError"},
+		Factor:  1,
+	}).compile() != nil {
+		t.Error("expected factor of 1 to disable escalation")
+	}
+}
+
+func TestChunkResetPartialMemory(t *testing.T) {
+	retry := (&memoryRetryJson{
+		RetryOn: []string{"^Stage exceeded its memory quota"},
+		Factor:  2,
+	}).compile()
+	md := NewMetadata("ID.ps.PIPE.STAGE.fork0.chnk0",
+		filepath.Join(t.TempDir(), "chnk0"))
+	if err := md.mkdirs(); err != nil {
+		t.Fatal(err)
+	}
+	chunk := Chunk{metadata: md, fqname: md.fqname}
+	fail := func(t *testing.T, mem float64, msg string) {
+		t.Helper()
+		if err := md.Write(JobInfoFile, &JobInfo{MemGB: mem}); err != nil {
+			t.Fatal(err)
+		}
+		if err := md.WriteRaw(Errors, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fail(t, 3, "Stage exceeded its memory quota (using 3.5, allowed 3G)")
+	if err := chunk.resetPartial(retry); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := md.getState(); st != Waiting {
+		t.Errorf("expected chunk to be reset, got %q", st)
+	}
+	if mem := chunk.retryMemGB(); mem != 6 {
+		t.Errorf("expected 6 GB, got %g", mem)
+	}
+
+	// A failure for some other reason keeps the previous escalation.
+	fail(t, 6, "signal: killed")
+	if err := chunk.resetPartial(retry); err != nil {
+		t.Fatal(err)
+	}
+	if mem := chunk.retryMemGB(); mem != 6 {
+		t.Errorf("expected 6 GB, got %g", mem)
+	}
+
+	// Chunks which have not failed are left alone.
+	if err := chunk.resetPartial(retry); err != nil {
+		t.Fatal(err)
+	}
+	if mem := chunk.retryMemGB(); mem != 6 {
+		t.Errorf("expected 6 GB, got %g", mem)
+	}
+}
+
+func TestForkSaveRetryMem(t *testing.T) {
+	retry := (&memoryRetryJson{
+		RetryOn: []string{"^Stage exceeded its memory quota"},
+		Factor:  2,
+	}).compile()
+	dir := filepath.Join(t.TempDir(), "chnk1")
+	md := NewMetadata("ID.ps.PIPE.STAGE.fork0.chnk1", dir)
+	if err := md.mkdirs(); err != nil {
+		t.Fatal(err)
+	}
+	var fork Fork
+	fork.chunks = []*Chunk{{fork: &fork, metadata: md, fqname: md.fqname, index: 1}}
+	if err := md.Write(JobInfoFile, &JobInfo{MemGB: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := md.WriteRaw(Errors, "Stage exceeded its memory quota"); err != nil {
+		t.Fatal(err)
+	}
+	fork.saveRetryMem(retry)
+
+	// A full reset removes the chunk directory and rebuilds the chunks.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	md = NewMetadata(md.fqname, dir)
+	if err := md.mkdirs(); err != nil {
+		t.Fatal(err)
+	}
+	chunk := Chunk{fork: &fork, metadata: md, fqname: md.fqname, index: 1}
+	if mem := chunk.retryMemGB(); mem != 6 {
+		t.Errorf("expected 6 GB, got %g", mem)
+	}
+	chunk.index = 0
+	if mem := chunk.retryMemGB(); mem != 0 {
+		t.Errorf("expected no escalation for another chunk, got %g", mem)
+	}
+}
@@ -40,6 +40,7 @@ const (
 	JobModeFile    MetadataFileName = "jobmode"
 	Lock           MetadataFileName = "lock"
 	LogFile        MetadataFileName = "log"
+	Manifest       MetadataFileName = "manifest"
 	MetadataZip    MetadataFileName = "metadata.zip"
 	MroSourceFile  MetadataFileName = "mrosource"
 	OutsFile       MetadataFileName = "outs"
@@ -48,6 +49,7 @@ const (
 	ProfileOut     MetadataFileName = "profile.out"
 	ProgressFile   MetadataFileName = "progress"
 	QueuedLocally  MetadataFileName = "queued_locally"
+	RetryResources MetadataFileName = "retry_resources"
 	Stackvars      MetadataFileName = "stackvars"
 	StageDefsFile  MetadataFileName = "stage_defs"
 	StdErr         MetadataFileName = "stderr"
@@ -78,7 +80,8 @@ func (self MetadataFileName) MimeType() string {
 	case ArgsFile, OutsFile,
 		JobInfoFile,
 		StageDefsFile, ChunkDefsFile, ChunkOutsFile,
-		VdrKill, PartialVdr, FinalState,
+		RetryResources,
+		VdrKill, PartialVdr, FinalState, Manifest,
 		TagsFile, VersionsFile, Perf:
 		return "application/json"
 	case LogFile, StdErr, StdOut,
@@ -194,6 +197,10 @@ type Metadata struct {
 	// Empty for chunks, or SplitPrefix or JoinPrefix.
 	journalPrefix string
 
+	// If set, journal updates are sent to mrp through this client rather
+	// than written to the journal directory.
+	statusClient *StatusClient
+
 	mutex sync.Mutex
 }
 
@@ -307,6 +314,18 @@ func (self *Metadata) FilesPath() string {
 	return self.curFilesPath
 }
 
+// The fully qualified name of the job this metadata belongs to.
+func (self *Metadata) GetFQName() string {
+	return self.fqname
+}
+
+// Remove the sentinel file which marks a job as waiting to be started or
+// submitted by the job manager.  Job managers should call this once the job
+// has been handed off, so that it is not restarted if mrp is restarted.
+func (self *Metadata) ClearQueuedLocally() error {
+	return self.remove(QueuedLocally)
+}
+
 func (self *Metadata) TempDir() string {
 	if p := self.path; p != "" {
 		return path.Join(p, "tmp")
@@ -782,8 +801,19 @@ func (self *Metadata) WriteAtomic(name MetadataFileName, object interface{}) err
 // only scans the journal.  This means that when a metadata file is created
 // or modified (except by the runtime itself), the change won't be "noticed"
 // until the journal is updated.
+//
+// If a status client has been set, the update is sent through it instead,
+// falling back to the journal directory if that fails.
 func (self *Metadata) UpdateJournal(name MetadataFileName) error {
 	fname := self.journalPath + "." + self.journalPrefix + string(name)
+	if c := self.statusClient; c != nil {
+		if err := c.Send(self.path, path.Base(fname)); err == nil {
+			return nil
+		} else {
+			util.LogError(err, "runtime",
+				"Could not send status update for %s.", name)
+		}
+	}
 	if err := os.WriteFile(fname,
 		[]byte(util.Timestamp()), 0644); err != nil && !os.IsExist(err) {
 		return err
@@ -791,6 +821,11 @@ func (self *Metadata) UpdateJournal(name MetadataFileName) error {
 	return nil
 }
 
+// SetStatusClient sets the client used to send journal updates to mrp.
+func (self *Metadata) SetStatusClient(client *StatusClient) {
+	self.statusClient = client
+}
+
 func (self *Metadata) remove(name MetadataFileName) error {
 	self.uncache(name)
 	err := os.Remove(self.MetadataFilePath(name))
@@ -1030,6 +1065,7 @@ func (self *Metadata) checkHeartbeat() {
 			if state, _ := self.getState(); state != Running {
 				return
 			}
+			countHeartbeatFailure()
 			self.WriteErrorString(fmt.Sprintf(
 				"%s: No heartbeat detected for %d minutes. "+
 					"Assuming job has failed. This may be "+
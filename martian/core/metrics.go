@@ -0,0 +1,47 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+// Cumulative counters for runtime events, for use in monitoring.
+
+import "sync/atomic"
+
+// RuntimeCounters holds counts of events which occurred since the process
+// started.
+type RuntimeCounters struct {
+	// The number of times a job submission command for a remote job
+	// manager returned an error.
+	JobSubmitFailures int64
+
+	// The number of jobs which were declared failed because they stopped
+	// updating their heartbeat file.
+	HeartbeatFailures int64
+
+	// The total size of files removed by volatile data removal.
+	VdrBytesFreed int64
+}
+
+var runtimeCounters RuntimeCounters
+
+// GetRuntimeCounters returns a snapshot of the current event counts.
+func GetRuntimeCounters() RuntimeCounters {
+	return RuntimeCounters{
+		JobSubmitFailures: atomic.LoadInt64(&runtimeCounters.JobSubmitFailures),
+		HeartbeatFailures: atomic.LoadInt64(&runtimeCounters.HeartbeatFailures),
+		VdrBytesFreed:     atomic.LoadInt64(&runtimeCounters.VdrBytesFreed),
+	}
+}
+
+func countJobSubmitFailure() {
+	atomic.AddInt64(&runtimeCounters.JobSubmitFailures, 1)
+}
+
+func countHeartbeatFailure() {
+	atomic.AddInt64(&runtimeCounters.HeartbeatFailures, 1)
+}
+
+func countVdrBytesFreed(n int64) {
+	if n > 0 {
+		atomic.AddInt64(&runtimeCounters.VdrBytesFreed, n)
+	}
+}
@@ -198,6 +198,11 @@ func (self *Node) addDirectRefNodes(ref *syntax.RefExp,
 	prenodes map[Nodable]struct{}) map[Nodable]struct{} {
 	binding := self.call.Call().Bindings.Table[ref.Id]
 	if binding == nil {
+		if param := self.call.Callable().GetInParams().Table[ref.Id]; param != nil &&
+			param.Default != nil {
+			// Default values are constant.
+			return prenodes
+		}
 		panic(self.GetFQName() + " has no argument " + ref.Id)
 	}
 	var forks map[*syntax.CallStm]syntax.CollectionIndex
@@ -677,34 +682,7 @@ func (self *Node) getState() MetadataState {
 
 func (self *Node) reset() error {
 	if self.top.rt.Config.FullStageReset {
-		util.PrintInfo("runtime", "(reset)           %s", self.call.GetFqid())
-
-		// Blow away the entire stage node.
-		if err := os.RemoveAll(self.path); err != nil {
-			util.PrintInfo("runtime",
-				`Cannot reset the stage because its folder contents could not be deleted.
-
-Please resolve this error in order to continue running the pipeline:`)
-			return err
-		}
-		// Remove all related files from journal directory.
-		if files, err := util.Readdirnames(self.top.journalPath); err == nil {
-			base := strings.TrimPrefix(strings.TrimPrefix(self.call.GetFqid(),
-				self.top.fqname), ".")
-			for _, file := range files {
-				if strings.HasPrefix(file, base) {
-					os.Remove(path.Join(self.top.journalPath, file))
-				}
-			}
-		}
-
-		// Clear chunks in the forks so they can be rebuilt on split.
-		for _, fork := range self.forks {
-			fork.reset()
-		}
-
-		// Create stage node directories.
-		if err := self.mkdirs(); err != nil {
+		if err := self.resetAll(); err != nil {
 			return err
 		}
 	} else {
@@ -720,6 +698,89 @@ Please resolve this error in order to continue running the pipeline:`)
 	return nil
 }
 
+// resetAll removes all of the metadata and outputs for the node, so that it
+// runs again from the beginning.
+func (self *Node) resetAll() error {
+	util.PrintInfo("runtime", "(reset)           %s", self.call.GetFqid())
+
+	if self.call.Kind() != syntax.KindPipeline {
+		// Memory escalations are stored in the chunk directories, which
+		// are about to be removed.
+		memRetry := self.top.rt.memoryRetry()
+		for _, fork := range self.forks {
+			fork.saveRetryMem(memRetry)
+		}
+	}
+	if self.call.Kind() == syntax.KindPipeline {
+		// The pipeline directory also contains its subnodes, so only
+		// remove the forks.
+		for _, fork := range self.forks {
+			if err := fork.metadata.removeAll(true); err != nil {
+				util.PrintInfo("runtime",
+					`Cannot reset the pipeline because its folder contents could not be deleted.
+
+Please resolve this error in order to continue running the pipeline:`)
+				return err
+			}
+		}
+	} else if err := os.RemoveAll(self.path); err != nil {
+		// Blow away the entire stage node.
+		util.PrintInfo("runtime",
+			`Cannot reset the stage because its folder contents could not be deleted.
+
+Please resolve this error in order to continue running the pipeline:`)
+		return err
+	}
+	// Remove all related files from journal directory.
+	if files, err := util.Readdirnames(self.top.journalPath); err == nil {
+		base := strings.TrimPrefix(strings.TrimPrefix(self.call.GetFqid(),
+			self.top.fqname), ".") + ".fork"
+		for _, file := range files {
+			if strings.HasPrefix(file, base) {
+				os.Remove(path.Join(self.top.journalPath, file))
+			}
+		}
+	}
+
+	// Clear chunks in the forks so they can be rebuilt on split.
+	for _, fork := range self.forks {
+		fork.reset()
+	}
+
+	// Create stage node directories.
+	return self.mkdirs()
+}
+
+// downstreamNodes returns the nodes which must run again if the given nodes
+// are rerun, which are those nodes, their subnodes, and everything downstream
+// of those, sorted by name.
+func downstreamNodes(targets []*Node) []*Node {
+	found := make(map[*Node]struct{})
+	var queue []*Node
+	for _, node := range targets {
+		queue = append(queue, node.allNodes()...)
+	}
+	for len(queue) > 0 {
+		node := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if _, ok := found[node]; ok {
+			continue
+		}
+		found[node] = struct{}{}
+		for _, post := range node.postnodes {
+			queue = append(queue, post.getNode())
+		}
+	}
+	nodes := make([]*Node, 0, len(found))
+	for node := range found {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].GetFQName() < nodes[j].GetFQName()
+	})
+	return nodes
+}
+
 func (self *Node) restartLocallyQueuedJobs() error {
 	if self.top.rt.Config.FullStageReset {
 		// If entire stages got blown away then this isn't needed.
@@ -847,6 +908,7 @@ func (self *Node) getFatalError() (string, bool, string, string, MetadataFileNam
 // recur if the pipeline is rerun.
 func (self *Node) isErrorTransient() (bool, string) {
 	passRegexp, _ := getRetryRegexps()
+	memRetry := self.top.rt.memoryRetry()
 	for _, metadata := range self.collectMetadatas() {
 		if state, _ := metadata.getState(); state != Failed {
 			continue
@@ -863,12 +925,29 @@ func (self *Node) isErrorTransient() (bool, string) {
 					}
 				}
 			}
+			// Chunks which ran out of memory can be retried with a
+			// larger memory request.
+			if self.isChunkMetadata(metadata) &&
+				memRetry.escalateFailed(metadata) > 0 {
+				return true, errlog
+			}
 			return false, errlog
 		}
 	}
 	return true, ""
 }
 
+func (self *Node) isChunkMetadata(metadata *Metadata) bool {
+	for _, fork := range self.forks {
+		for _, chunk := range fork.chunks {
+			if chunk.metadata == metadata {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (self *Node) step() bool {
 	if self.state == Running {
 		for _, fork := range self.forks {
@@ -940,10 +1019,22 @@ func (self *Node) parseRunFilename(fqname string) (string, string, int, string,
 }
 
 func (self *Node) refreshState(readOnly bool) {
-	startTime := time.Now().Add(-self.top.rt.JobManager.queueCheckGrace())
-	files, err := util.Readdirnames(self.top.journalPath)
-	if err != nil {
-		util.LogError(err, "runtime", "Could not read journal directory.")
+	refreshStart := time.Now()
+	grace := self.top.rt.JobManager.QueueCheckGrace()
+	startTime := refreshStart.Add(-grace)
+	status := self.top.rt.StatusServer
+	var files []string
+	// When jobs are sending status updates directly, the journal directory
+	// is only needed for updates from stage code which does not go through
+	// mrjob, so it does not need to be read as often.
+	if status == nil ||
+		refreshStart.Sub(self.top.lastJournalScan) >= statusJournalScanInterval {
+		self.top.lastJournalScan = refreshStart
+		var err error
+		files, err = util.Readdirnames(self.top.journalPath)
+		if err != nil {
+			util.LogError(err, "runtime", "Could not read journal directory.")
+		}
 	}
 	updatedForks := make(map[*Fork]struct{})
 	for _, file := range files {
@@ -951,43 +1042,30 @@ func (self *Node) refreshState(readOnly bool) {
 		if strings.HasSuffix(filename, ".tmp") {
 			continue
 		}
-
-		fqname, forkIndex, chunkIndex, uniquifier, state := self.parseRunFilename(filename)
-		if fqname == "" {
-			util.LogInfo("runtime",
-				"WARNING: failed to parse journal file name %s",
-				filename)
-		} else if node := self.find(fqname); node != nil {
-			if fork := node.getFork(forkIndex); fork != nil {
-				if chunkIndex >= 0 {
-					if chunk := fork.getChunk(chunkIndex); chunk != nil {
-						chunk.updateState(MetadataFileName(state), uniquifier)
-					} else {
-						util.LogInfo("runtime",
-							"WARNING: Journal update for unknown chunk %s.fork%s.chnk%d",
-							fqname, forkIndex, chunkIndex)
-					}
-				} else {
-					fork.updateState(state, uniquifier)
-				}
-				updatedForks[fork] = struct{}{}
-			} else {
-				util.LogInfo("runtime",
-					"WARNING: Journal update for unknown fork %s.fork%s",
-					fqname, forkIndex)
-			}
-		} else {
-			util.LogInfo("runtime",
-				"WARNING: Journal update for unknown node %s (%s)",
-				fqname, filename)
-		}
+		self.applyJournal(filename, updatedForks)
 		if !readOnly {
 			os.Remove(path.Join(self.top.journalPath, file))
 		}
 	}
+	if status != nil {
+		for _, filename := range status.takeJournal() {
+			self.applyJournal(filename, updatedForks)
+		}
+		// Updates from jobs which are sending them directly are not delayed,
+		// so there is no need to wait the full grace period before believing
+		// the job manager when it says such a job is not running.
+		if grace > statusQueueCheckGrace {
+			grace = statusQueueCheckGrace
+		}
+	}
+	pushedStartTime := refreshStart.Add(-grace)
 	for _, node := range self.getFrontierNodes() {
 		for _, meta := range node.collectMetadatas() {
-			meta.endRefresh(startTime)
+			if status != nil && status.hasConnected(meta.path) {
+				meta.endRefresh(pushedStartTime)
+			} else {
+				meta.endRefresh(startTime)
+			}
 		}
 	}
 	for fork := range updatedForks {
@@ -995,6 +1073,39 @@ func (self *Node) refreshState(readOnly bool) {
 	}
 }
 
+// Apply an update from a journal file name, as written by a job.
+func (self *Node) applyJournal(filename string, updatedForks map[*Fork]struct{}) {
+	fqname, forkIndex, chunkIndex, uniquifier, state := self.parseRunFilename(filename)
+	if fqname == "" {
+		util.LogInfo("runtime",
+			"WARNING: failed to parse journal file name %s",
+			filename)
+	} else if node := self.find(fqname); node != nil {
+		if fork := node.getFork(forkIndex); fork != nil {
+			if chunkIndex >= 0 {
+				if chunk := fork.getChunk(chunkIndex); chunk != nil {
+					chunk.updateState(MetadataFileName(state), uniquifier)
+				} else {
+					util.LogInfo("runtime",
+						"WARNING: Journal update for unknown chunk %s.fork%s.chnk%d",
+						fqname, forkIndex, chunkIndex)
+				}
+			} else {
+				fork.updateState(state, uniquifier)
+			}
+			updatedForks[fork] = struct{}{}
+		} else {
+			util.LogInfo("runtime",
+				"WARNING: Journal update for unknown fork %s.fork%s",
+				fqname, forkIndex)
+		}
+	} else {
+		util.LogInfo("runtime",
+			"WARNING: Journal update for unknown node %s (%s)",
+			fqname, filename)
+	}
+}
+
 // Serialization.
 func (self *Node) serializeState(ctx context.Context) *NodeInfo {
 	defer trace.StartRegion(ctx, "Node_serializeState").End()
@@ -1084,13 +1195,41 @@ func (self *Node) serializePerf(ctx context.Context) (*NodePerfInfo, []*VdrEvent
 // Job Runners
 //=============================================================================
 
-func (self *Node) getJobReqs(jobDef *JobResources, stageType string) JobResources {
+// Get the resources for a job.
+//
+// Values computed from the stage's resource expressions take precedence
+// over static values from the stage declaration, values set by the stage
+// code take precedence over those, and overrides take precedence over
+// everything.
+func (self *Node) getJobReqs(computed, jobDef *JobResources,
+	stageType string) JobResources {
+	res := self.requestedJobReqs(computed, jobDef, stageType)
+	return self.systemJobReqs(&res)
+}
+
+// Get the resources requested for the node, before the job manager applies
+// its limits.
+func (self *Node) requestedJobReqs(computed, jobDef *JobResources,
+	stageType string) JobResources {
 	var res JobResources
 
 	if self.resources != nil {
 		res = *self.resources
 	}
 
+	// Get values computed from the stage inputs.
+	if computed != nil {
+		if computed.Threads != 0 {
+			res.Threads = computed.Threads
+		}
+		if computed.MemGB != 0 {
+			res.MemGB = computed.MemGB
+		}
+		if computed.VMemGB != 0 {
+			res.VMemGB = computed.VMemGB
+		}
+	}
+
 	// Get values passed from the stage code
 	if jobDef != nil {
 		if jobDef.Threads != 0 {
@@ -1105,15 +1244,21 @@ func (self *Node) getJobReqs(jobDef *JobResources, stageType string) JobResource
 		if jobDef.Special != "" {
 			res.Special = jobDef.Special
 		}
+		if jobDef.Timeout != 0 {
+			res.Timeout = jobDef.Timeout
+		}
 	}
 
 	// Override with job manager caps specified from commandline
 	self.top.rt.overrides.GetResources(self.GetFQName(), stageType, &res)
+	return res
+}
 
+func (self *Node) systemJobReqs(res *JobResources) JobResources {
 	if self.local {
-		return self.top.rt.LocalJobManager.GetSystemReqs(&res)
+		return self.top.rt.LocalJobManager.GetSystemReqs(res)
 	} else {
-		return self.top.rt.JobManager.GetSystemReqs(&res)
+		return self.top.rt.JobManager.GetSystemReqs(res)
 	}
 }
 
@@ -1123,9 +1268,10 @@ func (self *Node) getProfileMode(stageType string) ProfileMode {
 		self.top.rt.Config.ProfileMode)
 }
 
-func (self *Node) setJobReqs(jobDef *JobResources, stageType string) JobResources {
+func (self *Node) setJobReqs(computed, jobDef *JobResources,
+	stageType string) JobResources {
 	// Get values and possibly modify them
-	res := self.getJobReqs(jobDef, stageType)
+	res := self.getJobReqs(computed, jobDef, stageType)
 
 	// Write modified values back
 	if jobDef != nil {
@@ -1135,20 +1281,38 @@ func (self *Node) setJobReqs(jobDef *JobResources, stageType string) JobResource
 	return res
 }
 
-func (self *Node) setSplitJobReqs() JobResources {
-	return self.setJobReqs(nil, STAGE_TYPE_SPLIT)
+func (self *Node) setSplitJobReqs(computed *JobResources) JobResources {
+	return self.setJobReqs(computed, nil, STAGE_TYPE_SPLIT)
 }
 
-func (self *Node) setChunkJobReqs(jobDef *JobResources) JobResources {
-	return self.setJobReqs(jobDef, STAGE_TYPE_CHUNK)
+// Get the resources for a chunk, and write them back to the chunk def.
+//
+// If retryMemGB is larger than the requested memory, because the chunk
+// previously ran out of memory, it is used instead.
+func (self *Node) setChunkJobReqs(computed, jobDef *JobResources,
+	retryMemGB float64) JobResources {
+	res := self.requestedJobReqs(computed, jobDef, STAGE_TYPE_CHUNK)
+	if retryMemGB > math.Abs(res.MemGB) {
+		if res.MemGB < 0 {
+			res.MemGB = -retryMemGB
+		} else {
+			res.MemGB = retryMemGB
+		}
+	}
+	res = self.systemJobReqs(&res)
+	if jobDef != nil {
+		*jobDef = res
+	}
+	return res
 }
 
-func (self *Node) setJoinJobReqs(jobDef *JobResources) JobResources {
-	return self.setJobReqs(jobDef, STAGE_TYPE_JOIN)
+func (self *Node) setJoinJobReqs(computed, jobDef *JobResources) JobResources {
+	return self.setJobReqs(computed, jobDef, STAGE_TYPE_JOIN)
 }
 
-func (self *Node) runSplit(fqname string, metadata *Metadata) {
-	res := self.setSplitJobReqs()
+func (self *Node) runSplit(computed *JobResources,
+	fqname string, metadata *Metadata) {
+	res := self.setSplitJobReqs(computed)
 	self.runJob("split", fqname, STAGE_TYPE_SPLIT, metadata, &res)
 }
 
@@ -1180,12 +1344,23 @@ func (self *Node) runJob(shellName, fqname, stageType string,
 	runFile := metadata.journalFile()
 	version := &self.top.version
 	envs := self.top.envs
-	if td := metadata.TempDir(); td != "" {
-		envs = make(map[string]string, len(self.top.envs)+1)
+	td := metadata.TempDir()
+	status := self.top.rt.StatusServer
+	if td != "" || status != nil {
+		envs = make(map[string]string, len(self.top.envs)+3)
 		for k, v := range self.top.envs {
 			envs[k] = v
 		}
-		envs["TMPDIR"] = td
+		if td != "" {
+			envs["TMPDIR"] = td
+		}
+		if status != nil {
+			// Each job gets a token which is only valid for its own
+			// metadata path and journal files.
+			for k, v := range status.JobEnvs(metadata.path, path.Base(runFile)) {
+				envs[k] = v
+			}
+		}
 	}
 	switch self.stagecode.Type {
 	case syntax.PythonStage:
@@ -1241,6 +1416,7 @@ func (self *Node) runJob(shellName, fqname, stageType string,
 		Threads:       res.Threads,
 		MemGB:         res.MemGB,
 		VMemGB:        res.VMemGB,
+		Timeout:       res.Timeout,
 		ProfileConfig: self.top.rt.ProfileConfig(profileMode),
 		ProfileMode:   profileMode,
 		Stackvars:     stackVars,
@@ -1264,6 +1440,6 @@ func (self *Node) runJob(shellName, fqname, stageType string,
 			"Could not write jobinfo file, aborting.")
 		util.Suicide(false)
 	}
-	jobManager.execJob(shellCmd, argv, envs, metadata, res, fqname,
+	jobManager.ExecJob(shellCmd, argv, envs, metadata, res, fqname,
 		shellName, self.call.Call().Modifiers.Preflight && self.local)
 }
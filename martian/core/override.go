@@ -50,26 +50,34 @@ func partiallyQualifiedName(n string) string {
 // can be overridden.
 type StageOverride struct {
 	ForceVolatile *bool `json:"force_volatile,omitempty"`
+	Cache         *bool `json:"cache,omitempty"`
 
 	JoinThreads *float64     `json:"join.threads,omitempty"`
 	JoinMem     *float64     `json:"join.mem_gb,omitempty"`
 	JoinVMem    *float64     `json:"join.vmem_gb,omitempty"`
+	JoinTimeout *int64       `json:"join.timeout,omitempty"`
 	JoinProfile *ProfileMode `json:"join.profile,omitempty"`
 
 	ChunkThreads *float64     `json:"chunk.threads,omitempty"`
 	ChunkMem     *float64     `json:"chunk.mem_gb,omitempty"`
 	ChunkVMem    *float64     `json:"chunk.vmem_gb,omitempty"`
+	ChunkTimeout *int64       `json:"chunk.timeout,omitempty"`
 	ChunkProfile *ProfileMode `json:"chunk.profile,omitempty"`
 
 	SplitThreads *float64     `json:"split.threads,omitempty"`
 	SplitMem     *float64     `json:"split.mem_gb,omitempty"`
 	SplitVMem    *float64     `json:"split.vmem_gb,omitempty"`
+	SplitTimeout *int64       `json:"split.timeout,omitempty"`
 	SplitProfile *ProfileMode `json:"split.profile,omitempty"`
 }
 
 type PipestanceOverrides struct {
 	overridesbystage map[string]*StageOverride
 	filename         string
+
+	// Resource requests, e.g. generated from historical performance data,
+	// to use where the overrides do not set a value.
+	resourceProfile *PipestanceOverrides
 }
 
 // Read the overrides file and produce a pipestance overrides object.
@@ -118,6 +126,12 @@ func (pse *PipestanceOverrides) ReadFile(path string) error {
 	return nil
 }
 
+// SetResourceProfile sets a second set of overrides, from which resource
+// requests are taken for stages where these overrides do not specify them.
+func (pse *PipestanceOverrides) SetResourceProfile(profile *PipestanceOverrides) {
+	pse.resourceProfile = profile
+}
+
 func getParent(n string) string {
 	for len(n) > 0 {
 		if n[len(n)-1] == '.' {
@@ -154,16 +168,42 @@ func (pse *PipestanceOverrides) GetForceVolatile(node string, def bool) bool {
 	return def
 }
 
+// Compute whether a stage's results may be read from or saved to the stage
+// result cache, which might be overridden.
+//
+// node is the fully qualified node name.
+//
+// def  is the default value to use if the value is not overridden.
+func (pse *PipestanceOverrides) GetCache(node string, def bool) bool {
+	if pse == nil {
+		return def
+	}
+	pqn := partiallyQualifiedName(node)
+	for pqn != "" {
+		so := pse.overridesbystage[pqn]
+		if so == nil || so.Cache == nil {
+			pqn = getParent(pqn)
+		} else {
+			util.LogInfo("overide", "At [cache:%v] replace %v with %v",
+				pqn, def, *so.Cache)
+			return *so.Cache
+		}
+	}
+	return def
+}
+
 // GetResources applies any resource overrides for the given node/phase to
 // the given resource object.
 func (pse *PipestanceOverrides) GetResources(node string, phase string, res *JobResources) {
 	if pse == nil {
 		return
 	}
+	pse.resourceProfile.GetResources(node, phase, res)
 	pqn := partiallyQualifiedName(node)
 	res.Threads = pse.getThreads(pqn, phase, res.Threads)
 	res.MemGB = pse.getMem(pqn, phase, res.MemGB)
 	res.VMemGB = pse.getVMem(pqn, phase, res.VMemGB)
+	res.Timeout = pse.getTimeout(pqn, phase, res.Timeout)
 }
 
 // Compute the value to use for a stage's thread reservation, which might be
@@ -232,6 +272,26 @@ func (pse *PipestanceOverrides) getVMem(pqn string, phase string, def float64) f
 	return def
 }
 
+// Compute the value to use for a stage's wall-clock time limit, in seconds,
+// which might be overridden.
+//
+// pqn is the partially qualified node name.
+//
+// def  is the default value to use if the value is not overridden.
+func (pse *PipestanceOverrides) getTimeout(pqn string, phase string, def int64) int64 {
+	for pqn != "" {
+		val := pse.overridesbystage[pqn].GetTimeout(phase)
+		if val == nil {
+			pqn = getParent(pqn)
+		} else {
+			util.LogInfo("overide", "At [%s.timeout:%s] replace %d with %d",
+				phase, pqn, def, *val)
+			return *val
+		}
+	}
+	return def
+}
+
 // Compute the value to use for a stage's profile mode, which might be
 // overridden.
 //
@@ -306,6 +366,22 @@ func (so *StageOverride) GetVMem(phase string) *float64 {
 	}
 }
 
+func (so *StageOverride) GetTimeout(phase string) *int64 {
+	if so == nil {
+		return nil
+	}
+	switch phase {
+	case STAGE_TYPE_SPLIT:
+		return so.SplitTimeout
+	case STAGE_TYPE_CHUNK:
+		return so.ChunkTimeout
+	case STAGE_TYPE_JOIN:
+		return so.JoinTimeout
+	default:
+		panic("invalid phase " + phase)
+	}
+}
+
 func (so *StageOverride) GetProfile(phase string) *ProfileMode {
 	if so == nil {
 		return nil
@@ -250,6 +250,9 @@ type NodePerfInfo struct {
 	BytesHist []*NodeByteStamp         `json:"bytehist"`
 	MaxBytes  int64                    `json:"maxbytes"`
 	Type      syntax.CallGraphNodeType `json:"type"`
+
+	// The usage of named local resources.  Only set for the top-level node.
+	LocalResources []LocalResourceStats `json:"local_resources,omitempty"`
 }
 
 func max(a, b int) int {
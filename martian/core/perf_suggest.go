@@ -0,0 +1,107 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+// Suggest resource requests for stages based on the observed performance of
+// previously completed pipestances.
+
+import (
+	"math"
+	"sort"
+
+	"github.com/martian-lang/martian/martian/syntax"
+)
+
+// resourceSamples collects the observed usage for the chunks of one stage.
+type resourceSamples struct {
+	memGB   []float64
+	threads []float64
+}
+
+func (s *resourceSamples) add(perf *PerfInfo) {
+	if perf == nil || perf.MaxRss <= 0 {
+		return
+	}
+	s.memGB = append(s.memGB, float64(perf.MaxRss)/(1024*1024))
+	if perf.Duration > 0 {
+		s.threads = append(s.threads,
+			(perf.UserTime+perf.SystemTime)/perf.Duration)
+	}
+}
+
+// Returns the value at the given percentile (0-100) of the samples, using
+// the nearest-rank method.
+func percentileOf(samples []float64, percentile float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	sort.Float64s(samples)
+	rank := int(math.Ceil(percentile/100*float64(len(samples)))) - 1
+	if rank < 0 {
+		rank = 0
+	} else if rank >= len(samples) {
+		rank = len(samples) - 1
+	}
+	return samples[rank]
+}
+
+// Scale the value by 1+headroom and round up to a whole number, with a
+// minimum of 1.
+func withHeadroom(v, headroom float64) float64 {
+	return math.Max(1, math.Ceil(v*(1+headroom)))
+}
+
+// SuggestOverrides computes chunk memory and thread requests for each stage
+// which appears in the given pipestance performance reports, as read from
+// the _perf files of completed pipestances.
+//
+// For each stage, the given percentile (between 0 and 100) of the observed
+// maximum RSS and average CPU usage across all chunks of all pipestances
+// is taken, scaled up by 1+headroom, and rounded up to a whole number.
+//
+// The result is keyed by partially qualified stage name, as used for
+// pipestance overrides, so that it applies to any pipestance with the same
+// pipeline structure.
+func SuggestOverrides(perfs [][]*NodePerfInfo,
+	percentile, headroom float64) map[string]*StageOverride {
+	samples := make(map[string]*resourceSamples)
+	for _, nodes := range perfs {
+		for _, node := range nodes {
+			if node == nil || node.Type != syntax.KindStage {
+				continue
+			}
+			pqn := partiallyQualifiedName(node.Fqname)
+			s := samples[pqn]
+			if s == nil {
+				s = new(resourceSamples)
+				samples[pqn] = s
+			}
+			for _, fork := range node.Forks {
+				if fork == nil {
+					continue
+				}
+				for _, chunk := range fork.Chunks {
+					if chunk != nil {
+						s.add(chunk.ChunkStats)
+					}
+				}
+			}
+		}
+	}
+	result := make(map[string]*StageOverride, len(samples))
+	for pqn, s := range samples {
+		var so StageOverride
+		if len(s.memGB) > 0 {
+			mem := withHeadroom(percentileOf(s.memGB, percentile), headroom)
+			so.ChunkMem = &mem
+		}
+		if len(s.threads) > 0 {
+			threads := withHeadroom(percentileOf(s.threads, percentile), headroom)
+			so.ChunkThreads = &threads
+		}
+		if so.ChunkMem != nil || so.ChunkThreads != nil {
+			result[pqn] = &so
+		}
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/martian-lang/martian/martian/syntax"
+)
+
+func chunkPerfs(rssGB []float64, cpu float64) []*ChunkPerfInfo {
+	chunks := make([]*ChunkPerfInfo, len(rssGB))
+	for i, rss := range rssGB {
+		chunks[i] = &ChunkPerfInfo{
+			Index: i,
+			ChunkStats: &PerfInfo{
+				MaxRss:   int(rss * 1024 * 1024),
+				Duration: 100,
+				UserTime: 100 * cpu,
+			},
+		}
+	}
+	return chunks
+}
+
+func TestSuggestOverrides(t *testing.T) {
+	perf := func(psid string, rssGB []float64, cpu float64) []*NodePerfInfo {
+		return []*NodePerfInfo{
+			{
+				Name:   "PIPE",
+				Fqname: "ID." + psid + ".PIPE",
+				Type:   syntax.KindPipeline,
+			},
+			{
+				Name:   "STAGE",
+				Fqname: "ID." + psid + ".PIPE.STAGE",
+				Type:   syntax.KindStage,
+				Forks: []*ForkPerfInfo{{
+					Chunks: chunkPerfs(rssGB, cpu),
+				}},
+			},
+		}
+	}
+	result := SuggestOverrides([][]*NodePerfInfo{
+		perf("ps1", []float64{1, 2, 3, 4}, 1.5),
+		perf("ps2", []float64{5, 6, 7, 8, 9, 10}, 3.1),
+	}, 90, 0.2)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 override, got %d", len(result))
+	}
+	so := result["PIPE.STAGE"]
+	if so == nil {
+		t.Fatal("expected override for PIPE.STAGE")
+	}
+	// The 90th percentile of 1..10 is 9.  9*1.2 = 10.8
+	if so.ChunkMem == nil || *so.ChunkMem != 11 {
+		t.Errorf("expected 11 GB, got %v", so.ChunkMem)
+	}
+	// 3.1*1.2 = 3.72
+	if so.ChunkThreads == nil || *so.ChunkThreads != 4 {
+		t.Errorf("expected 4 threads, got %v", so.ChunkThreads)
+	}
+}
+
+func TestResourceProfile(t *testing.T) {
+	mem := func(v float64) *float64 { return &v }
+	overrides := PipestanceOverrides{
+		overridesbystage: map[string]*StageOverride{
+			"PIPE": {ChunkThreads: mem(2)},
+		},
+	}
+	overrides.SetResourceProfile(&PipestanceOverrides{
+		overridesbystage: map[string]*StageOverride{
+			"PIPE.STAGE": {
+				ChunkMem:     mem(6),
+				ChunkThreads: mem(3),
+			},
+		},
+	})
+	res := JobResources{Threads: 1, MemGB: 1}
+	overrides.GetResources("ID.ps.PIPE.STAGE", STAGE_TYPE_CHUNK, &res)
+	if res.MemGB != 6 {
+		t.Errorf("expected memory from profile, got %g", res.MemGB)
+	}
+	if res.Threads != 2 {
+		t.Errorf("expected threads from overrides, got %g", res.Threads)
+	}
+	res = JobResources{Threads: 1, MemGB: 1}
+	overrides.GetResources("ID.ps.PIPE.STAGE", STAGE_TYPE_JOIN, &res)
+	if res.MemGB != 1 || res.Threads != 1 {
+		t.Errorf("expected join resources to be unchanged, got %v", res)
+	}
+}
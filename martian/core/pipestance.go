@@ -7,6 +7,7 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -14,6 +15,8 @@ import (
 	"path"
 	"path/filepath"
 	"runtime/trace"
+	"sort"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -56,6 +59,7 @@ func NewStagestance(parent Nodable, call *syntax.CallGraphStage, srcPaths []stri
 			MemGB:   float64(stage.Resources.MemGB),
 			VMemGB:  float64(stage.Resources.VMemGB),
 			Special: stage.Resources.Special,
+			Timeout: stage.Resources.Timeout,
 		}
 	}
 
@@ -492,7 +496,7 @@ func (self *Pipestance) queryQueue(outerCtx context.Context) {
 	}()
 	if self.node == nil || self.node.top == nil || self.node.top.rt == nil ||
 		self.node.top.rt.JobManager == nil ||
-		!self.node.top.rt.JobManager.hasQueueCheck() {
+		!self.node.top.rt.JobManager.HasQueueCheck() {
 		return
 	}
 	QUEUE_CHECK_LIMIT := 5 * time.Minute
@@ -539,7 +543,7 @@ func (self *Pipestance) queryQueue(outerCtx context.Context) {
 	prepDone = true
 	go func(ctx context.Context, task *trace.Task) {
 		defer task.End()
-		queued, raw := self.node.top.rt.JobManager.checkQueue(jobsIn, ctx)
+		queued, raw := self.node.top.rt.JobManager.CheckQueue(jobsIn, ctx)
 		for _, id := range queued {
 			delete(needsQuery, id)
 		}
@@ -616,13 +620,13 @@ func (self *Pipestance) StepNodes(ctx context.Context) bool {
 			return false
 		}
 	}
-	if err := self.node.top.rt.LocalJobManager.refreshResources(
+	if err := self.node.top.rt.LocalJobManager.RefreshResources(
 		self.node.top.rt.Config.JobMode == localMode); err != nil {
 		util.LogError(err, "runtime",
 			"Error refreshing local resources: %s", err.Error())
 	}
 	if self.node.top.rt.LocalJobManager != self.node.top.rt.JobManager {
-		if err := self.node.top.rt.JobManager.refreshResources(false); err != nil {
+		if err := self.node.top.rt.JobManager.RefreshResources(false); err != nil {
 			util.LogError(err, "runtime",
 				"Error refreshing cluster resources: %s", err.Error())
 		}
@@ -653,6 +657,125 @@ func (self *Pipestance) Reset() error {
 	return nil
 }
 
+// Rerun invalidates the given stage or pipeline, and every node downstream
+// of it, so that they run again.  The results of upstream nodes are kept.
+//
+// Fails if any of the invalidated nodes has a job which is still queued or
+// running, or if outputs of upstream nodes on which they depend were already
+// removed by VDR.
+func (self *Pipestance) Rerun(fqname string) error {
+	if self.readOnly() {
+		return &RuntimeError{"Pipestance is in read only mode."}
+	}
+	target := self.node.find(fqname)
+	if target == nil {
+		return &RuntimeError{"No stage or pipeline named " + fqname}
+	}
+	return self.invalidate([]*Node{target}, target.GetFQName())
+}
+
+// invalidateChanged resets the nodes with the given fully-qualified names,
+// which were changed in the MRO source since the pipestance was started, as
+// well as everything downstream of them.
+func (self *Pipestance) invalidateChanged(changed []string) error {
+	targets := make([]*Node, 0, len(changed))
+	for _, fqname := range changed {
+		if node := self.node.top.allNodes[fqname]; node != nil {
+			targets = append(targets, node)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	return self.invalidate(targets, "changed stages")
+}
+
+// invalidate resets the given nodes and everything downstream of them.
+func (self *Pipestance) invalidate(targets []*Node, desc string) error {
+	nodes := downstreamNodes(targets)
+
+	var running, killed []string
+	for _, node := range nodes {
+		for _, metadata := range node.collectMetadatas() {
+			if st, _ := metadata.getState(); st == Queued || st == Running {
+				running = append(running, node.GetFQName())
+				break
+			}
+		}
+	}
+	if len(running) > 0 {
+		return &RuntimeError{
+			"Cannot rerun stages which are still running: " +
+				strings.Join(running, ", "),
+		}
+	}
+	rerun := make(map[*Node]struct{}, len(nodes))
+	for _, node := range nodes {
+		rerun[node] = struct{}{}
+	}
+	seen := make(map[*Node]struct{})
+	for _, node := range nodes {
+		for _, prenode := range node.prenodes {
+			pre := prenode.getNode()
+			if _, ok := rerun[pre]; ok {
+				continue
+			} else if _, ok := seen[pre]; ok {
+				continue
+			}
+			seen[pre] = struct{}{}
+			if pre.vdrKilled() {
+				killed = append(killed, pre.GetFQName())
+			}
+		}
+	}
+	if len(killed) > 0 {
+		sort.Strings(killed)
+		return &RuntimeError{
+			"Cannot rerun " + desc +
+				" because outputs of upstream stages were removed by VDR: " +
+				strings.Join(killed, ", "),
+		}
+	}
+
+	util.PrintInfo("runtime", "Invalidating %d nodes to rerun %s.",
+		len(nodes), desc)
+	for _, node := range nodes {
+		if err := node.resetAll(); err != nil {
+			return err
+		}
+		node.loadMetadata()
+	}
+	return self.clearFinalState()
+}
+
+// clearFinalState removes the files written when the pipestance completed,
+// so that they are regenerated from the new results rather than describing
+// the previous run.
+func (self *Pipestance) clearFinalState() error {
+	var errs syntax.ErrorList
+	for _, name := range [...]MetadataFileName{
+		FinalState, Perf, MetadataZip, Manifest,
+	} {
+		if err := self.metadata.remove(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := os.Remove(path.Join(self.GetPath(),
+		ROCrateMetadataFile)); err != nil && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	// Drop the completion time, which is appended when the pipestance
+	// completes, but keep the start time.
+	if ts, err := self.metadata.readRawBytes(TimestampFile); err == nil {
+		if i := bytes.Index(ts, []byte("\nend:")); i >= 0 {
+			if err := self.metadata.WriteRawBytes(TimestampFile, ts[:i]); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs.If()
+}
+
 func (self *Pipestance) SerializeState(ctx context.Context) []*NodeInfo {
 	nodes := self.allNodes()
 	ser := make([]*NodeInfo, 0, len(nodes))
@@ -665,6 +788,50 @@ func (self *Pipestance) SerializeState(ctx context.Context) []*NodeInfo {
 	return ser
 }
 
+// ForkStateInfo is a lightweight summary of the state of a single fork.
+//
+// Unlike SerializeState, computing these does not require reading any
+// files, so it can be polled frequently to detect state transitions.
+type ForkStateInfo struct {
+	fork   *Fork
+	Fqname string
+	Index  int
+	State  MetadataState
+}
+
+// Alarms returns the content of any alarm files written by the jobs for
+// this fork.  Alarms from subpipelines are not included.
+func (self *ForkStateInfo) Alarms() string {
+	var alarms strings.Builder
+	for _, metadata := range self.fork.collectMetadatas() {
+		if !metadata.exists(AlarmFile) {
+			continue
+		}
+		if b, err := metadata.readRawBytes(AlarmFile); err == nil {
+			alarms.Write(b)
+		}
+	}
+	return alarms.String()
+}
+
+// ForkStates returns the current states of every fork of every node in the
+// pipestance.
+func (self *Pipestance) ForkStates() []ForkStateInfo {
+	nodes := self.allNodes()
+	states := make([]ForkStateInfo, 0, len(nodes))
+	for _, node := range nodes {
+		for _, fork := range node.forks {
+			states = append(states, ForkStateInfo{
+				fork:   fork,
+				Fqname: node.GetFQName(),
+				Index:  fork.index,
+				State:  fork.getState(),
+			})
+		}
+	}
+	return states
+}
+
 func (self *Pipestance) SerializePerf(ctx context.Context) []*NodePerfInfo {
 	nodes := self.allNodes()
 	ser := make([]*NodePerfInfo, 0, len(nodes))
@@ -683,6 +850,7 @@ func (self *Pipestance) SerializePerf(ctx context.Context) []*NodePerfInfo {
 		overallPerf := ser[0]
 		self.ComputeDiskUsage(ctx, overallPerf)
 		overallPerf.HighMem = &self.node.top.rt.LocalJobManager.highMem
+		overallPerf.LocalResources = self.node.top.rt.LocalJobManager.LocalResourceUsage()
 	}
 	return ser
 }
@@ -810,6 +978,10 @@ func (self *Pipestance) PostProcess() {
 	ctx, task := trace.NewTask(context.Background(), "PostProcess")
 	defer task.End()
 	self.node.postProcess(ctx)
+	if err := self.writeManifest(ctx); err != nil {
+		util.LogError(err, "runtime",
+			"Error writing output checksum manifest.")
+	}
 	start, _ := self.metadata.readRawBytes(TimestampFile)
 	start = append(start, "\nend: "...)
 	if err := self.metadata.WriteRawBytes(TimestampFile, append(start, util.Timestamp()...)); err != nil {
@@ -820,6 +992,12 @@ func (self *Pipestance) PostProcess() {
 		util.LogError(err, "runtime",
 			"Error finalizing pipestance state.")
 	}
+	if self.node.top.rt.Config.ROCrate {
+		if err := self.writeROCrate(); err != nil {
+			util.LogError(err, "runtime",
+				"Error writing RO-Crate provenance metadata.")
+		}
+	}
 }
 
 // Generate the final state file for the pipestance and zip the content up
@@ -960,6 +1138,9 @@ type TopNode struct {
 	version     VersionInfo
 	allNodes    map[string]*Node
 	node        Node
+
+	// The last time the journal directory was read.
+	lastJournalScan time.Time
 }
 
 func (self *TopNode) getNode() *Node { return &self.node }
@@ -0,0 +1,620 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+// Compare the metadata recorded by two pipestances.
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/martian-lang/martian/martian/util"
+)
+
+// A PipestanceDifference is a single difference between two pipestances.
+type PipestanceDifference struct {
+	// The partially qualified name of the node, e.g. PIPELINE.STAGE, or
+	// empty for differences in the top-level pipestance metadata.
+	Node string `json:"node,omitempty"`
+
+	// The location within the node, e.g. fork0 or fork0/chnk1.
+	Path string `json:"path,omitempty"`
+
+	// The kind of difference.  One of node, fork, chunks, args, outs,
+	// versions, or file.
+	Kind string `json:"kind"`
+
+	// The argument, output, version component, or file name which differs.
+	Key string `json:"key,omitempty"`
+
+	// The values in each pipestance.  Omitted if the value is not present.
+	A json.RawMessage `json:"a,omitempty"`
+	B json.RawMessage `json:"b,omitempty"`
+}
+
+func (self *PipestanceDifference) String() string {
+	var buf strings.Builder
+	if self.Node != "" {
+		buf.WriteString(self.Node)
+		if self.Path != "" {
+			buf.WriteByte(' ')
+			buf.WriteString(self.Path)
+		}
+		buf.WriteString(": ")
+	}
+	if (self.Kind == "node" || self.Kind == "fork") && self.Key == "" {
+		if len(self.A) == 0 {
+			buf.WriteString("only in b")
+		} else if len(self.B) == 0 {
+			buf.WriteString("only in a")
+		} else {
+			buf.WriteString(self.Kind)
+			buf.WriteString(": ")
+			buf.Write(self.A)
+			buf.WriteString(" != ")
+			buf.Write(self.B)
+		}
+		return buf.String()
+	}
+	buf.WriteString(self.Kind)
+	if self.Key != "" {
+		buf.WriteByte('.')
+		buf.WriteString(self.Key)
+	}
+	buf.WriteString(": ")
+	writeDiffValue(&buf, self.A)
+	buf.WriteString(" != ")
+	writeDiffValue(&buf, self.B)
+	return buf.String()
+}
+
+func writeDiffValue(buf *strings.Builder, v json.RawMessage) {
+	if len(v) == 0 {
+		buf.WriteString("<missing>")
+	} else {
+		buf.Write(v)
+	}
+}
+
+// pipestanceMetadataReader reads metadata files from a pipestance directory,
+// from its metadata zip archive, or both.
+type pipestanceMetadataReader struct {
+	// The pipestance directory, if available.
+	dir string
+
+	// The metadata zip archive, if available.
+	zipPath string
+
+	// The pipestance path recorded in the metadata, which is removed from
+	// paths in _args and _outs so that they can be compared.
+	root string
+
+	// Maps each directory, relative to the pipestance directory, to the
+	// names of its entries.
+	entries map[string][]string
+	seen    map[string]struct{}
+
+	// The content of the _finalstate file, if there is one.
+	finalState []*NodeInfo
+
+	// The argument permutation for each fork, keyed by the partially
+	// qualified name of the node and the fork index.
+	permutes map[string]map[int]map[string]interface{}
+}
+
+// Opens the pipestance at the given path, which may be either a pipestance
+// directory or a metadata zip archive.
+func openPipestanceMetadata(p string) (*pipestanceMetadataReader, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	self := &pipestanceMetadataReader{
+		entries: make(map[string][]string),
+		seen:    make(map[string]struct{}),
+	}
+	if info.IsDir() {
+		if self.dir, err = filepath.Abs(p); err != nil {
+			return nil, err
+		}
+		self.root = self.dir
+		if err := self.walkDir(); err != nil {
+			return nil, err
+		}
+		zipPath := path.Join(self.dir, MetadataZip.FileName())
+		if _, err := os.Stat(zipPath); err == nil {
+			self.zipPath = zipPath
+		}
+	} else {
+		self.zipPath = p
+	}
+	if self.zipPath != "" {
+		if err := self.listZip(); err != nil {
+			return nil, err
+		}
+	}
+	for _, names := range self.entries {
+		sort.Strings(names)
+	}
+	self.loadFinalState()
+	return self, nil
+}
+
+func (self *pipestanceMetadataReader) addEntry(rel string) {
+	for rel != "." && rel != "" {
+		if _, ok := self.seen[rel]; ok {
+			return
+		}
+		self.seen[rel] = struct{}{}
+		dir, name := path.Split(rel)
+		dir = strings.TrimSuffix(dir, "/")
+		self.entries[dir] = append(self.entries[dir], name)
+		rel = dir
+	}
+}
+
+// Record the directory structure of the pipestance.  Files are not recorded,
+// with the exception of symlinks, which are used for chunk directories.
+func (self *pipestanceMetadataReader) walkDir() error {
+	return filepath.WalkDir(self.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == self.dir {
+			return nil
+		}
+		rel, err := filepath.Rel(self.dir, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case "journal", "tmp", "files":
+				return filepath.SkipDir
+			}
+			self.addEntry(filepath.ToSlash(rel))
+		} else if d.Type()&fs.ModeSymlink != 0 {
+			self.addEntry(filepath.ToSlash(rel))
+		}
+		return nil
+	})
+}
+
+func (self *pipestanceMetadataReader) listZip() error {
+	zr, err := zip.OpenReader(self.zipPath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Mode()&fs.ModeSymlink != 0 {
+			self.addEntry(f.Name)
+		} else if dir := path.Dir(f.Name); dir != "." {
+			self.addEntry(dir)
+		}
+	}
+	return nil
+}
+
+// Returns the given path relative to the recorded pipestance path, or an
+// empty string if it is not inside the pipestance.
+func (self *pipestanceMetadataReader) relPath(p string) string {
+	if self.root == "" {
+		return ""
+	}
+	if rel, ok := strings.CutPrefix(p, self.root+"/"); ok {
+		return rel
+	}
+	return ""
+}
+
+// Read a file, relative to the pipestance directory.  Returns nil if the
+// file does not exist.
+func (self *pipestanceMetadataReader) read(rel string) []byte {
+	if self.dir != "" {
+		if b, err := os.ReadFile(path.Join(self.dir, rel)); err == nil {
+			return b
+		}
+	}
+	if self.zipPath != "" {
+		if r, err := util.ReadZipFile(self.zipPath, rel); err == nil {
+			defer r.Close()
+			if b, err := io.ReadAll(r); err == nil {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
+// Read the argument permutations for forks from the _finalstate file, if
+// there is one, and use it to determine the pipestance path at the time the
+// metadata was recorded.
+func (self *pipestanceMetadataReader) loadFinalState() {
+	b := self.read(FinalState.FileName())
+	if b == nil {
+		return
+	}
+	var nodes []*NodeInfo
+	if err := json.Unmarshal(b, &nodes); err != nil {
+		util.PrintError(err, "diff", "Could not parse final state.")
+		return
+	}
+	self.finalState = nodes
+	self.permutes = make(map[string]map[int]map[string]interface{}, len(nodes))
+	for _, node := range nodes {
+		name := partiallyQualifiedName(node.Fqname)
+		if root := strings.TrimSuffix(node.Path,
+			"/"+strings.ReplaceAll(name, ".", "/")); root != node.Path {
+			self.root = root
+		}
+		forks := make(map[int]map[string]interface{}, len(node.Forks))
+		for _, fork := range node.Forks {
+			if fork.ArgPermute != nil {
+				forks[fork.Index] = fork.ArgPermute
+			}
+		}
+		self.permutes[name] = forks
+	}
+}
+
+func isIndexedDir(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+		return false
+	}
+	_, err := strconv.Atoi(name[len(prefix):])
+	return err == nil
+}
+
+// Returns the nodes in the pipestance, keyed by partially qualified name,
+// with their paths relative to the pipestance directory.
+func (self *pipestanceMetadataReader) nodes() map[string]string {
+	nodes := make(map[string]string)
+	var visit func(string)
+	visit = func(dir string) {
+		isNode := false
+		for _, name := range self.entries[dir] {
+			if isIndexedDir(name, "fork") {
+				isNode = true
+				break
+			}
+		}
+		if isNode {
+			nodes[strings.ReplaceAll(dir, "/", ".")] = dir
+		} else if dir != "" {
+			return
+		}
+		for _, name := range self.entries[dir] {
+			if strings.HasPrefix(name, "_") || isIndexedDir(name, "fork") {
+				continue
+			}
+			child := path.Join(dir, name)
+			if _, ok := self.entries[child]; ok {
+				visit(child)
+			}
+		}
+	}
+	visit("")
+	return nodes
+}
+
+// Returns the names of the entries in the given directory which match
+// prefix followed by an integer, keyed by the integer.
+func (self *pipestanceMetadataReader) indexed(dir, prefix string) map[int]string {
+	result := make(map[int]string)
+	for _, name := range self.entries[dir] {
+		if isIndexedDir(name, prefix) {
+			i, _ := strconv.Atoi(name[len(prefix):])
+			result[i] = name
+		}
+	}
+	return result
+}
+
+// Read a json metadata file, with the recorded pipestance path removed from
+// any paths so that files within the pipestance can be compared.
+func (self *pipestanceMetadataReader) readJson(rel string) (interface{}, bool) {
+	b := self.read(rel)
+	if b == nil {
+		return nil, false
+	}
+	if self.root != "" {
+		b = bytes.ReplaceAll(b, []byte(self.root+"/"), nil)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return string(b), true
+	}
+	return v, true
+}
+
+// Returns the sha256 checksums of the files in the given directory, relative
+// to the pipestance directory, keyed by path relative to that directory.
+func (self *pipestanceMetadataReader) checksums(rel string) (map[string]string, error) {
+	if self.dir == "" {
+		return nil, errors.New("file checksums require a pipestance directory")
+	}
+	root := path.Join(self.dir, rel)
+	sums := make(map[string]string)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if info, err := f.Stat(); err != nil {
+			return err
+		} else if !info.Mode().IsRegular() {
+			return nil
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		name, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		sums[filepath.ToSlash(name)] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	return sums, err
+}
+
+func diffJson(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+type pipestanceDiffer struct {
+	a, b      *pipestanceMetadataReader
+	checksums bool
+	result    []*PipestanceDifference
+}
+
+func (self *pipestanceDiffer) add(node, loc, kind, key string, a, b interface{}, inA, inB bool) {
+	d := &PipestanceDifference{
+		Node: node,
+		Path: loc,
+		Kind: kind,
+		Key:  key,
+	}
+	if inA {
+		d.A = diffJson(a)
+	}
+	if inB {
+		d.B = diffJson(b)
+	}
+	self.result = append(self.result, d)
+}
+
+// Compare the keys of two json objects.  If either is not an object, the
+// values are compared as a whole.
+func (self *pipestanceDiffer) compareObjects(node, loc, kind string,
+	a, b interface{}, inA, inB bool) {
+	if !inA && !inB {
+		return
+	}
+	objA, okA := a.(map[string]interface{})
+	objB, okB := b.(map[string]interface{})
+	if !okA || !okB {
+		if inA != inB || string(diffJson(a)) != string(diffJson(b)) {
+			self.add(node, loc, kind, "", a, b, inA, inB)
+		}
+		return
+	}
+	keys := make([]string, 0, len(objA)+len(objB))
+	for k := range objA {
+		keys = append(keys, k)
+	}
+	for k := range objB {
+		if _, ok := objA[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		va, inA := objA[k]
+		vb, inB := objB[k]
+		if inA != inB || string(diffJson(va)) != string(diffJson(vb)) {
+			self.add(node, loc, kind, k, va, vb, inA, inB)
+		}
+	}
+}
+
+// Compare the _args, _outs, and optionally output files for a metadata
+// directory.
+func (self *pipestanceDiffer) compareLocation(node, loc, relA, relB string) error {
+	for _, f := range [...]struct {
+		kind string
+		name MetadataFileName
+	}{{"args", ArgsFile}, {"outs", OutsFile}} {
+		a, inA := self.a.readJson(path.Join(relA, f.name.FileName()))
+		b, inB := self.b.readJson(path.Join(relB, f.name.FileName()))
+		self.compareObjects(node, loc, f.kind, a, b, inA, inB)
+	}
+	if !self.checksums {
+		return nil
+	}
+	sumsA, err := self.a.checksums(path.Join(relA, "files"))
+	if err != nil {
+		return err
+	}
+	sumsB, err := self.b.checksums(path.Join(relB, "files"))
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(sumsA)+len(sumsB))
+	for name := range sumsA {
+		names = append(names, name)
+	}
+	for name := range sumsB {
+		if _, ok := sumsA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a, inA := sumsA[name]
+		b, inB := sumsB[name]
+		if a != b {
+			self.add(node, loc, "file", "files/"+name, a, b, inA, inB)
+		}
+	}
+	return nil
+}
+
+func (self *pipestanceDiffer) compareFork(node, fork, relA, relB string) error {
+	if err := self.compareLocation(node, fork, relA, relB); err != nil {
+		return err
+	}
+	for _, sub := range [...]string{"split", "join"} {
+		if err := self.compareLocation(node, fork+"/"+sub,
+			path.Join(relA, sub), path.Join(relB, sub)); err != nil {
+			return err
+		}
+	}
+	chunksA := self.a.indexed(relA, "chnk")
+	chunksB := self.b.indexed(relB, "chnk")
+	if len(chunksA) != len(chunksB) {
+		self.add(node, fork, "chunks", "",
+			len(chunksA), len(chunksB), true, true)
+	}
+	for _, i := range sortedIndexes(chunksA) {
+		if chunk, ok := chunksB[i]; ok {
+			if err := self.compareLocation(node, fork+"/"+chunk,
+				path.Join(relA, chunk), path.Join(relB, chunk)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (self *pipestanceDiffer) compareNode(node, relA, relB string) error {
+	forksA := self.a.indexed(relA, "fork")
+	forksB := self.b.indexed(relB, "fork")
+	permA, permB := self.a.permutes[node], self.b.permutes[node]
+	indexes := sortedIndexes(forksA)
+	for i := range forksB {
+		if _, ok := forksA[i]; !ok {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		fork := "fork" + strconv.Itoa(i)
+		_, inA := forksA[i]
+		_, inB := forksB[i]
+		pa, pb := permA[i], permB[i]
+		if !inA || !inB {
+			// Report the argument permutation for the fork, if known.
+			var a, b interface{} = true, true
+			if pa != nil {
+				a = pa
+			}
+			if pb != nil {
+				b = pb
+			}
+			self.add(node, fork, "fork", "", a, b, inA, inB)
+			continue
+		}
+		if pa != nil && pb != nil {
+			self.compareObjects(node, fork, "fork", pa, pb, true, true)
+		}
+		if err := self.compareFork(node, fork,
+			path.Join(relA, fork), path.Join(relB, fork)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedIndexes(m map[int]string) []int {
+	result := make([]int, 0, len(m))
+	for i := range m {
+		result = append(result, i)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// DiffPipestances compares two pipestances, each given as either a
+// pipestance directory or a metadata zip archive.  For a pipestance directory
+// with a metadata zip archive, metadata files which are not present in the
+// directory are read from the archive.
+//
+// Nodes are matched by their partially qualified name.  For each node, the
+// set of forks and the number of chunks in each fork are compared, as well as
+// the _args and _outs for each fork, split, join, and chunk.  Paths within
+// the pipestance are made relative before comparison.  The _versions of the
+// pipestances are also compared.
+//
+// If checksums is true, the files in the output directories are also
+// compared.  This requires that both pipestances are directories.
+func DiffPipestances(a, b string, checksums bool) ([]*PipestanceDifference, error) {
+	readerA, err := openPipestanceMetadata(a)
+	if err != nil {
+		return nil, err
+	}
+	readerB, err := openPipestanceMetadata(b)
+	if err != nil {
+		return nil, err
+	}
+	differ := pipestanceDiffer{
+		a:         readerA,
+		b:         readerB,
+		checksums: checksums,
+	}
+	va, inA := readerA.readJson(VersionsFile.FileName())
+	vb, inB := readerB.readJson(VersionsFile.FileName())
+	differ.compareObjects("", "", "versions", va, vb, inA, inB)
+
+	nodesA, nodesB := readerA.nodes(), readerB.nodes()
+	names := make([]string, 0, len(nodesA)+len(nodesB))
+	for name := range nodesA {
+		names = append(names, name)
+	}
+	for name := range nodesB {
+		if _, ok := nodesA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		relA, inA := nodesA[name]
+		relB, inB := nodesB[name]
+		if !inA || !inB {
+			differ.add(name, "", "node", "", true, true, inA, inB)
+		} else if err := differ.compareNode(name, relA, relB); err != nil {
+			return differ.result, err
+		}
+	}
+	return differ.result, nil
+}
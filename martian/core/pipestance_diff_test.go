@@ -0,0 +1,128 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/martian-lang/martian/martian/util"
+)
+
+// Write a set of files relative to dir.  Values starting with "->" are
+// written as symlinks.
+func writeTestPipestance(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := path.Join(dir, name)
+		if err := os.MkdirAll(path.Dir(p), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if target, ok := strings.CutPrefix(content, "->"); ok {
+			if err := os.Symlink(target, p); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			content = strings.ReplaceAll(content, "$PS", dir)
+			if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func testPipestanceFiles(n string, chunks int, version string) map[string]string {
+	files := map[string]string{
+		"_versions":                     `{"pipelines":"` + version + `"}`,
+		"_finalstate":                   `[{"fqname":"ID.ps.P","path":"$PS/P"}]`,
+		"P/fork0/_outs":                 `{"x":"$PS/P/fork0/files/x.txt"}`,
+		"P/fork0/files/x.txt":           "a",
+		"P/A/fork0/_outs":               `{"y":4}`,
+		"P/A/fork0/split/_args":         `{"n":` + n + `}`,
+		"P/A/fork0/join/_outs":          `{"y":4}`,
+		"P/A/fork0/join/files/join.txt": n,
+	}
+	for i := 0; i < chunks; i++ {
+		c := "chnk" + strconv.Itoa(i)
+		files["P/A/fork0/"+c] = "->" + c + "-u123"
+		files["P/A/fork0/"+c+"-u123/_args"] = `{"i":` + strconv.Itoa(i) + `}`
+	}
+	return files
+}
+
+func TestDiffPipestances(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	filesA := testPipestanceFiles("3", 2, "1.0")
+	filesA["P/A/fork1/_outs"] = `{"y":5}`
+	writeTestPipestance(t, dirA, filesA)
+	filesB := testPipestanceFiles("4", 3, "1.1")
+	filesB["P/B/fork0/_outs"] = `{}`
+	writeTestPipestance(t, dirB, filesB)
+
+	check := func(t *testing.T, a, b string, checksums bool, expect []string) {
+		t.Helper()
+		diffs, err := DiffPipestances(a, b, checksums)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := make([]string, len(diffs))
+		for i, d := range diffs {
+			result[i] = d.String()
+		}
+		if s, e := strings.Join(result, "\n"), strings.Join(expect, "\n"); s != e {
+			t.Errorf("expected\n%s\ngot\n%s", e, s)
+		}
+	}
+	expect := []string{
+		`versions.pipelines: "1.0" != "1.1"`,
+		`P.A fork0/split: args.n: 3 != 4`,
+		`P.A fork0: chunks: 2 != 3`,
+		`P.A fork1: only in a`,
+		`P.B: only in b`,
+	}
+	t.Run("dir", func(t *testing.T) {
+		check(t, dirA, dirB, false, expect)
+	})
+	t.Run("checksums", func(t *testing.T) {
+		check(t, dirA, dirB, true, []string{
+			expect[0],
+			expect[1],
+			`P.A fork0/join: file.files/join.txt: ` +
+				`"4e07408562bedb8b60ce05c1decfe3ad16b72230967de01f640b7e4729b49fce" != ` +
+				`"4b227777d4dd1fc61c6f884f48641d02b4d121d3fd328cb08b5531fcacdabf8a"`,
+			expect[2],
+			expect[3],
+			expect[4],
+		})
+	})
+	t.Run("zip", func(t *testing.T) {
+		var files []string
+		if err := filepath.WalkDir(dirA, func(p string, d fs.DirEntry, err error) error {
+			if err == nil && strings.HasPrefix(d.Name(), "_") {
+				files = append(files, p)
+			} else if err == nil && d.Type()&fs.ModeSymlink != 0 {
+				files = append(files, p)
+			}
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		zipPath := path.Join(dirA, MetadataZip.FileName())
+		if err := util.CreateZip(zipPath, files); err != nil {
+			t.Fatal(err)
+		}
+		movedZip := path.Join(t.TempDir(), "metadata.zip")
+		if err := os.Rename(zipPath, movedZip); err != nil {
+			t.Fatal(err)
+		}
+		check(t, movedZip, dirB, false, expect)
+		if _, err := DiffPipestances(movedZip, dirB, true); err == nil {
+			t.Error("expected checksums of a zip archive to fail")
+		}
+	})
+}
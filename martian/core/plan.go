@@ -0,0 +1,242 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+//
+// Static execution planning for pipestances.
+//
+
+import (
+	"context"
+	"os"
+
+	"github.com/martian-lang/martian/martian/syntax"
+)
+
+// PlannedState describes whether a fork of a stage would run.
+type PlannedState string
+
+const (
+	// The fork will run.
+	PlanRun PlannedState = "run"
+	// The fork is statically disabled, and will not run.
+	PlanDisabled PlannedState = "disabled"
+	// Whether the fork runs depends on the outputs of other stages.
+	PlanConditional PlannedState = "conditional"
+)
+
+// ExecutionPlan describes the work which would be done by a pipestance,
+// as far as it can be determined without running any stages.
+type ExecutionPlan struct {
+	Psid     string         `json:"psid"`
+	Pipeline string         `json:"pipeline"`
+	JobMode  string         `json:"jobmode"`
+	VdrMode  VdrMode        `json:"vdrmode"`
+	Stages   []*PlannedFork `json:"stages"`
+}
+
+// PlannedFork describes a single fork of a stage in an ExecutionPlan.
+type PlannedFork struct {
+	Fqname string       `json:"fqname"`
+	Fork   string       `json:"fork"`
+	State  PlannedState `json:"state"`
+
+	// For conditional forks, the expressions which may disable the fork.
+	DisabledBy []string `json:"disabled_by,omitempty"`
+
+	// True if the number of forks could not be determined statically,
+	// because it depends on the outputs of another stage.
+	Undetermined bool `json:"undetermined,omitempty"`
+
+	Split     bool `json:"split"`
+	Local     bool `json:"local,omitempty"`
+	Preflight bool `json:"preflight,omitempty"`
+
+	// The resources which would be requested for each phase of the stage,
+	// after applying overrides and job manager limits.  For stages which
+	// split, chunk and join resources may be further modified by the
+	// split phase.
+	Resources map[string]JobResources `json:"resources"`
+
+	// Resource expressions from the stage's using block which depend on the
+	// outputs of other stages, and so can only be computed at runtime, keyed
+	// by resource name.
+	ComputedResources map[string]string `json:"computed_resources,omitempty"`
+
+	Volatile bool `json:"volatile,omitempty"`
+
+	// The file-bearing outputs which would be removed by volatile data
+	// removal once all downstream stages have completed.
+	VolatileOutputs []string `json:"volatile_outputs,omitempty"`
+}
+
+// Summary returns the number of forks which would run, which are disabled,
+// and which may or may not run.
+func (plan *ExecutionPlan) Summary() (run, disabled, conditional int) {
+	for _, f := range plan.Stages {
+		switch f.State {
+		case PlanRun:
+			run++
+		case PlanDisabled:
+			disabled++
+		case PlanConditional:
+			conditional++
+		}
+	}
+	return run, disabled, conditional
+}
+
+// PlanPipeline instantiates a pipestance from the given invocation source
+// and reports the stages which would be run, without creating any files
+// or directories.
+func (self *Runtime) PlanPipeline(src string, srcPath string, psid string,
+	pipestancePath string, mroPaths []string, mroVersion string,
+	envs map[string]string) (*ExecutionPlan, error) {
+	src = os.ExpandEnv(src)
+	_, _, pipestance, err := self.instantiatePipeline([]byte(src), srcPath,
+		psid, pipestancePath, mroPaths,
+		mroVersion, envs, false, true, context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return pipestance.Plan(), nil
+}
+
+// Plan computes the execution plan for the pipestance in its current state.
+func (self *Pipestance) Plan() *ExecutionPlan {
+	plan := ExecutionPlan{
+		Psid:     self.GetPsid(),
+		Pipeline: self.GetPname(),
+		JobMode:  self.node.top.rt.Config.JobMode,
+		VdrMode:  self.node.top.rt.Config.VdrMode,
+	}
+	for _, node := range self.allNodes() {
+		if node.call.Kind() != syntax.KindStage {
+			continue
+		}
+		for _, fork := range node.forks {
+			plan.Stages = append(plan.Stages, fork.plan())
+		}
+	}
+	return &plan
+}
+
+func (self *Fork) plan() *PlannedFork {
+	node := self.node
+	p := PlannedFork{
+		Fqname:    node.GetFQName(),
+		Fork:      self.id,
+		State:     PlanRun,
+		Split:     self.Split(),
+		Local:     node.local,
+		Preflight: node.call.Call().Modifiers.Preflight,
+	}
+	for _, part := range self.forkId {
+		if part.Id.IndexSource() != nil {
+			p.Undetermined = true
+			break
+		}
+	}
+	if disabled, err := self.disabled(); disabled {
+		p.State = PlanDisabled
+		return &p
+	} else if err != nil || len(node.call.Disabled()) > 0 {
+		p.State = PlanConditional
+		for _, exp := range node.call.Disabled() {
+			p.DisabledBy = append(p.DisabledBy, exp.GoString())
+		}
+	}
+	var computed *JobResources
+	computed, p.ComputedResources = self.planResources()
+	if p.Split {
+		p.Resources = map[string]JobResources{
+			STAGE_TYPE_SPLIT: node.getJobReqs(computed, nil, STAGE_TYPE_SPLIT),
+			STAGE_TYPE_CHUNK: node.getJobReqs(computed, nil, STAGE_TYPE_CHUNK),
+			STAGE_TYPE_JOIN:  node.getJobReqs(computed, nil, STAGE_TYPE_JOIN),
+		}
+	} else {
+		p.Resources = map[string]JobResources{
+			STAGE_TYPE_CHUNK: node.getJobReqs(computed, nil, STAGE_TYPE_CHUNK),
+		}
+	}
+	if self.isVolatile() {
+		p.Volatile = true
+		for _, param := range self.OutParams().List {
+			if param.IsFile() == syntax.KindIsNotFile {
+				continue
+			}
+			if !retainedArg(self.fileArgs[param.Id]) {
+				p.VolatileOutputs = append(p.VolatileOutputs, param.Id)
+			}
+		}
+	}
+	return &p
+}
+
+// planResources evaluates the stage's resource expressions, if it has any,
+// if the inputs they refer to can be resolved.  Otherwise, it returns the
+// expressions which will be evaluated at runtime.
+func (self *Fork) planResources() (*JobResources, map[string]string) {
+	resources := self.resourceExps()
+	if resources == nil {
+		return nil, nil
+	}
+	exps := [...]struct {
+		name string
+		exp  syntax.Exp
+	}{
+		{"threads", resources.ThreadsExp},
+		{"mem_gb", resources.MemGBExp},
+		{"vmem_gb", resources.VMemGBExp},
+	}
+	ids := make(map[string]struct{})
+	for _, r := range exps {
+		resourceRefs(r.exp, ids)
+	}
+	node := self.node
+	readSize := node.top.rt.FreeMemBytes() / int64(len(node.prenodes)+1)
+	bindings := make(MarshalerMap, len(ids))
+	for id := range ids {
+		rb := node.call.ResolvedInputs()[id]
+		if rb == nil {
+			continue
+		}
+		ready, v, err := node.top.resolve(rb.Exp, rb.Type, self.forkId, readSize)
+		if err != nil || !ready {
+			computed := make(map[string]string, len(exps))
+			for _, r := range exps {
+				if r.exp != nil {
+					computed[r.name] = r.exp.GoString()
+				}
+			}
+			return nil, computed
+		}
+		bindings[id] = v
+	}
+	return self.evalResources(bindings), nil
+}
+
+// resourceRefs adds the ids of the input parameters referred to by a
+// resource expression to the set.
+func resourceRefs(exp syntax.Exp, ids map[string]struct{}) {
+	switch exp := exp.(type) {
+	case *syntax.RefExp:
+		ids[exp.Id] = struct{}{}
+	case *syntax.FuncExp:
+		for _, arg := range exp.Args {
+			resourceRefs(arg, ids)
+		}
+	}
+}
+
+// retainedArg returns true if the set of nodes keeping an argument alive
+// includes the top level, which never completes.
+func retainedArg(nodes map[Nodable]struct{}) bool {
+	for n := range nodes {
+		// Top-level return bindings are recorded as a nil *Node.
+		if n == nil || n.getNode() == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const planTestSrc = `
+stage CHOOSE(
+    out bool skip,
+    out int  count,
+    src py   "stages/choose",
+)
+
+stage PRODUCE(
+    in  int  value,
+    out file result,
+    out file scratch,
+    src py   "stages/produce",
+) split (
+) using (
+    mem_gb   = self.value + 2,
+    volatile = strict,
+)
+
+stage SCALE(
+    in  int count,
+    src py  "stages/scale",
+) using (
+    mem_gb  = self.count + 1,
+    threads = 2,
+)
+
+stage CONSUME(
+    in  file[] results,
+    src py     "stages/consume",
+) using (
+    threads = 2,
+)
+
+pipeline PLAN(
+    in  int[]  values,
+    in  bool   off,
+    out file[] kept,
+)
+{
+    call CHOOSE()
+
+    map call PRODUCE(
+        value = split self.values,
+    )
+
+    call SCALE(
+        count = CHOOSE.count,
+    )
+
+    call CONSUME as ALWAYS_OFF(
+        results = PRODUCE.result,
+    ) using (
+        disabled = self.off,
+    )
+
+    call CONSUME(
+        results = PRODUCE.result,
+    ) using (
+        disabled = CHOOSE.skip,
+    )
+
+    return (
+        kept = PRODUCE.result,
+    )
+}
+
+call PLAN(
+    values = [1, 2],
+    off    = true,
+)
+`
+
+func TestPlanPipeline(t *testing.T) {
+	conf := DefaultRuntimeOptions()
+	rt := Runtime{
+		Config: &conf,
+		LocalJobManager: &LocalJobManager{
+			jobSettings: &JobManagerSettings{
+				ThreadsPerJob: 1,
+				MemGBPerJob:   1,
+			},
+			maxCores: 8,
+			maxMemGB: 16,
+		},
+	}
+	rt.JobManager = rt.LocalJobManager
+	psPath := filepath.Join(t.TempDir(), "plan")
+	plan, err := rt.PlanPipeline(planTestSrc, "plan.mro", "plan",
+		psPath, nil, "none", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(psPath); !os.IsNotExist(err) {
+		t.Errorf("expected no pipestance directory, got %v", err)
+	}
+	byName := make(map[string][]*PlannedFork, len(plan.Stages))
+	for _, f := range plan.Stages {
+		name := f.Fqname[strings.LastIndexByte(f.Fqname, '.')+1:]
+		byName[name] = append(byName[name], f)
+	}
+	if forks := byName["PRODUCE"]; len(forks) != 2 {
+		t.Errorf("expected 2 forks of PRODUCE, got %d", len(forks))
+	} else {
+		for i, f := range forks {
+			if f.State != PlanRun {
+				t.Errorf("expected %s %s to run, got %s",
+					f.Fqname, f.Fork, f.State)
+			}
+			// The resources are computed from the value for each fork.
+			if !f.Split || f.Resources[STAGE_TYPE_SPLIT].MemGB != float64(i+3) ||
+				f.Resources[STAGE_TYPE_JOIN].MemGB != float64(i+3) {
+				t.Errorf("incorrect resources %v", f.Resources)
+			}
+			if !f.Volatile {
+				t.Error("expected volatile")
+			}
+			if len(f.VolatileOutputs) != 1 || f.VolatileOutputs[0] != "scratch" {
+				t.Errorf("expected only scratch to be volatile, got %v",
+					f.VolatileOutputs)
+			}
+		}
+	}
+	if f := byName["SCALE"]; len(f) != 1 {
+		t.Errorf("expected 1 fork of SCALE, got %d", len(f))
+	} else {
+		// The memory depends on the output of another stage.
+		if f[0].Resources[STAGE_TYPE_CHUNK].Threads != 2 {
+			t.Errorf("incorrect resources %v", f[0].Resources)
+		}
+		if exp := f[0].ComputedResources["mem_gb"]; exp != "self.count + 1" {
+			t.Errorf("expected computed mem_gb, got %q", exp)
+		}
+	}
+	if f := byName["ALWAYS_OFF"]; len(f) != 1 || f[0].State != PlanDisabled {
+		t.Errorf("expected ALWAYS_OFF to be disabled, got %v", f)
+	}
+	if f := byName["CONSUME"]; len(f) != 1 {
+		t.Errorf("expected 1 fork of CONSUME, got %d", len(f))
+	} else {
+		if f[0].State != PlanConditional {
+			t.Errorf("expected CONSUME to be conditional, got %s", f[0].State)
+		}
+		if f[0].Resources[STAGE_TYPE_CHUNK].Threads != 2 {
+			t.Errorf("incorrect resources %v", f[0].Resources)
+		}
+	}
+	if run, disabled, conditional := plan.Summary(); run != 4 ||
+		disabled != 1 || conditional != 1 {
+		t.Errorf("incorrect summary %d %d %d", run, disabled, conditional)
+	}
+}
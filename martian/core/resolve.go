@@ -33,15 +33,15 @@ type BindingInfo struct {
 func (node *Node) inputBindingInfo(fork ForkId) []BindingInfo {
 	readSize := node.top.rt.FreeMemBytes() / int64(len(node.prenodes)+1)
 	result := make([]BindingInfo, 0, len(node.call.ResolvedInputs()))
-	for _, input := range node.call.Call().Bindings.List {
-		if input.Id == "*" {
+	for _, input := range node.call.Callable().GetInParams().List {
+		rb := node.call.ResolvedInputs()[input.Id]
+		if rb == nil {
 			continue
 		}
 		result = append(result, BindingInfo{
 			Id: input.Id,
 		})
 		r := &result[len(result)-1]
-		rb := node.call.ResolvedInputs()[input.Id]
 		r.Type = input.Tname
 		if refs, err := rb.FindRefs(node.top.types); err != nil {
 			panic(err)
@@ -237,6 +237,10 @@ func (node *TopNode) resolve(binding syntax.Exp, t syntax.Type,
 		return node.resolveMerge(binding, t, fork, readSize)
 	case *syntax.DisabledExp:
 		return node.resolveDisabledExp(binding, t, fork, readSize)
+	case *syntax.CondExp:
+		return node.resolveCondExp(binding, t, fork, readSize)
+	case *syntax.FuncExp:
+		return node.resolveFuncExp(binding, fork, readSize)
 	default:
 		tid := t.TypeId()
 		panic(fmt.Sprintf("unexpected ref or sweep type %T, wanted %s",
@@ -289,6 +293,110 @@ func (node *TopNode) resolveDisabledExp(binding *syntax.DisabledExp, t syntax.Ty
 	return ready, result, err
 }
 
+// resolveCondExp resolves the condition and then only the selected branch,
+// so that an unresolvable value in the other branch does not block or fail
+// the binding.
+func (node *TopNode) resolveCondExp(binding *syntax.CondExp, t syntax.Type,
+	fork ForkId, readSize int64) (bool, json.Marshaler, error) {
+	ready, cond, err := node.resolve(binding.Condition,
+		node.Types().Get(syntax.TypeId{Tname: syntax.KindBool}), fork, readSize)
+	if err != nil {
+		return ready, nil, &elementError{
+			element: "condition",
+			inner:   err,
+		}
+	} else if !ready {
+		return ready, nil, nil
+	}
+	branch, err := selectCondBranch(binding, cond)
+	if err != nil {
+		return true, nil, err
+	}
+	ready, result, err := node.resolve(branch, t, fork, readSize)
+	if err != nil {
+		element := "false branch"
+		if branch == binding.Then {
+			element = "true branch"
+		}
+		return ready, result, &elementError{
+			element: element,
+			inner:   err,
+		}
+	}
+	return ready, result, err
+}
+
+// selectCondBranch returns the branch of the conditional expression selected
+// by the given resolved condition value.  A null condition selects the else
+// branch.
+func selectCondBranch(binding *syntax.CondExp, cond json.Marshaler) (syntax.Exp, error) {
+	switch cond := cond.(type) {
+	case nil, *syntax.NullExp:
+		return binding.Else, nil
+	case *syntax.BoolExp:
+		if cond.Value {
+			return binding.Then, nil
+		}
+		return binding.Else, nil
+	case json.RawMessage:
+		var b bool
+		if err := json.Unmarshal(cond, &b); err != nil {
+			return nil, &elementError{
+				element: "condition",
+				inner:   err,
+			}
+		}
+		if b {
+			return binding.Then, nil
+		}
+		return binding.Else, nil
+	default:
+		return nil, &elementError{
+			element: fmt.Sprintf(
+				"invalid type %T for condition %s",
+				cond, binding.Condition.GoString()),
+		}
+	}
+}
+
+// resolveFuncExp resolves the arguments to a function expression and then
+// evaluates it.
+func (node *TopNode) resolveFuncExp(binding *syntax.FuncExp,
+	fork ForkId, readSize int64) (bool, json.Marshaler, error) {
+	types := node.Types()
+	args := make([]json.RawMessage, len(binding.Args))
+	for i, arg := range binding.Args {
+		ready, v, err := node.resolve(arg, binding.ArgType(i, types),
+			fork, readSize)
+		if err != nil {
+			return ready, nil, &elementError{
+				element: "argument " + strconv.Itoa(i+1) + " of " + binding.Func,
+				inner:   err,
+			}
+		} else if !ready {
+			return ready, nil, nil
+		}
+		if v == nil {
+			args[i] = nullBytes
+		} else if b, err := v.MarshalJSON(); err != nil {
+			return true, nil, &elementError{
+				element: "argument " + strconv.Itoa(i+1) + " of " + binding.Func,
+				inner:   err,
+			}
+		} else {
+			args[i] = b
+		}
+	}
+	result, err := binding.Evaluate(args)
+	if err != nil {
+		return true, nil, &elementError{
+			element: binding.Func,
+			inner:   err,
+		}
+	}
+	return true, result, nil
+}
+
 func (node *TopNode) resolveMerge(binding *syntax.MergeExp, t syntax.Type,
 	fork ForkId, readSize int64) (bool, json.Marshaler, error) {
 	var innerT syntax.Type
@@ -788,3 +788,77 @@ func TestResolveInputs(t *testing.T) {
 }`
 	checkJsonOutput(t, result, psPath, expected)
 }
+
+func TestResolveFuncExp(t *testing.T) {
+	pipestance, psPath := setupTestPipestance(t,
+		"testdata/func_exp_pipeline.mro", "resolve_func_exp")
+	defer func() {
+		if !t.Failed() {
+			os.RemoveAll(psPath)
+		}
+	}()
+	if pipestance == nil {
+		return
+	}
+	fqname := pipestance.node.GetFQName()
+	producer := pipestance.node.top.allNodes[fqname+".PRODUCE"]
+	if producer == nil {
+		t.Fatal("could not get node " + fqname + ".PRODUCE")
+	}
+	if err := producer.mkdirs(); err != nil {
+		t.Fatal(err)
+	}
+	md := producer.forks[0].metadata
+	if err := md.Write(OutsFile, map[string]interface{}{
+		"outdir":  md.FilePath("outdir"),
+		"name":    "sample",
+		"missing": nil,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	node := pipestance.node.top.allNodes[fqname+".CONSUMER"]
+	if node == nil {
+		t.Fatal("could not get node " + fqname + ".CONSUMER")
+	}
+	_, result, err := node.resolveInputs(nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Null arguments make the result null.
+	const expected = `{
+	"label": "sample_R1",
+	"missing": null,
+	"missing_dir": null,
+	"reads": "FUNCS/PRODUCE/fork0/files/outdir/reads/sample.bam"
+}`
+	checkJsonOutput(t, result, psPath, expected)
+}
+
+func TestSelectCondBranch(t *testing.T) {
+	exp := &syntax.CondExp{
+		Condition: new(syntax.RefExp),
+		Then:      &syntax.IntExp{Value: 1},
+		Else:      &syntax.IntExp{Value: 2},
+	}
+	for _, c := range []struct {
+		cond   json.Marshaler
+		expect syntax.Exp
+	}{
+		{nil, exp.Else},
+		{new(syntax.NullExp), exp.Else},
+		{&syntax.BoolExp{Value: true}, exp.Then},
+		{&syntax.BoolExp{Value: false}, exp.Else},
+		{json.RawMessage("true"), exp.Then},
+		{json.RawMessage("false"), exp.Else},
+		{json.RawMessage("null"), exp.Else},
+	} {
+		if b, err := selectCondBranch(exp, c.cond); err != nil {
+			t.Errorf("%v: %v", c.cond, err)
+		} else if b != c.expect {
+			t.Errorf("%v: expected %v, got %v", c.cond, c.expect, b)
+		}
+	}
+	if _, err := selectCondBranch(exp, json.RawMessage(`"yes"`)); err == nil {
+		t.Error("expected error for non-boolean condition")
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+// Evaluation of stage resource expressions which depend on stage inputs.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/martian-lang/martian/martian/syntax"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+// computeResources evaluates the resource expressions for the stage, if it
+// has any, given the resolved input bindings.  The result is cached, so
+// the expressions are only evaluated once per fork.
+//
+// If an expression cannot be evaluated, or evaluates to null, the static
+// value from the stage declaration is used.
+func (self *Fork) computeResources(bindings MarshalerMap) *JobResources {
+	if self.resources != nil || bindings == nil {
+		return self.resources
+	}
+	self.resources = self.evalResources(bindings)
+	return self.resources
+}
+
+// resourceExps returns the stage's resource expressions, or nil if the
+// stage has none.
+func (self *Fork) resourceExps() *syntax.Resources {
+	stage, ok := self.node.call.Callable().(*syntax.Stage)
+	if !ok || stage.Resources == nil ||
+		stage.Resources.ThreadsExp == nil &&
+			stage.Resources.MemGBExp == nil &&
+			stage.Resources.VMemGBExp == nil {
+		return nil
+	}
+	return stage.Resources
+}
+
+// evalResources evaluates the resource expressions for the stage without
+// caching the result.
+func (self *Fork) evalResources(bindings MarshalerMap) *JobResources {
+	resources := self.resourceExps()
+	if resources == nil {
+		return nil
+	}
+	args, err := bindings.ToLazyArgumentMap()
+	if err != nil {
+		util.LogError(err, "runtime",
+			"%s: Could not evaluate resources.", self.fqname)
+		return nil
+	}
+	eval := resourceEvaluator{
+		args:   args,
+		params: self.node.call.Callable().GetInParams(),
+		types:  self.node.top.types,
+	}
+	var res JobResources
+	for _, r := range [...]struct {
+		name        string
+		exp         syntax.Exp
+		dest        *float64
+		granularity float64
+	}{
+		{"threads", resources.ThreadsExp, &res.Threads, 100},
+		{"mem_gb", resources.MemGBExp, &res.MemGB, 1024},
+		{"vmem_gb", resources.VMemGBExp, &res.VMemGB, 1024},
+	} {
+		if r.exp == nil {
+			continue
+		}
+		if v, err := eval.number(r.exp); err != nil {
+			util.LogError(err, "runtime",
+				"%s: Could not evaluate %s = %s.",
+				self.fqname, r.name, r.exp.GoString())
+		} else if v != nil {
+			*r.dest = roundResource(*v, r.granularity)
+		}
+	}
+	return &res
+}
+
+// roundResource rounds a value away from zero to the nearest
+// 1/granularity, in the same way as static values are rounded by the
+// parser.
+func roundResource(value, granularity float64) float64 {
+	if value > 0 {
+		return math.Ceil(value*granularity) / granularity
+	} else if value < 0 {
+		return math.Floor(value*granularity) / granularity
+	}
+	return 0
+}
+
+// resourceEvaluator evaluates resource expressions against a stage's
+// input arguments.
+type resourceEvaluator struct {
+	args   LazyArgumentMap
+	params *syntax.InParams
+	types  *syntax.TypeLookup
+}
+
+// number evaluates the expression, returning nil if the result is null.
+func (eval *resourceEvaluator) number(exp syntax.Exp) (*float64, error) {
+	v, err := eval.eval(exp)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(v, nullBytes) {
+		return nil, nil
+	}
+	var f float64
+	if err := json.Unmarshal(v, &f); err != nil {
+		return nil, fmt.Errorf("%s is not a number: %w", exp.GoString(), err)
+	}
+	return &f, nil
+}
+
+// eval returns the json-encoded value of the expression.
+func (eval *resourceEvaluator) eval(exp syntax.Exp) (json.RawMessage, error) {
+	switch exp := exp.(type) {
+	case *syntax.RefExp:
+		v, _, err := eval.ref(exp)
+		return v, err
+	case *syntax.FuncExp:
+		if exp.Func == syntax.FuncFileSize {
+			return eval.fileSize(exp)
+		}
+		args := make([]json.RawMessage, len(exp.Args))
+		for i, arg := range exp.Args {
+			v, err := eval.eval(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v
+		}
+		result, err := exp.Evaluate(args)
+		if err != nil {
+			return nil, err
+		}
+		return result.MarshalJSON()
+	}
+	return exp.MarshalJSON()
+}
+
+// ref returns the value and type of an input parameter reference.
+func (eval *resourceEvaluator) ref(exp *syntax.RefExp) (json.RawMessage, syntax.Type, error) {
+	param := eval.params.Table[exp.Id]
+	if param == nil {
+		return nil, nil, fmt.Errorf("no input parameter %s", exp.Id)
+	}
+	t := eval.types.Get(param.GetTname())
+	v := eval.args[exp.Id]
+	if v == nil {
+		return nullBytes, t, nil
+	} else if exp.OutputId == "" {
+		return v, t, nil
+	}
+	m, err := resolvePath(v, exp.OutputId, t, nil, eval.types)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m == nil {
+		return nullBytes, nil, nil
+	}
+	b, err := m.MarshalJSON()
+	if err != nil {
+		return nil, nil, err
+	}
+	tid, err := eval.types.FieldType(param.GetTname(), exp.OutputId)
+	if err != nil {
+		return b, nil, err
+	}
+	return b, eval.types.Get(tid), nil
+}
+
+// fileSize computes the total size of the files referenced by the
+// argument.
+func (eval *resourceEvaluator) fileSize(exp *syntax.FuncExp) (json.RawMessage, error) {
+	if len(exp.Args) != 1 {
+		return nil, fmt.Errorf("%s requires 1 argument", exp.Func)
+	}
+	ref, ok := exp.Args[0].(*syntax.RefExp)
+	if !ok {
+		return nil, fmt.Errorf("the argument to %s must be an input parameter",
+			exp.Func)
+	}
+	v, t, err := eval.ref(ref)
+	if err != nil {
+		return nil, err
+	}
+	size, err := eval.totalSize(v, t)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(size)
+}
+
+// totalSize sums the sizes of the files in the value, which has the given
+// type.
+func (eval *resourceEvaluator) totalSize(v json.RawMessage, t syntax.Type) (int64, error) {
+	if len(v) == 0 || bytes.Equal(v, nullBytes) || t == nil ||
+		t.IsFile() < syntax.KindIsFile {
+		return 0, nil
+	}
+	switch t := t.(type) {
+	case *syntax.ArrayType:
+		var arr []json.RawMessage
+		if err := json.Unmarshal(v, &arr); err != nil {
+			return 0, err
+		}
+		et := eval.types.GetArray(t, -1)
+		var total int64
+		for _, elem := range arr {
+			size, err := eval.totalSize(elem, et)
+			if err != nil {
+				return total, err
+			}
+			total += size
+		}
+		return total, nil
+	case *syntax.TypedMapType:
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(v, &m); err != nil {
+			return 0, err
+		}
+		var total int64
+		for _, elem := range m {
+			size, err := eval.totalSize(elem, t.Elem)
+			if err != nil {
+				return total, err
+			}
+			total += size
+		}
+		return total, nil
+	case *syntax.StructType:
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(v, &m); err != nil {
+			return 0, err
+		}
+		var total int64
+		for _, member := range t.Members {
+			if member.IsFile() < syntax.KindIsFile {
+				continue
+			}
+			size, err := eval.totalSize(m[member.Id],
+				eval.types.Get(member.Tname))
+			if err != nil {
+				return total, err
+			}
+			total += size
+		}
+		return total, nil
+	}
+	var fn string
+	if err := json.Unmarshal(v, &fn); err != nil {
+		return 0, err
+	}
+	return pathSize(fn)
+}
+
+// pathSize returns the size of a file, or the total size of the files in a
+// directory.
+func pathSize(fn string) (int64, error) {
+	info, err := os.Stat(fn)
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return info.Size(), nil
+	}
+	var total int64
+	err = filepath.Walk(fn, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/martian-lang/martian/martian/syntax"
+)
+
+func TestResourceExp(t *testing.T) {
+	dir := t.TempDir()
+	writeSize := func(name string, size int) string {
+		t.Helper()
+		fn := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fn, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return fn
+	}
+	input := writeSize("input.txt", 1000)
+	other := writeSize("other.txt", 24)
+	writeSize("outdir/a.txt", 100)
+	writeSize("outdir/sub/b.txt", 50)
+	_, _, ast, err := syntax.ParseSourceBytes([]byte(`
+filetype txt;
+
+struct READS(
+    txt    reads,
+    string name,
+    path   outdir,
+)
+
+stage SORT(
+    in  txt     input,
+    in  READS   reads,
+    in  txt[]   others,
+    in  int     threads,
+    in  float   scale,
+    src comp    "sort",
+) using (
+    mem_gb  = 1 + self.scale * file_size(self.input) / 1000,
+    threads = max(1, self.threads),
+    vmem_gb = file_size(self.reads) + file_size(self.others) + file_size(self.reads.reads),
+)
+`), "example.mro", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage := ast.Callables.Table["SORT"].(*syntax.Stage)
+	var args LazyArgumentMap
+	if err := json.Unmarshal([]byte(`{
+		"input": `+quoteJson(input)+`,
+		"reads": {
+			"reads": `+quoteJson(input)+`,
+			"name": "not a file",
+			"outdir": `+quoteJson(filepath.Join(dir, "outdir"))+`
+		},
+		"others": [`+quoteJson(other)+`, null],
+		"threads": null,
+		"scale": 2.5
+	}`), &args); err != nil {
+		t.Fatal(err)
+	}
+	eval := resourceEvaluator{
+		args:   args,
+		params: stage.InParams,
+		types:  &ast.TypeTable,
+	}
+	check := func(t *testing.T, exp syntax.Exp, expect float64) {
+		t.Helper()
+		if v, err := eval.number(exp); err != nil {
+			t.Error(err)
+		} else if v == nil {
+			t.Errorf("expected %g, got null", expect)
+		} else if *v != expect {
+			t.Errorf("expected %g, got %g", expect, *v)
+		}
+	}
+	check(t, stage.Resources.MemGBExp, 3.5)
+	check(t, stage.Resources.ThreadsExp, 1)
+	check(t, stage.Resources.VMemGBExp, 1000+150+24+1000)
+	args["threads"] = json.RawMessage("4")
+	check(t, stage.Resources.ThreadsExp, 4)
+	args["scale"] = json.RawMessage("null")
+	if v, err := eval.number(stage.Resources.MemGBExp); err != nil {
+		t.Error(err)
+	} else if v != nil {
+		t.Errorf("expected null, got %g", *v)
+	}
+	args["input"] = json.RawMessage(quoteJson(filepath.Join(dir, "missing")))
+	if _, err := eval.number(stage.Resources.VMemGBExp); err != nil {
+		t.Error(err)
+	}
+	args["scale"] = json.RawMessage("1")
+	if _, err := eval.number(stage.Resources.MemGBExp); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestRoundResource(t *testing.T) {
+	for _, c := range [...]struct {
+		value, granularity, expect float64
+	}{
+		{1.001, 100, 1.01},
+		{-1.001, 100, -1.01},
+		{0, 100, 0},
+		{2, 1024, 2},
+		{0.0001, 1024, 1.0 / 1024},
+	} {
+		if r := roundResource(c.value, c.granularity); r != c.expect {
+			t.Errorf("roundResource(%g, %g): expected %g, got %g",
+				c.value, c.granularity, c.expect, r)
+		}
+	}
+}
+
+func TestResetResources(t *testing.T) {
+	fork := Fork{
+		split_metadata: new(Metadata),
+		join_metadata:  new(Metadata),
+		resources:      &JobResources{Threads: 4},
+	}
+	fork.reset()
+	if fork.resources != nil {
+		t.Error("expected computed resources to be cleared by reset")
+	}
+}
+
+func quoteJson(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
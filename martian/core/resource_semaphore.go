@@ -180,6 +180,27 @@ func (self *ResourceSemaphore) CurrentSize() int64 {
 	return res
 }
 
+// ResourceUsage is a snapshot of the state of a ResourceSemaphore.
+type ResourceUsage struct {
+	// The amount reserved plus the amount in use by unknown consumers.
+	InUse int64
+	// The amount explicitly reserved.
+	Reserved int64
+	// The amount which can currently be reserved.
+	Available int64
+}
+
+// Get a consistent snapshot of the in-use, reserved, and available amounts.
+func (self *ResourceSemaphore) Usage() ResourceUsage {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return ResourceUsage{
+		InUse:     self.maxSize - self.curSize + self.reserved,
+		Reserved:  self.reserved,
+		Available: self.curSize - self.reserved,
+	}
+}
+
 // Get the number of items waiting on the semaphore.
 func (self *ResourceSemaphore) QueueLength() int {
 	self.mu.Lock()
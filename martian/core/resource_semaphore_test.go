@@ -266,3 +266,22 @@ func TestResourceSemaphoreActualRelease(t *testing.T) {
 		t.Errorf("Timed out.")
 	}
 }
+
+func TestResourceSemaphoreUsage(t *testing.T) {
+	sem := NewResourceSemaphore(100, DefaultResourceFormatter("test"))
+	sem.UpdateActual(70)
+	if err := sem.Acquire(20); err != nil {
+		t.Fatal(err)
+	}
+	if u := sem.Usage(); u != (ResourceUsage{
+		InUse:     50,
+		Reserved:  20,
+		Available: 50,
+	}) {
+		t.Errorf("incorrect usage %#v", u)
+	}
+	sem.Release(20)
+	if u := sem.Usage(); u.Reserved != 0 || u.InUse != 30 || u.Available != 70 {
+		t.Errorf("incorrect usage %#v", u)
+	}
+}
@@ -0,0 +1,475 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+// Export the provenance of a completed pipestance as an RO-Crate
+// (https://w3id.org/ro/crate) JSON-LD document.  Following the conventions
+// of the Provenance Run Crate profile, the pipestance run and each stage
+// fork are described as a CreateAction, whose object and result are the
+// files and values it used and generated.
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martian-lang/martian/martian/syntax"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+const (
+	// The name of the RO-Crate metadata file, which is written to the
+	// pipestance directory.
+	ROCrateMetadataFile = "ro-crate-metadata.json"
+
+	roCrateContext = "https://w3id.org/ro/crate/1.1/context"
+	roCrateSpec    = "https://w3id.org/ro/crate/1.1"
+)
+
+type crateEntity map[string]interface{}
+
+func crateRef(id string) crateEntity {
+	return crateEntity{"@id": id}
+}
+
+// An ROCrate is an RO-Crate metadata document.
+type ROCrate struct {
+	Context string        `json:"@context"`
+	Graph   []crateEntity `json:"@graph"`
+}
+
+type roCrateBuilder struct {
+	reader *pipestanceMetadataReader
+	graph  []crateEntity
+
+	// File and directory entities, keyed by @id.
+	files   map[string]crateEntity
+	fileIds []string
+
+	// The output checksums recorded in the _manifest file, if any.
+	manifest map[string]ManifestEntry
+}
+
+func (self *roCrateBuilder) add(ent crateEntity) crateEntity {
+	self.graph = append(self.graph, ent)
+	return crateRef(ent["@id"].(string))
+}
+
+// Returns a reference to the entity for the file at the given absolute path,
+// creating it if required.  Files within the pipestance are identified by
+// their path relative to the pipestance directory.
+func (self *roCrateBuilder) file(p string) crateEntity {
+	rel := self.reader.relPath(p)
+	id := rel
+	statPath := p
+	if rel == "" {
+		id = (&url.URL{Scheme: "file", Path: p}).String()
+	} else if self.reader.dir != "" {
+		statPath = path.Join(self.reader.dir, rel)
+	}
+	info, err := os.Stat(statPath)
+	if err == nil && info.IsDir() && !strings.HasSuffix(id, "/") {
+		id += "/"
+	}
+	if _, ok := self.files[id]; ok {
+		return crateRef(id)
+	}
+	ent := crateEntity{
+		"@id":   id,
+		"@type": "File",
+		"name":  path.Base(p),
+	}
+	if err == nil {
+		if info.IsDir() {
+			ent["@type"] = "Dataset"
+		} else {
+			ent["contentSize"] = strconv.FormatInt(info.Size(), 10)
+		}
+	}
+	if m, ok := self.manifest[rel]; ok {
+		ent["sha256"] = m.Sha256
+	}
+	self.files[id] = ent
+	self.fileIds = append(self.fileIds, id)
+	return crateRef(id)
+}
+
+// Calls fn for each string in the value which is an absolute path.
+func forEachPath(value interface{}, fn func(string)) {
+	switch value := value.(type) {
+	case string:
+		if strings.HasPrefix(value, "/") {
+			fn(value)
+		}
+	case []interface{}:
+		for _, v := range value {
+			forEachPath(v, fn)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			forEachPath(value[k], fn)
+		}
+	}
+}
+
+// Returns references to the entities for a parameter value.  Paths in the
+// value become File entities, and other values become PropertyValue
+// entities.
+func (self *roCrateBuilder) parameter(id, name string, value interface{}) []crateEntity {
+	var refs []crateEntity
+	forEachPath(value, func(p string) {
+		refs = append(refs, self.file(p))
+	})
+	if len(refs) > 0 || value == nil {
+		return refs
+	}
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		b, _ := json.Marshal(value)
+		value = string(b)
+	}
+	return []crateEntity{self.add(crateEntity{
+		"@id":   id,
+		"@type": "PropertyValue",
+		"name":  name,
+		"value": value,
+	})}
+}
+
+// Returns references to the entities for the outputs recorded in the _outs
+// file in the given metadata directory.
+func (self *roCrateBuilder) outputs(actionId string, md *MetadataInfo) []crateEntity {
+	if md == nil {
+		return nil
+	}
+	rel := self.reader.relPath(md.Path)
+	if rel == "" {
+		return nil
+	}
+	var outs map[string]interface{}
+	if b := self.reader.read(path.Join(rel, OutsFile.FileName())); b == nil {
+		return nil
+	} else if err := json.Unmarshal(b, &outs); err != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(outs))
+	for k := range outs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var refs []crateEntity
+	for _, k := range keys {
+		refs = append(refs, self.parameter(actionId+"/out/"+k, k, outs[k])...)
+	}
+	return refs
+}
+
+func (self *roCrateBuilder) inputs(actionId string, fork *ForkInfo) []crateEntity {
+	if fork.Bindings == nil {
+		return nil
+	}
+	var refs []crateEntity
+	for _, arg := range fork.Bindings.Argument {
+		refs = append(refs,
+			self.parameter(actionId+"/in/"+arg.Id, arg.Id, arg.Value)...)
+	}
+	return refs
+}
+
+// Returns the hosts on which the jobs for a fork ran, from their _jobinfo.
+func (self *roCrateBuilder) hosts(fork *ForkInfo) []string {
+	mds := []*MetadataInfo{fork.SplitMetadata, fork.JoinMetadata}
+	for _, chunk := range fork.Chunks {
+		mds = append(mds, chunk.Metadata)
+	}
+	seen := make(map[string]struct{})
+	var hosts []string
+	for _, md := range mds {
+		if md == nil {
+			continue
+		}
+		rel := self.reader.relPath(md.Path)
+		if rel == "" {
+			continue
+		}
+		var info JobInfo
+		if b := self.reader.read(path.Join(rel, JobInfoFile.FileName())); b == nil {
+			continue
+		} else if err := json.Unmarshal(b, &info); err != nil || info.Host == "" {
+			continue
+		}
+		if _, ok := seen[info.Host]; !ok {
+			seen[info.Host] = struct{}{}
+			hosts = append(hosts, info.Host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Add the start and end times and resource usage for an action.
+func (self *roCrateBuilder) addPerf(action crateEntity, perf *PerfInfo, hosts []string) {
+	id := action["@id"].(string)
+	var usage []crateEntity
+	property := func(name string, value interface{}, unit string) {
+		ent := crateEntity{
+			"@id":   id + "/usage/" + name,
+			"@type": "PropertyValue",
+			"name":  name,
+			"value": value,
+		}
+		if unit != "" {
+			ent["unitText"] = unit
+		}
+		usage = append(usage, self.add(ent))
+	}
+	if perf != nil {
+		if !perf.Start.IsZero() {
+			action["startTime"] = perf.Start.Format(time.RFC3339)
+		}
+		if !perf.End.IsZero() {
+			action["endTime"] = perf.End.Format(time.RFC3339)
+		}
+		property("walltime", perf.WallTime, "s")
+		property("core_hours", perf.CoreHours, "h")
+		property("maxrss", perf.MaxRss, "KiB")
+	}
+	if len(hosts) > 0 {
+		property("host", strings.Join(hosts, ","), "")
+	}
+	if len(usage) > 0 {
+		action["resourceUsage"] = usage
+	}
+}
+
+func (self *roCrateBuilder) readPerf() map[string][]*ForkPerfInfo {
+	b := self.reader.read(Perf.FileName())
+	if b == nil {
+		return nil
+	}
+	var perfs []*NodePerfInfo
+	if err := json.Unmarshal(b, &perfs); err != nil {
+		util.PrintError(err, "rocrate", "Could not parse performance data.")
+		return nil
+	}
+	result := make(map[string][]*ForkPerfInfo, len(perfs))
+	for _, perf := range perfs {
+		result[partiallyQualifiedName(perf.Fqname)] = perf.Forks
+	}
+	return result
+}
+
+func forkPerf(forks []*ForkPerfInfo, index int) *PerfInfo {
+	for _, fork := range forks {
+		if fork.Index == index {
+			return fork.ForkStats
+		}
+	}
+	return nil
+}
+
+// Parse a _timestamp file into start and end times in RFC 3339 format.
+func parseTimestampRange(data string) (string, string) {
+	var start, end string
+	for _, line := range strings.Split(data, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		t, err := time.ParseInLocation(util.TIMEFMT,
+			strings.TrimSpace(value), time.Local)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "start":
+			start = t.Format(time.RFC3339)
+		case "end":
+			end = t.Format(time.RFC3339)
+		}
+	}
+	return start, end
+}
+
+// ExportROCrate generates an RO-Crate metadata document describing the
+// provenance of a completed pipestance.  Metadata files which were archived
+// by mrp --zip are read from the metadata zip archive.
+//
+// The document describes the pipestance run, and each stage fork, as an
+// activity with the files and values it used and generated, taken from the
+// fork bindings in _finalstate and from the _outs files, with timing and
+// resource usage from _perf and _jobinfo.  Files inside the pipestance are
+// identified by their paths relative to the pipestance directory.
+func ExportROCrate(pipestancePath string) (*ROCrate, error) {
+	reader, err := openPipestanceMetadata(pipestancePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(reader.finalState) == 0 {
+		return nil, errors.New("pipestance has no final state; " +
+			"only completed pipestances can be exported")
+	}
+	self := roCrateBuilder{
+		reader: reader,
+		files:  make(map[string]crateEntity),
+	}
+	var manifest OutputManifest
+	if b := reader.read(Manifest.FileName()); b != nil &&
+		json.Unmarshal(b, &manifest) == nil {
+		self.manifest = manifest.Files
+	}
+	root := crateEntity{
+		"@id":   "./",
+		"@type": "Dataset",
+	}
+	self.graph = append(self.graph,
+		crateEntity{
+			"@id":        ROCrateMetadataFile,
+			"@type":      "CreativeWork",
+			"conformsTo": crateRef(roCrateSpec),
+			"about":      crateRef("./"),
+		},
+		root)
+
+	var tools []crateEntity
+	var versions VersionInfo
+	if b := reader.read(VersionsFile.FileName()); b != nil &&
+		json.Unmarshal(b, &versions) == nil {
+		tools = append(tools, self.add(crateEntity{
+			"@id":     "#martian",
+			"@type":   "SoftwareApplication",
+			"name":    "Martian",
+			"url":     "https://martian-lang.org",
+			"version": versions.Martian,
+		}))
+		if versions.Pipelines != "" {
+			tools = append(tools, self.add(crateEntity{
+				"@id":     "#pipelines",
+				"@type":   "SoftwareApplication",
+				"name":    "Pipelines",
+				"version": versions.Pipelines,
+			}))
+		}
+	}
+	for _, f := range [...]struct {
+		name MetadataFileName
+		desc string
+	}{
+		{InvocationFile, "Pipeline invocation"},
+		{MroSourceFile, "Pipeline source, with includes expanded"},
+	} {
+		if reader.read(f.name.FileName()) != nil {
+			self.add(crateEntity{
+				"@id":            f.name.FileName(),
+				"@type":          "File",
+				"name":           f.desc,
+				"encodingFormat": "text/plain",
+			})
+			self.fileIds = append(self.fileIds, f.name.FileName())
+		}
+	}
+
+	perfs := self.readPerf()
+	var actions []crateEntity
+	for _, node := range reader.finalState {
+		name := partiallyQualifiedName(node.Fqname)
+		isTop := !strings.ContainsRune(name, '.')
+		if node.Type != syntax.KindStage && !isTop {
+			continue
+		}
+		var instrument crateEntity
+		if isTop {
+			root["name"] = name
+			root["description"] = "Pipestance for " + name
+			if reader.read(MroSourceFile.FileName()) != nil {
+				instrument = crateRef(MroSourceFile.FileName())
+			}
+		} else {
+			instrument = self.add(crateEntity{
+				"@id":                 "#" + name + "/code",
+				"@type":               "SoftwareApplication",
+				"name":                node.Name,
+				"programmingLanguage": node.StagecodeLang.String(),
+				"identifier":          node.StagecodeCmd,
+			})
+		}
+		for _, fork := range node.Forks {
+			id := "#" + name + "/fork" + strconv.Itoa(fork.Index)
+			actionName := "Run of " + name
+			if len(node.Forks) > 1 {
+				actionName += " fork " + strconv.Itoa(fork.Index)
+			}
+			action := crateEntity{
+				"@id":   id,
+				"@type": "CreateAction",
+				"name":  actionName,
+			}
+			if instrument != nil {
+				action["instrument"] = instrument
+			}
+			if objects := self.inputs(id, fork); len(objects) > 0 {
+				action["object"] = objects
+			}
+			if results := self.outputs(id, fork.Metadata); len(results) > 0 {
+				action["result"] = results
+			}
+			if isTop {
+				if len(tools) > 0 {
+					action["agent"] = tools
+				}
+				start, end := parseTimestampRange(
+					string(reader.read(TimestampFile.FileName())))
+				if start != "" {
+					action["startTime"] = start
+				}
+				if end != "" {
+					action["endTime"] = end
+					root["datePublished"] = end
+				}
+			} else {
+				self.addPerf(action, forkPerf(perfs[name], fork.Index),
+					self.hosts(fork))
+			}
+			actions = append(actions, self.add(action))
+		}
+	}
+	root["mentions"] = actions
+	parts := make([]crateEntity, 0, len(self.fileIds))
+	for _, id := range self.fileIds {
+		if !strings.HasPrefix(id, "file:") {
+			parts = append(parts, crateRef(id))
+		}
+		if ent := self.files[id]; ent != nil {
+			self.graph = append(self.graph, ent)
+		}
+	}
+	root["hasPart"] = parts
+	return &ROCrate{
+		Context: roCrateContext,
+		Graph:   self.graph,
+	}, nil
+}
+
+// Write the RO-Crate metadata file for the pipestance.
+func (self *Pipestance) writeROCrate() error {
+	crate, err := ExportROCrate(self.GetPath())
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(crate, "", "    ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path.Join(self.GetPath(), ROCrateMetadataFile), b)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExportROCrate(t *testing.T) {
+	psPath := t.TempDir()
+	writeTestPipestance(t, psPath, map[string]string{
+		"_versions":   `{"martian":"v4.0.0","pipelines":"1.0"}`,
+		"_invocation": "call P(n = 3)",
+		"_timestamp":  "start: 2020-01-02 03:04:05\nend: 2020-01-02 04:05:06",
+		"_finalstate": `[{
+			"name": "P", "fqname": "ID.ps.P", "path": "$PS/P", "type": "pipeline",
+			"forks": [{
+				"index": 0,
+				"metadata": {"path": "$PS/P/fork0"},
+				"bindings": {"Argument": [{"id": "n", "value": 3}]}
+			}]
+		}, {
+			"name": "A", "fqname": "ID.ps.P.A", "path": "$PS/P/A", "type": "stage",
+			"stagecodeCmd": "stages/a", "stagecodeLang": "Python",
+			"forks": [{
+				"index": 0,
+				"metadata": {"path": "$PS/P/A/fork0"},
+				"chunks": [{"index": 0, "metadata": {"path": "$PS/P/A/fork0/chnk0"}}],
+				"bindings": {"Argument": [
+					{"id": "n", "value": 3},
+					{"id": "ref", "value": "/ref/genome.fa"}
+				]}
+			}]
+		}]`,
+		"_perf": `[{"fqname": "ID.ps.P.A", "forks": [{"index": 0, "fork_stats": {
+			"start": "2020-01-02T03:04:10Z", "end": "2020-01-02T03:05:10Z",
+			"walltime": 60, "core_hours": 0.5, "maxrss": 1024}}]}]`,
+		"_manifest":                `{"files": {"outs/x.txt": {"sha256": "abc", "size": 1}}}`,
+		"P/fork0/_outs":            `{"x": "$PS/outs/x.txt"}`,
+		"P/A/fork0/_outs":          `{"x": "$PS/P/A/fork0/files/x.txt", "count": 2}`,
+		"P/A/fork0/chnk0/_jobinfo": `{"host": "node1"}`,
+		"outs/x.txt":               "x",
+	})
+	crate, err := ExportROCrate(psPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entities := make(map[string]crateEntity, len(crate.Graph))
+	for _, ent := range crate.Graph {
+		id := ent["@id"].(string)
+		if _, ok := entities[id]; ok {
+			t.Errorf("duplicate entity %s", id)
+		}
+		entities[id] = ent
+	}
+	check := func(id, expect string) {
+		t.Helper()
+		ent, ok := entities[id]
+		if !ok {
+			t.Errorf("missing entity %s", id)
+			return
+		}
+		b, err := json.Marshal(ent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := string(b); s != expect {
+			t.Errorf("expected %s\ngot      %s", expect, s)
+		}
+	}
+	check("#P/fork0", `{"@id":"#P/fork0","@type":"CreateAction",`+
+		`"agent":[{"@id":"#martian"},{"@id":"#pipelines"}],`+
+		`"endTime":"`+formatLocal("2020-01-02 04:05:06")+`",`+
+		`"name":"Run of P","object":[{"@id":"#P/fork0/in/n"}],`+
+		`"result":[{"@id":"outs/x.txt"}],`+
+		`"startTime":"`+formatLocal("2020-01-02 03:04:05")+`"}`)
+	check("#P.A/fork0", `{"@id":"#P.A/fork0","@type":"CreateAction",`+
+		`"endTime":"2020-01-02T03:05:10Z",`+
+		`"instrument":{"@id":"#P.A/code"},"name":"Run of P.A",`+
+		`"object":[{"@id":"#P.A/fork0/in/n"},{"@id":"file:///ref/genome.fa"}],`+
+		`"resourceUsage":[{"@id":"#P.A/fork0/usage/walltime"},`+
+		`{"@id":"#P.A/fork0/usage/core_hours"},`+
+		`{"@id":"#P.A/fork0/usage/maxrss"},`+
+		`{"@id":"#P.A/fork0/usage/host"}],`+
+		`"result":[{"@id":"#P.A/fork0/out/count"},`+
+		`{"@id":"P/A/fork0/files/x.txt"}],`+
+		`"startTime":"2020-01-02T03:04:10Z"}`)
+	check("#P.A/fork0/usage/host", `{"@id":"#P.A/fork0/usage/host",`+
+		`"@type":"PropertyValue","name":"host","value":"node1"}`)
+	check("outs/x.txt", `{"@id":"outs/x.txt","@type":"File",`+
+		`"contentSize":"1","name":"x.txt","sha256":"abc"}`)
+	check("P/A/fork0/files/x.txt", `{"@id":"P/A/fork0/files/x.txt",`+
+		`"@type":"File","name":"x.txt"}`)
+	root := entities["./"]
+	if b, err := json.Marshal(root["hasPart"]); err != nil {
+		t.Error(err)
+	} else if s := string(b); strings.Contains(s, "file:") ||
+		!strings.Contains(s, `{"@id":"outs/x.txt"}`) {
+		t.Errorf("incorrect hasPart %s", s)
+	}
+}
+
+func formatLocal(ts string) string {
+	start, _ := parseTimestampRange("start: " + ts)
+	return start
+}
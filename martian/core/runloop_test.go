@@ -17,6 +17,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/martian-lang/martian/martian/syntax"
 	"github.com/martian-lang/martian/martian/util"
 )
 
@@ -63,36 +64,16 @@ func loopBody(t *testing.T, pipestance *Pipestance) (bool, bool) {
 	}
 }
 
-type testLogger struct {
-	t *testing.T
-}
-
-func (t testLogger) Write(b []byte) (int, error) {
-	t.t.Helper()
-	t.t.Log(string(bytes.TrimSpace(b)))
-	return len(b), nil
-}
-
-func (t testLogger) WriteString(b string) (int, error) {
-	t.t.Helper()
-	t.t.Log(strings.TrimSpace(b))
-	return len(b), nil
-}
-
-// Tests actually running a pipestance.
-//
-// The reason this test exists, rather than simply relying on the end-to-end
-// integration tests, is mainly to be able to see code coverage.  It's also
-// very fast because of the trivial stage code.
-func TestPipestanceRun(t *testing.T) {
+// Start the pipeline in testdata/map_call_edge_cases.mro in a temporary
+// directory, using the local job manager.
+func invokeTestPipeline(t *testing.T) (*Runtime, *Pipestance, string) {
+	t.Helper()
 	data, err := os.ReadFile("testdata/map_call_edge_cases.mro")
 	if err != nil {
 		t.Fatal(err)
 	}
-	util.SetPrintLogger(testLogger{t: t})
-	defer util.SetPrintLogger(&devNull)
 	rtOpts := DefaultRuntimeOptions()
-	rt := Runtime{
+	rt := &Runtime{
 		Config: &rtOpts,
 	}
 	rt.jobConfig = &JobManagerJson{
@@ -113,11 +94,11 @@ func TestPipestanceRun(t *testing.T) {
 		t.Fatal(err)
 	}
 	rt.JobManager = rt.LocalJobManager
-	psdir, err := os.MkdirTemp("", "TestPipestanceRun")
+	psdir, err := os.MkdirTemp("", t.Name())
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer os.RemoveAll(psdir)
+	t.Cleanup(func() { os.RemoveAll(psdir) })
 	t.Log("Starting pipestance in", psdir)
 	pipestance, err := rt.InvokePipeline(string(data),
 		"testdata/map_call_edge_cases.mro", t.Name(),
@@ -126,7 +107,12 @@ func TestPipestanceRun(t *testing.T) {
 		t.Fatal("Invoking pipeline:", err)
 	}
 	pipestance.LoadMetadata(context.Background())
+	return rt, pipestance, psdir
+}
 
+// Step the pipestance until it completes or fails.
+func runPipestance(t *testing.T, rt *Runtime, pipestance *Pipestance) {
+	t.Helper()
 	ti := time.NewTimer(0)
 	if !ti.Stop() {
 		<-ti.C
@@ -136,7 +122,7 @@ func TestPipestanceRun(t *testing.T) {
 		done, hadProgress := loopBody(t, pipestance)
 
 		if done {
-			break
+			return
 		}
 
 		if !hadProgress {
@@ -151,6 +137,34 @@ func TestPipestanceRun(t *testing.T) {
 			}
 		}
 	}
+}
+
+type testLogger struct {
+	t *testing.T
+}
+
+func (t testLogger) Write(b []byte) (int, error) {
+	t.t.Helper()
+	t.t.Log(string(bytes.TrimSpace(b)))
+	return len(b), nil
+}
+
+func (t testLogger) WriteString(b string) (int, error) {
+	t.t.Helper()
+	t.t.Log(strings.TrimSpace(b))
+	return len(b), nil
+}
+
+// Tests actually running a pipestance.
+//
+// The reason this test exists, rather than simply relying on the end-to-end
+// integration tests, is mainly to be able to see code coverage.  It's also
+// very fast because of the trivial stage code.
+func TestPipestanceRun(t *testing.T) {
+	util.SetPrintLogger(testLogger{t: t})
+	defer util.SetPrintLogger(&devNull)
+	rt, pipestance, psdir := invokeTestPipeline(t)
+	runPipestance(t, rt, pipestance)
 
 	// Test that serializing the state works correctly with a canceled context.
 	// Coverage from this call is going to be racy, of course, but it's very
@@ -261,3 +275,146 @@ func TestPipestanceRun(t *testing.T) {
 			len(b))
 	}
 }
+
+// Tests invalidating a stage of a completed pipestance and running it again.
+func TestPipestanceRerun(t *testing.T) {
+	util.SetPrintLogger(testLogger{t: t})
+	defer util.SetPrintLogger(&devNull)
+	rt, pipestance, _ := invokeTestPipeline(t)
+	runPipestance(t, rt, pipestance)
+	if t.Failed() {
+		return
+	}
+
+	upstream := pipestance.node.find("TOP.GENERATE_INPUTS")
+	if upstream == nil {
+		t.Fatal("could not find GENERATE_INPUTS")
+	}
+	var target *Node
+	for _, node := range upstream.postnodes {
+		if node.getNode().call.Kind() == syntax.KindStage {
+			target = node.getNode()
+			break
+		}
+	}
+	if target == nil {
+		t.Fatal("expected a stage downstream of GENERATE_INPUTS")
+	}
+	if err := pipestance.Rerun("TOP.NOT_A_STAGE"); err == nil {
+		t.Error("expected an error for a nonexistent stage")
+	}
+
+	// Pretend that VDR deleted the upstream stage's outputs.
+	kill := upstream.forks[0].metadata
+	if err := kill.Write(VdrKill, &VDRKillReport{Count: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pipestance.Rerun(target.GetFQName()); err == nil {
+		t.Error("expected rerun to fail after VDR")
+	} else if !strings.Contains(err.Error(), upstream.GetFQName()) {
+		t.Errorf("expected error to mention %s, got %v",
+			upstream.GetFQName(), err)
+	}
+	kill.remove(VdrKill)
+
+	// Stand-ins for the files written when the pipestance completed.
+	for _, name := range [...]MetadataFileName{
+		FinalState, Perf, MetadataZip, Manifest,
+	} {
+		if err := pipestance.metadata.WriteRaw(name, "stale"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	crate := path.Join(pipestance.GetPath(), ROCrateMetadataFile)
+	if err := os.WriteFile(crate, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	start := pipestance.metadata.readRaw(TimestampFile)
+	if err := pipestance.metadata.WriteRaw(TimestampFile,
+		start+"\nend: 2020-01-01 00:00:00"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pipestance.Rerun(target.GetFQName()); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range [...]MetadataFileName{
+		FinalState, Perf, MetadataZip, Manifest,
+	} {
+		if _, err := os.Stat(pipestance.metadata.MetadataFilePath(name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", name.FileName())
+		}
+	}
+	if _, err := os.Stat(crate); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed", ROCrateMetadataFile)
+	}
+	if ts := pipestance.metadata.readRaw(TimestampFile); ts != start {
+		t.Errorf("expected timestamp %q, got %q", start, ts)
+	}
+	if target.state == Complete {
+		t.Errorf("expected %s to be invalidated", target.GetFQName())
+	}
+	if upstream.state != Complete {
+		t.Errorf("expected %s to be kept, got %v",
+			upstream.GetFQName(), upstream.state)
+	}
+	if st := pipestance.GetState(context.Background()); st == Complete {
+		t.Error("expected pipestance to not be complete")
+	}
+	runPipestance(t, rt, pipestance)
+	if st := pipestance.GetState(context.Background()); st != Complete {
+		t.Errorf("expected pipestance to complete, got %v", st)
+	}
+}
+
+// Tests that reattaching to a completed pipestance after a stage definition
+// changed reruns the stage and discards the final state of the first run.
+func TestReattachChangedStage(t *testing.T) {
+	util.SetPrintLogger(testLogger{t: t})
+	defer util.SetPrintLogger(&devNull)
+	rt, pipestance, psdir := invokeTestPipeline(t)
+	runPipestance(t, rt, pipestance)
+	if t.Failed() {
+		return
+	}
+	for _, name := range [...]MetadataFileName{FinalState, Perf} {
+		if err := pipestance.metadata.WriteRaw(name, "stale"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pipestance.Unlock()
+
+	data, err := os.ReadFile("testdata/map_call_edge_cases.mro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const stageSrc = `    out Inputs2Plus result,
+    src exec        "stage.py",
+)
+`
+	src := strings.Replace(string(data), stageSrc,
+		stageSrc[:len(stageSrc)-1]+` using (
+    mem_gb = 2,
+)
+`, 1)
+	if src == string(data) {
+		t.Fatal("failed to modify source")
+	}
+	pipestance, err = rt.ReattachToPipestance(t.Name(), psdir, src,
+		"testdata/map_call_edge_cases.mro", []string{"testdata"}, "<none>",
+		nil, true, false, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pipestance.Unlock()
+	if node := pipestance.node.find("TOP.GENERATE_INPUTS"); node == nil {
+		t.Error("could not find GENERATE_INPUTS")
+	} else if node.state == Complete {
+		t.Error("expected GENERATE_INPUTS to be invalidated")
+	}
+	for _, name := range [...]MetadataFileName{FinalState, Perf} {
+		if _, err := os.Stat(pipestance.metadata.MetadataFilePath(name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", name.FileName())
+		}
+	}
+}
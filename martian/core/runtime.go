@@ -18,6 +18,7 @@ import (
 	"regexp"
 	"runtime/trace"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/martian-lang/martian/martian/syntax"
@@ -73,19 +74,26 @@ func VerifyOnFinish(onfinish string) {
 	}
 }
 
-// Reads config file for regexps which, when matched, indicate that
-// an error is likely transient.
-func getRetryRegexps() (retryOn []*regexp.Regexp, defaultRetries int) {
+// TimeoutError is the beginning of the error message which mrjob writes when
+// it kills a job for exceeding its timeout.
+const TimeoutError = "Stage exceeded its time limit"
+
+type retryJson struct {
+	RetryOn        []string         `json:"retry_on"`
+	DefaultRetries int              `json:"default_retries"`
+	MemoryRetry    *memoryRetryJson `json:"memory_retry,omitempty"`
+
+	// If true, jobs which were killed for exceeding their timeout are
+	// retried like other transient errors.
+	RetryTimeouts bool `json:"retry_timeouts,omitempty"`
+}
+
+// Reads the retry config file, or returns nil if there isn't one.
+func readRetryConfig() *retryJson {
 	retryfile := util.RelPath(path.Join("..", "jobmanagers", "retry.json"))
 
 	if _, err := os.Stat(retryfile); os.IsNotExist(err) {
-		return []*regexp.Regexp{
-			regexp.MustCompile("^signal: "),
-		}, 0
-	}
-	type retryJson struct {
-		RetryOn        []string `json:"retry_on"`
-		DefaultRetries int      `json:"default_retries"`
+		return nil
 	}
 	bytes, err := os.ReadFile(retryfile)
 	if err != nil {
@@ -97,13 +105,47 @@ func getRetryRegexps() (retryOn []*regexp.Regexp, defaultRetries int) {
 		util.PrintInfo("runtime", "Retry config file could not be parsed:\n%v\n", err)
 		os.Exit(1)
 	}
-	regexps := make([]*regexp.Regexp, len(retryInfo.RetryOn))
+	return retryInfo
+}
+
+// Reads config file for regexps which, when matched, indicate that
+// an error is likely transient.
+func getRetryRegexps() (retryOn []*regexp.Regexp, defaultRetries int) {
+	retryInfo := readRetryConfig()
+	if retryInfo == nil {
+		return []*regexp.Regexp{
+			regexp.MustCompile("^signal: "),
+		}, 0
+	}
+	regexps := make([]*regexp.Regexp, len(retryInfo.RetryOn), len(retryInfo.RetryOn)+1)
 	for i, exp := range retryInfo.RetryOn {
 		regexps[i] = regexp.MustCompile(exp)
 	}
+	if retryInfo.RetryTimeouts {
+		regexps = append(regexps,
+			regexp.MustCompile("^"+regexp.QuoteMeta(TimeoutError)))
+	}
 	return regexps, retryInfo.DefaultRetries
 }
 
+// Reads the configuration for escalating memory requests on retry, or
+// returns nil if memory escalation is not configured.
+func getMemoryRetry() *memoryRetry {
+	if retryInfo := readRetryConfig(); retryInfo != nil {
+		return retryInfo.MemoryRetry.compile()
+	}
+	return nil
+}
+
+// memoryRetry returns the configuration for escalating memory requests on
+// retry, which is only read once.
+func (self *Runtime) memoryRetry() *memoryRetry {
+	self.memRetryOnce.Do(func() {
+		self.memRetry = getMemoryRetry()
+	})
+	return self.memRetry
+}
+
 func DefaultRetries() int {
 	_, def := getRetryRegexps()
 	return def
@@ -136,12 +178,21 @@ type RuntimeOptions struct {
 	// "rolling", "strict", or "disable".
 	VdrMode VdrMode
 
+	// The output checksum manifest mode: either "outs", "all", or
+	// "disable".  If empty, "outs" is assumed.
+	ManifestMode ManifestMode
+
+	// Named resources, with their capacities, which local jobs may reserve
+	// through their special resource request.
+	LocalResources map[string]int
+
 	// The profiling mode (required): "disable" or one of the available
 	// constants.
 	ProfileMode     ProfileMode
 	MartianVersion  string
 	ResourceSpecial string
 	OnFinishHandler string
+	StageCache      string
 	LocalMem        int
 	LocalVMem       int
 	LocalCores      int
@@ -151,6 +202,7 @@ type RuntimeOptions struct {
 	FullStageReset  bool
 	StackVars       bool
 	Zip             bool
+	ROCrate         bool
 	SkipPreflight   bool
 	Monitor         bool
 	Debug           bool
@@ -167,6 +219,7 @@ func DefaultRuntimeOptions() RuntimeOptions {
 		ProfileMode:    DisableProfile,
 		JobMode:        localMode,
 		VdrMode:        VdrRolling,
+		ManifestMode:   ManifestOuts,
 	}
 }
 
@@ -179,6 +232,9 @@ func (config *RuntimeOptions) ToFlags() []string {
 	if config.VdrMode != VdrRolling {
 		flags = append(flags, "--vdrmode="+string(config.VdrMode))
 	}
+	if config.ManifestMode != ManifestOuts && config.ManifestMode != "" {
+		flags = append(flags, "--manifest="+string(config.ManifestMode))
+	}
 	if config.ProfileMode != DisableProfile {
 		flags = append(flags, fmt.Sprintf("--profile=%v",
 			config.ProfileMode))
@@ -191,6 +247,10 @@ func (config *RuntimeOptions) ToFlags() []string {
 		flags = append(flags, fmt.Sprintf("--localvmem=%d",
 			config.LocalVMem))
 	}
+	if len(config.LocalResources) > 0 {
+		flags = append(flags, "--localresource="+
+			FormatLocalResources(config.LocalResources))
+	}
 	if config.LocalCores != 0 {
 		flags = append(flags, fmt.Sprintf("--localcores=%d",
 			config.LocalCores))
@@ -213,6 +273,9 @@ func (config *RuntimeOptions) ToFlags() []string {
 	if config.Zip {
 		flags = append(flags, "--zip")
 	}
+	if config.ROCrate {
+		flags = append(flags, "--rocrate")
+	}
 	if config.SkipPreflight {
 		flags = append(flags, "--nopreflight")
 	}
@@ -244,6 +307,9 @@ func (config *RuntimeOptions) ToFlags() []string {
 	if config.NeverLocal {
 		flags = append(flags, "--never-local")
 	}
+	if config.StageCache != "" {
+		flags = append(flags, "--stage-cache="+config.StageCache)
+	}
 	return flags
 }
 
@@ -254,9 +320,17 @@ type Runtime struct {
 	JobManager      JobManager
 	LocalJobManager *LocalJobManager
 	overrides       *PipestanceOverrides
+	stageCache      *StageCache
 	jobConfig       *JobManagerJson
 	adaptersPath    string
 	mrjob           string
+
+	// If set, jobs may send status updates through this server rather than
+	// the journal directory.
+	StatusServer *StatusServer
+
+	memRetry     *memoryRetry
+	memRetryOnce sync.Once
 }
 
 func (c *RuntimeOptions) NewRuntime() (*Runtime, error) {
@@ -280,11 +354,12 @@ func (c *RuntimeOptions) NewRuntime() (*Runtime, error) {
 	if err != nil {
 		return self, err
 	}
+	self.LocalJobManager.setupLocalResources(c.LocalResources)
 	if c.JobMode == localMode {
 		self.JobManager = self.LocalJobManager
 	} else {
-		self.JobManager, err = NewRemoteJobManager(c.JobMode, c.MemPerCore, c.MaxJobs,
-			c.JobFreqMillis, c.ResourceSpecial, self.jobConfig, c.Debug)
+		self.JobManager, err = getJobManagerFactory(c.JobMode)(
+			c.JobMode, c, self.jobConfig)
 		if err != nil {
 			return self, err
 		}
@@ -297,6 +372,15 @@ func (c *RuntimeOptions) NewRuntime() (*Runtime, error) {
 		self.overrides = c.Overrides
 	}
 
+	if c.StageCache != "" {
+		self.stageCache, err = NewStageCache(c.StageCache)
+		if err != nil {
+			return self, err
+		}
+		util.LogInfo("runtime", "Using stage result cache at %s",
+			self.stageCache.Path())
+	}
+
 	return self, err
 }
 
@@ -482,40 +566,60 @@ func (self *Runtime) reattachToPipestance(psid string, pipestancePath string,
 	} else {
 		src = []byte(srcStr)
 	}
+	invocationChanged := false
 	if checkSrc {
 		// Read in the existing _invocation file.
 		data, err := os.ReadFile(path.Join(pipestancePath, srcType.FileName()))
 		if err != nil {
 			return nil, &PipestancePathError{pipestancePath}
 		}
-		// Check if _invocation has changed.
+		// Check if _invocation has changed.  If it has, the call must still
+		// be the same, which is checked below.
 		if !bytes.Equal(src, data) {
-			return nil, &PipestanceInvocationError{psid, invocationPath}
+			if srcType == MroSourceFile {
+				return nil, &PipestanceInvocationError{psid, invocationPath}
+			}
+			invocationChanged = true
 		}
 	}
 	// Instantiate the pipestance.
-	_, ast, pipestance, err := self.instantiatePipeline(
+	postsrc, ast, pipestance, err := self.instantiatePipeline(
 		src, invocationPath,
 		psid, pipestancePath, mroPaths,
 		mroVersion, envs, checkSrc, readOnly, ctx)
 	if err != nil {
 		return pipestance, err
 	}
+	var changed []string
 	if checkSrc && srcType != MroSourceFile {
 		// If the MroSourceFile was used then the earlier check for exact
-		// equality should be sufficient.  Otherwise we need to check for AST
-		// equality.
+		// equality should be sufficient.  Otherwise we need to find the
+		// nodes for which the stage definitions changed.
 		oldSrcFile := path.Join(pipestancePath, MroSourceFile.FileName())
 		if _, _, oldAst, err := syntax.Compile(oldSrcFile, mroPaths, false); err != nil {
 			if !readOnly {
 				pipestance.Unlock()
 			}
 			return nil, err
-		} else if !ast.EquivalentCall(oldAst) {
+		} else if invocationChanged && (ast.Call.Id != oldAst.Call.Id ||
+			!ast.Call.Bindings.Equals(oldAst.Call.Bindings)) {
 			if !readOnly {
 				pipestance.Unlock()
 			}
 			return pipestance, &PipestanceInvocationError{psid, invocationPath}
+		} else if oldGraph, err := oldAst.MakePipelineCallGraph(
+			"ID."+psid+".", oldAst.Call); err != nil {
+			if !readOnly {
+				pipestance.Unlock()
+			}
+			return nil, err
+		} else {
+			changed = syntax.ChangedNodes(oldGraph, pipestance.node.call)
+		}
+		if len(changed) > 0 {
+			util.PrintInfo("runtime",
+				"Definitions changed since the pipestance was started for:\n  %s",
+				strings.Join(changed, "\n  "))
 		}
 	}
 
@@ -546,11 +650,30 @@ func (self *Runtime) reattachToPipestance(psid string, pipestancePath string,
 	// mrp process died (on OSes where pdeathsig is supported).
 	if !readOnly {
 		util.PrintInfo("runtime", "Reattaching in %s mode.", self.Config.JobMode)
-		self.JobManager.resetMaxJobs()
+		self.JobManager.ResetMaxJobs()
 		if err := pipestance.RestartRunningNodes(self.Config.JobMode, ctx); err != nil {
 			pipestance.Unlock()
 			return pipestance, err
 		}
+		if len(changed) > 0 {
+			if err := pipestance.invalidateChanged(changed); err != nil {
+				pipestance.Unlock()
+				return pipestance, err
+			}
+		}
+		if invocationChanged || len(changed) > 0 {
+			// Save the new source so that the same nodes are not
+			// invalidated again the next time.
+			if err := pipestance.metadata.WriteRaw(InvocationFile,
+				string(src)); err != nil {
+				pipestance.Unlock()
+				return pipestance, err
+			}
+			if err := pipestance.metadata.WriteRaw(MroSourceFile, postsrc); err != nil {
+				pipestance.Unlock()
+				return pipestance, err
+			}
+		}
 	}
 
 	return pipestance, nil
@@ -902,8 +1025,12 @@ func BuildCallAst(
 	}
 	var parser syntax.Parser
 	var null syntax.NullExp
-	// for each parameter, either provide the value or null.
+	// for each parameter, either provide the value, or null if the
+	// parameter has no default value.
 	for _, param := range callable.GetInParams().List {
+		if _, ok := args[param.GetId()]; !ok && param.Default != nil {
+			continue
+		}
 		binding := syntax.BindStm{
 			Id:    param.GetId(),
 			Tname: param.GetTname(),
@@ -54,6 +54,15 @@ func ExampleBuildCallSource() {
 						Tname: syntax.TypeId{Tname: syntax.KindMap},
 						Id:    "input3",
 					},
+					{
+						Tname:   syntax.TypeId{Tname: syntax.KindInt},
+						Id:      "input4",
+						Default: &syntax.IntExp{Value: 4},
+					},
+					{
+						Tname: syntax.TypeId{Tname: syntax.KindInt},
+						Id:    "input5",
+					},
 				},
 			},
 		},
@@ -72,6 +81,7 @@ func ExampleBuildCallSource() {
 	//     input3 = {
 	//         "foo": "bar",
 	//     },
+	//     input5 = null,
 	// )
 }
 
@@ -81,6 +81,10 @@ type Chunk struct {
 	fqname     string
 	index      int
 	hasBeenRun bool
+
+	// The last progress message printed, to avoid repeating it if the
+	// update is received both from the journal and the status server.
+	lastProgress string
 }
 
 // Exportable information about a Chunk object.
@@ -251,9 +255,12 @@ func (self *Chunk) updateState(state MetadataFileName, uniquifier string) {
 	if state == ProgressFile {
 		self.fork.lastPrint = time.Now()
 		if msg, err := self.metadata.readRawSafe(state); err == nil {
-			util.PrintInfo("runtime",
-				"(progress)        %s: %s",
-				self.fqname, msg)
+			if msg != self.lastProgress {
+				self.lastProgress = msg
+				util.PrintInfo("runtime",
+					"(progress)        %s: %s",
+					self.fqname, msg)
+			}
 		} else {
 			util.LogError(err, "progres",
 				"Error reading progress file for %s",
@@ -262,7 +269,7 @@ func (self *Chunk) updateState(state MetadataFileName, uniquifier string) {
 	}
 	if beginState == Running || beginState == Queued {
 		if st, _ := self.metadata.getState(); st != Running && st != Queued {
-			self.fork.node.top.rt.JobManager.endJob(self.metadata)
+			self.fork.node.top.rt.JobManager.EndJob(self.metadata)
 		}
 	}
 }
@@ -282,7 +289,8 @@ func (self *Chunk) step(bindings MarshalerMap) {
 	if self.chunkDef.Resources == nil {
 		self.chunkDef.Resources = &JobResources{}
 	}
-	res := self.fork.node.setChunkJobReqs(self.chunkDef.Resources)
+	res := self.fork.node.setChunkJobReqs(self.fork.computeResources(bindings),
+		self.chunkDef.Resources, self.retryMemGB())
 
 	// Resolve input argument bindings and merge in the chunk defs.
 	resolvedBindings := self.chunkDef.Merge(bindings)
@@ -313,7 +321,8 @@ func (self *Chunk) serializeState() *ChunkInfo {
 }
 
 func (self *Chunk) serializePerf() *ChunkPerfInfo {
-	res := self.fork.node.getJobReqs(self.chunkDef.Resources, STAGE_TYPE_CHUNK)
+	res := self.fork.node.getJobReqs(self.fork.resources,
+		self.chunkDef.Resources, STAGE_TYPE_CHUNK)
 	stats := self.metadata.serializePerf(res.Threads)
 	return &ChunkPerfInfo{
 		Index:      self.index,
@@ -346,6 +355,7 @@ type Fork struct {
 	stageDefs      *StageDefs
 	perfCache      *ForkPerfCache
 	lastPrint      time.Time
+	lastProgress   string
 
 	// Caches the set of strict-mode VDR-able files and the
 	// arguments which are keeping them alive.
@@ -362,10 +372,26 @@ type Fork struct {
 
 	metadatasCache []*Metadata // cache for collectMetadata
 
+	// The key for this fork in the stage result cache, if caching is
+	// enabled and the key has been computed.
+	cacheKey string
+
+	// Resources computed from the stage's resource expressions, once the
+	// inputs are available.
+	resources *JobResources
+
+	// Escalated memory requests for chunks which ran out of memory, by
+	// chunk index, saved when the stage is fully reset.
+	retryMem map[int]float64
+
 	storageLock   sync.Mutex
 	index         int
 	split_has_run bool
 	join_has_run  bool
+
+	// True if the outputs of this fork were restored from the stage
+	// result cache.
+	cacheHit bool
 }
 
 // Exportable information from a Fork object.
@@ -484,12 +510,16 @@ func (self *Fork) kill(message string) {
 
 func (self *Fork) reset() {
 	for _, chunk := range self.chunks {
-		self.node.top.rt.JobManager.endJob(chunk.metadata)
+		self.node.top.rt.JobManager.EndJob(chunk.metadata)
 	}
 	self.chunks = nil
 	self.metadatasCache = nil
 	self.split_has_run = false
 	self.join_has_run = false
+	self.cacheKey = ""
+	self.cacheHit = false
+	// Inputs may have changed, e.g. if an upstream stage was rerun.
+	self.resources = nil
 	self.split_metadata.notRunningSince = time.Time{}
 	self.split_metadata.lastRefresh = time.Time{}
 	self.join_metadata.notRunningSince = time.Time{}
@@ -504,8 +534,9 @@ func (self *Fork) resetPartial() error {
 	if err := self.join_metadata.checkedReset(); err != nil {
 		return err
 	}
+	memRetry := self.node.top.rt.memoryRetry()
 	for _, chunk := range self.chunks {
-		if err := chunk.metadata.checkedReset(); err != nil {
+		if err := chunk.resetPartial(memRetry); err != nil {
 			return err
 		}
 	}
@@ -534,7 +565,7 @@ func (metadata *Metadata) reattachJob(j JobManager) bool {
 	}
 	st, _ := metadata.getState()
 	if st == Running || st == Queued {
-		j.reattach(metadata)
+		j.Reattach(metadata)
 	}
 	return false
 }
@@ -734,6 +765,52 @@ func (self *Fork) removeEmptyFileArgs(outs LazyArgumentMap) {
 	}
 }
 
+// verifyInputs checks the resolved arguments to the stage against its input
+// parameters.  Most type errors are caught when the pipeline is compiled, but
+// for example a string value may only be found to be illegal for an enum
+// parameter at runtime.
+func (self *Fork) verifyInputs(bindings MarshalerMap) (bool, string) {
+	level := syntax.GetEnforcementLevel()
+	if level <= syntax.EnforceDisable {
+		return true, ""
+	}
+	inParams := self.node.call.Callable().GetInParams()
+	if bindings == nil || inParams == nil || len(inParams.List) == 0 {
+		return true, ""
+	}
+	args, err := bindings.ToLazyArgumentMap()
+	if err != nil {
+		util.LogError(err, "runtime",
+			"%s: Error serializing input arguments.",
+			self.fqname)
+		return true, ""
+	}
+	err, alarms := args.ValidateInputs(self.node.top.types, inParams)
+	if err != nil {
+		if level >= syntax.EnforceError {
+			return false, err.Error() + alarms
+		}
+		if alarms == "" {
+			alarms = err.Error() + "\n"
+		} else {
+			alarms = err.Error() + "\n" + alarms
+		}
+	}
+	if alarms != "" {
+		switch level {
+		case syntax.EnforceError:
+			return false, alarms
+		case syntax.EnforceAlarm:
+			return true, alarms
+		case syntax.EnforceLog:
+			util.PrintInfo("runtime",
+				"(inputs )         %s: WARNING: invalid input\n%s",
+				self.fqname, strings.TrimSpace(alarms))
+		}
+	}
+	return true, ""
+}
+
 func (self *Fork) verifyOutput(outs LazyArgumentMap) (bool, string) {
 	outparams := self.OutParams()
 	if len(outparams.List) > 0 {
@@ -914,9 +991,12 @@ func (self *Fork) updateState(state, uniquifier string) {
 	if state == string(ProgressFile) {
 		self.lastPrint = time.Now()
 		if msg, err := self.metadata.readRawSafe(MetadataFileName(state)); err == nil {
-			util.PrintInfo("runtime",
-				"(progress)        %s: %s",
-				self.fqname, msg)
+			if msg != self.lastProgress {
+				self.lastProgress = msg
+				util.PrintInfo("runtime",
+					"(progress)        %s: %s",
+					self.fqname, msg)
+			}
 		} else {
 			util.LogError(err, "progres",
 				"Error reading progress file for %s",
@@ -928,14 +1008,14 @@ func (self *Fork) updateState(state, uniquifier string) {
 			MetadataFileName(strings.TrimPrefix(state, SplitPrefix)),
 			uniquifier)
 		if st, _ := self.split_metadata.getState(); st != Running && st != Queued {
-			self.node.top.rt.JobManager.endJob(self.split_metadata)
+			self.node.top.rt.JobManager.EndJob(self.split_metadata)
 		}
 	} else if strings.HasPrefix(state, JoinPrefix) {
 		self.join_metadata.cache(
 			MetadataFileName(strings.TrimPrefix(state, JoinPrefix)),
 			uniquifier)
 		if st, _ := self.join_metadata.getState(); st != Running && st != Queued {
-			self.node.top.rt.JobManager.endJob(self.join_metadata)
+			self.node.top.rt.JobManager.EndJob(self.join_metadata)
 		}
 	} else {
 		self.metadata.cache(MetadataFileName(state), uniquifier)
@@ -1014,16 +1094,31 @@ func (self *Fork) doSplit(getBindings func() MarshalerMap) MetadataState {
 		return Failed
 	}
 	self.writeInvocation()
-	if err := self.split_metadata.Write(ArgsFile, getBindings()); err != nil {
+	bindings := getBindings()
+	if err := self.split_metadata.Write(ArgsFile, bindings); err != nil {
 		util.LogError(err, "runtime",
 			"%s: Error writing args file.",
 			self.fqname)
 	}
+	if ok, msg := self.verifyInputs(bindings); !ok {
+		self.metadata.WriteErrorString(msg)
+		return Failed
+	} else if msg != "" {
+		if err := self.metadata.AppendAlarm(msg); err != nil {
+			util.LogError(err, "runtime",
+				"(inputs )         %s: Could not write alarms.",
+				self.fqname)
+		}
+	}
+	if self.loadFromCache() {
+		return Complete.Prefixed(JoinPrefix)
+	}
 	if self.Split() {
 		if !self.split_has_run {
 			self.split_has_run = true
 			self.lastPrint = time.Now()
-			self.node.runSplit(self.fqname, self.split_metadata)
+			self.node.runSplit(self.computeResources(bindings),
+				self.fqname, self.split_metadata)
 		}
 	} else {
 		_ = self.split_metadata.Write(StageDefsFile, self.stageDefs)
@@ -1038,7 +1133,7 @@ func (self *Fork) doSplit(getBindings func() MarshalerMap) MetadataState {
 }
 
 func (self *Fork) doChunks(state MetadataState, getBindings func() MarshalerMap) MetadataState {
-	self.node.top.rt.JobManager.endJob(self.split_metadata)
+	self.node.top.rt.JobManager.EndJob(self.split_metadata)
 	if self.isVolatile() {
 		lockAquired := make(chan struct{}, 1)
 		go func() {
@@ -1118,8 +1213,10 @@ func (self *Fork) doJoin(state MetadataState, getBindings func() MarshalerMap) M
 	if self.stageDefs.JoinDef == nil {
 		self.stageDefs.JoinDef = &JobResources{}
 	}
-	res := self.node.setJoinJobReqs(self.stageDefs.JoinDef)
-	args, err := getBindings().ToLazyArgumentMap()
+	bindings := getBindings()
+	res := self.node.setJoinJobReqs(self.computeResources(bindings),
+		self.stageDefs.JoinDef)
+	args, err := bindings.ToLazyArgumentMap()
 	if err != nil {
 		panic(err)
 	}
@@ -1214,7 +1311,7 @@ func (self *Fork) doJoin(state MetadataState, getBindings func() MarshalerMap) M
 }
 
 func (self *Fork) doComplete() {
-	self.node.top.rt.JobManager.endJob(self.join_metadata)
+	self.node.top.rt.JobManager.EndJob(self.join_metadata)
 	var joinOut LazyArgumentMap
 	if len(self.OutParams().List) > 0 {
 		var err error
@@ -1230,7 +1327,13 @@ func (self *Fork) doComplete() {
 	} else {
 		self.metadata.WriteRaw(OutsFile, "{}")
 	}
+	ok, msg := self.verifyOutput(joinOut)
 	if self.node.top.rt.Config.VdrMode == VdrPost {
+		if ok {
+			// Save the outputs to the cache before VDR deletes any
+			// of the files they refer to.
+			self.storeToCache()
+		}
 		// Still clean up tmp, but run before we've declared
 		// the stage maybe complete.
 		func() {
@@ -1240,7 +1343,7 @@ func (self *Fork) doComplete() {
 		}()
 		self.partialVdrKill()
 	}
-	if ok, msg := self.verifyOutput(joinOut); ok {
+	if ok {
 		if msg != "" {
 			err := self.metadata.AppendAlarm("Incorrect _outs: " + msg)
 			if err != nil {
@@ -1248,6 +1351,9 @@ func (self *Fork) doComplete() {
 			}
 		}
 		self.metadata.WriteTime(CompleteFile)
+		if self.node.top.rt.Config.VdrMode != VdrPost {
+			self.storeToCache()
+		}
 		// Print alerts
 		var alarms strings.Builder
 		self.getAlarms(&alarms)
@@ -1560,13 +1666,15 @@ func (self *Fork) serializePerf(ctx context.Context) (*ForkPerfInfo, *VDRKillRep
 		}
 	}
 
-	numThreads := self.node.getJobReqs(nil, STAGE_TYPE_SPLIT).Threads
+	numThreads := self.node.getJobReqs(self.resources,
+		nil, STAGE_TYPE_SPLIT).Threads
 	splitStats := self.split_metadata.serializePerf(numThreads)
 	if splitStats != nil {
 		stats = append(stats, splitStats)
 	}
 
-	numThreads = self.node.getJobReqs(self.stageDefs.JoinDef, STAGE_TYPE_JOIN).Threads
+	numThreads = self.node.getJobReqs(self.resources,
+		self.stageDefs.JoinDef, STAGE_TYPE_JOIN).Threads
 	joinStats := self.join_metadata.serializePerf(numThreads)
 	if joinStats != nil {
 		stats = append(stats, joinStats)
@@ -0,0 +1,503 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+// Content-addressed caching of stage results across pipestances.
+
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/martian-lang/martian/martian/syntax"
+	"github.com/martian-lang/martian/martian/util"
+)
+
+// StageCache is a directory shared between pipestances which holds the
+// outputs of completed stages, keyed by a hash of the stage definition,
+// stage code path, and resolved arguments.
+//
+// Each entry is a directory named by the key, containing the stage's _outs
+// and a files directory with copies (or hard links) of its output files.
+// Entries are populated by renaming a fully-written temporary directory
+// into place, so a partially-written entry is never visible.
+type StageCache struct {
+	path string
+
+	// Memoized file content digests, keyed by path, size and mtime, so that
+	// large inputs which are shared between many stages are only read once.
+	// Directories are not memoized.
+	digests     map[fileDigestKey]string
+	digestsLock sync.Mutex
+}
+
+type fileDigestKey struct {
+	path  string
+	size  int64
+	mtime int64
+}
+
+const (
+	stageCacheFiles  = "files"
+	stageCacheTmpDir = ".tmp"
+)
+
+// NewStageCache returns a stage cache backed by the given directory,
+// creating it if required.
+func NewStageCache(p string) (*StageCache, error) {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := util.MkdirAll(path.Join(p, stageCacheTmpDir)); err != nil {
+		return nil, err
+	}
+	return &StageCache{
+		path:    p,
+		digests: make(map[fileDigestKey]string),
+	}, nil
+}
+
+// Path returns the directory backing the cache.
+func (c *StageCache) Path() string {
+	if c == nil {
+		return ""
+	}
+	return c.path
+}
+
+func (c *StageCache) entryPath(key string) string {
+	return path.Join(c.path, key[:2], key)
+}
+
+// Key computes the cache key for a stage invocation.
+//
+// The args are the content of the stage's _args file.  Absolute paths to
+// files or directories which exist are replaced by digests of their content
+// before hashing, so that a stage whose inputs were produced by an
+// identical upstream stage in another pipestance gets the same key.
+func (c *StageCache) Key(stage *syntax.Stage, src string, args []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.UseNumber()
+	var argVal interface{}
+	if err := dec.Decode(&argVal); err != nil {
+		return "", fmt.Errorf("decoding args: %w", err)
+	}
+	argVal, err := c.replaceFileNames(argVal)
+	if err != nil {
+		return "", err
+	}
+	normArgs, err := json.Marshal(argVal)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, part := range [...][]byte{
+		[]byte(syntax.FormatCallable(stage)),
+		[]byte(src),
+		normArgs,
+	} {
+		// Length-prefix each part so that the boundaries are unambiguous.
+		if _, err := io.WriteString(h, strconv.Itoa(len(part))+":"); err != nil {
+			return "", err
+		}
+		if _, err := h.Write(part); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Recursively replace strings in a decoded json value which are absolute
+// paths to existing files with a digest of the file content.
+func (c *StageCache) replaceFileNames(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return c.replaceFileName(v)
+	case []interface{}:
+		for i, elem := range v {
+			r, err := c.replaceFileNames(elem)
+			if err != nil {
+				return v, err
+			}
+			v[i] = r
+		}
+		return v, nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			r, err := c.replaceFileNames(elem)
+			if err != nil {
+				return v, err
+			}
+			if rk, err := c.replaceFileName(k); err != nil {
+				return v, err
+			} else {
+				result[rk.(string)] = r
+			}
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
+
+func (c *StageCache) replaceFileName(s string) (interface{}, error) {
+	if !filepath.IsAbs(s) {
+		return s, nil
+	}
+	digest, err := c.digest(s)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return s, err
+	}
+	return "sha256:" + digest, nil
+}
+
+// digest computes a digest for the content of the file or directory at the
+// given path.  For directories, the digest covers the names and content
+// of everything inside it.
+func (c *StageCache) digest(p string) (string, error) {
+	return c.digestPath(p, make(map[string]struct{}))
+}
+
+// digestPath computes the digest for a file or directory.  The ancestors
+// are the real paths of the directories being digested which contain p,
+// used to detect symlink cycles.
+func (c *StageCache) digestPath(p string, ancestors map[string]struct{}) (string, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		// Directory digests are not memoized, since the size and mtime of a
+		// directory do not change when a file inside it is rewritten.  The
+		// digests of the files inside are still memoized.
+		return c.digestDir(p, ancestors)
+	}
+	key := fileDigestKey{
+		path:  p,
+		size:  info.Size(),
+		mtime: info.ModTime().UnixNano(),
+	}
+	c.digestsLock.Lock()
+	d, ok := c.digests[key]
+	c.digestsLock.Unlock()
+	if ok {
+		return d, nil
+	}
+	h := sha256.New()
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+	d = hex.EncodeToString(h.Sum(nil))
+	c.digestsLock.Lock()
+	c.digests[key] = d
+	c.digestsLock.Unlock()
+	return d, nil
+}
+
+func (c *StageCache) digestDir(p string, ancestors map[string]struct{}) (string, error) {
+	real, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, ok := ancestors[real]; ok {
+		// A symlink back to a containing directory, such as current -> .
+		// Following it would never terminate, so use the link target.
+		target, err := os.Readlink(p)
+		if err != nil {
+			target = real
+		}
+		if _, err := io.WriteString(h, "symlink\x00"+target); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	ancestors[real] = struct{}{}
+	defer delete(ancestors, real)
+	names, err := util.Readdirnames(p)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sub, err := c.digestPath(path.Join(p, name), ancestors)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.WriteString(h, name+"\x00"+sub+"\n"); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// escapedJsonPath returns the given path with a trailing separator, escaped
+// the way it would appear inside a json string.
+func escapedJsonPath(p string) []byte {
+	b, err := json.Marshal(p + "/")
+	if err != nil {
+		panic(err)
+	}
+	return b[1 : len(b)-1]
+}
+
+// Load restores a cached stage result, if one is present.
+//
+// Cached files are linked (or copied, if linking fails) into filesPath, and
+// the returned outs are rewritten to point at them.  Returns nil if there
+// is no entry for the key.
+func (c *StageCache) Load(key, filesPath string) ([]byte, error) {
+	entry := c.entryPath(key)
+	outs, err := os.ReadFile(path.Join(entry, string(OutsFile)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := linkTree(path.Join(entry, stageCacheFiles), filesPath); err != nil {
+		return nil, err
+	}
+	return bytes.ReplaceAll(outs,
+		escapedJsonPath(path.Join(entry, stageCacheFiles)),
+		escapedJsonPath(filesPath)), nil
+}
+
+// Store saves the outputs of a completed stage to the cache.
+//
+// The files are the paths referenced by the outs which are inside root.
+// They are saved at the same location relative to the files directory of
+// the cache entry, and references to them in the outs are rewritten
+// accordingly.  It is not an error if the entry already exists.
+func (c *StageCache) Store(key, root string, files []string, outs []byte) error {
+	entry := c.entryPath(key)
+	if _, err := os.Stat(entry); err == nil {
+		return nil
+	}
+	tmp, err := os.MkdirTemp(path.Join(c.path, stageCacheTmpDir), key)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := util.Mkdir(path.Join(tmp, stageCacheFiles)); err != nil {
+		return err
+	}
+	for _, f := range files {
+		rel, err := filepath.Rel(root, f)
+		if err != nil {
+			return err
+		}
+		target := path.Join(tmp, stageCacheFiles, rel)
+		if err := util.MkdirAll(path.Dir(target)); err != nil {
+			return err
+		}
+		// Resolve symlinks such as the fork's files directory, so that
+		// what gets saved is the content rather than the link.
+		if real, err := filepath.EvalSymlinks(f); err == nil {
+			f = real
+		}
+		if err := linkTree(f, target); err != nil {
+			return err
+		}
+	}
+	// Rewrite the outs to refer to the final location of the cache entry.
+	outs = bytes.ReplaceAll(outs,
+		escapedJsonPath(root),
+		escapedJsonPath(path.Join(entry, stageCacheFiles)))
+	if err := os.WriteFile(path.Join(tmp, string(OutsFile)), outs, 0644); err != nil {
+		return err
+	}
+	if err := util.MkdirAll(path.Dir(entry)); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, entry); err != nil {
+		if _, serr := os.Stat(entry); serr == nil {
+			// Another pipestance got there first.
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// linkTree recreates the file or directory tree at src as dst, hard-linking
+// regular files where possible and copying them otherwise.  Files which
+// already exist at the destination are left alone.
+func linkTree(src, dst string) error {
+	return util.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == src {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := path.Join(dst, rel)
+		switch mode := info.Mode(); {
+		case mode.IsDir():
+			return util.Mkdir(target)
+		case mode&os.ModeSymlink != 0:
+			dest, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(dest, target); err != nil && !os.IsExist(err) {
+				return err
+			}
+			return nil
+		case mode.IsRegular():
+			if err := os.Link(p, target); err == nil || os.IsExist(err) {
+				return nil
+			}
+			return copyFile(p, target, mode.Perm())
+		default:
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Returns the stage result cache for this fork, and whether it applies.
+//
+// Preflight stages are never cached.
+func (self *Fork) stageCache() (*StageCache, bool) {
+	c := self.node.top.rt.stageCache
+	if c == nil || self.node.call.Call().Modifiers.Preflight {
+		return nil, false
+	}
+	return c, self.node.top.rt.overrides.GetCache(self.fqname, true)
+}
+
+// Compute the cache key for this fork from its _args file.
+func (self *Fork) computeCacheKey(c *StageCache) (string, error) {
+	args, err := self.split_metadata.readRawBytes(ArgsFile)
+	if err != nil {
+		return "", err
+	}
+	stage, ok := self.node.call.Callable().(*syntax.Stage)
+	if !ok {
+		return "", fmt.Errorf("%s is not a stage", self.fqname)
+	}
+	src := self.node.resolvedCmd
+	if src == "" {
+		src = stage.Src.Path
+	}
+	return c.Key(stage, src, args)
+}
+
+// Attempt to satisfy this fork from the stage result cache.  If there is a
+// cached result, it is written as the join outputs and the fork skips
+// directly to completion.
+func (self *Fork) loadFromCache() bool {
+	c, ok := self.stageCache()
+	if !ok {
+		return false
+	}
+	key, err := self.computeCacheKey(c)
+	if err != nil {
+		util.LogError(err, "cache  ",
+			"Could not compute cache key for %s", self.fqname)
+		return false
+	}
+	self.cacheKey = key
+	outs, err := c.Load(key, self.metadata.curFilesPath)
+	if err != nil {
+		util.LogError(err, "cache  ",
+			"Could not load cached result for %s", self.fqname)
+		return false
+	} else if outs == nil {
+		return false
+	}
+	self.cacheHit = true
+	if err := self.join_metadata.WriteRawBytes(OutsFile, outs); err != nil {
+		util.LogError(err, "cache  ",
+			"Could not write cached outs for %s", self.fqname)
+		return false
+	}
+	_ = self.split_metadata.WriteTime(CompleteFile)
+	if err := self.join_metadata.WriteTime(CompleteFile); err != nil {
+		util.LogError(err, "cache  ",
+			"Could not write completion file for %s", self.fqname)
+		return false
+	}
+	if len(self.node.forks) > 1 {
+		util.PrintInfo("cache  ", "(cached)          %s", self.fqname)
+	} else {
+		util.PrintInfo("cache  ", "(cached)          %s", self.node.GetFQName())
+	}
+	return true
+}
+
+// Save the outputs of this fork to the stage result cache.
+func (self *Fork) storeToCache() {
+	if self.cacheHit {
+		return
+	}
+	c, ok := self.stageCache()
+	if !ok {
+		return
+	}
+	key := self.cacheKey
+	if key == "" {
+		// The fork was started by a previous mrp invocation.
+		var err error
+		if key, err = self.computeCacheKey(c); err != nil {
+			util.LogError(err, "cache  ",
+				"Could not compute cache key for %s", self.fqname)
+			return
+		}
+	}
+	outs, err := self.metadata.readRawBytes(OutsFile)
+	if err != nil {
+		util.LogError(err, "cache  ",
+			"Could not read outs for %s", self.fqname)
+		return
+	}
+	var files []string
+	for _, f := range getMaybeFileNames(json.RawMessage(outs)) {
+		if pathIsInside(f, self.path) {
+			files = append(files, f)
+		}
+	}
+	if err := c.Store(key, self.path, files, outs); err != nil {
+		util.LogError(err, "cache  ",
+			"Could not save %s to the stage cache", self.fqname)
+	}
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/martian-lang/martian/martian/syntax"
+)
+
+func TestStageCacheKey(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	cache, err := NewStageCache(path.Join(dir, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		content := "same"
+		if name == "c.txt" {
+			content = "different"
+		}
+		if err := os.WriteFile(path.Join(dir, name),
+			[]byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stage := &syntax.Stage{
+		Id:        "STAGE",
+		InParams:  &syntax.InParams{},
+		OutParams: &syntax.OutParams{},
+		ChunkIns:  &syntax.InParams{},
+		ChunkOuts: &syntax.OutParams{},
+		Src: &syntax.SrcParam{
+			Lang: "py",
+			Path: "stages/stage",
+		},
+	}
+	argsFor := func(f string) []byte {
+		b, err := json.Marshal(map[string]interface{}{
+			"input":   path.Join(dir, f),
+			"threads": 4,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	keyA, err := cache.Key(stage, "/src/stage", argsFor("a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyB, err := cache.Key(stage, "/src/stage", argsFor("b.txt")); err != nil {
+		t.Error(err)
+	} else if keyA != keyB {
+		t.Error("files with identical content should have the same key")
+	}
+	if keyC, err := cache.Key(stage, "/src/stage", argsFor("c.txt")); err != nil {
+		t.Error(err)
+	} else if keyA == keyC {
+		t.Error("files with different content should have different keys")
+	}
+	if key, err := cache.Key(stage, "/src/other", argsFor("a.txt")); err != nil {
+		t.Error(err)
+	} else if keyA == key {
+		t.Error("different stage code should have different keys")
+	}
+	stage.Split = true
+	if key, err := cache.Key(stage, "/src/stage", argsFor("a.txt")); err != nil {
+		t.Error(err)
+	} else if keyA == key {
+		t.Error("different stage definitions should have different keys")
+	}
+}
+
+func TestStageCacheDigestDir(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	cache, err := NewStageCache(path.Join(dir, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := path.Join(dir, "ref")
+	if err := os.MkdirAll(path.Join(ref, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	nested := path.Join(ref, "sub", "genome.fa")
+	if err := os.WriteFile(nested, []byte("ACGT"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A symlink cycle must not recurse forever.
+	if err := os.Symlink(".", path.Join(ref, "current")); err != nil {
+		t.Fatal(err)
+	}
+	d1, err := cache.digest(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rewriting a nested file doesn't change the size or mtime of the
+	// top-level directory, but must change its digest.
+	info, err := os.Stat(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(nested, []byte("TTTTT"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(ref, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if d2, err := cache.digest(ref); err != nil {
+		t.Error(err)
+	} else if d1 == d2 {
+		t.Error("digest did not change when a nested file was rewritten")
+	}
+}
+
+func TestStageCacheStoreLoad(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	cache, err := NewStageCache(path.Join(dir, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const key = "0123456789abcdef"
+	if outs, err := cache.Load(key, path.Join(dir, "unused")); err != nil {
+		t.Error(err)
+	} else if outs != nil {
+		t.Error("expected cache miss")
+	}
+	root := path.Join(dir, "ps1")
+	src := path.Join(root, "chnk0", "files")
+	if err := os.MkdirAll(path.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path.Join(src, "sub", "out.txt"),
+		[]byte("result"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outs, err := json.Marshal(map[string]string{
+		"out":   path.Join(src, "sub", "out.txt"),
+		"input": "/elsewhere/input.txt",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []string{path.Join(src, "sub", "out.txt")}
+	if err := cache.Store(key, root, files, outs); err != nil {
+		t.Fatal(err)
+	}
+	// Storing a second time should be a no-op.
+	if err := cache.Store(key, root, files, outs); err != nil {
+		t.Error(err)
+	}
+	dest := path.Join(dir, "ps2", "files")
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := cache.Load(key, dest)
+	if err != nil {
+		t.Fatal(err)
+	} else if loaded == nil {
+		t.Fatal("expected cache hit")
+	}
+	var result map[string]string
+	if err := json.Unmarshal(loaded, &result); err != nil {
+		t.Fatal(err)
+	}
+	if p := result["out"]; p != path.Join(dest, "chnk0", "files", "sub", "out.txt") {
+		t.Errorf("expected out to be rewritten, got %s", p)
+	} else if b, err := os.ReadFile(p); err != nil {
+		t.Error(err)
+	} else if string(b) != "result" {
+		t.Errorf("incorrect file content %q", b)
+	}
+	if p := result["input"]; p != "/elsewhere/input.txt" {
+		t.Errorf("expected input to be unchanged, got %s", p)
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+//
+// Optional socket channel for jobs to report status updates directly to mrp,
+// rather than only through the journal directory.
+//
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martian-lang/martian/martian/util"
+)
+
+const (
+	// The environment variable which gives jobs the address of the status
+	// server, as network:address, e.g. unix:/tmp/mrp123/status.sock or
+	// tcp:host:port.
+	StatusAddrEnv = "MRO_STATUS_ADDR"
+
+	// The environment variable which gives jobs the token used to
+	// authenticate with the status server.  Each token is only valid for
+	// sending updates for a single job.
+	StatusKeyEnv = "MRO_STATUS_KEY"
+)
+
+const (
+	// How long to wait for a status update to be sent before giving up and
+	// falling back to the journal.
+	statusSendTimeout = 10 * time.Second
+
+	// How often to read the journal directory when a status server is
+	// running.
+	statusJournalScanInterval = 30 * time.Second
+
+	// The maximum time to wait after the job manager reports that a job which
+	// has been sending status updates is no longer running before failing it.
+	statusQueueCheckGrace = 10 * time.Second
+)
+
+// A statusMessage is sent as a single line of json.  The first message on a
+// connection must contain only the job's token, metadata path, and journal
+// name prefix.
+type statusMessage struct {
+	Auth string `json:"auth,omitempty"`
+
+	// The metadata directory of the job sending the update.
+	Path string `json:"path,omitempty"`
+
+	// The journal file name prefix for the job, e.g.
+	// STAGE.fork0.chnk0.u0123456789.  Updates are only accepted for journal
+	// names starting with this prefix.
+	Prefix string `json:"prefix,omitempty"`
+
+	// The name of the journal file which would otherwise have been written.
+	Journal string `json:"journal,omitempty"`
+}
+
+// A StatusServer receives status updates from jobs.
+type StatusServer struct {
+	listener  net.Listener
+	addr      string
+	key       []byte
+	socketDir string
+
+	closeOnce sync.Once
+	closeErr  error
+
+	mutex   sync.Mutex
+	journal []string
+
+	// The metadata paths of jobs which are currently connected.
+	connected map[string]struct{}
+}
+
+// NewStatusServer starts listening for job status updates.  The network may
+// be "unix", in which case the socket is created in a temporary directory
+// and is only accessible to jobs on the same host, or "tcp".
+//
+// Jobs authenticate with a token derived from a randomly generated key, the
+// job's metadata path, and its journal name prefix, so the key itself is
+// never given to jobs.
+func NewStatusServer(network string) (*StatusServer, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	self := &StatusServer{
+		key:       key,
+		connected: make(map[string]struct{}),
+	}
+	switch network {
+	case "unix":
+		dir, err := os.MkdirTemp("", "mrp")
+		if err != nil {
+			return nil, err
+		}
+		self.socketDir = dir
+		sock := path.Join(dir, "status.sock")
+		if self.listener, err = net.Listen("unix", sock); err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+		self.addr = "unix:" + sock
+	case "tcp":
+		listener, err := net.Listen("tcp", ":0")
+		if err != nil {
+			return nil, err
+		}
+		self.listener = listener
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "localhost"
+		}
+		_, port, _ := net.SplitHostPort(listener.Addr().String())
+		self.addr = "tcp:" + net.JoinHostPort(hostname, port)
+	default:
+		return nil, fmt.Errorf("invalid status channel network %q", network)
+	}
+	util.LogInfo("runtime", "Listening for job status updates on %s", self.addr)
+	util.RegisterSignalHandler(self)
+	go self.serve()
+	return self, nil
+}
+
+// JobEnvs returns the environment variables which the job with the given
+// metadata path and journal name prefix requires in order to send updates to
+// this server.
+func (self *StatusServer) JobEnvs(metadataPath, journalPrefix string) map[string]string {
+	return map[string]string{
+		StatusAddrEnv: self.addr,
+		StatusKeyEnv:  self.jobToken(metadataPath, journalPrefix),
+	}
+}
+
+// Returns the token which authenticates updates for the given metadata path
+// and journal name prefix.
+func (self *StatusServer) jobToken(metadataPath, journalPrefix string) string {
+	mac := hmac.New(sha256.New, self.key)
+	mac.Write([]byte(metadataPath))
+	mac.Write([]byte{0})
+	mac.Write([]byte(journalPrefix))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Close stops listening for updates and removes the socket, if any.  It is
+// safe to call more than once.
+func (self *StatusServer) Close() error {
+	util.UnregisterSignalHandler(self)
+	return self.close()
+}
+
+func (self *StatusServer) close() error {
+	self.closeOnce.Do(func() {
+		self.closeErr = self.listener.Close()
+		if self.socketDir != "" {
+			os.RemoveAll(self.socketDir)
+		}
+	})
+	return self.closeErr
+}
+
+// The signal handler's lock is held while this runs, so this must not
+// unregister the handler.
+func (self *StatusServer) HandleSignal(os.Signal) {
+	self.close()
+}
+
+func (self *StatusServer) serve() {
+	for {
+		conn, err := self.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				util.LogError(err, "runtime",
+					"Error accepting status update connection.")
+			}
+			return
+		}
+		go self.handle(conn)
+	}
+}
+
+func (self *StatusServer) handle(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	var msg statusMessage
+	if !scanner.Scan() {
+		return
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil ||
+		msg.Path == "" || msg.Prefix == "" ||
+		subtle.ConstantTimeCompare([]byte(msg.Auth),
+			[]byte(self.jobToken(msg.Path, msg.Prefix))) != 1 {
+		util.LogInfo("runtime",
+			"Rejected status update connection from %v",
+			conn.RemoteAddr())
+		return
+	}
+	jobPath, journalPrefix := msg.Path, msg.Prefix+"."
+	defer self.disconnect(jobPath)
+	for scanner.Scan() {
+		msg = statusMessage{}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			util.LogError(err, "runtime", "Invalid status update.")
+			return
+		}
+		if msg.Journal == "" || strings.ContainsRune(msg.Journal, '/') {
+			util.LogInfo("runtime", "Invalid status update journal name %q",
+				msg.Journal)
+			return
+		}
+		if msg.Path != jobPath || !strings.HasPrefix(msg.Journal, journalPrefix) {
+			util.LogInfo("runtime",
+				"Rejected status update %s for %s from job %s",
+				msg.Journal, msg.Path, jobPath)
+			return
+		}
+		self.mutex.Lock()
+		self.journal = append(self.journal, msg.Journal)
+		self.connected[jobPath] = struct{}{}
+		self.mutex.Unlock()
+	}
+}
+
+// Forget that the job with the given metadata path is connected.
+func (self *StatusServer) disconnect(p string) {
+	self.mutex.Lock()
+	delete(self.connected, p)
+	self.mutex.Unlock()
+}
+
+// Returns the journal entries received since the last call.
+func (self *StatusServer) takeJournal() []string {
+	self.mutex.Lock()
+	journal := self.journal
+	self.journal = nil
+	self.mutex.Unlock()
+	return journal
+}
+
+// Returns true if the job with the given metadata path has sent updates and
+// is still connected.
+func (self *StatusServer) hasConnected(p string) bool {
+	self.mutex.Lock()
+	_, ok := self.connected[p]
+	self.mutex.Unlock()
+	return ok
+}
+
+// A StatusClient sends job status updates to mrp.
+type StatusClient struct {
+	mutex  sync.Mutex
+	conn   net.Conn
+	failed error
+}
+
+// DialStatusServer connects to the status server configured in the
+// environment, to send updates for the job with the given metadata path and
+// journal name prefix.  Returns nil if no status server is configured.
+func DialStatusServer(metadataPath, journalPrefix string) (*StatusClient, error) {
+	addr, key := os.Getenv(StatusAddrEnv), os.Getenv(StatusKeyEnv)
+	if addr == "" || key == "" {
+		return nil, nil
+	}
+	network, address, ok := strings.Cut(addr, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid status server address %q", addr)
+	}
+	conn, err := net.DialTimeout(network, address, statusSendTimeout)
+	if err != nil {
+		return nil, err
+	}
+	self := &StatusClient{conn: conn}
+	if err := self.send(&statusMessage{
+		Auth:   key,
+		Path:   metadataPath,
+		Prefix: journalPrefix,
+	}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return self, nil
+}
+
+// Send a status update for the given journal file name.  Once sending has
+// failed, all subsequent calls will also fail, so that updates are not
+// received out of order.
+func (self *StatusClient) Send(metadataPath, journal string) error {
+	return self.send(&statusMessage{
+		Path:    metadataPath,
+		Journal: journal,
+	})
+}
+
+func (self *StatusClient) send(msg *statusMessage) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.failed != nil {
+		return self.failed
+	}
+	if err := self.conn.SetWriteDeadline(
+		time.Now().Add(statusSendTimeout)); err != nil {
+		self.failed = err
+		return err
+	}
+	if _, err := self.conn.Write(b); err != nil {
+		self.failed = err
+		self.conn.Close()
+		return err
+	}
+	return nil
+}
+
+// Close the connection.
+func (self *StatusClient) Close() error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.failed == nil {
+		self.failed = net.ErrClosed
+	}
+	return self.conn.Close()
+}
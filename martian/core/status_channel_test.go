@@ -0,0 +1,147 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package core
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestStatusChannel(t *testing.T) {
+	server, err := NewStatusServer("unix")
+	if err != nil {
+		t.Skip("could not listen on unix socket:", err)
+	}
+	defer server.Close()
+	journalDir := t.TempDir()
+	mdPath := path.Join(t.TempDir(), "chnk0-u0123")
+	const prefix = "STAGE.fork0.chnk0.u0123"
+	for k, v := range server.JobEnvs(mdPath, prefix) {
+		t.Setenv(k, v)
+	}
+
+	md := NewMetadataRunWithJournalPath(prefix,
+		mdPath, path.Join(mdPath, "files"), journalDir, "main")
+	client, err := DialStatusServer(mdPath, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	md.SetStatusClient(client)
+	if err := md.UpdateJournal(CompleteFile); err != nil {
+		t.Fatal(err)
+	}
+	var journal []string
+	for start := time.Now(); len(journal) == 0 &&
+		time.Since(start) < 10*time.Second; {
+		time.Sleep(10 * time.Millisecond)
+		journal = server.takeJournal()
+	}
+	if len(journal) != 1 || journal[0] != "STAGE.fork0.chnk0.u0123.complete" {
+		t.Errorf("incorrect journal updates %v", journal)
+	}
+	if !server.hasConnected(mdPath) {
+		t.Error("expected job to be connected")
+	}
+	if files, err := os.ReadDir(journalDir); err != nil {
+		t.Error(err)
+	} else if len(files) != 0 {
+		t.Errorf("expected no journal files, got %d", len(files))
+	}
+
+	// After the connection fails, updates go to the journal directory.
+	client.Close()
+	if err := md.UpdateJournal(Heartbeat); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path.Join(journalDir,
+		"STAGE.fork0.chnk0.u0123.heartbeat")); err != nil {
+		t.Error(err)
+	}
+	for start := time.Now(); server.hasConnected(mdPath) &&
+		time.Since(start) < 10*time.Second; {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if server.hasConnected(mdPath) {
+		t.Error("expected job to be disconnected")
+	}
+}
+
+func TestStatusChannelClose(t *testing.T) {
+	server, err := NewStatusServer("unix")
+	if err != nil {
+		t.Skip("could not listen on unix socket:", err)
+	}
+	if err := server.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(server.socketDir); !os.IsNotExist(err) {
+		t.Errorf("expected socket directory to be removed, got %v", err)
+	}
+	// Closing again is harmless.
+	if err := server.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatusChannelAuth(t *testing.T) {
+	server, err := NewStatusServer("unix")
+	if err != nil {
+		t.Skip("could not listen on unix socket:", err)
+	}
+	defer server.Close()
+	const prefix = "STAGE.fork0.chnk0.u0123"
+	check := func(t *testing.T, dialPath, dialPrefix, sendPath, journal string) {
+		t.Helper()
+		client, err := DialStatusServer(dialPath, dialPrefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		// The server will close the connection after rejecting it, but the
+		// first send may still succeed.
+		for i := 0; i < 50; i++ {
+			if client.Send(sendPath, journal) != nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if journal := server.takeJournal(); len(journal) != 0 {
+			t.Errorf("expected no updates, got %v", journal)
+		}
+	}
+	t.Run("wrong key", func(t *testing.T) {
+		t.Setenv(StatusAddrEnv, server.JobEnvs("p", prefix)[StatusAddrEnv])
+		t.Setenv(StatusKeyEnv, "wrong")
+		check(t, "p", prefix, "p", prefix+".complete")
+	})
+	t.Run("other job", func(t *testing.T) {
+		// A token for one job can't be used to connect as another job.
+		for k, v := range server.JobEnvs("p", prefix) {
+			t.Setenv(k, v)
+		}
+		check(t, "q", prefix, "q", prefix+".complete")
+		check(t, "p", "STAGE.fork0.chnk1.u0123",
+			"p", "STAGE.fork0.chnk1.u0123.complete")
+	})
+	t.Run("other path", func(t *testing.T) {
+		// Nor to send updates for another job.
+		for k, v := range server.JobEnvs("p", prefix) {
+			t.Setenv(k, v)
+		}
+		check(t, "p", prefix, "q", prefix+".complete")
+	})
+	t.Run("other journal", func(t *testing.T) {
+		// Nor to write journal entries for another job, even with its own
+		// metadata path.
+		for k, v := range server.JobEnvs("p", prefix) {
+			t.Setenv(k, v)
+		}
+		check(t, "p", prefix, "p", "OTHER.fork0.chnk0.u0123.complete")
+		check(t, "p", prefix, "p", "STAGE.fork1.chnk0.u0123.complete")
+		check(t, "p", prefix, "p", "STAGE.fork0.chnk0.u0124.complete")
+		check(t, "p", prefix, "p", prefix+"5.complete")
+	})
+}
@@ -295,6 +295,7 @@ func (self *Fork) vdrKillSome(partial *PartialVdrKillReport, done bool) (*VDRKil
 		}
 		delete(self.fileParamMap, fpath)
 	}
+	countVdrBytesFreed(-event.DeltaBytes)
 	event.Timestamp = time.Now()
 	partial.Timestamp = WallClockTime(event.Timestamp)
 
@@ -988,6 +989,7 @@ func (self *Fork) vdrKill(partialKill *PartialVdrKillReport) *VDRKillReport {
 	for _, p := range killPaths {
 		os.RemoveAll(p)
 	}
+	countVdrBytesFreed(int64(killReport.Size))
 	// update timestamp to mark actual kill time
 	killReport.Timestamp = WallClockTime(time.Now())
 	if killReport.Size > 0 {
@@ -1060,6 +1062,19 @@ func (self *Node) vdrKill() (*VDRKillReport, bool) {
 	return mergeVDRKillReports(killReports), allDone
 }
 
+// Returns true if any files from this node have been removed by VDR.
+func (self *Node) vdrKilled() bool {
+	for _, fork := range self.forks {
+		if report, ok := fork.getVdrKillReport(); ok && report.Count > 0 {
+			return true
+		} else if partial := fork.getPartialKillReport(); partial != nil &&
+			partial.Count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 type StorageEvent struct {
 	Timestamp time.Time
 	Name      string
@@ -31,6 +31,9 @@ type (
 		FileName     string
 		FullPath     string
 		IncludedFrom []*SourceLoc
+
+		// If set, the namespace under which the file was included.
+		namespace string
 	}
 
 	AstNodable interface {
@@ -64,6 +67,10 @@ type (
 	Include struct {
 		Node  AstNode
 		Value string
+
+		// If set, the callables and types declared in the included file
+		// are only visible as As.NAME in the including file.
+		As string
 	}
 
 	// Comments are also not, strictly speaking, part of the AST, but for
@@ -80,6 +87,9 @@ type (
 		// All struct types found in the source.
 		StructTypes []*StructType
 
+		// All enumerated string types found in the source.
+		EnumTypes []*EnumType
+
 		// All valid types, both user-defined and builtin.
 		TypeTable TypeLookup
 
@@ -110,6 +120,8 @@ func NewAst(decs []Dec, call *CallStm, srcFile *SourceFile) *Ast {
 			self.UserTypes = append(self.UserTypes, dec)
 		case *StructType:
 			self.StructTypes = append(self.StructTypes, dec)
+		case *EnumType:
+			self.EnumTypes = append(self.EnumTypes, dec)
 		case *Stage:
 			self.Stages = append(self.Stages, dec)
 			self.Callables.List = append(self.Callables.List, dec)
@@ -145,6 +157,7 @@ func (s *Ast) getSubnodes() []AstNodable {
 	subs := make([]AstNodable, 0,
 		1+len(s.UserTypes)+
 			len(s.StructTypes)+
+			len(s.EnumTypes)+
 			len(s.Callables.List)+
 			len(s.Includes))
 	for _, n := range s.Includes {
@@ -153,6 +166,9 @@ func (s *Ast) getSubnodes() []AstNodable {
 	for _, n := range s.UserTypes {
 		subs = append(subs, n)
 	}
+	for _, n := range s.EnumTypes {
+		subs = append(subs, n)
+	}
 	for _, n := range s.StructTypes {
 		subs = append(subs, n)
 	}
@@ -180,6 +196,7 @@ func (s *Include) Line() int                 { return s.Node.Loc.Line }
 func (ast *Ast) merge(other *Ast) error {
 	ast.UserTypes = append(other.UserTypes, ast.UserTypes...)
 	ast.StructTypes = append(other.StructTypes, ast.StructTypes...)
+	ast.EnumTypes = append(other.EnumTypes, ast.EnumTypes...)
 	ast.Stages = append(other.Stages, ast.Stages...)
 	ast.Pipelines = append(other.Pipelines, ast.Pipelines...)
 	if ast.Call == nil {
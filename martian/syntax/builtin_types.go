@@ -76,6 +76,14 @@ func (s *BuiltinType) IsAssignableFrom(other Type, _ *TypeLookup) error {
 			Message: fmt.Sprintf("%s cannot be assigned to %s",
 				other.Id, s.Id),
 		}
+	case *EnumType:
+		if s.Id == KindString {
+			return nil
+		}
+		return &IncompatibleTypeError{
+			Message: fmt.Sprintf("enum type %s cannot be assigned to %s",
+				other.Id, s.Id),
+		}
 	case *StructType:
 		if s.Id == KindMap {
 			return nil
@@ -139,6 +147,16 @@ func (s *BuiltinType) IsValidExpression(exp Exp, pipeline *Pipeline, ast *Ast) e
 		return isValidSplit(s, exp, pipeline, ast)
 	case *DisabledExp:
 		return s.IsValidExpression(exp.Value, pipeline, ast)
+	case *CondExp:
+		return isValidCond(s, exp, pipeline, ast)
+	case *FuncExp:
+		if s.Id == KindString || s.Id == KindFile || s.Id == KindPath ||
+			isNumericFunc(exp.Func) {
+			return isValidFunc(exp, pipeline, ast)
+		}
+		return &IncompatibleTypeError{
+			Message: fmt.Sprintf("cannot assign %s result to %s", exp.Func, s.Id),
+		}
 	case *NullExp:
 		return nil
 	case *StringExp:
@@ -4,6 +4,8 @@
 
 package syntax
 
+import "strings"
+
 type (
 	// A CallStm contains information about the invocation of a callable
 	// object.  These declarations may exist in pipeline or as the top
@@ -16,7 +18,9 @@ type (
 		Id string
 
 		// The name of the callable object being called.  This will
-		// be the same as Id unless the call is aliased.
+		// be the same as Id unless the call is aliased or the callable
+		// is in a namespace, in which case Id is the name without the
+		// namespace.
 		DecId string
 
 		// The set of bindings for the input arguments of the callable.
@@ -49,6 +53,22 @@ type (
 	}
 )
 
+// LocalName returns the name of a callable or type without the namespace
+// prefix, if any, from an include of the form
+//
+//	@include "lib.mro" as lib
+//
+// For example, LocalName("lib.STAGE") returns "STAGE".
+func LocalName(id string) string {
+	return id[strings.LastIndexByte(id, '.')+1:]
+}
+
+// IsAliased returns true if the call was given an explicit name with
+// "as".
+func (s *CallStm) IsAliased() bool {
+	return s.Id != LocalName(s.DecId)
+}
+
 func (s *CallStm) getNode() *AstNode { return &s.Node }
 func (s *CallStm) File() *SourceFile { return s.Node.Loc.File }
 func (s *CallStm) Line() int         { return s.Node.Loc.Line }
@@ -64,8 +84,8 @@ func (s *CallStm) getSubnodes() []AstNodable {
 }
 
 func (s *CallStm) GoString() string {
-	if s.DecId != s.Id {
+	if s.IsAliased() {
 		return s.DecId + " as " + s.Id
 	}
-	return s.Id
+	return s.DecId
 }
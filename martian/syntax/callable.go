@@ -67,6 +67,7 @@ type (
 		MemNode      *AstNode
 		VMemNode     *AstNode
 		SpecialNode  *AstNode
+		TimeoutNode  *AstNode
 		VolatileNode *AstNode
 
 		Special        string
@@ -74,6 +75,16 @@ type (
 		MemGB          float32
 		VMemGB         float32
 		StrictVolatile bool
+
+		// The wall-clock time limit for each job, in seconds.
+		Timeout int64
+
+		// Expressions for resources which are computed from the stage's
+		// inputs at runtime.  If set, the corresponding static value is
+		// zero.
+		ThreadsExp Exp
+		MemGBExp   Exp
+		VMemGBExp  Exp
 	}
 
 	Pipeline struct {
@@ -169,6 +180,7 @@ func (s *Resources) getSubnodes() []AstNodable {
 		s.MemNode,
 		s.SpecialNode,
 		s.ThreadNode,
+		s.TimeoutNode,
 		s.VMemNode,
 		s.VolatileNode,
 	}
@@ -106,6 +106,8 @@ func (s *ArrayType) IsValidExpression(exp Exp, pipeline *Pipeline, ast *Ast) err
 		return isValidSplit(s, exp, pipeline, ast)
 	case *DisabledExp:
 		return s.IsValidExpression(exp.Value, pipeline, ast)
+	case *CondExp:
+		return isValidCond(s, exp, pipeline, ast)
 	case *NullExp:
 		return nil
 	case *ArrayExp:
@@ -402,6 +404,8 @@ func (s *TypedMapType) IsValidExpression(exp Exp, pipeline *Pipeline, ast *Ast)
 		return isValidSplit(s, exp, pipeline, ast)
 	case *DisabledExp:
 		return s.IsValidExpression(exp.Value, pipeline, ast)
+	case *CondExp:
+		return isValidCond(s, exp, pipeline, ast)
 	case *NullExp:
 		return nil
 	case *MapExp:
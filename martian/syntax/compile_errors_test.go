@@ -4,6 +4,7 @@ package syntax
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -1861,3 +1862,113 @@ return(
 )
 }`, "must be a reference to a struct")
 }
+
+func TestCondExpBadBranch(t *testing.T) {
+	t.Parallel()
+	testBadCompile(t, `
+pipeline PICK(
+    in  bool   flag,
+    in  string name,
+    out int    value,
+)
+{
+    return (
+        value = self.flag ? 1 : self.name,
+    )
+}
+`, "false branch")
+	testBadCompile(t, `
+pipeline PICK(
+    in  int flag,
+    out int value,
+)
+{
+    return (
+        value = self.flag ? 1 : 2,
+    )
+}
+`, "condition self.flag")
+	testBadCompile(t, `
+stage PRODUCER(
+    out int value,
+    src py  "stages/producer",
+)
+
+pipeline PICK(
+    in  bool flag,
+    out int  value,
+)
+{
+    call PRODUCER()
+
+    return (
+        value = self.flag ? PRODUCER.missing : 2,
+    )
+}
+`, "missing")
+}
+
+func TestBadInParamDefault(t *testing.T) {
+	t.Parallel()
+	testBadCompile(t, `
+stage SQUARE(
+    in  int value = "four",
+    src py  "stages/square",
+)
+`, "TypeMismatchError: default value \"four\" for parameter value")
+	testBadCompile(t, `
+pipeline SQ_PIPE(
+    in  int   value,
+    in  int[] values = [self.value],
+)
+{
+    return ()
+}
+`, "default value for parameter 'values' cannot contain references")
+}
+
+func TestFuncExpErrors(t *testing.T) {
+	t.Parallel()
+	const src = `
+stage PRODUCE(
+    out int[] values,
+    out map   meta,
+    src py    "stages/produce",
+)
+
+stage USE(
+    in  string name,
+    in  int    count,
+    in  path   dir,
+    src py     "stages/use",
+)
+
+pipeline PIPE(
+    in  string name,
+)
+{
+    call PRODUCE()
+
+    call USE(
+        name  = self.name,
+        count = 1,
+        dir   = "/tmp",
+    )
+
+    return ()
+}
+`
+	for _, c := range [...]struct {
+		old, new, expect string
+	}{
+		{`count = 1,`, `count = f"1",`, "cannot assign interpolate result to int"},
+		{`name  = self.name,`, `name  = f"{PRODUCE.values}",`, "binding is an array"},
+		{`name  = self.name,`, `name  = f"{PRODUCE.meta}",`, "cannot use map in interpolate"},
+		{`name  = self.name,`, `name  = f"{PRODUCE.missing}",`, "missing"},
+		{`dir   = "/tmp",`, `dir   = path_join(self.name),`, "path_join requires at least 2 arguments"},
+		{`dir   = "/tmp",`, `dir   = path_join(self.name, 1),`, "cannot use int in path_join"},
+		{`dir   = "/tmp",`, `dir   = path_concat(self.name, "a"),`, "unknown function path_concat"},
+	} {
+		testBadCompile(t, strings.Replace(src, c.old, c.new, 1), c.expect)
+	}
+}
@@ -32,11 +32,42 @@ func (params *InParams) compile(global *Ast) error {
 				param.GetTname().Tname))
 		} else {
 			param.setIsFile(t.IsFile())
+			if param.Default != nil {
+				if err := param.compileDefault(global, t); err != nil {
+					errs = append(errs, err)
+				}
+			}
 		}
 	}
 	return errs.If()
 }
 
+// compileDefault checks that the default value for a parameter is a valid
+// constant of the parameter's type.
+func (param *InParam) compileDefault(global *Ast, t Type) error {
+	if param.Default.HasRef() {
+		return global.err(param.Default,
+			"BindingError: default value for parameter '%s' cannot contain references",
+			param.Id)
+	}
+	if param.NonNull && mayBeNull(param.Default) {
+		return global.err(param.Default,
+			"NullBindingError: default value for non-null parameter '%s' cannot be null",
+			param.Id)
+	}
+	if err := t.IsValidExpression(param.Default, nil, global); err != nil {
+		return &wrapError{
+			innerError: &IncompatibleTypeError{
+				Message: "TypeMismatchError: default value " +
+					param.Default.GoString() + " for parameter " + param.Id,
+				Reason: err,
+			},
+			loc: param.Default.getNode().Loc,
+		}
+	}
+	return nil
+}
+
 // IsLegalUnixFilename returns nil for legal file names, or an error
 // describing the reason why the file name is illegal.
 func IsLegalUnixFilename(name string) error {
@@ -70,7 +101,7 @@ func (param *OutParam) compile(global *Ast) error {
 		// Cache if param is file or path.
 		param.setIsFile(t.IsFile())
 		switch t.(type) {
-		case *BuiltinType, *UserType:
+		case *BuiltinType, *UserType, *EnumType:
 			param.isComplex = false
 		default:
 			param.isComplex = true
@@ -298,9 +329,10 @@ func (bindings *BindStms) compile(global *Ast, pipeline *Pipeline, params *InPar
 			"No parameters to bind")
 	}
 	errs := bindings.compileGeneric(global, pipeline, params)
-	// Check that all input params of the called segment are bound.
+	// Check that all input params of the called segment without a default
+	// value are bound.
 	for _, param := range params.List {
-		if _, ok := bindings.Table[param.GetId()]; !ok {
+		if _, ok := bindings.Table[param.GetId()]; !ok && param.Default == nil {
 			errs = append(errs, global.err(bindings,
 				"ArgumentNotSuppliedError: no argument supplied for parameter '%s'",
 				param.GetId()))
@@ -460,6 +492,18 @@ func (binding *BindStm) rewriteToDefaultOutput(global *Ast,
 	return false
 }
 
+// mayBeNull returns true if the expression is null, or is a conditional
+// expression for which either branch may be null.
+func mayBeNull(exp Exp) bool {
+	switch exp := exp.(type) {
+	case *NullExp:
+		return true
+	case *CondExp:
+		return mayBeNull(exp.Then) || mayBeNull(exp.Else)
+	}
+	return false
+}
+
 func (binding *BindStm) compileParam(global *Ast, pipeline *Pipeline, param Param) error {
 	binding.Tname = param.GetTname()
 	t := global.TypeTable.Get(binding.Tname)
@@ -468,6 +512,18 @@ func (binding *BindStm) compileParam(global *Ast, pipeline *Pipeline, param Para
 			"BindingError: invalid type %q for parameter %q",
 			binding.Tname, binding.Id))
 	}
+	if param.IsNonNull() {
+		if _, ok := binding.Exp.(*NullExp); ok {
+			return global.err(binding,
+				"NullBindingError: cannot bind null to non-null parameter '%s'",
+				binding.Id)
+		} else if mayBeNull(binding.Exp) {
+			return global.err(binding,
+				"NullBindingError: cannot bind a value which may be null "+
+					"to non-null parameter '%s'",
+				binding.Id)
+		}
+	}
 	if err := t.IsValidExpression(binding.Exp, pipeline, global); err != nil {
 		if !binding.rewriteToDefaultOutput(global, pipeline, t) {
 			return &wrapError{
@@ -530,6 +586,15 @@ func getBoundParamIds(exp Exp, arr []string) []string {
 		return arr
 	case *SplitExp:
 		return getBoundParamIds(exp.Value, arr)
+	case *CondExp:
+		arr = getBoundParamIds(exp.Condition, arr)
+		arr = getBoundParamIds(exp.Then, arr)
+		return getBoundParamIds(exp.Else, arr)
+	case *FuncExp:
+		for _, arg := range exp.Args {
+			arr = getBoundParamIds(arg, arr)
+		}
+		return arr
 	}
 	return arr
 }
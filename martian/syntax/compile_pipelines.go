@@ -63,6 +63,22 @@ func (pipeline *Pipeline) directDepsMap() (map[*CallStm]map[*CallStm]struct{}, e
 			return errs.If()
 		case *SplitExp:
 			return findDeps(src, exp.Value)
+		case *CondExp:
+			var errs ErrorList
+			for _, subExp := range [...]Exp{exp.Condition, exp.Then, exp.Else} {
+				if err := findDeps(src, subExp); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			return errs.If()
+		case *FuncExp:
+			var errs ErrorList
+			for _, subExp := range exp.Args {
+				if err := findDeps(src, subExp); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			return errs.If()
 		}
 		return nil
 	}
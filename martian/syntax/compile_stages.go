@@ -74,12 +74,125 @@ func (stage *Stage) compile(global *Ast) error {
 			errs = append(errs, err)
 		}
 	}
+	if stage.Resources != nil {
+		if err := stage.Resources.compile(global, stage); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	if err := stage.Src.compile(global); err != nil {
 		errs = append(errs, err)
 	}
 	return errs.If()
 }
 
+// Check that resource expressions are numeric and only refer to the stage's
+// input parameters.
+func (res *Resources) compile(global *Ast, stage *Stage) error {
+	var errs ErrorList
+	for _, exp := range [...]Exp{res.ThreadsExp, res.MemGBExp, res.VMemGBExp} {
+		if exp != nil {
+			if err := stage.compileResourceExp(global, exp); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs.If()
+}
+
+func (stage *Stage) compileResourceExp(global *Ast, exp Exp) error {
+	switch exp := exp.(type) {
+	case *IntExp, *FloatExp:
+		return nil
+	case *RefExp:
+		t, err := stage.resourceRefType(global, exp)
+		if err != nil {
+			return err
+		}
+		if t.ArrayDim == 0 && t.MapDim == 0 &&
+			(t.Tname == KindInt || t.Tname == KindFloat) {
+			return nil
+		}
+		return global.err(exp,
+			"ResourceError: %s has type %s, which is not a number",
+			exp.GoString(), t.str())
+	case *FuncExp:
+		var errs ErrorList
+		switch exp.Func {
+		case FuncAdd, FuncSub, FuncMul, FuncDiv:
+			if len(exp.Args) != 2 {
+				errs = append(errs, global.err(exp,
+					"ResourceError: %s requires 2 arguments",
+					exp.Func))
+			}
+		case FuncMin, FuncMax:
+			if len(exp.Args) < 2 {
+				errs = append(errs, global.err(exp,
+					"ResourceError: %s requires at least 2 arguments",
+					exp.Func))
+			}
+		case FuncFileSize:
+			if len(exp.Args) != 1 {
+				return global.err(exp,
+					"ResourceError: %s requires 1 argument",
+					exp.Func)
+			}
+			ref, ok := exp.Args[0].(*RefExp)
+			if !ok {
+				return global.err(exp,
+					"ResourceError: the argument to %s must be an input parameter",
+					exp.Func)
+			}
+			t, err := stage.resourceRefType(global, ref)
+			if err != nil {
+				return err
+			}
+			if tt := global.TypeTable.Get(t); tt == nil ||
+				tt.IsFile() < KindIsFile {
+				return global.err(exp,
+					"ResourceError: %s has type %s, which does not contain files",
+					ref.GoString(), t.str())
+			}
+			return nil
+		default:
+			return global.err(exp,
+				"ResourceError: cannot use %s in stage resources",
+				exp.Func)
+		}
+		for _, arg := range exp.Args {
+			if err := stage.compileResourceExp(global, arg); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errs.If()
+	}
+	return global.err(exp,
+		"ResourceError: cannot use %s in stage resources",
+		exp.getKind())
+}
+
+// resourceRefType returns the type of a reference to an input parameter of
+// the stage.
+func (stage *Stage) resourceRefType(global *Ast, exp *RefExp) (TypeId, error) {
+	if exp.Kind != KindSelf {
+		return TypeId{}, global.err(exp,
+			"ResourceError: stage resources may only refer to input parameters")
+	}
+	param := stage.InParams.Table[exp.Id]
+	if param == nil {
+		return TypeId{}, global.err(exp,
+			"ScopeNameError: '%s' is not an input parameter of stage '%s'",
+			exp.Id, stage.Id)
+	}
+	t, err := fieldType(param.GetTname(), &global.TypeTable, exp.OutputId)
+	if err != nil {
+		return t, &wrapError{
+			innerError: err,
+			loc:        exp.Node.Loc,
+		}
+	}
+	return t, nil
+}
+
 func (src *SrcParam) compile(global *Ast) error {
 	var errs ErrorList
 	if strings.ContainsAny(src.cmd, `"'`) {
@@ -13,16 +13,25 @@ import (
 // builtins.
 //
 // Duplicate declarations are allowed for user-defined file types.
-// For struct types and callables, duplicates are allowed (at this stage) if
+// For enum types, struct types and callables, duplicates are allowed (at this stage) if
 // and only if they are functionally identical.
 func (global *Ast) CompileTypes() error {
 	var errs ErrorList
-	global.TypeTable.init(len(global.UserTypes) + len(global.StructTypes) + len(global.Callables.List))
+	global.TypeTable.init(len(global.UserTypes) + len(global.EnumTypes) +
+		len(global.StructTypes) + len(global.Callables.List))
 	for _, userType := range global.UserTypes {
 		if err := global.TypeTable.AddUserType(userType); err != nil {
 			errs = append(errs, err)
 		}
 	}
+	for _, enumType := range global.EnumTypes {
+		if err := enumType.compile(global); err != nil {
+			errs = append(errs, err)
+		}
+		if err := global.TypeTable.AddEnumType(enumType); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	for _, structType := range global.StructTypes {
 		if err := structType.compile(global); err != nil {
 			errs = append(errs, err)
@@ -86,7 +95,7 @@ func (st *StructType) compile(global *Ast) error {
 
 func (member *StructMember) CacheIsFile(t Type) {
 	switch t.(type) {
-	case *BuiltinType, *UserType:
+	case *BuiltinType, *UserType, *EnumType:
 		member.isComplex = false
 	default:
 		member.isComplex = true
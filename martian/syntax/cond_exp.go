@@ -0,0 +1,272 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package syntax
+
+import (
+	"bytes"
+)
+
+// CondExp is an expression which evaluates to either the Then or the Else
+// expression, depending on the value of the Condition.
+//
+// In mro, this is written as
+//
+//	self.flag ? "value if true" : "value if false"
+//
+// Only the selected branch is evaluated, so the other branch may refer to
+// the output of a call which was disabled.
+type CondExp struct {
+	Node AstNode
+
+	// The boolean-valued expression which selects the branch.  A null
+	// condition selects the Else branch.
+	Condition Exp
+
+	// The value of this expression if the Condition is true.
+	Then Exp
+
+	// The value of this expression if the Condition is false or null.
+	Else Exp
+}
+
+func (s *CondExp) getNode() *AstNode {
+	return &s.Node
+}
+func (s *CondExp) File() *SourceFile {
+	return s.Node.Loc.File
+}
+func (s *CondExp) Line() int {
+	return s.Node.Loc.Line
+}
+func (s *CondExp) inheritComments() bool {
+	return false
+}
+func (s *CondExp) getSubnodes() []AstNodable {
+	return []AstNodable{s.Condition, s.Then, s.Else}
+}
+func (s *CondExp) HasRef() bool {
+	return s.Condition.HasRef() || s.Then.HasRef() || s.Else.HasRef()
+}
+func (s *CondExp) HasSplit() bool {
+	return s.Condition.HasSplit() || s.Then.HasSplit() || s.Else.HasSplit()
+}
+func (s *CondExp) FindRefs() []*RefExp {
+	refs := s.Condition.FindRefs()
+	refs = append(refs, s.Then.FindRefs()...)
+	return append(refs, s.Else.FindRefs()...)
+}
+func (s *CondExp) getKind() ExpKind {
+	return KindCond
+}
+
+func (s *CondExp) FindTypedRefs(list []*BoundReference,
+	t Type, lookup *TypeLookup) ([]*BoundReference, error) {
+	list, err := s.Condition.FindTypedRefs(list, &builtinBool, lookup)
+	if err != nil {
+		return list, err
+	}
+	list, err = s.Then.FindTypedRefs(list, t, lookup)
+	if err != nil {
+		return list, err
+	}
+	return s.Else.FindTypedRefs(list, t, lookup)
+}
+
+// selectBranch returns the branch selected by the given condition, or nil
+// if the condition cannot be evaluated yet.
+func selectBranch(cond, then, els Exp) Exp {
+	switch cond := cond.(type) {
+	case *BoolExp:
+		if cond.Value {
+			return then
+		}
+		return els
+	case *NullExp:
+		return els
+	}
+	return nil
+}
+
+func (s *CondExp) BindingPath(bindPath string,
+	forks map[*CallStm]CollectionIndex,
+	lookup *TypeLookup) (Exp, error) {
+	cond, err := s.Condition.BindingPath("", forks, lookup)
+	if err != nil {
+		return s, err
+	}
+	if branch := selectBranch(cond, s.Then, s.Else); branch != nil {
+		return branch.BindingPath(bindPath, forks, lookup)
+	}
+	then, err := s.Then.BindingPath(bindPath, forks, lookup)
+	if err != nil {
+		return s, err
+	}
+	els, err := s.Else.BindingPath(bindPath, forks, lookup)
+	if err != nil {
+		return s, err
+	}
+	return s.makeCondExp(cond, then, els), nil
+}
+
+func (s *CondExp) resolveRefs(self, siblings map[string]*ResolvedBinding,
+	lookup *TypeLookup) (Exp, error) {
+	cond, err := s.Condition.resolveRefs(self, siblings, lookup)
+	if err != nil {
+		return s, &bindingError{
+			Msg: "condition",
+			Err: err,
+		}
+	}
+	// Avoid resolving the branch which won't be used, if possible.
+	if branch := selectBranch(cond, s.Then, s.Else); branch != nil {
+		return branch.resolveRefs(self, siblings, lookup)
+	}
+	then, err := s.Then.resolveRefs(self, siblings, lookup)
+	if err != nil {
+		return s, &bindingError{
+			Msg: "true branch",
+			Err: err,
+		}
+	}
+	els, err := s.Else.resolveRefs(self, siblings, lookup)
+	if err != nil {
+		return s, &bindingError{
+			Msg: "false branch",
+			Err: err,
+		}
+	}
+	return s.makeCondExp(cond, then, els), nil
+}
+
+func (s *CondExp) filter(t Type, lookup *TypeLookup) (Exp, error) {
+	then, err := s.Then.filter(t, lookup)
+	if err != nil {
+		return s, err
+	}
+	els, err := s.Else.filter(t, lookup)
+	if err != nil {
+		return s, err
+	}
+	return s.makeCondExp(s.Condition, then, els), nil
+}
+
+// makeCondExp returns a conditional expression with the given components,
+// reusing s if nothing changed.
+func (s *CondExp) makeCondExp(cond, then, els Exp) Exp {
+	if branch := selectBranch(cond, then, els); branch != nil {
+		return branch
+	}
+	if then.equal(els) == nil {
+		// The condition doesn't matter.
+		return then
+	}
+	if cond == s.Condition && then == s.Then && els == s.Else {
+		return s
+	}
+	return &CondExp{
+		Node:      s.Node,
+		Condition: cond,
+		Then:      then,
+		Else:      els,
+	}
+}
+
+func (s *CondExp) EncodeJSON(buf *bytes.Buffer) error {
+	if _, err := buf.WriteString(`{"__cond__":`); err != nil {
+		return err
+	}
+	if err := s.Condition.EncodeJSON(buf); err != nil {
+		return err
+	}
+	if _, err := buf.WriteString(`,"then":`); err != nil {
+		return err
+	}
+	if err := s.Then.EncodeJSON(buf); err != nil {
+		return err
+	}
+	if _, err := buf.WriteString(`,"else":`); err != nil {
+		return err
+	}
+	if err := s.Else.EncodeJSON(buf); err != nil {
+		return err
+	}
+	return buf.WriteByte('}')
+}
+
+func (s *CondExp) jsonSizeEstimate() int {
+	return s.Condition.jsonSizeEstimate() +
+		s.Then.jsonSizeEstimate() +
+		s.Else.jsonSizeEstimate() +
+		len(`{"__cond__":,"then":,"else":}`)
+}
+
+func (s *CondExp) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	buf.Grow(s.jsonSizeEstimate())
+	err := s.EncodeJSON(&buf)
+	return buf.Bytes(), err
+}
+
+func (s *CondExp) GoString() string {
+	if s == nil {
+		return KindNull
+	}
+	return s.Condition.GoString() + " ? " +
+		s.Then.GoString() + " : " +
+		s.Else.GoString()
+}
+
+func (s *CondExp) String() string {
+	return s.GoString()
+}
+
+func (s *CondExp) format(w stringWriter, prefix string) {
+	s.Condition.format(w, prefix)
+	mustWriteString(w, " ? ")
+	s.Then.format(w, prefix)
+	mustWriteString(w, " : ")
+	s.Else.format(w, prefix)
+}
+
+func (s *CondExp) equal(other Exp) error {
+	o, ok := other.(*CondExp)
+	if !ok {
+		return notEqualError
+	}
+	if err := s.Condition.equal(o.Condition); err != nil {
+		return err
+	}
+	if err := s.Then.equal(o.Then); err != nil {
+		return err
+	}
+	return s.Else.equal(o.Else)
+}
+
+// isValidCond checks that the condition of a conditional expression is
+// boolean and that both branches are valid for the given type.
+func isValidCond(s Type, exp *CondExp, pipeline *Pipeline, ast *Ast) error {
+	var errs ErrorList
+	if err := builtinBool.IsValidExpression(exp.Condition,
+		pipeline, ast); err != nil {
+		errs = append(errs, &IncompatibleTypeError{
+			Message: "condition " + exp.Condition.GoString(),
+			Reason:  err,
+		})
+	}
+	if err := s.IsValidExpression(exp.Then, pipeline, ast); err != nil {
+		errs = append(errs, &IncompatibleTypeError{
+			Message: "true branch",
+			Reason:  err,
+		})
+	}
+	if err := s.IsValidExpression(exp.Else, pipeline, ast); err != nil {
+		errs = append(errs, &IncompatibleTypeError{
+			Message: "false branch",
+			Reason:  err,
+		})
+	}
+	return errs.If()
+}
@@ -138,7 +138,7 @@ func (s *DisabledExp) makeDisabledExp(disable, inner Exp) (Exp, error) {
 		} else {
 			return inner, nil
 		}
-	case *RefExp:
+	case *RefExp, *CondExp:
 		if s != nil && inner == s.Value && disable == s.Disabled {
 			return s, nil
 		}
@@ -0,0 +1,276 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+// AST entry for enumerated string types.
+
+package syntax
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type (
+	// A legal value for an enumerated type.
+	EnumValue struct {
+		Node  AstNode
+		Value string
+	}
+
+	// A string type which may only take one of a fixed set of values.
+	EnumType struct {
+		Node   AstNode
+		Id     string
+		Values []*EnumValue
+		table  map[string]struct{}
+	}
+)
+
+func (v *EnumValue) getNode() *AstNode       { return &v.Node }
+func (v *EnumValue) File() *SourceFile       { return v.Node.Loc.File }
+func (v *EnumValue) Line() int               { return v.Node.Loc.Line }
+func (*EnumValue) inheritComments() bool     { return false }
+func (*EnumValue) getSubnodes() []AstNodable { return nil }
+
+func (*EnumType) getDec() {}
+
+func (s *EnumType) TypeId() TypeId    { return TypeId{Tname: s.Id} }
+func (s *EnumType) GetId() string     { return s.Id }
+func (s *EnumType) IsFile() FileKind  { return KindIsNotFile }
+func (*EnumType) ElementType() Type   { return nil }
+func (s *EnumType) getNode() *AstNode { return &s.Node }
+func (s *EnumType) File() *SourceFile { return s.Node.Loc.File }
+func (s *EnumType) Line() int         { return s.Node.Loc.Line }
+
+func (*EnumType) inheritComments() bool { return false }
+func (s *EnumType) getSubnodes() []AstNodable {
+	values := make([]AstNodable, 0, len(s.Values))
+	for _, v := range s.Values {
+		values = append(values, v)
+	}
+	return values
+}
+
+// Has returns true if the given string is one of the legal values for
+// the type.
+func (s *EnumType) Has(value string) bool {
+	if s.table != nil {
+		_, ok := s.table[value]
+		return ok
+	}
+	for _, v := range s.Values {
+		if v.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ValueStrings returns the legal values for the type, in declaration order.
+func (s *EnumType) ValueStrings() []string {
+	values := make([]string, len(s.Values))
+	for i, v := range s.Values {
+		values[i] = v.Value
+	}
+	return values
+}
+
+func (s *EnumType) invalidValueError(value string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "%q is not a legal value for enum %s (expected one of ",
+		value, s.Id)
+	for i, v := range s.Values {
+		if i > 0 {
+			msg.WriteString(", ")
+		}
+		msg.WriteString(strconv.Quote(v.Value))
+	}
+	msg.WriteRune(')')
+	return &IncompatibleTypeError{
+		Message: msg.String(),
+	}
+}
+
+func (s *EnumType) IsAssignableFrom(other Type, _ *TypeLookup) error {
+	if s == other {
+		return nil
+	}
+	switch t := other.(type) {
+	case *nullType:
+		return nil
+	case *BuiltinType:
+		// Strings cannot be checked until runtime, but are allowed so that
+		// pipelines can pass through their string inputs.
+		if t.Id == KindString {
+			return nil
+		}
+		return &IncompatibleTypeError{
+			Message: fmt.Sprintf("%s cannot be assigned to %s",
+				t.Id, s.Id),
+		}
+	case *EnumType:
+		if s.Id == t.Id {
+			return nil
+		}
+		return &IncompatibleTypeError{
+			Message: fmt.Sprintf(
+				"conversion between enum types %s and %s is not allowed",
+				t.Id, s.Id),
+		}
+	case *ArrayType:
+		return &IncompatibleTypeError{
+			Message: fmt.Sprintf(
+				"cannot assign array %s to singleton %s",
+				t.Elem.TypeId().str(), s.Id),
+		}
+	case *TypedMapType:
+		return &IncompatibleTypeError{
+			Message: fmt.Sprintf(
+				"cannot assign map<%s> to singleton %s",
+				t.Elem.TypeId().str(), s.Id),
+		}
+	default:
+		return &IncompatibleTypeError{
+			Message: fmt.Sprintf(
+				"%T type %s cannot be assigned to enum type %s",
+				t, t.TypeId().str(), s.Id),
+		}
+	}
+}
+func (s *EnumType) IsValidExpression(exp Exp, pipeline *Pipeline, ast *Ast) error {
+	switch exp := exp.(type) {
+	case *RefExp:
+		if tname, _, err := exp.resolveType(ast, pipeline); err != nil {
+			return err
+		} else if tname.ArrayDim != 0 {
+			return &IncompatibleTypeError{
+				Message: "ReferenceError: binding is an array",
+			}
+		} else if tname.MapDim != 0 {
+			return &IncompatibleTypeError{
+				Message: "ReferenceError: binding is a map",
+			}
+		} else if t := ast.TypeTable.Get(tname); t == nil {
+			return &IncompatibleTypeError{
+				Message: "Unknown type " + tname.Tname,
+			}
+		} else if err := s.IsAssignableFrom(t, &ast.TypeTable); err != nil {
+			return &IncompatibleTypeError{
+				Message: "ReferenceError: incompatible types",
+				Reason:  err,
+			}
+		} else {
+			return nil
+		}
+	case *SplitExp:
+		return isValidSplit(s, exp, pipeline, ast)
+	case *DisabledExp:
+		return s.IsValidExpression(exp.Value, pipeline, ast)
+	case *CondExp:
+		return isValidCond(s, exp, pipeline, ast)
+	case *FuncExp:
+		if exp.Func == FuncInterpolate {
+			if err := isValidFunc(exp, pipeline, ast); err != nil {
+				return err
+			}
+			if exp.isStatic(exp.Args) {
+				// If the value is known now, check it now.  Otherwise it
+				// is checked against the enum at runtime.
+				v, err := exp.evaluateStatic(exp.Args)
+				if err != nil {
+					return err
+				}
+				return s.IsValidExpression(v, pipeline, ast)
+			}
+			return nil
+		}
+		return &IncompatibleTypeError{
+			Message: fmt.Sprintf("cannot assign %s result to %s", exp.Func, s.Id),
+		}
+	case *NullExp:
+		return nil
+	case *StringExp:
+		if s.Has(exp.Value) {
+			return nil
+		}
+		return s.invalidValueError(exp.Value)
+	default:
+		return &IncompatibleTypeError{
+			Message: fmt.Sprintf("cannot assign %s to %s", exp.getKind(), s.Id),
+		}
+	}
+}
+func (s *EnumType) CheckEqual(other Type) error {
+	if other, ok := other.(*EnumType); !ok {
+		return &IncompatibleTypeError{
+			Message: other.Id + " is not an enum type",
+		}
+	} else if s.Id != other.Id {
+		return &IncompatibleTypeError{
+			Message: other.Id + " != " + s.Id,
+		}
+	} else if len(s.Values) != len(other.Values) {
+		return &IncompatibleTypeError{
+			Message: fmt.Sprintf("enum %s has %d values, not %d",
+				s.Id, len(other.Values), len(s.Values)),
+		}
+	} else {
+		for i, v := range s.Values {
+			if v.Value != other.Values[i].Value {
+				return &IncompatibleTypeError{
+					Message: fmt.Sprintf("enum %s value %d: %q != %q",
+						s.Id, i, other.Values[i].Value, v.Value),
+				}
+			}
+		}
+		return nil
+	}
+}
+func (s *EnumType) CanFilter() bool {
+	return false
+}
+func (s *EnumType) IsValidJson(data json.RawMessage,
+	_ *strings.Builder,
+	_ *TypeLookup) error {
+	if isNullBytes(data) {
+		return nil
+	}
+	var st string
+	if err := attemptJsonUnmarshal(data, &st, "a string"); err != nil {
+		return err
+	}
+	if !s.Has(st) {
+		return s.invalidValueError(st)
+	}
+	return nil
+}
+func (s *EnumType) FilterJson(data json.RawMessage, lookup *TypeLookup) (json.RawMessage, bool, error) {
+	if isNullBytes(data) {
+		return data, false, nil
+	}
+	err := s.IsValidJson(data, nil, lookup)
+	return data, err != nil, err
+}
+
+func (s *EnumType) String() string {
+	return "enum " + s.Id
+}
+
+func (s *EnumType) compile(global *Ast) error {
+	if len(s.Values) < 1 {
+		return global.err(s, "EmptyEnumError: enum has no values")
+	}
+	s.table = make(map[string]struct{}, len(s.Values))
+	var errs ErrorList
+	for _, v := range s.Values {
+		if _, ok := s.table[v.Value]; ok {
+			errs = append(errs, global.err(v,
+				"DuplicateNameError: value %q was already declared for enum %s",
+				v.Value, s.Id))
+		} else {
+			s.table[v.Value] = struct{}{}
+		}
+	}
+	return errs.If()
+}
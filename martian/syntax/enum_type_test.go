@@ -0,0 +1,169 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package syntax
+
+import (
+	"strings"
+	"testing"
+)
+
+const enumPipelineSrc = `
+enum CHEMISTRY(
+    "SC3Pv2",
+    "SC3Pv3",
+    "auto",
+)
+
+stage COUNT(
+    in  CHEMISTRY chemistry,
+    in  string    label,
+    out CHEMISTRY used,
+    src py        "stages/count",
+)
+
+pipeline PIPE(
+    in  string    chemistry,
+    out CHEMISTRY used,
+)
+{
+    call COUNT(
+        chemistry = self.chemistry,
+        label     = COUNT_DEFAULT.used,
+    )
+
+    call COUNT as COUNT_DEFAULT(
+        chemistry = "auto",
+        label     = "default",
+    )
+
+    return (
+        used = COUNT.used,
+    )
+}
+`
+
+func TestEnumGood(t *testing.T) {
+	t.Parallel()
+	ast := testGood(t, enumPipelineSrc)
+	if ast == nil {
+		return
+	}
+	if len(ast.EnumTypes) != 1 {
+		t.Fatalf("expected 1 enum, got %d", len(ast.EnumTypes))
+	}
+	et, ok := ast.TypeTable.Get(TypeId{Tname: "CHEMISTRY"}).(*EnumType)
+	if !ok {
+		t.Fatal("CHEMISTRY was not an enum type")
+	}
+	if v := strings.Join(et.ValueStrings(), ","); v != "SC3Pv2,SC3Pv3,auto" {
+		t.Errorf("incorrect values %s", v)
+	}
+}
+
+func TestEnumBadLiteral(t *testing.T) {
+	t.Parallel()
+	testBadCompile(t, strings.Replace(enumPipelineSrc,
+		`chemistry = "auto"`, `chemistry = "SC5P"`, 1),
+		`"SC5P" is not a legal value for enum CHEMISTRY`)
+}
+
+func TestEnumInterpolate(t *testing.T) {
+	t.Parallel()
+	testGood(t, strings.Replace(enumPipelineSrc,
+		`chemistry = "auto"`, `chemistry = f"SC3Pv3"`, 1))
+	testBadCompile(t, strings.Replace(enumPipelineSrc,
+		`chemistry = "auto"`, `chemistry = f"SC5P"`, 1),
+		`"SC5P" is not a legal value for enum CHEMISTRY`)
+}
+
+func TestEnumBadType(t *testing.T) {
+	t.Parallel()
+	testBadCompile(t, strings.Replace(enumPipelineSrc,
+		`chemistry = "auto"`, `chemistry = 3`, 1),
+		"cannot assign int to CHEMISTRY")
+}
+
+func TestEnumDuplicateValue(t *testing.T) {
+	t.Parallel()
+	testBadCompile(t, `
+enum CHEMISTRY(
+    "SC3Pv2",
+    "SC3Pv2",
+)
+`, "DuplicateNameError")
+}
+
+func TestEnumConflictingDeclaration(t *testing.T) {
+	t.Parallel()
+	testBadCompile(t, `
+enum CHEMISTRY(
+    "SC3Pv2",
+)
+
+enum CHEMISTRY(
+    "SC3Pv3",
+)
+`, "conflicts with previously declared enum type")
+	testBadCompile(t, `
+filetype CHEMISTRY;
+
+enum CHEMISTRY(
+    "SC3Pv3",
+)
+`, "conflicts")
+}
+
+func TestEnumEmpty(t *testing.T) {
+	t.Parallel()
+	testBadGrammar(t, `
+enum CHEMISTRY()
+`)
+}
+
+func TestEnumIsValidJson(t *testing.T) {
+	t.Parallel()
+	et := &EnumType{
+		Id: "CHEMISTRY",
+		Values: []*EnumValue{
+			{Value: "SC3Pv2"},
+			{Value: "auto"},
+		},
+	}
+	lookup := NewTypeLookup()
+	var alarms strings.Builder
+	for _, good := range []string{`"auto"`, `"SC3Pv2"`, `null`} {
+		if err := et.IsValidJson([]byte(good), &alarms, lookup); err != nil {
+			t.Errorf("%s: %v", good, err)
+		}
+	}
+	for _, bad := range []string{`"SC3Pv3"`, `""`, `3`, `["auto"]`} {
+		if err := et.IsValidJson([]byte(bad), &alarms, lookup); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+	if alarms.Len() != 0 {
+		t.Errorf("unexpected alarms %s", alarms.String())
+	}
+}
+
+func TestEnumAssignable(t *testing.T) {
+	t.Parallel()
+	lookup := NewTypeLookup()
+	et := &EnumType{Id: "CHEMISTRY"}
+	other := &EnumType{Id: "LIBRARY"}
+	if err := et.IsAssignableFrom(&builtinString, lookup); err != nil {
+		t.Error(err)
+	}
+	if err := builtinString.IsAssignableFrom(et, lookup); err != nil {
+		t.Error(err)
+	}
+	if err := et.IsAssignableFrom(&builtinInt, lookup); err == nil {
+		t.Error("expected int to not be assignable to enum")
+	}
+	if err := et.IsAssignableFrom(other, lookup); err == nil {
+		t.Error("expected enums to not be assignable to each other")
+	}
+	if err := builtinFile.IsAssignableFrom(et, lookup); err == nil {
+		t.Error("expected enum to not be assignable to file")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 
 	"github.com/martian-lang/martian/martian/util"
 )
@@ -133,7 +134,7 @@ func (mods *Modifiers) EquivalentTo(other *Modifiers) bool {
 }
 
 // Equals returns true if the two parameter sets share the same parameter
-// names and types.  Changes to file type names are ignored.
+// names, types, and nullability.  Changes to file type names are ignored.
 func (params *InParams) Equals(other *InParams) bool {
 	if params == nil || len(params.List) == 0 {
 		return other == nil || len(other.List) == 0
@@ -155,14 +156,16 @@ func (params *InParams) Equals(other *InParams) bool {
 			return false
 		} else if arg.IsFile() != KindIsFile && arg.GetTname() != oa.GetTname() {
 			return false
+		} else if arg.IsNonNull() != oa.IsNonNull() {
+			return false
 		}
 	}
 	return true
 }
 
 // Equals returns true if the two parameter sets share the same parameter
-// names and types.  Changes to file type names are ignored.  If checkOutNames
-// is true, the output name for the parameters are also compared.
+// names, types, and nullability.  Changes to file type names are ignored.  If
+// checkOutNames is true, the output name for the parameters are also compared.
 func (params *OutParams) Equals(other *OutParams, checkOutNames bool) bool {
 	if params == nil || len(params.List) == 0 {
 		return other == nil || len(other.List) == 0
@@ -188,6 +191,8 @@ func (params *OutParams) Equals(other *OutParams, checkOutNames bool) bool {
 		} else if (fk == KindIsFile || fk == KindIsDirectory) &&
 			checkOutNames && arg.GetOutName() != oa.GetOutName() {
 			return false
+		} else if arg.IsNonNull() != oa.IsNonNull() {
+			return false
 		}
 	}
 	return true
@@ -569,3 +574,176 @@ func (exp *RefExp) equal(other Exp) error {
 		return nil
 	}
 }
+
+// ChangedNodes compares two call graphs for the same pipeline, for example
+// one compiled from the source stored in a pipestance and one compiled from
+// updated source, and returns the sorted fully-qualified IDs of the nodes
+// present in both graphs which may not produce the same outputs, because the
+// stage definition, including its source and resources, the forking, or the
+// resolved input bindings have changed.
+//
+// Nodes which are only downstream of a changed node are not included.
+func ChangedNodes(old, new CallGraphNode) []string {
+	oldNodes := old.NodeClosure()
+	var changed []string
+	for id, node := range new.NodeClosure() {
+		if o := oldNodes[id]; o != nil && !equivalentNode(node, o) {
+			changed = append(changed, id)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func equivalentNode(node, other CallGraphNode) bool {
+	id := node.GetFqid()
+	if node.Kind() != other.Kind() {
+		util.PrintInfo("compare",
+			"%s changed between stage and pipeline.", id)
+		return false
+	}
+	switch c := node.Callable().(type) {
+	case *Stage:
+		if !c.equivalentDefinition(other.Callable().(*Stage)) {
+			return false
+		}
+	case *Pipeline:
+		op := other.Callable().(*Pipeline)
+		if !c.InParams.Equals(op.InParams) ||
+			!c.OutParams.Equals(op.OutParams, true) {
+			util.PrintInfo("compare",
+				"Pipeline %s parameters changed.", c.Id)
+			return false
+		}
+		if !node.ResolvedOutputs().equal(other.ResolvedOutputs()) {
+			util.PrintInfo("compare",
+				"Outputs of %s changed.", id)
+			return false
+		}
+	}
+	if !node.ResolvedInputs().equal(other.ResolvedInputs()) {
+		util.PrintInfo("compare",
+			"Input bindings for %s changed.", id)
+		return false
+	}
+	if !equalExps(node.Disabled(), other.Disabled()) {
+		util.PrintInfo("compare",
+			"Disabled bindings for %s changed.", id)
+		return false
+	}
+	if fr, ofr := node.ForkRoots(), other.ForkRoots(); len(fr) != len(ofr) {
+		util.PrintInfo("compare",
+			"Forking for %s changed.", id)
+		return false
+	} else {
+		for i, root := range fr {
+			if root.GetFqid() != ofr[i].GetFqid() {
+				util.PrintInfo("compare",
+					"Forking for %s changed.", id)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns true if the two stages have the same parameters, splitting
+// behavior, source, and resources.  Unlike EquivalentTo, this is not
+// concerned with whether the stage can be reattached, but whether it can be
+// expected to produce the same outputs.
+func (stage *Stage) equivalentDefinition(other *Stage) bool {
+	if stage.Split != other.Split ||
+		!stage.InParams.Equals(other.InParams) ||
+		!stage.OutParams.Equals(other.OutParams, true) ||
+		!stage.ChunkIns.Equals(other.ChunkIns) ||
+		!stage.ChunkOuts.Equals(other.ChunkOuts, true) {
+		util.PrintInfo("compare",
+			"Stage %s parameters changed.", stage.Id)
+		return false
+	}
+	if !stage.Src.equal(other.Src) {
+		util.PrintInfo("compare",
+			"Stage %s source changed.", stage.Id)
+		return false
+	}
+	if !stage.Resources.equal(other.Resources) {
+		util.PrintInfo("compare",
+			"Stage %s resources changed.", stage.Id)
+		return false
+	}
+	return true
+}
+
+func (src *SrcParam) equal(other *SrcParam) bool {
+	if src == nil || other == nil {
+		return src == other
+	}
+	if src.Type != other.Type || src.Path != other.Path ||
+		len(src.Args) != len(other.Args) {
+		return false
+	}
+	for i, arg := range src.Args {
+		if other.Args[i] != arg {
+			return false
+		}
+	}
+	return true
+}
+
+func (res *Resources) equal(other *Resources) bool {
+	if res == nil {
+		res = new(Resources)
+	}
+	if other == nil {
+		other = new(Resources)
+	}
+	return res.Threads == other.Threads &&
+		res.MemGB == other.MemGB &&
+		res.VMemGB == other.VMemGB &&
+		res.Special == other.Special &&
+		res.Timeout == other.Timeout &&
+		equalResourceExp(res.ThreadsExp, other.ThreadsExp) &&
+		equalResourceExp(res.MemGBExp, other.MemGBExp) &&
+		equalResourceExp(res.VMemGBExp, other.VMemGBExp)
+}
+
+func equalResourceExp(exp, other Exp) bool {
+	if exp == nil || other == nil {
+		return exp == other
+	}
+	return exp.equal(other) == nil
+}
+
+func (bindings ResolvedBindingMap) equal(other ResolvedBindingMap) bool {
+	if len(bindings) != len(other) {
+		return false
+	}
+	for id, binding := range bindings {
+		if !binding.equal(other[id]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (binding *ResolvedBinding) equal(other *ResolvedBinding) bool {
+	if binding == nil || other == nil {
+		return binding == other
+	}
+	if binding.Exp == nil || other.Exp == nil {
+		return binding.Exp == other.Exp
+	}
+	return binding.Exp.equal(other.Exp) == nil
+}
+
+func equalExps(exps, other []Exp) bool {
+	if len(exps) != len(other) {
+		return false
+	}
+	for i, exp := range exps {
+		if exp.equal(other[i]) != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -332,3 +332,91 @@ func TestNumExpEquivalence(t *testing.T) {
 		t.Error("Expected false == false:", err)
 	}
 }
+
+func TestChangedNodes(t *testing.T) {
+	const src = `
+stage A(
+    in  int n,
+    out int x,
+    src py  "stages/a",
+)
+
+stage B(
+    in  int x,
+    out int y,
+    src py  "stages/b",
+)
+
+pipeline P(
+    in  int n,
+    out int y,
+)
+{
+    call A(
+        n = self.n,
+    )
+
+    call B(
+        x = A.x,
+    )
+
+    call B as C(
+        x = self.n,
+    )
+
+    return (
+        y = B.y,
+    )
+}
+
+call P(
+    n = 1,
+)
+`
+	check := func(t *testing.T, newSrc string, expect ...string) {
+		t.Helper()
+		var buf strings.Builder
+		util.SetPrintLogger(&buf)
+		defer util.SetPrintLogger(&devNull)
+		ast1, ast2 := testGood(t, src), testGood(t, newSrc)
+		if ast1 == nil || ast2 == nil {
+			return
+		}
+		g1, err := ast1.MakePipelineCallGraph("", ast1.Call)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g2, err := ast2.MakePipelineCallGraph("", ast2.Call)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changed := ChangedNodes(g1, g2)
+		if strings.Join(changed, ",") != strings.Join(expect, ",") {
+			t.Errorf("expected changes %v, got %v", expect, changed)
+			t.Log(buf.String())
+		}
+	}
+	t.Run("unchanged", func(t *testing.T) {
+		check(t, "# comment\n"+src)
+	})
+	t.Run("source", func(t *testing.T) {
+		check(t, strings.Replace(src, `"stages/a"`, `"stages/a2"`, 1),
+			"P.A")
+	})
+	t.Run("resources", func(t *testing.T) {
+		check(t, strings.Replace(src, `"stages/b",
+)`, `"stages/b",
+) using (
+    mem_gb = 4,
+)`, 1),
+			"P.B", "P.C")
+	})
+	t.Run("binding", func(t *testing.T) {
+		check(t, strings.Replace(src, "x = self.n", "x = 2", 1),
+			"P.C")
+	})
+	t.Run("input", func(t *testing.T) {
+		check(t, strings.Replace(src, "n = 1", "n = 2", 1),
+			"P", "P.A", "P.C")
+	})
+}
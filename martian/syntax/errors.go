@@ -365,3 +365,34 @@ func (errs ErrorList) Unwrap() error {
 	}
 	return nil
 }
+
+// locatedError is implemented by errors which are associated with a
+// specific location in an mro source file.
+type locatedError interface {
+	location() SourceLoc
+}
+
+func (err *AstError) location() SourceLoc           { return err.Node.Loc }
+func (err *FileNotFoundError) location() SourceLoc  { return err.loc }
+func (err *DuplicateCallError) location() SourceLoc { return err.Second.Node.Loc }
+func (err *wrapError) location() SourceLoc          { return err.loc }
+func (err *ParseError) location() SourceLoc         { return err.loc }
+func (err *mmLexError) location() SourceLoc         { return err.info.loc }
+
+// ErrorLocation returns the source location associated with an error, if
+// there is one.  For errors which wrap other errors, the location of the
+// innermost error which has one is returned, since that is generally the
+// most specific.  ErrorLists should be split up by the caller, as only
+// the first error in the list is considered.
+func ErrorLocation(err error) (SourceLoc, bool) {
+	var loc SourceLoc
+	found := false
+	for err != nil {
+		if le, ok := err.(locatedError); ok {
+			loc = le.location()
+			found = true
+		}
+		err = errors.Unwrap(err)
+	}
+	return loc, found
+}
@@ -14,6 +14,8 @@ const (
 	KindArray  = ExpKind("array")
 	KindSplit  = "split"
 	KindMerge  = "merge"
+	KindCond   = "cond"
+	KindFunc   = "func"
 	KindMap    = "map"
 	KindFloat  = "float"
 	KindInt    = "int"
@@ -338,6 +340,21 @@ func walkExp(exp Exp, visitor ExpVisitor, path string) error {
 			return err
 		}
 		return walkExp(exp.Value, visitor, path)
+	case *CondExp:
+		if err := visitor(exp.Condition, path); err != nil &&
+			err != SkipExp {
+			return err
+		}
+		if err := walkExp(exp.Then, visitor, path); err != nil {
+			return err
+		}
+		return walkExp(exp.Else, visitor, path)
+	case *FuncExp:
+		for _, arg := range exp.Args {
+			if err := walkExp(arg, visitor, path); err != nil {
+				return err
+			}
+		}
 	case *ArrayExp:
 		for _, val := range exp.Value {
 			if err := walkExp(val, visitor, path); err != nil {
@@ -3,6 +3,7 @@
 package syntax
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -101,3 +102,55 @@ func TestFindRefs(t *testing.T) {
 		}
 	}
 }
+
+func TestFuncExpEvaluate(t *testing.T) {
+	interp := &FuncExp{
+		Func: FuncInterpolate,
+		Args: []Exp{new(RefExp), new(StringExp), new(RefExp)},
+	}
+	join := &FuncExp{
+		Func: FuncPathJoin,
+		Args: []Exp{new(RefExp), new(StringExp), new(RefExp)},
+	}
+	for _, c := range []struct {
+		exp    *FuncExp
+		args   [3]string
+		expect string
+	}{
+		{interp, [3]string{`"a"`, `"_"`, `"b"`}, `"a_b"`},
+		{interp, [3]string{`1`, `"_"`, `2.0`}, `"1_2"`},
+		{interp, [3]string{`1.5`, `"_"`, `true`}, `"1.5_true"`},
+		{interp, [3]string{`"a"`, `"_"`, `null`}, `null`},
+		{join, [3]string{`"/data"`, `"sub/"`, `"x.bam"`}, `"/data/sub/x.bam"`},
+		{join, [3]string{`"data"`, `".."`, `"x.bam"`}, `"x.bam"`},
+		{join, [3]string{`null`, `"sub"`, `"x.bam"`}, `null`},
+	} {
+		args := make([]json.RawMessage, len(c.args))
+		for i, a := range c.args {
+			args[i] = json.RawMessage(a)
+		}
+		if v, err := c.exp.Evaluate(args); err != nil {
+			t.Errorf("%s%v: %v", c.exp.Func, c.args, err)
+		} else if b, err := v.MarshalJSON(); err != nil {
+			t.Error(err)
+		} else if string(b) != c.expect {
+			t.Errorf("%s%v: expected %s, got %s",
+				c.exp.Func, c.args, c.expect, b)
+		}
+	}
+	for _, args := range [][]json.RawMessage{
+		{json.RawMessage(`"a"`), json.RawMessage(`"_"`), json.RawMessage(`[1]`)},
+		{json.RawMessage(`"a"`), json.RawMessage(`"_"`), json.RawMessage(`{}`)},
+	} {
+		if _, err := interp.Evaluate(args); err == nil {
+			t.Errorf("expected error for %s", args[2])
+		}
+	}
+	if _, err := join.Evaluate([]json.RawMessage{
+		json.RawMessage(`"a"`),
+		json.RawMessage(`"/b"`),
+		json.RawMessage(`"c"`),
+	}); err == nil {
+		t.Error("expected error for absolute path component")
+	}
+}
@@ -61,7 +61,7 @@ func (parser *Parser) FixIncludes(source *Ast, mropath []string) error {
 		incLookup := make(map[string]*SourceFile, len(needed))
 		for _, f := range needed {
 			if len(f.IncludedFrom) > 0 && f.IncludedFrom[0].File == srcFile {
-				incLookup[f.FileName] = f
+				incLookup[includeKey(f.FileName, f.namespace)] = f
 			} else if p, _, err := IncludeFilePath(f.FullPath, incPaths); err == nil {
 				incLookup[p] = f
 			} else {
@@ -71,7 +71,7 @@ func (parser *Parser) FixIncludes(source *Ast, mropath []string) error {
 		optionalLookup := make(map[string]*SourceFile, len(optional))
 		for _, f := range optional {
 			if len(f.IncludedFrom) > 0 && f.IncludedFrom[0].File == srcFile {
-				optionalLookup[f.FileName] = f
+				optionalLookup[includeKey(f.FileName, f.namespace)] = f
 			} else if p, _, err := IncludeFilePath(f.FullPath, incPaths); err == nil {
 				optionalLookup[p] = f
 			} else {
@@ -105,6 +105,14 @@ func uncheckedMakeTables(top *Ast, included *Ast) error {
 			errs = append(errs, err)
 		}
 	}
+	for _, enumType := range top.EnumTypes {
+		if top.TypeTable.baseTypes == nil {
+			top.TypeTable.init(len(top.UserTypes) + len(top.StructTypes) + len(top.Callables.List))
+		}
+		if err := top.TypeTable.AddEnumType(enumType); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	for _, structType := range top.StructTypes {
 		if top.TypeTable.baseTypes == nil {
 			top.TypeTable.init(len(top.UserTypes) + len(top.StructTypes) + len(top.Callables.List))
@@ -147,6 +155,17 @@ func uncheckedMakeTables(top *Ast, included *Ast) error {
 				errs = append(errs, err)
 			}
 		}
+		for _, enumType := range included.EnumTypes {
+			if top.TypeTable.baseTypes == nil {
+				top.TypeTable.init(
+					len(included.UserTypes) +
+						len(included.StructTypes) +
+						len(included.Callables.List))
+			}
+			if err := top.TypeTable.AddEnumType(enumType); err != nil {
+				errs = append(errs, err)
+			}
+		}
 		for _, structType := range included.StructTypes {
 			if top.TypeTable.baseTypes == nil {
 				top.TypeTable.init(
@@ -185,10 +204,43 @@ func uncheckedMakeTables(top *Ast, included *Ast) error {
 	return errs.If()
 }
 
+// includeKey returns the key used to identify an include of the given file
+// in the sets of required includes.  Files included under a namespace are
+// distinct from the same file included without one.
+func includeKey(path, namespace string) string {
+	if namespace == "" {
+		return path
+	}
+	return path + " as " + namespace
+}
+
+// includeFor returns the file which must be included in order to use the
+// declarations from the given file.
+//
+// Declarations from a file which was included under a namespace, directly
+// or transitively, are only visible through the include of the outermost
+// namespaced file.
+func includeFor(file *SourceFile) *SourceFile {
+	result := file
+	for f := file; f != nil && len(f.IncludedFrom) > 0 &&
+		f.IncludedFrom[0] != nil; f = f.IncludedFrom[0].File {
+		if f.namespace != "" {
+			if from := f.IncludedFrom[0].File; from != nil &&
+				len(from.IncludedFrom) > 0 {
+				result = from
+			} else {
+				result = f
+			}
+		}
+	}
+	return result
+}
+
 func isTransitivelyIncluded(file *SourceFile, included, usedTransitively map[string]*SourceFile) bool {
-	if _, ok := included[file.FullPath]; ok {
+	key := includeKey(file.FullPath, file.namespace)
+	if _, ok := included[key]; ok {
 		return true
-	} else if _, ok := usedTransitively[file.FullPath]; ok {
+	} else if _, ok := usedTransitively[key]; ok {
 		return true
 	}
 	for _, from := range file.IncludedFrom {
@@ -196,7 +248,7 @@ func isTransitivelyIncluded(file *SourceFile, included, usedTransitively map[str
 			len(from.File.IncludedFrom) > 0 &&
 			isTransitivelyIncluded(from.File, included, nil) {
 			if usedTransitively != nil {
-				usedTransitively[file.FullPath] = file
+				usedTransitively[key] = file
 			}
 			return true
 		}
@@ -212,7 +264,7 @@ func addIncludesForInParamTypes(source *Ast, params *InParams,
 		if t := source.TypeTable.Get(TypeId{Tname: tName.Tname}); t != nil {
 			// Don't worry about builtin types
 			if tn, ok := t.(AstNodable); ok {
-				if srcFile := tn.getNode().Loc.File; srcFile != param.File() {
+				if srcFile := includeFor(tn.getNode().Loc.File); srcFile != param.File() {
 					switch t := t.(type) {
 					case *UserType:
 						if !allowTransitive || !isTransitivelyIncluded(srcFile,
@@ -224,7 +276,7 @@ func addIncludesForInParamTypes(source *Ast, params *InParams,
 						if !allowTransitive || !isTransitivelyIncluded(srcFile,
 							required, usedTransitively) {
 							// Structs, etc
-							required[srcFile.FullPath] = srcFile
+							required[includeKey(srcFile.FullPath, srcFile.namespace)] = srcFile
 						}
 					}
 				}
@@ -253,7 +305,7 @@ func addIncludesForMemberType(source *Ast, param *StructMember,
 	if t := source.TypeTable.Get(TypeId{Tname: tName.Tname}); t != nil {
 		// Don't worry about builtin types
 		if tn, ok := t.(AstNodable); ok {
-			if srcFile := tn.getNode().Loc.File; srcFile != param.File() {
+			if srcFile := includeFor(tn.getNode().Loc.File); srcFile != param.File() {
 				switch t := t.(type) {
 				case *UserType:
 					if !allowTransitive || !isTransitivelyIncluded(srcFile,
@@ -265,7 +317,7 @@ func addIncludesForMemberType(source *Ast, param *StructMember,
 					if !allowTransitive || !isTransitivelyIncluded(srcFile,
 						required, usedTransitively) {
 						// Structs, etc
-						required[srcFile.FullPath] = srcFile
+						required[includeKey(srcFile.FullPath, srcFile.namespace)] = srcFile
 					}
 				}
 			}
@@ -292,8 +344,8 @@ func getRequiredIncludes(source *Ast, userTypes []*UserType) (
 	unknownCallables = make(map[string]struct{})
 	if source.Call != nil {
 		if call := source.Callables.Table[source.Call.DecId]; call != nil {
-			file := call.getNode().Loc.File
-			required[file.FullPath] = file
+			file := includeFor(call.getNode().Loc.File)
+			required[includeKey(file.FullPath, file.namespace)] = file
 		} else {
 			unknownCallables[source.Call.DecId] = struct{}{}
 		}
@@ -301,8 +353,8 @@ func getRequiredIncludes(source *Ast, userTypes []*UserType) (
 	for _, pipeline := range source.Pipelines {
 		for _, call := range pipeline.Calls {
 			if c := source.Callables.Table[call.DecId]; c != nil {
-				file := c.getNode().Loc.File
-				required[file.FullPath] = file
+				file := includeFor(c.getNode().Loc.File)
+				required[includeKey(file.FullPath, file.namespace)] = file
 			} else {
 				unknownCallables[call.DecId] = struct{}{}
 			}
@@ -332,14 +384,15 @@ func getRequiredIncludes(source *Ast, userTypes []*UserType) (
 		isAlreadyIncluded := func(name string) bool {
 			if ty := source.TypeTable.Get(TypeId{Tname: name}); ty != nil {
 				if tn, ok := ty.(AstNodable); ok && isTransitivelyIncluded(
-					tn.getNode().Loc.File, required, optional) {
+					includeFor(tn.getNode().Loc.File), required, optional) {
 					return true
 				} else if ok {
 					// The type may have been declared in other included files
 					// besides the first one which is listed on the type info.
 					for _, t := range userTypes {
 						if t.Id == name {
-							if isTransitivelyIncluded(t.Node.Loc.File, required, optional) {
+							if isTransitivelyIncluded(includeFor(t.Node.Loc.File),
+								required, optional) {
 								return true
 							}
 						}
@@ -413,6 +466,15 @@ func (parser *Parser) findMissingIncludes(seenFiles map[string]*SourceFile,
 									delete(neededTypes, st.Id)
 								}
 							}
+							for _, et := range ast.EnumTypes {
+								if _, ok := neededTypes[et.GetId()]; ok {
+									util.PrintInfo("include",
+										"Found %s in %s\n",
+										et.Id, absPath)
+									needed = true
+									delete(neededTypes, et.Id)
+								}
+							}
 							if needed {
 								for _, t := range ast.UserTypes {
 									delete(neededTypes, t.Id)
@@ -462,12 +524,13 @@ func fixIncludes(source *Ast, needed, optional map[string]*SourceFile, extraType
 	var loc SourceLoc
 	newIncludes := make([]*Include, 0, len(needed))
 	for _, inc := range source.Includes {
-		if _, ok := needed[inc.Value]; ok {
+		key := includeKey(inc.Value, inc.As)
+		if _, ok := needed[key]; ok {
 			newIncludes = append(newIncludes, inc)
-			delete(needed, inc.Value)
-		} else if _, ok := optional[inc.Value]; ok {
+			delete(needed, key)
+		} else if _, ok := optional[key]; ok {
 			newIncludes = append(newIncludes, inc)
-			delete(optional, inc.Value)
+			delete(optional, key)
 		}
 		loc = inc.Node.Loc
 	}
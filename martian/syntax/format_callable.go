@@ -52,11 +52,13 @@ func paramFormat(printer *printer, param Param, modeWidth int, typeWidth int, id
 	id := param.GetId()
 	if id == "default" {
 		id = ""
+	} else if p, ok := param.(*InParam); ok {
+		id = p.idWithDefault()
 	}
 
 	// Generate column alignment paddings.
 	tname := param.GetTname()
-	typePad := strings.Repeat(" ", typeWidth-tname.strlen())
+	typePad := strings.Repeat(" ", typeWidth-typeStrlen(param))
 
 	// Common columns up to type name.
 	printer.mustWriteString(INDENT)
@@ -66,6 +68,9 @@ func paramFormat(printer *printer, param Param, modeWidth int, typeWidth int, id
 	}
 	printer.mustWriteRune(' ')
 	printer.mustWriteString(tname.str())
+	if param.IsNonNull() {
+		printer.mustWriteRune('!')
+	}
 
 	// Add id if not default.
 	if id != "" {
@@ -104,6 +109,29 @@ func paramFormat(printer *printer, param Param, modeWidth int, typeWidth int, id
 	printer.mustWriteString(",\n")
 }
 
+// typeStrlen returns the formatted length of the parameter's type, including
+// the trailing ! for non-null parameters.
+func typeStrlen(param StructMemberLike) int {
+	tname := param.GetTname()
+	if param.IsNonNull() {
+		return tname.strlen() + 1
+	}
+	return tname.strlen()
+}
+
+// idWithDefault returns the parameter id, followed by the default value
+// if there is one.
+func (self *InParam) idWithDefault() string {
+	if self.Default == nil {
+		return self.Id
+	}
+	var buf strings.Builder
+	buf.WriteString(self.Id)
+	buf.WriteString(" = ")
+	self.Default.format(&buf, INDENT)
+	return buf.String()
+}
+
 func (self *InParams) getWidths() (int, int, int, int) {
 	modeWidth := 0
 	typeWidth := 0
@@ -111,10 +139,9 @@ func (self *InParams) getWidths() (int, int, int, int) {
 	helpWidth := 0
 	for _, param := range self.List {
 		modeWidth = max(modeWidth, len(param.getMode()))
-		tname := param.GetTname()
-		typeWidth = max(typeWidth, tname.strlen())
-		if len(param.GetId()) < 35 {
-			idWidth = max(idWidth, len(param.GetId()))
+		typeWidth = max(typeWidth, typeStrlen(param))
+		if id := param.idWithDefault(); len(id) < 35 {
+			idWidth = max(idWidth, len(id))
 		}
 		if len(param.GetHelp()) < 25 {
 			helpWidth = max(helpWidth, len(param.GetHelp()))
@@ -130,8 +157,7 @@ func (self *OutParams) getWidths() (int, int, int, int) {
 	helpWidth := 0
 	for _, param := range self.List {
 		modeWidth = max(modeWidth, len(param.getMode()))
-		tname := param.GetTname()
-		typeWidth = max(typeWidth, tname.strlen())
+		typeWidth = max(typeWidth, typeStrlen(param))
 		if len(param.GetId()) < 35 {
 			idWidth = max(idWidth, len(param.GetId()))
 		}
@@ -208,7 +234,7 @@ func (self *CallStm) format(printer *printer, prefix string) {
 	}
 	printer.mustWriteString("call ")
 	printer.mustWriteString(self.DecId)
-	if self.Id != self.DecId {
+	if self.IsAliased() {
 		printer.mustWriteString(" as ")
 		printer.mustWriteString(self.Id)
 	}
@@ -347,6 +373,7 @@ func (self *Resources) format(printer *printer) {
 	// mem_gb   = x,
 	// special  = y
 	// threads  = y,
+	// timeout  = y,
 	// volatile = z,
 	var memPad, threadPad string
 	if self.VolatileNode != nil {
@@ -354,7 +381,8 @@ func (self *Resources) format(printer *printer) {
 		threadPad = " "
 	} else if self.VMemNode != nil ||
 		self.SpecialNode != nil ||
-		self.ThreadNode != nil {
+		self.ThreadNode != nil ||
+		self.TimeoutNode != nil {
 		memPad = " "
 	}
 	if self.MemNode != nil {
@@ -363,7 +391,11 @@ func (self *Resources) format(printer *printer) {
 		printer.mustWriteString("mem_gb")
 		printer.mustWriteString(memPad)
 		printer.mustWriteString(" = ")
-		formatGB(&printer.buf, self.MemGB)
+		if self.MemGBExp != nil {
+			self.MemGBExp.format(printer, INDENT)
+		} else {
+			formatGB(&printer.buf, self.MemGB)
+		}
 		printer.mustWriteString(",\n")
 	}
 	if self.SpecialNode != nil {
@@ -380,7 +412,20 @@ func (self *Resources) format(printer *printer) {
 		printer.mustWriteString(INDENT)
 		printer.mustWriteString("threads")
 		printer.mustWriteString(threadPad)
-		printer.Printf(" = %g,\n", self.Threads)
+		if self.ThreadsExp != nil {
+			printer.mustWriteString(" = ")
+			self.ThreadsExp.format(printer, INDENT)
+			printer.mustWriteString(",\n")
+		} else {
+			printer.Printf(" = %g,\n", self.Threads)
+		}
+	}
+	if self.TimeoutNode != nil {
+		printer.printComments(self.TimeoutNode, INDENT)
+		printer.mustWriteString(INDENT)
+		printer.mustWriteString("timeout")
+		printer.mustWriteString(threadPad)
+		printer.Printf(" = %d,\n", self.Timeout)
 	}
 	if self.VMemNode != nil {
 		printer.printComments(self.VMemNode, INDENT)
@@ -388,7 +433,11 @@ func (self *Resources) format(printer *printer) {
 		printer.mustWriteString("vmem_gb")
 		printer.mustWriteString(threadPad)
 		printer.mustWriteString(" = ")
-		formatGB(&printer.buf, self.VMemGB)
+		if self.VMemGBExp != nil {
+			self.VMemGBExp.format(printer, INDENT)
+		} else {
+			formatGB(&printer.buf, self.VMemGB)
+		}
 		printer.mustWriteString(",\n")
 	}
 	if self.VolatileNode != nil {
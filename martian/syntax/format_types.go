@@ -10,7 +10,7 @@ func (self *StructType) format(printer *printer) {
 	idWidth := 0
 	helpWidth := 0
 	for _, m := range self.Members {
-		typeWidth = max(typeWidth, m.Tname.strlen())
+		typeWidth = max(typeWidth, typeStrlen(m))
 		idWidth = max(idWidth, len(m.Id))
 		helpWidth = max(helpWidth, len(m.Help))
 	}
@@ -30,7 +30,10 @@ func (member *StructMember) format(printer *printer, typeWidth, idWidth, helpWid
 	// Common columns up to type name.
 	printer.mustWriteString(INDENT)
 	member.Tname.writeTo(printer)
-	for i := member.Tname.strlen(); i < typeWidth; i++ {
+	if member.NonNull {
+		printer.mustWriteRune('!')
+	}
+	for i := typeStrlen(member); i < typeWidth; i++ {
 		printer.mustWriteRune(' ')
 	}
 	printer.mustWriteRune(' ')
@@ -52,6 +55,21 @@ func (member *StructMember) format(printer *printer, typeWidth, idWidth, helpWid
 	printer.mustWriteString(",\n")
 }
 
+// Enum
+func (self *EnumType) format(printer *printer) {
+	printer.printComments(&self.Node, "")
+	printer.mustWriteString("enum ")
+	printer.mustWriteString(self.Id)
+	printer.mustWriteString("(\n")
+	for _, v := range self.Values {
+		printer.printComments(&v.Node, INDENT)
+		printer.mustWriteString(INDENT)
+		quoteString(printer, v.Value)
+		printer.mustWriteString(",\n")
+	}
+	printer.mustWriteString(")\n")
+}
+
 // Filetype
 func (self *UserType) format(printer *printer) {
 	printer.printComments(&self.Node, "")
@@ -125,6 +125,14 @@ func (self *Ast) Format() string {
 	return self.format(!includesProcessed)
 }
 
+// FormatCallable returns mro source code for a single stage or pipeline
+// declaration.
+func FormatCallable(callable Callable) string {
+	var printer printer
+	callable.format(&printer)
+	return printer.String()
+}
+
 // AST
 func (self *Ast) format(writeIncludes bool) string {
 	needSpacer := false
@@ -160,6 +168,10 @@ func (self *Ast) format(writeIncludes bool) string {
 			printer.mustWriteString("@include \"")
 			printer.mustWriteString(directive.Value)
 			printer.mustWriteRune('"')
+			if directive.As != "" {
+				printer.mustWriteString(" as ")
+				printer.mustWriteString(directive.As)
+			}
 			printer.mustWriteString(NEWLINE)
 			needSpacer = true
 		}
@@ -173,6 +185,16 @@ func (self *Ast) format(writeIncludes bool) string {
 		filetype.format(&printer)
 		needSpacer = true
 	}
+	if needSpacer && len(self.EnumTypes) > 0 {
+		printer.mustWriteString(NEWLINE)
+	}
+	for i, enumType := range self.EnumTypes {
+		if i != 0 {
+			printer.mustWriteString(NEWLINE)
+		}
+		enumType.format(&printer)
+		needSpacer = true
+	}
 	if needSpacer && len(self.StructTypes) > 0 {
 		printer.mustWriteString(NEWLINE)
 	}
@@ -0,0 +1,695 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package syntax
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Builtin functions which may be used in a FuncExp.
+const (
+	// Concatenates the string representations of its arguments.
+	FuncInterpolate = "interpolate"
+
+	// Joins its arguments as path components.
+	FuncPathJoin = "path_join"
+
+	// Arithmetic on numbers, which may only be used in stage resources.
+	// In mro these are written as the infix operators +, -, *, and /.
+	FuncAdd = "add"
+	FuncSub = "sub"
+	FuncMul = "mul"
+	FuncDiv = "div"
+
+	// The smallest or largest of the arguments.  Unlike other functions,
+	// null arguments are ignored, and the result is only null if all of
+	// the arguments are null.
+	FuncMin = "min"
+	FuncMax = "max"
+
+	// The total size, in bytes, of the files referenced by the argument.
+	// Directories are measured recursively.  It can only be evaluated at
+	// runtime, and may only be used in stage resources.
+	FuncFileSize = "file_size"
+)
+
+// infixOperator returns the operator and precedence used to format the
+// given function as a binary operator, or an empty string if it is not
+// formatted that way.
+func infixOperator(fn string) (string, int) {
+	switch fn {
+	case FuncAdd:
+		return "+", 1
+	case FuncSub:
+		return "-", 1
+	case FuncMul:
+		return "*", 2
+	case FuncDiv:
+		return "/", 2
+	}
+	return "", 0
+}
+
+// isNumericFunc returns true for functions which compute a number.
+func isNumericFunc(fn string) bool {
+	switch fn {
+	case FuncAdd, FuncSub, FuncMul, FuncDiv, FuncMin, FuncMax, FuncFileSize:
+		return true
+	}
+	return false
+}
+
+// FuncExp is an expression which computes a value by applying a builtin
+// function to its arguments.
+//
+// In mro, string interpolation is written as
+//
+//	f"{self.sample_id}_R1.fastq"
+//
+// which is parsed as a call to the interpolate function, with the literal
+// parts of the string and the references as arguments.  Paths are joined
+// with
+//
+//	path_join(self.outdir, "reads", f"{self.sample_id}.bam")
+//
+// If the values of all arguments are known statically, the expression is
+// evaluated during call graph resolution.  Otherwise it is evaluated at
+// runtime once the referenced values are available.  If any argument is
+// null, the result is null.
+type FuncExp struct {
+	Node AstNode
+
+	// The name of the function.
+	Func string
+
+	Args []Exp
+
+	// The types of the arguments, recorded during type checking.
+	argTypes []TypeId
+}
+
+func (s *FuncExp) getNode() *AstNode {
+	return &s.Node
+}
+func (s *FuncExp) File() *SourceFile {
+	return s.Node.Loc.File
+}
+func (s *FuncExp) Line() int {
+	return s.Node.Loc.Line
+}
+func (s *FuncExp) inheritComments() bool {
+	return false
+}
+func (s *FuncExp) getSubnodes() []AstNodable {
+	subs := make([]AstNodable, 0, len(s.Args))
+	for _, n := range s.Args {
+		subs = append(subs, n)
+	}
+	return subs
+}
+func (s *FuncExp) HasRef() bool {
+	for _, arg := range s.Args {
+		if arg.HasRef() {
+			return true
+		}
+	}
+	return false
+}
+func (s *FuncExp) HasSplit() bool {
+	for _, arg := range s.Args {
+		if arg.HasSplit() {
+			return true
+		}
+	}
+	return false
+}
+func (s *FuncExp) FindRefs() []*RefExp {
+	var refs []*RefExp
+	for _, arg := range s.Args {
+		refs = append(refs, arg.FindRefs()...)
+	}
+	return refs
+}
+func (s *FuncExp) getKind() ExpKind {
+	return KindFunc
+}
+
+// ArgType returns the type of the given argument, as determined during type
+// checking.  Arguments of unknown type are treated as strings.
+func (s *FuncExp) ArgType(i int, lookup *TypeLookup) Type {
+	if i < len(s.argTypes) && s.argTypes[i].Tname != "" {
+		if t := lookup.Get(s.argTypes[i]); t != nil {
+			return t
+		}
+	}
+	return &builtinString
+}
+
+func (s *FuncExp) FindTypedRefs(list []*BoundReference,
+	_ Type, lookup *TypeLookup) ([]*BoundReference, error) {
+	for i, arg := range s.Args {
+		var err error
+		list, err = arg.FindTypedRefs(list, s.ArgType(i, lookup), lookup)
+		if err != nil {
+			return list, err
+		}
+	}
+	return list, nil
+}
+
+// Evaluate computes the result of applying the function to the json-encoded
+// values of its arguments.  Arguments must be strings, numbers, booleans,
+// or null.  The file_size function cannot be evaluated this way, since its
+// argument must be the files themselves rather than their sizes.
+func (s *FuncExp) Evaluate(args []json.RawMessage) (Exp, error) {
+	if len(args) != len(s.Args) {
+		return nil, fmt.Errorf("%s expected %d arguments, got %d",
+			s.Func, len(s.Args), len(args))
+	}
+	values := make([]string, 0, len(args))
+	for i, arg := range args {
+		dec := json.NewDecoder(bytes.NewReader(arg))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("argument %d of %s: %w", i+1, s.Func, err)
+		}
+		switch v := v.(type) {
+		case nil:
+			if s.Func == FuncMin || s.Func == FuncMax {
+				continue
+			}
+			return &NullExp{valExp: valExp{Node: s.Node}}, nil
+		case string:
+			values = append(values, v)
+		case bool:
+			values = append(values, strconv.FormatBool(v))
+		case json.Number:
+			// Format the same way as a literal would be formatted.
+			if n, err := v.Int64(); err == nil {
+				values = append(values, strconv.FormatInt(n, 10))
+			} else if f, err := v.Float64(); err == nil {
+				values = append(values, strconv.FormatFloat(f, 'g', -1, 64))
+			} else {
+				values = append(values, v.String())
+			}
+		default:
+			return nil, fmt.Errorf("argument %d of %s is not a scalar value: %s",
+				i+1, s.Func, arg)
+		}
+	}
+	if len(values) == 0 && len(args) != 0 {
+		return &NullExp{valExp: valExp{Node: s.Node}}, nil
+	}
+	return s.apply(values)
+}
+
+// apply computes the result of the function given the string values of its
+// arguments.
+func (s *FuncExp) apply(values []string) (Exp, error) {
+	switch s.Func {
+	case FuncInterpolate:
+		return &StringExp{
+			valExp: valExp{Node: s.Node},
+			Value:  strings.Join(values, ""),
+		}, nil
+	case FuncPathJoin:
+		for i := 1; i < len(values); i++ {
+			if path.IsAbs(values[i]) {
+				return nil, fmt.Errorf(
+					"argument %d of %s is an absolute path: %q",
+					i+1, s.Func, values[i])
+			}
+		}
+		return &StringExp{
+			valExp: valExp{Node: s.Node},
+			Value:  path.Join(values...),
+		}, nil
+	case FuncAdd, FuncSub, FuncMul, FuncDiv, FuncMin, FuncMax:
+		return s.applyNumeric(values)
+	case FuncFileSize:
+		return nil, fmt.Errorf("%s can only be evaluated at runtime", s.Func)
+	}
+	return nil, fmt.Errorf("unknown function %s", s.Func)
+}
+
+// applyNumeric computes the result of an arithmetic function.  The result
+// is an integer if all of the arguments are integers, except for division.
+func (s *FuncExp) applyNumeric(values []string) (Exp, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s requires at least one argument", s.Func)
+	}
+	ints := make([]int64, len(values))
+	floats := make([]float64, len(values))
+	isInt := s.Func != FuncDiv
+	for i, v := range values {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			ints[i] = n
+			floats[i] = float64(n)
+		} else if f, err := strconv.ParseFloat(v, 64); err == nil {
+			floats[i] = f
+			isInt = false
+		} else {
+			return nil, fmt.Errorf("argument %d of %s is not a number: %q",
+				i+1, s.Func, v)
+		}
+	}
+	if isInt {
+		result := ints[0]
+		for _, n := range ints[1:] {
+			switch s.Func {
+			case FuncAdd:
+				result += n
+			case FuncSub:
+				result -= n
+			case FuncMul:
+				result *= n
+			case FuncMin:
+				if n < result {
+					result = n
+				}
+			case FuncMax:
+				if n > result {
+					result = n
+				}
+			}
+		}
+		return &IntExp{
+			valExp: valExp{Node: s.Node},
+			Value:  result,
+		}, nil
+	}
+	result := floats[0]
+	for _, f := range floats[1:] {
+		switch s.Func {
+		case FuncAdd:
+			result += f
+		case FuncSub:
+			result -= f
+		case FuncMul:
+			result *= f
+		case FuncDiv:
+			if f == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			result /= f
+		case FuncMin:
+			result = math.Min(result, f)
+		case FuncMax:
+			result = math.Max(result, f)
+		}
+	}
+	return &FloatExp{
+		valExp: valExp{Node: s.Node},
+		Value:  result,
+	}, nil
+}
+
+// isStatic returns true if the function can be evaluated given the
+// arguments.
+func (s *FuncExp) isStatic(args []Exp) bool {
+	return s.Func != FuncFileSize && isStaticArgs(args)
+}
+
+func (s *FuncExp) EncodeJSON(buf *bytes.Buffer) error {
+	if s.isStatic(s.Args) {
+		v, err := s.evaluateStatic(s.Args)
+		if err != nil {
+			return err
+		}
+		return v.EncodeJSON(buf)
+	}
+	if _, err := buf.WriteString(`{"__func__":`); err != nil {
+		return err
+	}
+	quoteString(buf, s.Func)
+	if _, err := buf.WriteString(`,"args":[`); err != nil {
+		return err
+	}
+	for i, arg := range s.Args {
+		if i != 0 {
+			if err := buf.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if err := arg.EncodeJSON(buf); err != nil {
+			return err
+		}
+	}
+	_, err := buf.WriteString("]}")
+	return err
+}
+
+func (s *FuncExp) jsonSizeEstimate() int {
+	size := len(`{"__func__":"","args":[]}`) + len(s.Func) + len(s.Args)
+	for _, arg := range s.Args {
+		size += arg.jsonSizeEstimate()
+	}
+	return size
+}
+
+func (s *FuncExp) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	buf.Grow(s.jsonSizeEstimate())
+	err := s.EncodeJSON(&buf)
+	return buf.Bytes(), err
+}
+
+// isFString returns true if the expression can be formatted as an f-string
+// literal.
+func (s *FuncExp) isFString() bool {
+	if s.Func != FuncInterpolate {
+		return false
+	}
+	for _, arg := range s.Args {
+		switch arg.(type) {
+		case *StringExp, *RefExp:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+var fstringEscaper = strings.NewReplacer("{", "{{", "}", "}}")
+
+func (s *FuncExp) GoString() string {
+	if s == nil {
+		return KindNull
+	}
+	var buf strings.Builder
+	if s.Func == FuncInterpolate {
+		buf.WriteString(`f"`)
+		for _, arg := range s.Args {
+			if str, ok := arg.(*StringExp); ok {
+				buf.WriteString(fstringEscaper.Replace(str.Value))
+			} else {
+				buf.WriteRune('{')
+				buf.WriteString(arg.GoString())
+				buf.WriteRune('}')
+			}
+		}
+		buf.WriteRune('"')
+		return buf.String()
+	}
+	if op, prec := infixOperator(s.Func); op != "" && len(s.Args) == 2 {
+		for i, arg := range s.Args {
+			if i != 0 {
+				buf.WriteString(" " + op + " ")
+			}
+			if needsParens(arg, prec, i != 0) {
+				buf.WriteRune('(')
+				buf.WriteString(arg.GoString())
+				buf.WriteRune(')')
+			} else {
+				buf.WriteString(arg.GoString())
+			}
+		}
+		return buf.String()
+	}
+	buf.WriteString(s.Func)
+	buf.WriteRune('(')
+	for i, arg := range s.Args {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(arg.GoString())
+	}
+	buf.WriteRune(')')
+	return buf.String()
+}
+
+func (s *FuncExp) String() string {
+	return s.GoString()
+}
+
+func (s *FuncExp) format(w stringWriter, prefix string) {
+	if s.isFString() {
+		var buf strings.Builder
+		for _, arg := range s.Args {
+			if str, ok := arg.(*StringExp); ok {
+				buf.WriteString(fstringEscaper.Replace(str.Value))
+			} else {
+				buf.WriteRune('{')
+				arg.format(&buf, prefix)
+				buf.WriteRune('}')
+			}
+		}
+		mustWriteRune(w, 'f')
+		quoteString(w, buf.String())
+		return
+	}
+	if op, prec := infixOperator(s.Func); op != "" && len(s.Args) == 2 {
+		for i, arg := range s.Args {
+			if i != 0 {
+				mustWriteString(w, " "+op+" ")
+			}
+			if needsParens(arg, prec, i != 0) {
+				mustWriteRune(w, '(')
+				arg.format(w, prefix)
+				mustWriteRune(w, ')')
+			} else {
+				arg.format(w, prefix)
+			}
+		}
+		return
+	}
+	mustWriteString(w, s.Func)
+	mustWriteRune(w, '(')
+	for i, arg := range s.Args {
+		if i != 0 {
+			mustWriteString(w, ", ")
+		}
+		arg.format(w, prefix)
+	}
+	mustWriteRune(w, ')')
+}
+
+// needsParens returns true if the given operand to an infix operator with
+// the given precedence must be parenthesized.  Operators are left
+// associative, so a right operand of the same precedence also requires
+// parentheses.
+func needsParens(arg Exp, prec int, right bool) bool {
+	fn, ok := arg.(*FuncExp)
+	if !ok || len(fn.Args) != 2 {
+		return false
+	}
+	op, argPrec := infixOperator(fn.Func)
+	if op == "" {
+		return false
+	}
+	return argPrec < prec || right && argPrec == prec
+}
+
+func (s *FuncExp) equal(other Exp) error {
+	o, ok := other.(*FuncExp)
+	if !ok {
+		return notEqualError
+	}
+	if s.Func != o.Func || len(s.Args) != len(o.Args) {
+		return notEqualError
+	}
+	for i, arg := range s.Args {
+		if err := arg.equal(o.Args[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseInterpolation parses an f-string token into an interpolate function
+// expression.  The tokenizer guarantees that the token is well-formed.
+func parseInterpolation(loc SourceLoc, b []byte, intern *stringIntern) *FuncExp {
+	exp := &FuncExp{
+		Node: NewAstNode(loc),
+		Func: FuncInterpolate,
+	}
+	// Strip the f prefix and the quotes.
+	content := b[2 : len(b)-1]
+	lit := []byte{'"'}
+	flush := func() {
+		if len(lit) > 1 {
+			exp.Args = append(exp.Args, &StringExp{
+				valExp: valExp{Node: NewAstNode(loc)},
+				Value:  unquote(append(lit, '"')),
+			})
+			lit = lit[:1]
+		}
+	}
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; c {
+		case '\\':
+			// Copy escape sequences through for unquote.
+			lit = append(lit, c, content[i+1])
+			i++
+		case '{':
+			if content[i+1] == '{' {
+				lit = append(lit, c)
+				i++
+				continue
+			}
+			flush()
+			end := i + bytes.IndexByte(content[i:], '}')
+			exp.Args = append(exp.Args,
+				parseInterpolatedRef(loc, content[i+1:end], intern))
+			i = end
+		case '}':
+			// Must be }}
+			lit = append(lit, c)
+			i++
+		default:
+			lit = append(lit, c)
+		}
+	}
+	flush()
+	return exp
+}
+
+// parseInterpolatedRef parses a reference such as self.foo or CALL.out.bar
+// which appeared inside an f-string.
+func parseInterpolatedRef(loc SourceLoc, ref []byte, intern *stringIntern) *RefExp {
+	id, rest := ref, []byte(nil)
+	if i := bytes.IndexByte(ref, '.'); i >= 0 {
+		id, rest = ref[:i], ref[i+1:]
+	}
+	if string(id) == KindSelf {
+		id, rest = rest, nil
+		if i := bytes.IndexByte(id, '.'); i >= 0 {
+			id, rest = id[:i], id[i+1:]
+		}
+		return &RefExp{
+			Node:     NewAstNode(loc),
+			Kind:     KindSelf,
+			Id:       intern.Get(id),
+			OutputId: intern.Get(rest),
+		}
+	}
+	return &RefExp{
+		Node:     NewAstNode(loc),
+		Kind:     KindCall,
+		Id:       intern.Get(id),
+		OutputId: intern.Get(rest),
+	}
+}
+
+// isValidFunc checks that the function is known and that its arguments
+// are valid.  The type of the result is checked by the caller.
+func isValidFunc(exp *FuncExp, pipeline *Pipeline, ast *Ast) error {
+	var errs ErrorList
+	switch exp.Func {
+	case FuncInterpolate:
+	case FuncPathJoin:
+		if len(exp.Args) < 2 {
+			errs = append(errs, &IncompatibleTypeError{
+				Message: FuncPathJoin + " requires at least 2 arguments",
+			})
+		}
+	default:
+		if isNumericFunc(exp.Func) {
+			return &IncompatibleTypeError{
+				Message: exp.GoString() + " may only be used in stage resources",
+			}
+		}
+		return &IncompatibleTypeError{
+			Message: "unknown function " + exp.Func,
+		}
+	}
+	types := make([]TypeId, len(exp.Args))
+	for i, arg := range exp.Args {
+		t, err := funcArgType(exp.Func, arg, pipeline, ast)
+		if err != nil {
+			errs = append(errs, &IncompatibleTypeError{
+				Message: "argument " + strconv.Itoa(i+1) + " of " + exp.Func,
+				Reason:  err,
+			})
+		}
+		types[i] = t
+	}
+	if len(errs) == 0 && exp.argTypes == nil {
+		exp.argTypes = types
+	}
+	return errs.If()
+}
+
+// funcArgType checks that an argument to a function is a scalar value which
+// can be converted to a string, and returns its type.
+func funcArgType(fn string, arg Exp, pipeline *Pipeline, ast *Ast) (TypeId, error) {
+	switch arg := arg.(type) {
+	case *RefExp:
+		tname, _, err := arg.resolveType(ast, pipeline)
+		if err != nil {
+			return tname, err
+		}
+		if tname.ArrayDim > 0 {
+			return tname, &IncompatibleTypeError{
+				Message: "ReferenceError: binding is an array",
+			}
+		} else if tname.MapDim != 0 {
+			return tname, &IncompatibleTypeError{
+				Message: "ReferenceError: binding is a map",
+			}
+		}
+		switch t := ast.TypeTable.Get(tname).(type) {
+		case nil:
+			return tname, &IncompatibleTypeError{
+				Message: "Unknown type " + tname.Tname,
+			}
+		case *BuiltinType:
+			switch t.Id {
+			case KindString, KindPath, KindFile:
+				return tname, nil
+			case KindInt, KindFloat, KindBool:
+				if fn == FuncInterpolate {
+					return tname, nil
+				}
+			}
+		case *UserType, *EnumType:
+			return tname, nil
+		}
+		return tname, &IncompatibleTypeError{
+			Message: fmt.Sprintf("ReferenceError: cannot use %s in %s",
+				tname.str(), fn),
+		}
+	case *StringExp, *NullExp:
+		return TypeId{Tname: KindString}, nil
+	case *IntExp, *FloatExp, *BoolExp:
+		if fn == FuncInterpolate {
+			return TypeId{Tname: string(arg.getKind())}, nil
+		}
+	case *FuncExp:
+		return TypeId{Tname: KindString}, isValidFunc(arg, pipeline, ast)
+	case *DisabledExp:
+		return funcArgType(fn, arg.Value, pipeline, ast)
+	case *CondExp:
+		var errs ErrorList
+		if err := builtinBool.IsValidExpression(arg.Condition,
+			pipeline, ast); err != nil {
+			errs = append(errs, &IncompatibleTypeError{
+				Message: "condition " + arg.Condition.GoString(),
+				Reason:  err,
+			})
+		}
+		t, err := funcArgType(fn, arg.Then, pipeline, ast)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if et, err := funcArgType(fn, arg.Else, pipeline, ast); err != nil {
+			errs = append(errs, err)
+		} else if _, ok := arg.Then.(*RefExp); !ok {
+			t = et
+		}
+		return t, errs.If()
+	}
+	return TypeId{}, &IncompatibleTypeError{
+		Message: fmt.Sprintf("cannot use %s in %s", arg.getKind(), fn),
+	}
+}
@@ -30,6 +30,7 @@ type mmSymType struct {
 	i_params  *InParams
 	o_params  *OutParams
 	s_members []*StructMember
+	e_values  []*EnumValue
 	res       *Resources
 	par_tuple paramsTuple
 	src       *SrcParam
@@ -48,7 +49,7 @@ type mmSymType struct {
 	reflist   []*RefExp
 	includes  []*Include
 	intern    *stringIntern
-	f32       float32
+	nonnull   bool
 }
 
 const SKIP = 57346
@@ -79,22 +80,25 @@ const VOLATILE = 57370
 const DISABLED = 57371
 const STRICT = 57372
 const STRUCT = 57373
-const THREADS = 57374
-const MEM_GB = 57375
-const VMEM_GB = 57376
-const SPECIAL = 57377
-const ID = 57378
-const LITSTRING = 57379
-const NUM_FLOAT = 57380
-const NUM_INT = 57381
-const PY = 57382
-const EXEC = 57383
-const COMPILED = 57384
-const SELF = 57385
-const TRUE = 57386
-const FALSE = 57387
-const NULL = 57388
-const DEFAULT = 57389
+const ENUM = 57374
+const THREADS = 57375
+const MEM_GB = 57376
+const VMEM_GB = 57377
+const SPECIAL = 57378
+const TIMEOUT = 57379
+const ID = 57380
+const LITSTRING = 57381
+const FSTRING = 57382
+const NUM_FLOAT = 57383
+const NUM_INT = 57384
+const PY = 57385
+const EXEC = 57386
+const COMPILED = 57387
+const SELF = 57388
+const TRUE = 57389
+const FALSE = 57390
+const NULL = 57391
+const DEFAULT = 57392
 
 var mmToknames = [...]string{
 	"$end",
@@ -109,6 +113,11 @@ var mmToknames = [...]string{
 	"'='",
 	"'.'",
 	"'*'",
+	"'?'",
+	"'!'",
+	"'+'",
+	"'-'",
+	"'/'",
 	"'['",
 	"']'",
 	"'('",
@@ -142,12 +151,15 @@ var mmToknames = [...]string{
 	"DISABLED",
 	"STRICT",
 	"STRUCT",
+	"ENUM",
 	"THREADS",
 	"MEM_GB",
 	"VMEM_GB",
 	"SPECIAL",
+	"TIMEOUT",
 	"ID",
 	"LITSTRING",
+	"FSTRING",
 	"NUM_FLOAT",
 	"NUM_INT",
 	"PY",
@@ -170,280 +182,324 @@ var mmExca = [...]int16{
 	-1, 1,
 	1, -1,
 	-2, 0,
-	-1, 91,
-	15, 154,
-	29, 154,
-	-2, 86,
-	-1, 92,
-	15, 157,
-	29, 157,
-	-2, 87,
-	-1, 93,
-	15, 165,
-	29, 165,
-	-2, 88,
+	-1, 98,
+	11, 180,
+	20, 180,
+	34, 180,
+	-2, 108,
+	-1, 99,
+	11, 183,
+	20, 183,
+	34, 183,
+	-2, 109,
+	-1, 100,
+	11, 192,
+	20, 192,
+	34, 192,
+	-2, 110,
 }
 
 const mmPrivate = 57344
 
-const mmLast = 783
+const mmLast = 982
 
 var mmAct = [...]int16{
-	66, 287, 161, 81, 65, 128, 245, 169, 4, 230,
-	212, 32, 34, 188, 131, 132, 24, 22, 41, 15,
-	135, 82, 84, 63, 267, 115, 74, 293, 141, 75,
-	76, 77, 26, 27, 246, 83, 204, 205, 206, 86,
-	78, 292, 294, 289, 288, 224, 40, 165, 73, 240,
-	272, 228, 211, 187, 268, 269, 270, 271, 124, 250,
-	90, 46, 133, 136, 137, 139, 138, 140, 53, 57,
-	51, 47, 50, 58, 44, 54, 55, 56, 48, 49,
-	52, 42, 223, 86, 79, 37, 45, 43, 117, 238,
-	118, 213, 36, 189, 213, 189, 236, 41, 123, 21,
-	232, 119, 125, 184, 183, 109, 9, 41, 163, 116,
-	21, 129, 237, 284, 168, 117, 148, 117, 110, 120,
-	157, 28, 29, 21, 184, 121, 126, 127, 191, 17,
-	9, 41, 164, 167, 7, 234, 150, 153, 35, 109,
-	152, 263, 155, 274, 30, 166, 33, 28, 29, 21,
-	154, 186, 256, 210, 184, 17, 9, 99, 98, 41,
-	265, 202, 184, 151, 41, 108, 35, 259, 257, 41,
-	30, 252, 251, 247, 106, 105, 215, 142, 195, 185,
-	150, 104, 197, 179, 180, 41, 209, 178, 87, 190,
-	192, 193, 194, 80, 199, 198, 38, 94, 214, 208,
-	207, 144, 145, 146, 147, 191, 88, 175, 167, 97,
-	89, 89, 158, 97, 96, 283, 282, 281, 280, 231,
-	279, 226, 173, 227, 172, 171, 170, 156, 112, 111,
-	8, 300, 299, 298, 297, 296, 295, 241, 244, 243,
-	39, 286, 248, 285, 253, 254, 242, 239, 86, 233,
-	255, 258, 221, 220, 219, 218, 262, 160, 261, 217,
-	216, 23, 61, 176, 273, 25, 174, 278, 101, 276,
-	100, 95, 159, 103, 102, 1, 3, 260, 46, 31,
-	64, 5, 290, 291, 235, 53, 57, 51, 47, 50,
-	58, 44, 54, 55, 56, 48, 49, 52, 42, 12,
-	10, 11, 107, 45, 43, 67, 26, 27, 16, 23,
-	113, 114, 143, 25, 229, 72, 69, 71, 68, 62,
-	60, 196, 14, 13, 181, 222, 46, 130, 264, 249,
-	266, 182, 162, 53, 57, 51, 47, 50, 58, 44,
-	54, 55, 56, 48, 49, 52, 42, 12, 10, 11,
-	20, 45, 43, 67, 26, 27, 16, 23, 19, 18,
-	59, 25, 203, 134, 2, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 46, 0, 0, 0, 0, 0,
-	0, 177, 57, 51, 47, 50, 58, 44, 54, 55,
-	56, 48, 49, 52, 42, 12, 10, 11, 0, 45,
-	43, 67, 26, 27, 16, 46, 133, 136, 137, 139,
-	138, 140, 53, 57, 51, 47, 50, 58, 44, 54,
-	55, 56, 48, 49, 52, 42, 46, 0, 0, 0,
-	45, 43, 0, 53, 57, 51, 47, 50, 58, 44,
-	54, 55, 56, 48, 49, 52, 42, 0, 0, 0,
-	0, 45, 43, 0, 0, 0, 46, 122, 136, 137,
-	139, 138, 140, 53, 57, 51, 47, 50, 58, 44,
-	54, 55, 56, 48, 49, 52, 42, 0, 0, 200,
-	0, 45, 43, 201, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 46, 0, 0, 0,
-	0, 0, 0, 53, 57, 51, 47, 50, 58, 44,
-	54, 55, 56, 48, 49, 52, 42, 275, 0, 0,
-	0, 45, 43, 67, 0, 0, 0, 0, 0, 0,
-	0, 46, 225, 0, 0, 0, 0, 0, 53, 57,
-	51, 47, 50, 58, 44, 54, 55, 56, 48, 49,
-	52, 42, 0, 46, 0, 0, 45, 43, 67, 0,
-	53, 57, 51, 47, 50, 58, 44, 54, 55, 56,
-	48, 49, 52, 42, 189, 46, 0, 0, 45, 43,
-	0, 0, 53, 57, 51, 47, 50, 58, 44, 54,
-	55, 56, 48, 49, 52, 42, 46, 0, 0, 0,
-	45, 43, 67, 53, 57, 51, 47, 50, 58, 44,
-	54, 55, 56, 48, 49, 52, 42, 70, 0, 0,
-	0, 45, 43, 149, 0, 0, 0, 0, 0, 46,
-	0, 0, 0, 0, 0, 0, 53, 57, 51, 47,
-	50, 58, 44, 54, 55, 56, 48, 49, 52, 42,
-	73, 0, 23, 0, 45, 43, 25, 0, 0, 0,
-	6, 28, 29, 21, 0, 0, 0, 0, 0, 17,
-	9, 0, 0, 0, 0, 0, 0, 0, 0, 277,
-	0, 0, 0, 0, 30, 0, 0, 0, 0, 0,
-	12, 10, 11, 46, 0, 0, 85, 26, 27, 16,
-	53, 57, 51, 47, 50, 58, 44, 54, 55, 56,
-	48, 49, 52, 42, 46, 0, 0, 0, 45, 43,
-	0, 53, 57, 51, 47, 50, 58, 44, 54, 55,
-	56, 48, 49, 52, 42, 46, 0, 0, 0, 45,
-	43, 0, 53, 57, 51, 47, 50, 58, 44, 54,
-	55, 56, 48, 49, 52, 42, 46, 0, 0, 0,
-	45, 43, 0, 53, 57, 51, 91, 92, 93, 44,
-	54, 55, 56, 48, 49, 52, 42, 0, 0, 0,
-	0, 45, 43,
+	43, 181, 236, 88, 142, 271, 69, 68, 5, 325,
+	190, 252, 256, 323, 324, 187, 4, 146, 145, 34,
+	36, 25, 15, 149, 91, 71, 64, 79, 23, 127,
+	67, 89, 90, 335, 334, 155, 27, 28, 228, 229,
+	230, 93, 267, 268, 333, 295, 248, 281, 78, 157,
+	84, 336, 255, 42, 138, 85, 39, 235, 272, 277,
+	38, 260, 247, 191, 49, 80, 81, 82, 83, 209,
+	208, 56, 61, 54, 50, 53, 62, 46, 57, 58,
+	47, 59, 51, 52, 55, 60, 44, 262, 119, 117,
+	93, 237, 48, 45, 237, 129, 257, 130, 136, 97,
+	192, 257, 87, 71, 71, 71, 237, 137, 22, 254,
+	261, 139, 71, 71, 121, 9, 96, 320, 158, 143,
+	183, 22, 122, 159, 167, 71, 128, 129, 209, 129,
+	165, 134, 175, 132, 133, 300, 24, 214, 296, 131,
+	26, 135, 140, 141, 258, 184, 308, 161, 162, 163,
+	164, 171, 189, 121, 211, 168, 170, 209, 173, 172,
+	7, 306, 286, 186, 37, 209, 301, 302, 303, 304,
+	305, 108, 107, 234, 105, 71, 12, 71, 10, 11,
+	71, 71, 298, 342, 27, 28, 16, 358, 212, 338,
+	339, 226, 177, 169, 37, 356, 120, 96, 167, 357,
+	96, 210, 167, 96, 219, 168, 116, 202, 221, 115,
+	204, 205, 114, 213, 203, 289, 287, 216, 217, 218,
+	279, 278, 273, 223, 238, 71, 233, 231, 232, 239,
+	222, 29, 30, 22, 250, 35, 29, 30, 22, 17,
+	9, 94, 86, 40, 17, 9, 101, 249, 96, 105,
+	212, 253, 265, 266, 31, 32, 340, 113, 104, 31,
+	32, 341, 188, 269, 345, 214, 344, 280, 270, 275,
+	338, 339, 338, 339, 93, 285, 284, 288, 282, 337,
+	338, 339, 292, 96, 291, 338, 339, 294, 103, 199,
+	95, 106, 105, 307, 96, 178, 106, 319, 167, 318,
+	313, 317, 316, 315, 311, 314, 197, 196, 195, 194,
+	174, 124, 123, 349, 8, 329, 329, 329, 348, 347,
+	346, 328, 328, 328, 41, 322, 321, 309, 293, 331,
+	332, 329, 283, 274, 263, 245, 244, 328, 243, 329,
+	329, 329, 329, 329, 343, 328, 328, 328, 328, 328,
+	352, 353, 242, 350, 351, 241, 355, 24, 65, 329,
+	240, 26, 215, 200, 198, 328, 193, 110, 109, 102,
+	180, 179, 359, 176, 49, 112, 111, 3, 1, 354,
+	33, 56, 61, 54, 50, 53, 62, 46, 57, 58,
+	47, 59, 51, 52, 55, 60, 44, 12, 70, 10,
+	11, 290, 48, 45, 72, 27, 28, 16, 24, 259,
+	118, 125, 26, 126, 160, 251, 77, 74, 76, 73,
+	66, 220, 14, 13, 206, 49, 246, 156, 144, 297,
+	276, 299, 56, 61, 54, 50, 53, 62, 46, 57,
+	58, 47, 59, 51, 52, 55, 60, 44, 12, 70,
+	10, 11, 207, 48, 45, 72, 27, 28, 16, 24,
+	182, 21, 20, 26, 19, 18, 63, 227, 148, 2,
+	0, 0, 0, 0, 0, 0, 49, 0, 0, 0,
+	0, 0, 0, 201, 61, 54, 50, 53, 62, 46,
+	57, 58, 47, 59, 51, 52, 55, 60, 44, 12,
+	70, 10, 11, 185, 48, 45, 72, 27, 28, 16,
+	0, 0, 0, 0, 0, 0, 0, 49, 147, 150,
+	151, 153, 152, 154, 56, 61, 54, 50, 53, 62,
+	46, 57, 58, 47, 59, 51, 52, 55, 60, 44,
+	0, 0, 0, 0, 0, 48, 45, 49, 147, 150,
+	151, 153, 152, 154, 56, 61, 54, 50, 53, 62,
+	46, 57, 58, 47, 59, 51, 52, 55, 60, 44,
+	330, 0, 0, 0, 0, 48, 45, 0, 0, 0,
+	0, 0, 0, 0, 0, 49, 0, 0, 0, 0,
+	0, 0, 56, 61, 54, 50, 53, 62, 46, 57,
+	58, 47, 59, 51, 52, 55, 60, 44, 0, 0,
+	326, 327, 0, 48, 45, 72, 49, 0, 150, 151,
+	153, 152, 154, 56, 61, 54, 50, 53, 62, 46,
+	57, 58, 47, 59, 51, 52, 55, 60, 44, 224,
+	0, 0, 0, 225, 48, 45, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 49, 0, 0, 0,
+	0, 0, 0, 56, 61, 54, 50, 53, 62, 46,
+	57, 58, 47, 59, 51, 52, 55, 60, 44, 310,
+	0, 0, 0, 0, 48, 45, 72, 0, 0, 0,
+	0, 264, 0, 49, 0, 0, 0, 0, 0, 0,
+	56, 61, 54, 50, 53, 62, 46, 57, 58, 47,
+	59, 51, 52, 55, 60, 44, 0, 49, 0, 0,
+	0, 48, 45, 72, 56, 61, 54, 50, 53, 62,
+	46, 57, 58, 47, 59, 51, 52, 55, 60, 44,
+	237, 49, 0, 0, 0, 48, 45, 0, 56, 61,
+	54, 50, 53, 62, 46, 57, 58, 47, 59, 51,
+	52, 55, 60, 44, 0, 49, 0, 0, 0, 48,
+	45, 72, 56, 61, 54, 50, 53, 62, 46, 57,
+	58, 47, 59, 51, 52, 55, 60, 44, 75, 0,
+	0, 0, 0, 48, 45, 166, 0, 0, 0, 0,
+	49, 0, 0, 0, 0, 0, 0, 56, 61, 54,
+	50, 53, 62, 46, 57, 58, 47, 59, 51, 52,
+	55, 60, 44, 78, 312, 0, 0, 0, 48, 45,
+	0, 0, 0, 0, 0, 0, 0, 0, 49, 92,
+	0, 0, 0, 0, 0, 56, 61, 54, 50, 53,
+	62, 46, 57, 58, 47, 59, 51, 52, 55, 60,
+	44, 0, 49, 0, 0, 0, 48, 45, 0, 56,
+	61, 54, 50, 53, 62, 46, 57, 58, 47, 59,
+	51, 52, 55, 60, 44, 24, 0, 0, 0, 26,
+	48, 45, 0, 6, 29, 30, 22, 0, 0, 0,
+	0, 0, 17, 9, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 31, 32, 0,
+	0, 0, 0, 0, 0, 12, 0, 10, 11, 49,
+	0, 0, 0, 27, 28, 16, 56, 61, 54, 50,
+	53, 62, 46, 57, 58, 47, 59, 51, 52, 55,
+	60, 44, 49, 0, 0, 0, 0, 48, 45, 56,
+	61, 54, 98, 99, 100, 46, 57, 58, 47, 59,
+	51, 52, 55, 60, 44, 0, 0, 0, 0, 0,
+	48, 45,
 }
 
 var mmPact = [...]int16{
-	639, -1000, 125, 99, 54, -1000, 34, -1000, 181, 86,
-	-1000, -1000, -1000, -1000, -1000, -1000, -1000, 705, -1000, -1000,
-	-1000, -1000, -1000, 248, -1000, 599, -1000, -1000, 705, 705,
-	705, 99, 54, 33, 54, -1000, 178, -1000, 684, 173,
-	199, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 726,
-	183, -1000, 262, -1000, -1000, -1000, 203, 202, 140, 139,
-	-1000, 261, 259, 266, 265, 166, 160, 159, 54, -1000,
-	-1000, 149, 684, -1000, -1000, 219, 218, 705, -1000, 705,
-	72, -1000, -1000, -1000, -1000, 296, 396, 705, -1000, -1000,
-	7, 705, 296, 296, -1000, -1000, 375, 161, -1000, -1000,
-	-1000, 566, 296, 147, 684, -1000, 705, 217, -1000, 705,
-	-1000, 200, -1000, 201, 264, 249, -1000, -1000, 82, 82,
-	31, -1000, 705, 95, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, 200, -1000, -1000, 216, 215, 214, 212, 257, 198,
-	254, -1000, -1000, -1000, -1000, -1000, 344, -1000, 705, 296,
-	296, 76, -1000, 375, 135, -1000, -1000, 44, 426, 192,
-	-26, -26, -26, 545, -1000, -1000, -1000, 466, 200, -1000,
-	-1000, 145, -1000, -18, 375, 705, 136, -1000, 43, -1000,
-	-1000, 162, 251, 250, 246, 245, 244, 243, -1000, -1000,
-	296, -3, 45, -6, -1000, -1000, -1000, 523, -1000, 42,
-	75, -1000, 240, -1000, 115, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, 58, 74, 238, -1000, 40, 237, -1000, 75,
-	-5, 54, 158, -1000, -1000, 20, 157, 156, -1000, -1000,
-	-1000, 236, -1000, -5, 54, 134, 153, 684, 192, -1000,
-	152, -1000, -1000, 82, -1000, 123, -1000, -1000, 144, -1000,
-	8, 82, 127, -1000, 501, -1000, 663, -1000, 210, 208,
-	207, 206, 205, 97, -1000, -1000, 234, -1000, 232, -9,
-	-9, -9, -10, -17, -1000, -1000, -1000, 227, -1000, -1000,
-	226, 225, 224, 223, 222, -1000, -1000, -1000, -1000, -1000,
-	-1000,
+	867, -1000, 209, 204, 17, -1000, -2, -1000, 223, 92,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, 894, -1000, -1000,
+	-1000, -1000, -1000, -1000, 339, -1000, 765, -1000, -1000, 894,
+	894, 894, 894, 204, 17, -3, 17, -1000, 222, 68,
+	827, 221, 283, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, 917, 227, -1000, 360, -1000, -1000, 275,
+	-1000, 238, 285, 149, 148, -1000, 359, 358, 368, 367,
+	237, 192, 189, 186, 17, 55, -1000, 894, 175, 827,
+	-1000, -1000, 302, 301, 894, -1000, 894, 105, -1000, -1000,
+	-1000, -1000, 390, 390, 390, 29, 894, -1000, -1000, -4,
+	894, 390, 390, -1000, -1000, 512, -9, 894, 102, -1000,
+	-1000, -1000, -1000, 730, 390, 172, 827, -1000, 894, 300,
+	-1000, 894, -1000, 365, 171, 272, -1000, 284, 363, 362,
+	-1000, -1000, 89, 89, 482, -1000, 248, 128, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, 272, 42, 357, -1000, -1000,
+	-1000, 299, 298, 297, 296, 355, 280, 281, 354, -1000,
+	-1000, -1000, -1000, -1000, 441, -1000, 390, -1000, 894, 390,
+	390, 37, -1000, 512, 133, -1000, -1000, 894, -1000, 581,
+	247, -1000, 353, -1000, -30, -30, -30, 706, -1000, -1000,
+	-1000, 621, -1000, 272, -1000, -1000, 170, -1000, -24, 512,
+	248, 151, 48, -1000, 210, -1000, 351, 346, 343, 329,
+	327, 326, -1000, -1000, 390, -10, 20, -12, -1000, -1000,
+	-1000, 248, -1000, 894, 79, -1000, 43, -1000, 119, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, 18, 67, 325, 682,
+	33, 79, 14, 17, 202, -1000, 324, -1000, -1000, 15,
+	201, 200, -1000, -1000, -1000, 38, 323, -1000, 118, 14,
+	17, 139, 196, 827, -1000, 247, -1000, 195, -1000, -1000,
+	89, -1000, 319, -1000, 36, 115, -1000, -1000, 161, -1000,
+	114, 89, 125, -1000, 318, -1000, -1000, 658, -1000, 803,
+	-1000, 295, 293, 292, 291, 289, 287, 96, -1000, -1000,
+	-1000, 317, -1000, 316, 550, 550, 550, -14, -27, -16,
+	-1000, -1000, -1000, 270, 244, -1000, -1000, -1000, -1000, 163,
+	550, 257, 255, 311, 310, 309, 304, -1000, 550, 550,
+	550, 550, 550, 174, -1000, -1000, -1000, -1000, -1000, -1000,
+	244, 244, -1000, -1000, 178, 265, -1000, -1000, 550, 265,
 }
 
 var mmPgo = [...]int16{
-	0, 364, 0, 28, 20, 363, 13, 362, 10, 360,
-	7, 134, 359, 358, 350, 276, 332, 331, 14, 330,
-	329, 328, 6, 5, 2, 327, 325, 324, 15, 23,
-	4, 280, 19, 323, 17, 322, 16, 321, 320, 319,
-	318, 317, 316, 315, 8, 230, 314, 22, 25, 35,
-	312, 3, 21, 311, 310, 302, 9, 284, 277, 1,
-	275,
+	0, 469, 0, 35, 23, 468, 2, 467, 12, 466,
+	10, 160, 465, 464, 462, 461, 377, 460, 452, 18,
+	431, 430, 429, 5, 4, 1, 428, 427, 426, 424,
+	17, 15, 30, 6, 7, 22, 423, 28, 422, 21,
+	421, 26, 420, 419, 418, 417, 416, 16, 314, 415,
+	24, 29, 32, 414, 3, 31, 413, 411, 410, 11,
+	409, 401, 13, 14, 9, 379, 378,
 }
 
 var mmR1 = [...]int8{
-	0, 60, 60, 60, 60, 60, 60, 60, 1, 1,
-	15, 15, 11, 11, 11, 11, 13, 13, 12, 14,
-	57, 57, 58, 58, 58, 58, 58, 58, 58, 59,
-	59, 20, 20, 19, 19, 3, 3, 10, 10, 23,
-	23, 16, 16, 24, 24, 17, 17, 17, 17, 25,
-	25, 18, 18, 18, 27, 6, 8, 5, 5, 4,
-	4, 4, 4, 4, 4, 28, 28, 7, 7, 7,
-	26, 26, 26, 56, 22, 22, 21, 21, 46, 46,
-	45, 45, 44, 44, 44, 9, 9, 9, 9, 55,
-	55, 50, 50, 50, 50, 52, 52, 51, 51, 51,
-	51, 53, 53, 53, 53, 54, 54, 47, 49, 49,
-	48, 48, 37, 37, 39, 39, 38, 38, 41, 41,
-	40, 40, 43, 43, 42, 42, 29, 29, 31, 31,
-	31, 31, 31, 31, 31, 34, 33, 33, 36, 35,
-	35, 35, 32, 32, 30, 30, 30, 30, 30, 2,
+	0, 66, 66, 66, 66, 66, 66, 66, 1, 1,
+	1, 1, 16, 16, 11, 11, 11, 11, 11, 13,
+	13, 12, 14, 15, 60, 60, 61, 61, 61, 61,
+	61, 61, 61, 61, 62, 62, 62, 63, 63, 63,
+	64, 64, 64, 64, 64, 65, 65, 21, 21, 20,
+	20, 3, 3, 10, 10, 24, 24, 17, 17, 17,
+	17, 25, 25, 18, 18, 18, 18, 26, 26, 27,
+	27, 19, 19, 19, 29, 6, 8, 5, 5, 4,
+	4, 4, 4, 4, 4, 30, 30, 31, 31, 7,
+	7, 7, 28, 28, 28, 59, 23, 23, 22, 22,
+	49, 49, 48, 48, 47, 47, 47, 9, 9, 9,
+	9, 58, 58, 53, 53, 53, 53, 55, 55, 54,
+	54, 54, 54, 56, 56, 56, 56, 57, 57, 50,
+	52, 52, 51, 51, 40, 40, 42, 42, 41, 41,
+	44, 44, 43, 43, 46, 46, 45, 45, 32, 32,
+	32, 32, 32, 34, 34, 34, 34, 34, 34, 34,
+	37, 36, 36, 39, 38, 38, 38, 35, 35, 33,
+	33, 33, 33, 33, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
-	2, 2, 2, 2, 2, 2,
+	2, 2, 2,
 }
 
 var mmR2 = [...]int8{
-	0, 2, 3, 2, 1, 2, 1, 1, 3, 2,
-	2, 1, 3, 1, 1, 1, 11, 10, 10, 5,
-	0, 4, 0, 5, 5, 5, 5, 5, 5, 1,
-	1, 0, 4, 0, 3, 3, 1, 0, 3, 0,
-	2, 5, 4, 0, 2, 3, 4, 5, 2, 1,
-	2, 3, 4, 5, 4, 1, 1, 1, 1, 1,
-	1, 1, 1, 1, 1, 6, 2, 1, 1, 1,
-	0, 6, 5, 4, 0, 4, 0, 3, 2, 1,
-	3, 5, 4, 5, 5, 0, 2, 2, 2, 0,
-	2, 4, 4, 4, 4, 2, 1, 1, 2, 1,
-	0, 1, 2, 2, 2, 1, 2, 4, 4, 4,
-	5, 5, 1, 1, 3, 1, 2, 1, 5, 3,
-	2, 1, 5, 3, 2, 1, 1, 1, 1, 1,
-	1, 1, 1, 1, 1, 3, 1, 2, 3, 1,
-	3, 2, 1, 1, 3, 3, 1, 3, 5, 1,
+	0, 2, 3, 2, 1, 2, 1, 1, 3, 5,
+	2, 4, 2, 1, 3, 1, 1, 1, 1, 11,
+	10, 10, 5, 5, 0, 4, 0, 5, 5, 5,
+	5, 5, 5, 5, 1, 3, 3, 1, 3, 3,
+	1, 1, 1, 4, 3, 1, 3, 0, 4, 0,
+	3, 3, 1, 0, 3, 0, 2, 6, 5, 8,
+	7, 0, 2, 4, 5, 6, 2, 1, 2, 2,
+	3, 4, 5, 6, 4, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 6, 2, 0, 1, 1,
+	1, 1, 0, 6, 5, 4, 0, 4, 0, 3,
+	2, 1, 3, 5, 4, 5, 5, 0, 2, 2,
+	2, 0, 2, 4, 4, 4, 4, 2, 1, 1,
+	2, 1, 0, 1, 2, 2, 2, 1, 2, 4,
+	4, 4, 5, 5, 1, 1, 3, 1, 2, 1,
+	5, 3, 2, 1, 5, 3, 2, 1, 1, 1,
+	5, 1, 4, 1, 1, 1, 1, 1, 1, 1,
+	3, 1, 2, 3, 1, 3, 2, 1, 1, 3,
+	3, 1, 3, 5, 1, 1, 1, 1, 1, 1,
 	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
-	1, 1, 1, 1, 1, 1,
+	1, 1, 1,
 }
 
 var mmChk = [...]int16{
-	-1000, -60, -1, -15, -44, -31, 21, -11, -45, 31,
-	52, 53, 51, -33, -35, -32, 60, 30, -12, -13,
-	-14, 24, -34, 13, -36, 17, 58, 59, 22, 23,
-	45, -15, -44, 21, -44, -11, 38, 51, 15, -45,
-	-3, -2, 50, 56, 43, 55, 30, 40, 47, 48,
-	41, 39, 49, 37, 44, 45, 46, 38, 42, -9,
-	-38, 14, -39, -29, -31, -30, -2, 57, -40, -42,
-	18, -41, -43, 51, -2, -2, -2, -2, -44, 51,
-	15, -51, -52, -49, -47, 12, -2, 15, 7, 11,
-	-2, 40, 41, 42, 14, 9, 11, 11, 18, 18,
-	9, 9, 8, 8, 15, 15, 15, -55, 16, -47,
-	-49, 10, 10, -54, -53, -48, -52, -2, -2, 29,
-	-29, -3, 61, -2, 51, -2, -29, -29, -23, -23,
-	-25, -18, -28, 31, -5, -4, 32, 33, 35, 34,
-	36, -3, 16, -50, 40, 41, 42, 43, -30, 57,
-	-29, 16, -48, -47, -49, -48, 10, -2, 11, 8,
-	8, -24, -16, 26, -24, 16, -18, -2, 19, -10,
-	10, 10, 10, 10, 9, 9, 9, 37, -3, -29,
-	-29, -27, -17, 28, 27, -28, 16, 9, -6, 51,
-	-4, 13, -32, -32, -32, -30, -37, -30, -34, -36,
-	13, 17, 16, -7, 54, 55, 56, -28, -18, -2,
-	17, 9, -8, 51, -10, 14, 9, 9, 9, 9,
-	9, 9, -26, 37, 51, 9, -6, -6, 9, -46,
-	-56, -44, 25, 9, 20, -57, 38, 38, 15, 9,
-	9, -8, 9, -56, -44, -22, 39, 15, -10, -20,
-	39, 15, 15, -23, 9, -22, 18, 15, -51, 15,
-	-58, -23, -24, 18, -21, 16, -19, 16, 46, 47,
-	48, 49, 42, -24, 16, 16, -30, 16, -2, 10,
-	10, 10, 10, 10, 16, 9, 9, -59, 53, 52,
-	-59, -59, 51, 44, 59, 9, 9, 9, 9, 9,
-	9,
+	-1000, -66, -1, -16, -47, -34, 26, -11, -48, 36,
+	60, 61, 58, -36, -38, -35, 68, 35, -12, -13,
+	-14, -15, 29, -37, 18, -39, 22, 66, 67, 27,
+	28, 50, 51, -16, -47, 26, -47, -11, 43, 58,
+	20, -48, -3, -2, 57, 64, 48, 51, 63, 35,
+	45, 53, 54, 46, 44, 55, 42, 49, 50, 52,
+	56, 43, 47, -9, -41, 19, -42, -32, -34, -33,
+	59, -2, 65, -43, -45, 23, -44, -46, 58, -2,
+	-3, -3, -3, -3, -47, 58, 20, 34, -54, -55,
+	-52, -50, 12, -2, 20, 7, 11, -3, 45, 46,
+	47, 19, 9, 13, 20, 11, 11, 23, 23, 9,
+	9, 8, 8, 20, 20, 20, 20, 34, -58, -2,
+	21, -50, -52, 10, 10, -57, -56, -51, -55, -2,
+	-2, 34, -32, -32, -41, -3, 69, -2, 58, -2,
+	-32, -32, -24, -24, -26, -19, -30, 36, -5, -4,
+	37, 38, 40, 39, 41, -3, -27, 58, -2, 21,
+	-53, 45, 46, 47, 48, -33, 65, -2, -32, 21,
+	-51, -50, -52, -51, 10, -2, 8, 21, 11, 8,
+	8, -25, -17, 31, -25, 21, -19, -31, 14, 24,
+	-10, 21, 58, 9, 10, 10, 10, 10, 9, 9,
+	9, 42, -32, -3, -32, -32, -29, -18, 33, 32,
+	-30, 21, -2, -4, 18, 9, -35, -35, -35, -33,
+	-40, -33, -37, -39, 18, 22, 21, -7, 62, 63,
+	64, -30, -19, -31, 22, 9, -6, 58, -10, 19,
+	9, 9, 9, 9, 9, 9, -28, 42, 58, -31,
+	-2, -49, -59, -47, 30, 9, -8, 58, 25, -60,
+	43, 43, 20, 9, 9, -6, -6, 9, 10, -59,
+	-47, -23, 44, 20, 9, -10, -21, 44, 20, 20,
+	-24, 9, -8, 9, -34, -23, 23, 20, -54, 20,
+	-61, -24, -25, 9, -6, 9, 23, -22, 21, -20,
+	21, 52, 53, 54, 55, 56, 47, -25, 21, 9,
+	21, -33, 21, -2, 10, 10, 10, 10, 10, 10,
+	21, 9, 9, -62, -63, -64, 60, 61, -33, -2,
+	20, -62, -62, 58, 61, 49, 67, 9, 15, 16,
+	12, 17, 20, -62, 9, 9, 9, 9, 9, 9,
+	-63, -63, -64, -64, -65, -62, 21, 21, 9, -62,
 }
 
 var mmDef = [...]int16{
-	0, -2, 0, 4, 6, 7, 0, 11, 0, 0,
-	128, 129, 130, 131, 132, 133, 134, 0, 13, 14,
-	15, 85, 136, 0, 139, 0, 142, 143, 0, 0,
-	0, 1, 3, 0, 5, 10, 0, 9, 100, 0,
-	0, 36, 149, 150, 151, 152, 153, 154, 155, 156,
-	157, 158, 159, 160, 161, 162, 163, 164, 165, 0,
-	0, 137, 117, 115, 126, 127, 146, 0, 0, 0,
-	141, 121, 125, 0, 0, 0, 0, 0, 2, 8,
-	89, 0, 97, 99, 96, 0, 0, 0, 12, 0,
-	80, -2, -2, -2, 135, 116, 0, 0, 138, 140,
-	120, 124, 0, 0, 39, 39, 0, 0, 82, 95,
-	98, 0, 0, 0, 105, 101, 0, 0, 35, 0,
-	114, 144, 145, 147, 0, 0, 119, 123, 43, 43,
-	0, 49, 0, 58, 37, 57, 59, 60, 61, 62,
-	63, 64, 84, 90, 0, 0, 0, 0, 0, 0,
-	0, 83, 103, 104, 106, 102, 0, 81, 0, 0,
-	0, 0, 40, 0, 0, 19, 50, 0, 0, 66,
-	0, 0, 0, 0, 108, 109, 107, 160, 148, 118,
-	122, 0, 44, 0, 0, 0, 0, 51, 0, 55,
-	37, 0, 0, 0, 0, 0, 0, 0, 112, 113,
-	0, 0, 70, 0, 67, 68, 69, 0, 48, 0,
-	0, 52, 0, 56, 0, 38, 91, 92, 93, 94,
-	110, 111, 20, 0, 0, 45, 0, 0, 42, 0,
-	74, 79, 0, 53, 37, 31, 0, 0, 39, 54,
-	46, 0, 41, 74, 78, 0, 0, 100, 65, 18,
-	0, 22, 39, 43, 47, 0, 17, 76, 0, 33,
-	0, 43, 0, 16, 0, 73, 0, 21, 0, 0,
-	0, 0, 0, 0, 72, 75, 0, 32, 0, 0,
-	0, 0, 0, 0, 71, 77, 34, 0, 29, 30,
-	0, 0, 0, 0, 0, 23, 24, 25, 26, 27,
-	28,
+	0, -2, 0, 4, 6, 7, 0, 13, 0, 0,
+	153, 154, 155, 156, 157, 158, 159, 0, 15, 16,
+	17, 18, 107, 161, 0, 164, 0, 167, 168, 0,
+	0, 0, 0, 1, 3, 0, 5, 12, 0, 10,
+	122, 0, 0, 52, 174, 175, 176, 177, 178, 179,
+	180, 181, 182, 183, 184, 185, 186, 187, 188, 189,
+	190, 191, 192, 0, 0, 162, 139, 137, 148, 149,
+	151, 171, 0, 0, 0, 166, 143, 147, 0, 0,
+	0, 0, 0, 0, 2, 8, 111, 0, 0, 119,
+	121, 118, 0, 0, 0, 14, 0, 102, -2, -2,
+	-2, 160, 138, 0, 0, 0, 0, 163, 165, 142,
+	146, 0, 0, 55, 55, 0, 0, 0, 0, 11,
+	104, 117, 120, 0, 0, 0, 127, 123, 0, 0,
+	51, 0, 136, 0, 0, 169, 170, 172, 0, 0,
+	141, 145, 61, 61, 0, 67, 87, 78, 53, 77,
+	79, 80, 81, 82, 83, 84, 0, 0, 9, 106,
+	112, 0, 0, 0, 0, 0, 0, 171, 0, 105,
+	125, 126, 128, 124, 0, 103, 0, 152, 0, 0,
+	0, 0, 56, 0, 0, 22, 68, 0, 88, 0,
+	86, 23, 0, 69, 0, 0, 0, 0, 130, 131,
+	129, 186, 150, 173, 140, 144, 0, 62, 0, 0,
+	87, 0, 0, 53, 0, 70, 0, 0, 0, 0,
+	0, 0, 134, 135, 0, 0, 92, 0, 89, 90,
+	91, 87, 66, 0, 0, 71, 0, 75, 0, 54,
+	113, 114, 115, 116, 132, 133, 24, 0, 0, 0,
+	0, 0, 96, 101, 0, 72, 0, 76, 53, 47,
+	0, 0, 55, 74, 63, 0, 0, 58, 0, 96,
+	100, 0, 0, 122, 73, 85, 21, 0, 26, 55,
+	61, 64, 0, 57, 0, 0, 20, 98, 0, 49,
+	0, 61, 0, 65, 0, 60, 19, 0, 95, 0,
+	25, 0, 0, 0, 0, 0, 0, 0, 94, 59,
+	97, 0, 48, 0, 0, 0, 0, 0, 0, 0,
+	93, 99, 50, 0, 34, 37, 40, 41, 42, 171,
+	0, 0, 0, 0, 0, 0, 0, 27, 0, 0,
+	0, 0, 0, 0, 28, 29, 30, 31, 32, 33,
+	35, 36, 38, 39, 0, 45, 44, 43, 0, 46,
 }
 
 var mmTok1 = [...]int8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	15, 16, 12, 3, 9, 3, 11, 3, 3, 3,
+	3, 3, 3, 14, 3, 3, 3, 3, 3, 3,
+	20, 21, 12, 15, 9, 16, 11, 17, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 8, 7,
-	19, 10, 20, 3, 3, 3, 3, 3, 3, 3,
+	24, 10, 25, 13, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 13, 3, 14, 3, 3, 3, 3, 3, 3,
+	3, 18, 3, 19, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 17, 3, 18,
+	3, 3, 3, 22, 3, 23,
 }
 
 var mmTok2 = [...]int8{
-	2, 3, 4, 5, 6, 21, 22, 23, 24, 25,
-	26, 27, 28, 29, 30, 31, 32, 33, 34, 35,
-	36, 37, 38, 39, 40, 41, 42, 43, 44, 45,
-	46, 47, 48, 49, 50, 51, 52, 53, 54, 55,
-	56, 57, 58, 59, 60, 61,
+	2, 3, 4, 5, 6, 26, 27, 28, 29, 30,
+	31, 32, 33, 34, 35, 36, 37, 38, 39, 40,
+	41, 42, 43, 44, 45, 46, 47, 48, 49, 50,
+	51, 52, 53, 54, 55, 56, 57, 58, 59, 60,
+	61, 62, 63, 64, 65, 66, 67, 68, 69,
 }
 
 var mmTok3 = [...]int8{
@@ -840,6 +896,15 @@ mmdefault:
 			})
 		}
 	case 9:
+		mmDollar = mmS[mmpt-5 : mmpt+1]
+		{
+			mmVAL.includes = append(mmDollar[1].includes, &Include{
+				Node:  NewAstNode(mmDollar[2].loc),
+				Value: mmDollar[3].intern.unquote(mmDollar[3].val),
+				As:    mmDollar[5].intern.Get(mmDollar[5].val),
+			})
+		}
+	case 10:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmVAL.includes = []*Include{
@@ -849,17 +914,28 @@ mmdefault:
 				},
 			}
 		}
-	case 10:
+	case 11:
+		mmDollar = mmS[mmpt-4 : mmpt+1]
+		{
+			mmVAL.includes = []*Include{
+				{
+					Node:  NewAstNode(mmDollar[1].loc),
+					Value: mmDollar[2].intern.unquote(mmDollar[2].val),
+					As:    mmDollar[4].intern.Get(mmDollar[4].val),
+				},
+			}
+		}
+	case 12:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmVAL.decs = append(mmDollar[1].decs, mmDollar[2].dec)
 		}
-	case 11:
+	case 13:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.decs = []Dec{mmDollar[1].dec}
 		}
-	case 12:
+	case 14:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.dec = &UserType{
@@ -867,7 +943,7 @@ mmdefault:
 				Id:   mmDollar[2].intern.Get(mmDollar[2].val),
 			}
 		}
-	case 16:
+	case 19:
 		mmDollar = mmS[mmpt-11 : mmpt+1]
 		{
 			mmVAL.dec = &Pipeline{
@@ -880,7 +956,7 @@ mmdefault:
 				Retain:    mmDollar[10].plretains,
 			}
 		}
-	case 17:
+	case 20:
 		mmDollar = mmS[mmpt-10 : mmpt+1]
 		{
 			mmVAL.dec = &Pipeline{
@@ -893,7 +969,7 @@ mmdefault:
 				Retain:    mmDollar[9].plretains,
 			}
 		}
-	case 18:
+	case 21:
 		mmDollar = mmS[mmpt-10 : mmpt+1]
 		{
 			mmVAL.dec = &Stage{
@@ -909,7 +985,7 @@ mmdefault:
 				Retain:    mmDollar[10].stretains,
 			}
 		}
-	case 19:
+	case 22:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmVAL.dec = &StructType{
@@ -918,47 +994,56 @@ mmdefault:
 				Members: mmDollar[4].s_members,
 			}
 		}
-	case 20:
+	case 23:
+		mmDollar = mmS[mmpt-5 : mmpt+1]
+		{
+			mmVAL.dec = &EnumType{
+				Node:   NewAstNode(mmDollar[2].loc),
+				Id:     mmDollar[2].intern.Get(mmDollar[2].val),
+				Values: mmDollar[4].e_values,
+			}
+		}
+	case 24:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.res = nil
 		}
-	case 21:
+	case 25:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmDollar[3].res.Node = NewAstNode(mmDollar[1].loc)
 			mmVAL.res = mmDollar[3].res
 		}
-	case 22:
+	case 26:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.res = new(Resources)
 		}
-	case 23:
+	case 27:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			n := NewAstNode(mmDollar[2].loc)
 			mmDollar[1].res.ThreadNode = &n
-			mmDollar[1].res.Threads = roundUpTo(mmDollar[4].f32, 100)
+			mmDollar[1].res.Threads, mmDollar[1].res.ThreadsExp = resourceValue(mmDollar[4].exp, 100)
 			mmVAL.res = mmDollar[1].res
 		}
-	case 24:
+	case 28:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			n := NewAstNode(mmDollar[2].loc)
 			mmDollar[1].res.MemNode = &n
-			mmDollar[1].res.MemGB = roundUpTo(mmDollar[4].f32, 1024)
+			mmDollar[1].res.MemGB, mmDollar[1].res.MemGBExp = resourceValue(mmDollar[4].exp, 1024)
 			mmVAL.res = mmDollar[1].res
 		}
-	case 25:
+	case 29:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			n := NewAstNode(mmDollar[2].loc)
 			mmDollar[1].res.VMemNode = &n
-			mmDollar[1].res.VMemGB = roundUpTo(mmDollar[4].f32, 1024)
+			mmDollar[1].res.VMemGB, mmDollar[1].res.VMemGBExp = resourceValue(mmDollar[4].exp, 1024)
 			mmVAL.res = mmDollar[1].res
 		}
-	case 26:
+	case 30:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			n := NewAstNode(mmDollar[2].loc)
@@ -966,7 +1051,15 @@ mmdefault:
 			mmDollar[1].res.Special = mmDollar[4].intern.unquote(mmDollar[4].val)
 			mmVAL.res = mmDollar[1].res
 		}
-	case 27:
+	case 31:
+		mmDollar = mmS[mmpt-5 : mmpt+1]
+		{
+			n := NewAstNode(mmDollar[2].loc)
+			mmDollar[1].res.TimeoutNode = &n
+			mmDollar[1].res.Timeout = parseInt(mmDollar[4].val)
+			mmVAL.res = mmDollar[1].res
+		}
+	case 32:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			n := NewAstNode(mmDollar[2].loc)
@@ -974,7 +1067,7 @@ mmdefault:
 			mmDollar[1].res.StrictVolatile = true
 			mmVAL.res = mmDollar[1].res
 		}
-	case 28:
+	case 33:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			n := NewAstNode(mmDollar[2].loc)
@@ -982,22 +1075,93 @@ mmdefault:
 			mmDollar[1].res.StrictVolatile = false
 			mmVAL.res = mmDollar[1].res
 		}
-	case 29:
+	case 35:
+		mmDollar = mmS[mmpt-3 : mmpt+1]
+		{
+			mmVAL.exp = &FuncExp{
+				Node: NewAstNode(mmDollar[2].loc),
+				Func: FuncAdd,
+				Args: []Exp{mmDollar[1].exp, mmDollar[3].exp},
+			}
+		}
+	case 36:
+		mmDollar = mmS[mmpt-3 : mmpt+1]
+		{
+			mmVAL.exp = &FuncExp{
+				Node: NewAstNode(mmDollar[2].loc),
+				Func: FuncSub,
+				Args: []Exp{mmDollar[1].exp, mmDollar[3].exp},
+			}
+		}
+	case 38:
+		mmDollar = mmS[mmpt-3 : mmpt+1]
+		{
+			mmVAL.exp = &FuncExp{
+				Node: NewAstNode(mmDollar[2].loc),
+				Func: FuncMul,
+				Args: []Exp{mmDollar[1].exp, mmDollar[3].exp},
+			}
+		}
+	case 39:
+		mmDollar = mmS[mmpt-3 : mmpt+1]
+		{
+			mmVAL.exp = &FuncExp{
+				Node: NewAstNode(mmDollar[2].loc),
+				Func: FuncDiv,
+				Args: []Exp{mmDollar[1].exp, mmDollar[3].exp},
+			}
+		}
+	case 40:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
-			mmVAL.f32 = float32(parseInt(mmDollar[1].val))
+			mmVAL.exp = &FloatExp{
+				valExp: valExp{Node: NewAstNode(mmDollar[1].loc)},
+				Value:  parseFloat(mmDollar[1].val),
+			}
 		}
-	case 30:
+	case 41:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
-			mmVAL.f32 = parseFloat32(mmDollar[1].val)
+			mmVAL.exp = &IntExp{
+				valExp: valExp{Node: NewAstNode(mmDollar[1].loc)},
+				Value:  parseInt(mmDollar[1].val),
+			}
 		}
-	case 31:
+	case 42:
+		mmDollar = mmS[mmpt-1 : mmpt+1]
+		{
+			mmVAL.exp = mmDollar[1].rexp
+		}
+	case 43:
+		mmDollar = mmS[mmpt-4 : mmpt+1]
+		{
+			mmVAL.exp = &FuncExp{
+				Node: NewAstNode(mmDollar[1].loc),
+				Func: mmDollar[1].intern.Get(mmDollar[1].val),
+				Args: mmDollar[3].exps,
+			}
+		}
+	case 44:
+		mmDollar = mmS[mmpt-3 : mmpt+1]
+		{
+			mmVAL.exp = mmDollar[2].exp
+		}
+	case 45:
+		mmDollar = mmS[mmpt-1 : mmpt+1]
+		{
+			mmVAL.exps = []Exp{mmDollar[1].exp}
+		}
+	case 46:
+		mmDollar = mmS[mmpt-3 : mmpt+1]
+		{
+			mmVAL.exps = append(mmDollar[1].exps, mmDollar[3].exp)
+		}
+	case 47:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.stretains = nil
 		}
-	case 32:
+	case 48:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.stretains = &RetainParams{
@@ -1005,12 +1169,12 @@ mmdefault:
 				Params: mmDollar[3].retains,
 			}
 		}
-	case 33:
+	case 49:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.retains = nil
 		}
-	case 34:
+	case 50:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.retains = append(mmDollar[1].retains, &RetainParam{
@@ -1018,153 +1182,200 @@ mmdefault:
 				Id:   mmDollar[2].intern.Get(mmDollar[2].val),
 			})
 		}
-	case 35:
+	case 51:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.val = append(append(mmDollar[1].val, '.'), mmDollar[3].val...)
 		}
-	case 36:
+	case 52:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			// set capacity == length so append doesn't overwrite
 			// other parts of the buffer later.
 			mmVAL.val = mmDollar[1].val[:len(mmDollar[1].val):len(mmDollar[1].val)]
 		}
-	case 37:
+	case 53:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.arr = 0
 		}
-	case 38:
+	case 54:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.arr++
 		}
-	case 39:
+	case 55:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.i_params = new(InParams)
 		}
-	case 40:
+	case 56:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmDollar[1].i_params.List = append(mmDollar[1].i_params.List, mmDollar[2].inparam)
 			mmVAL.i_params = mmDollar[1].i_params
 		}
-	case 41:
+	case 57:
+		mmDollar = mmS[mmpt-6 : mmpt+1]
+		{
+			mmVAL.inparam = &InParam{
+				Node:    NewAstNode(mmDollar[1].loc),
+				Tname:   mmDollar[2].type_id,
+				Id:      mmDollar[4].intern.Get(mmDollar[4].val),
+				NonNull: mmDollar[3].nonnull,
+				Help:    unquote(mmDollar[5].val),
+			}
+		}
+	case 58:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmVAL.inparam = &InParam{
-				Node:  NewAstNode(mmDollar[1].loc),
-				Tname: mmDollar[2].type_id,
-				Id:    mmDollar[3].intern.Get(mmDollar[3].val),
-				Help:  unquote(mmDollar[4].val),
+				Node:    NewAstNode(mmDollar[1].loc),
+				Tname:   mmDollar[2].type_id,
+				Id:      mmDollar[4].intern.Get(mmDollar[4].val),
+				NonNull: mmDollar[3].nonnull,
 			}
 		}
-	case 42:
-		mmDollar = mmS[mmpt-4 : mmpt+1]
+	case 59:
+		mmDollar = mmS[mmpt-8 : mmpt+1]
 		{
 			mmVAL.inparam = &InParam{
-				Node:  NewAstNode(mmDollar[1].loc),
-				Tname: mmDollar[2].type_id,
-				Id:    mmDollar[3].intern.Get(mmDollar[3].val),
+				Node:    NewAstNode(mmDollar[1].loc),
+				Tname:   mmDollar[2].type_id,
+				Id:      mmDollar[4].intern.Get(mmDollar[4].val),
+				NonNull: mmDollar[3].nonnull,
+				Default: mmDollar[6].vexp,
+				Help:    unquote(mmDollar[7].val),
 			}
 		}
-	case 43:
+	case 60:
+		mmDollar = mmS[mmpt-7 : mmpt+1]
+		{
+			mmVAL.inparam = &InParam{
+				Node:    NewAstNode(mmDollar[1].loc),
+				Tname:   mmDollar[2].type_id,
+				Id:      mmDollar[4].intern.Get(mmDollar[4].val),
+				NonNull: mmDollar[3].nonnull,
+				Default: mmDollar[6].vexp,
+			}
+		}
+	case 61:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.o_params = new(OutParams)
 		}
-	case 44:
+	case 62:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmDollar[1].o_params.List = append(mmDollar[1].o_params.List, mmDollar[2].outparam)
 			mmVAL.o_params = mmDollar[1].o_params
 		}
-	case 45:
-		mmDollar = mmS[mmpt-3 : mmpt+1]
+	case 63:
+		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.outparam = &OutParam{
 				StructMember: StructMember{
-					Node:  NewAstNode(mmDollar[1].loc),
-					Tname: mmDollar[2].type_id,
-					Id:    defaultOutName,
+					Node:    NewAstNode(mmDollar[1].loc),
+					Tname:   mmDollar[2].type_id,
+					NonNull: mmDollar[3].nonnull,
+					Id:      defaultOutName,
 				},
 			}
 		}
-	case 46:
-		mmDollar = mmS[mmpt-4 : mmpt+1]
+	case 64:
+		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmVAL.outparam = &OutParam{
 				StructMember: StructMember{
-					Node:  NewAstNode(mmDollar[1].loc),
-					Tname: mmDollar[2].type_id,
-					Id:    defaultOutName,
-					Help:  unquote(mmDollar[3].val),
+					Node:    NewAstNode(mmDollar[1].loc),
+					Tname:   mmDollar[2].type_id,
+					NonNull: mmDollar[3].nonnull,
+					Id:      defaultOutName,
+					Help:    unquote(mmDollar[4].val),
 				},
 			}
 		}
-	case 47:
-		mmDollar = mmS[mmpt-5 : mmpt+1]
+	case 65:
+		mmDollar = mmS[mmpt-6 : mmpt+1]
 		{
 			mmVAL.outparam = &OutParam{
 				StructMember: StructMember{
 					Node:    NewAstNode(mmDollar[1].loc),
 					Tname:   mmDollar[2].type_id,
+					NonNull: mmDollar[3].nonnull,
 					Id:      defaultOutName,
-					OutName: mmDollar[5].intern.unquote(mmDollar[4].val),
-					Help:    unquote(mmDollar[3].val),
+					OutName: mmDollar[6].intern.unquote(mmDollar[5].val),
+					Help:    unquote(mmDollar[4].val),
 				},
 			}
 		}
-	case 48:
+	case 66:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmVAL.outparam = &OutParam{
 				StructMember: *mmDollar[2].s_member,
 			}
 		}
-	case 49:
+	case 67:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.s_members = []*StructMember{mmDollar[1].s_member}
 		}
-	case 50:
+	case 68:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmVAL.s_members = append(mmDollar[1].s_members, mmDollar[2].s_member)
 		}
-	case 51:
-		mmDollar = mmS[mmpt-3 : mmpt+1]
+	case 69:
+		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
-			mmVAL.s_member = &StructMember{
+			mmVAL.e_values = []*EnumValue{{
 				Node:  NewAstNode(mmDollar[1].loc),
-				Tname: mmDollar[1].type_id,
-				Id:    mmDollar[2].intern.Get(mmDollar[2].val),
-			}
+				Value: mmDollar[1].intern.unquote(mmDollar[1].val),
+			}}
 		}
-	case 52:
+	case 70:
+		mmDollar = mmS[mmpt-3 : mmpt+1]
+		{
+			mmVAL.e_values = append(mmDollar[1].e_values, &EnumValue{
+				Node:  NewAstNode(mmDollar[2].loc),
+				Value: mmDollar[2].intern.unquote(mmDollar[2].val),
+			})
+		}
+	case 71:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.s_member = &StructMember{
-				Node:  NewAstNode(mmDollar[1].loc),
-				Tname: mmDollar[1].type_id,
-				Id:    mmDollar[2].intern.Get(mmDollar[2].val),
-				Help:  unquote(mmDollar[3].val),
+				Node:    NewAstNode(mmDollar[1].loc),
+				Tname:   mmDollar[1].type_id,
+				Id:      mmDollar[3].intern.Get(mmDollar[3].val),
+				NonNull: mmDollar[2].nonnull,
 			}
 		}
-	case 53:
+	case 72:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmVAL.s_member = &StructMember{
 				Node:    NewAstNode(mmDollar[1].loc),
 				Tname:   mmDollar[1].type_id,
-				Id:      mmDollar[2].intern.Get(mmDollar[2].val),
-				OutName: mmDollar[4].intern.unquote(mmDollar[4].val),
-				Help:    unquote(mmDollar[3].val),
+				Id:      mmDollar[3].intern.Get(mmDollar[3].val),
+				NonNull: mmDollar[2].nonnull,
+				Help:    unquote(mmDollar[4].val),
 			}
 		}
-	case 54:
+	case 73:
+		mmDollar = mmS[mmpt-6 : mmpt+1]
+		{
+			mmVAL.s_member = &StructMember{
+				Node:    NewAstNode(mmDollar[1].loc),
+				Tname:   mmDollar[1].type_id,
+				Id:      mmDollar[3].intern.Get(mmDollar[3].val),
+				NonNull: mmDollar[2].nonnull,
+				OutName: mmDollar[5].intern.unquote(mmDollar[5].val),
+				Help:    unquote(mmDollar[4].val),
+			}
+		}
+	case 74:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			cmd := strings.TrimSpace(mmDollar[3].intern.unquote(mmDollar[3].val))
@@ -1177,7 +1388,7 @@ mmdefault:
 				Args: stagecodeParts[1:],
 			}
 		}
-	case 65:
+	case 85:
 		mmDollar = mmS[mmpt-6 : mmpt+1]
 		{
 			mmVAL.type_id = TypeId{
@@ -1186,7 +1397,7 @@ mmdefault:
 				MapDim:   1 + mmDollar[4].arr,
 			}
 		}
-	case 66:
+	case 86:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmVAL.type_id = TypeId{
@@ -1194,7 +1405,17 @@ mmdefault:
 				ArrayDim: mmDollar[2].arr,
 			}
 		}
-	case 70:
+	case 87:
+		mmDollar = mmS[mmpt-0 : mmpt+1]
+		{
+			mmVAL.nonnull = false
+		}
+	case 88:
+		mmDollar = mmS[mmpt-1 : mmpt+1]
+		{
+			mmVAL.nonnull = true
+		}
+	case 92:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.par_tuple = paramsTuple{
@@ -1203,7 +1424,7 @@ mmdefault:
 				Outs:    new(OutParams),
 			}
 		}
-	case 71:
+	case 93:
 		mmDollar = mmS[mmpt-6 : mmpt+1]
 		{
 			mmVAL.par_tuple = paramsTuple{
@@ -1212,7 +1433,7 @@ mmdefault:
 				Outs:    mmDollar[5].o_params,
 			}
 		}
-	case 72:
+	case 94:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmVAL.par_tuple = paramsTuple{
@@ -1221,7 +1442,7 @@ mmdefault:
 				Outs:    mmDollar[4].o_params,
 			}
 		}
-	case 73:
+	case 95:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.retstm = &ReturnStm{
@@ -1229,12 +1450,12 @@ mmdefault:
 				Bindings: mmDollar[3].bindings,
 			}
 		}
-	case 74:
+	case 96:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.plretains = nil
 		}
-	case 75:
+	case 97:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.plretains = &PipelineRetains{
@@ -1242,38 +1463,38 @@ mmdefault:
 				Refs: mmDollar[3].reflist,
 			}
 		}
-	case 76:
+	case 98:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.reflist = nil
 		}
-	case 77:
+	case 99:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.reflist = append(mmDollar[1].reflist, mmDollar[2].rexp)
 		}
-	case 78:
+	case 100:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmVAL.calls = append(mmDollar[1].calls, mmDollar[2].call)
 		}
-	case 79:
+	case 101:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.calls = []*CallStm{mmDollar[1].call}
 		}
-	case 80:
+	case 102:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			id := mmDollar[3].intern.Get(mmDollar[3].val)
 			mmVAL.call = &CallStm{
 				Node:      NewAstNode(mmDollar[1].loc),
 				Modifiers: mmDollar[2].modifiers,
-				Id:        id,
+				Id:        LocalName(id),
 				DecId:     id,
 			}
 		}
-	case 81:
+	case 103:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmVAL.call = &CallStm{
@@ -1283,59 +1504,59 @@ mmdefault:
 				DecId:     mmDollar[3].intern.Get(mmDollar[3].val),
 			}
 		}
-	case 82:
+	case 104:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmDollar[1].call.Bindings = mmDollar[3].bindings
 			mmVAL.call = mmDollar[1].call
 		}
-	case 83:
+	case 105:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmDollar[2].call.Bindings = mmDollar[4].bindings
 			mmDollar[2].call.Mapping = &mapSourcePlaceholder
 			mmVAL.call = mmDollar[2].call
 		}
-	case 84:
+	case 106:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmDollar[1].call.Modifiers.Bindings = mmDollar[4].bindings
 			mmVAL.call = mmDollar[1].call
 		}
-	case 85:
+	case 107:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.modifiers = new(Modifiers)
 		}
-	case 86:
+	case 108:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmVAL.modifiers.Local = true
 		}
-	case 87:
+	case 109:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmVAL.modifiers.Preflight = true
 		}
-	case 88:
+	case 110:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmVAL.modifiers.Volatile = true
 		}
-	case 89:
+	case 111:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.bindings = &BindStms{
 				Node: NewAstNode(mmDollar[0].loc),
 			}
 		}
-	case 90:
+	case 112:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmDollar[1].bindings.List = append(mmDollar[1].bindings.List, mmDollar[2].binding)
 			mmVAL.bindings = mmDollar[1].bindings
 		}
-	case 91:
+	case 113:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.binding = &BindStm{
@@ -1344,7 +1565,7 @@ mmdefault:
 				Exp:  mmDollar[3].vexp,
 			}
 		}
-	case 92:
+	case 114:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.binding = &BindStm{
@@ -1353,7 +1574,7 @@ mmdefault:
 				Exp:  mmDollar[3].vexp,
 			}
 		}
-	case 93:
+	case 115:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.binding = &BindStm{
@@ -1362,7 +1583,7 @@ mmdefault:
 				Exp:  mmDollar[3].vexp,
 			}
 		}
-	case 94:
+	case 116:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.binding = &BindStm{
@@ -1371,13 +1592,13 @@ mmdefault:
 				Exp:  mmDollar[3].rexp,
 			}
 		}
-	case 95:
+	case 117:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmDollar[1].bindings.List = append(mmDollar[1].bindings.List, mmDollar[2].binding)
 			mmVAL.bindings = mmDollar[1].bindings
 		}
-	case 96:
+	case 118:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.bindings = &BindStms{
@@ -1385,13 +1606,13 @@ mmdefault:
 				List: []*BindStm{mmDollar[1].binding},
 			}
 		}
-	case 98:
+	case 120:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmDollar[1].bindings.List = append(mmDollar[1].bindings.List, mmDollar[2].binding)
 			mmVAL.bindings = mmDollar[1].bindings
 		}
-	case 99:
+	case 121:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.bindings = &BindStms{
@@ -1399,14 +1620,14 @@ mmdefault:
 				List: []*BindStm{mmDollar[1].binding},
 			}
 		}
-	case 100:
+	case 122:
 		mmDollar = mmS[mmpt-0 : mmpt+1]
 		{
 			mmVAL.bindings = &BindStms{
 				Node: NewAstNode(mmDollar[0].loc),
 			}
 		}
-	case 101:
+	case 123:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.bindings = &BindStms{
@@ -1414,31 +1635,31 @@ mmdefault:
 				List: []*BindStm{mmDollar[1].binding},
 			}
 		}
-	case 102:
+	case 124:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmDollar[1].bindings.List = append(mmDollar[1].bindings.List, mmDollar[2].binding)
 			mmVAL.bindings = mmDollar[1].bindings
 		}
-	case 103:
+	case 125:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmDollar[1].bindings.List = append(mmDollar[1].bindings.List, mmDollar[2].binding)
 			mmVAL.bindings = mmDollar[1].bindings
 		}
-	case 104:
+	case 126:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmDollar[1].bindings.List = append(mmDollar[1].bindings.List, mmDollar[2].binding)
 			mmVAL.bindings = mmDollar[1].bindings
 		}
-	case 106:
+	case 128:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmDollar[1].bindings.List = append(mmDollar[1].bindings.List, mmDollar[2].binding)
 			mmVAL.bindings = mmDollar[1].bindings
 		}
-	case 107:
+	case 129:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.binding = &BindStm{
@@ -1447,7 +1668,7 @@ mmdefault:
 				Exp:  mmDollar[3].exp,
 			}
 		}
-	case 108:
+	case 130:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.binding = &BindStm{
@@ -1456,7 +1677,7 @@ mmdefault:
 				Exp:  mmDollar[3].rexp,
 			}
 		}
-	case 109:
+	case 131:
 		mmDollar = mmS[mmpt-4 : mmpt+1]
 		{
 			mmVAL.binding = &BindStm{
@@ -1468,7 +1689,7 @@ mmdefault:
 				},
 			}
 		}
-	case 110:
+	case 132:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmVAL.binding = &BindStm{
@@ -1481,7 +1702,7 @@ mmdefault:
 				},
 			}
 		}
-	case 111:
+	case 133:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmVAL.binding = &BindStm{
@@ -1493,49 +1714,73 @@ mmdefault:
 				},
 			}
 		}
-	case 114:
+	case 136:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.exps = append(mmDollar[1].exps, mmDollar[3].exp)
 		}
-	case 115:
+	case 137:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.exps = []Exp{mmDollar[1].exp}
 		}
-	case 118:
+	case 140:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmDollar[1].kvpairs[unquote(mmDollar[3].val)] = mmDollar[5].exp
 			mmVAL.kvpairs = mmDollar[1].kvpairs
 		}
-	case 119:
+	case 141:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.kvpairs = map[string]Exp{unquote(mmDollar[1].val): mmDollar[3].exp}
 		}
-	case 122:
+	case 144:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmDollar[1].kvpairs[mmDollar[3].intern.Get(mmDollar[3].val)] = mmDollar[5].exp
 			mmVAL.kvpairs = mmDollar[1].kvpairs
 		}
-	case 123:
+	case 145:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.kvpairs = map[string]Exp{mmDollar[1].intern.Get(mmDollar[1].val): mmDollar[3].exp}
 		}
-	case 126:
+	case 148:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.exp = mmDollar[1].vexp
 		}
-	case 127:
+	case 149:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.exp = mmDollar[1].rexp
 		}
-	case 128:
+	case 150:
+		mmDollar = mmS[mmpt-5 : mmpt+1]
+		{
+			mmVAL.exp = &CondExp{
+				Node:      NewAstNode(mmDollar[1].loc),
+				Condition: mmDollar[1].rexp,
+				Then:      mmDollar[3].exp,
+				Else:      mmDollar[5].exp,
+			}
+		}
+	case 151:
+		mmDollar = mmS[mmpt-1 : mmpt+1]
+		{
+			mmVAL.exp = parseInterpolation(mmDollar[1].loc, mmDollar[1].val, mmDollar[1].intern)
+		}
+	case 152:
+		mmDollar = mmS[mmpt-4 : mmpt+1]
+		{
+			mmVAL.exp = &FuncExp{
+				Node: NewAstNode(mmDollar[1].loc),
+				Func: mmDollar[1].intern.Get(mmDollar[1].val),
+				Args: mmDollar[3].exps,
+			}
+		}
+	case 153:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{ // Lexer guarantees parseable float strings.
 			f := parseFloat(mmDollar[1].val)
@@ -1544,7 +1789,7 @@ mmdefault:
 				Value:  f,
 			}
 		}
-	case 129:
+	case 154:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{ // Lexer guarantees parseable int strings.
 			i := parseInt(mmDollar[1].val)
@@ -1553,7 +1798,7 @@ mmdefault:
 				Value:  i,
 			}
 		}
-	case 130:
+	case 155:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.vexp = &StringExp{
@@ -1561,14 +1806,14 @@ mmdefault:
 				Value:  unquote(mmDollar[1].val),
 			}
 		}
-	case 134:
+	case 159:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.vexp = &NullExp{
 				valExp: valExp{Node: NewAstNode(mmDollar[1].loc)},
 			}
 		}
-	case 135:
+	case 160:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.vexp = &ArrayExp{
@@ -1576,7 +1821,7 @@ mmdefault:
 				Value:  mmDollar[2].exps,
 			}
 		}
-	case 137:
+	case 162:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmVAL.vexp = &ArrayExp{
@@ -1584,7 +1829,7 @@ mmdefault:
 				Value:  make([]Exp, 0),
 			}
 		}
-	case 138:
+	case 163:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.vexp = &MapExp{
@@ -1593,7 +1838,7 @@ mmdefault:
 				Value:  mmDollar[2].kvpairs,
 			}
 		}
-	case 140:
+	case 165:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.vexp = &MapExp{
@@ -1602,7 +1847,7 @@ mmdefault:
 				Value:  mmDollar[2].kvpairs,
 			}
 		}
-	case 141:
+	case 166:
 		mmDollar = mmS[mmpt-2 : mmpt+1]
 		{
 			mmVAL.vexp = &MapExp{
@@ -1611,7 +1856,7 @@ mmdefault:
 				Value:  make(map[string]Exp, 0),
 			}
 		}
-	case 142:
+	case 167:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.vexp = &BoolExp{
@@ -1619,7 +1864,7 @@ mmdefault:
 				Value:  true,
 			}
 		}
-	case 143:
+	case 168:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.vexp = &BoolExp{
@@ -1627,7 +1872,7 @@ mmdefault:
 				Value:  false,
 			}
 		}
-	case 144:
+	case 169:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.rexp = &RefExp{
@@ -1637,7 +1882,7 @@ mmdefault:
 				OutputId: mmDollar[3].intern.Get(mmDollar[3].val),
 			}
 		}
-	case 145:
+	case 170:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.rexp = &RefExp{
@@ -1647,7 +1892,7 @@ mmdefault:
 				OutputId: defaultOutName,
 			}
 		}
-	case 146:
+	case 171:
 		mmDollar = mmS[mmpt-1 : mmpt+1]
 		{
 			mmVAL.rexp = &RefExp{
@@ -1656,7 +1901,7 @@ mmdefault:
 				Id:   mmDollar[1].intern.Get(mmDollar[1].val),
 			}
 		}
-	case 147:
+	case 172:
 		mmDollar = mmS[mmpt-3 : mmpt+1]
 		{
 			mmVAL.rexp = &RefExp{
@@ -1665,7 +1910,7 @@ mmdefault:
 				Id:   mmDollar[3].intern.Get(mmDollar[3].val),
 			}
 		}
-	case 148:
+	case 173:
 		mmDollar = mmS[mmpt-5 : mmpt+1]
 		{
 			mmVAL.rexp = &RefExp{
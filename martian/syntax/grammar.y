@@ -29,6 +29,7 @@ import (
     i_params  *InParams
     o_params  *OutParams
     s_members []*StructMember
+    e_values  []*EnumValue
     res       *Resources
     par_tuple paramsTuple
     src       *SrcParam
@@ -47,14 +48,14 @@ import (
     reflist   []*RefExp
     includes  []*Include
     intern    *stringIntern
-    f32       float32
+    nonnull   bool
 }
 
 %type <includes>  includes
 %type <val>       id id_list nonmap_type type help src_lang outname
 %type <modifiers> modifiers
 %type <arr>       arr_list
-%type <dec>       dec stage pipeline struct
+%type <dec>       dec stage pipeline struct enum
 %type <decs>      dec_list
 %type <inparam>   in_param
 %type <outparam>  out_param
@@ -66,9 +67,11 @@ import (
 %type <i_params>  in_param_list
 %type <o_params>  out_param_list
 %type <s_members> struct_field_list
+%type <e_values>  enum_value_list
 %type <par_tuple> split_param_list
 %type <src>       src_stm
 %type <type_id>   type_id
+%type <nonnull>   nonnull
 %type <exp>       exp
 %type <rexp>      ref_exp
 %type <vexp>      val_exp bool_exp
@@ -86,18 +89,19 @@ import (
 %type <bindings>  modifier_stm_list
 %type <retstm>    return_stm
 %type <res>       resources resource_list
-%type <f32>       float_32
+%type <exp>       resource_exp resource_term resource_factor
+%type <exps>      resource_exp_list
 
 %token SKIP COMMENT INVALID
-%token ';' ':' ',' '=' '.' '*'
+%token ';' ':' ',' '=' '.' '*' '?' '!' '+' '-' '/'
 %token '[' ']' '(' ')' '{' '}' '<' '>'
 %token INCLUDE_DIRECTIVE STAGE PIPELINE CALL RETURN
 %token IN OUT SRC AS
 %token <val> FILETYPE MAP INT STRING FLOAT PATH BOOL
 %token <val> SPLIT USING RETAIN
-%token <val> LOCAL PREFLIGHT VOLATILE DISABLED STRICT STRUCT
-%token <val> THREADS MEM_GB VMEM_GB SPECIAL
-%token <val> ID LITSTRING NUM_FLOAT NUM_INT
+%token <val> LOCAL PREFLIGHT VOLATILE DISABLED STRICT STRUCT ENUM
+%token <val> THREADS MEM_GB VMEM_GB SPECIAL TIMEOUT
+%token <val> ID LITSTRING FSTRING NUM_FLOAT NUM_INT
 %token <val> PY EXEC COMPILED
 %token SELF TRUE FALSE NULL DEFAULT
 
@@ -151,6 +155,13 @@ includes
             Value: $<intern>3.unquote($3),
            })
         }
+    | includes INCLUDE_DIRECTIVE LITSTRING AS id
+        { $$ = append($1, &Include{
+            Node: NewAstNode($<loc>2),
+            Value: $<intern>3.unquote($3),
+            As: $<intern>5.Get($5),
+           })
+        }
     | INCLUDE_DIRECTIVE LITSTRING
         { $$ = []*Include{
               &Include{
@@ -159,6 +170,15 @@ includes
               },
            }
         }
+    | INCLUDE_DIRECTIVE LITSTRING AS id
+        { $$ = []*Include{
+              &Include{
+                  Node: NewAstNode($<loc>1),
+                  Value: $<intern>2.unquote($2),
+                  As: $<intern>4.Get($4),
+              },
+           }
+        }
 
 dec_list
     : dec_list dec
@@ -176,10 +196,11 @@ dec
     | stage
     | pipeline
     | struct
+    | enum
     ;
 
 pipeline
-    : PIPELINE id '(' in_param_list out_param_list ')' '{' call_stm_list return_stm pipeline_retain '}'
+    : PIPELINE id_list '(' in_param_list out_param_list ')' '{' call_stm_list return_stm pipeline_retain '}'
         { $$ = &Pipeline{
             Node: NewAstNode($<loc>2),
             Id: $<intern>2.Get($2),
@@ -189,7 +210,7 @@ pipeline
             Ret: $9,
             Retain: $10,
         } }
-    | PIPELINE id '(' in_param_list out_param_list ')' '{' return_stm pipeline_retain '}'
+    | PIPELINE id_list '(' in_param_list out_param_list ')' '{' return_stm pipeline_retain '}'
         { $$ = &Pipeline{
             Node: NewAstNode($<loc>2),
             Id: $<intern>2.Get($2),
@@ -202,7 +223,7 @@ pipeline
     ;
 
 stage
-    : STAGE id '(' in_param_list out_param_list src_stm ')' split_param_list resources stage_retain
+    : STAGE id_list '(' in_param_list out_param_list src_stm ')' split_param_list resources stage_retain
         { $$ = &Stage{
                 Node: NewAstNode($<loc>2),
                 Id: $<intern>2.Get($2),
@@ -219,7 +240,7 @@ stage
    ;
 
 struct
-   : STRUCT id '(' struct_field_list ')'
+   : STRUCT id_list '(' struct_field_list ')'
         { $$ = &StructType{
                 Node: NewAstNode($<loc>2),
                 Id: $<intern>2.Get($2),
@@ -227,6 +248,15 @@ struct
            }
         }
 
+enum
+   : ENUM id_list '(' enum_value_list ')'
+        { $$ = &EnumType{
+                Node: NewAstNode($<loc>2),
+                Id: $<intern>2.Get($2),
+                Values: $4,
+           }
+        }
+
 resources
     :
         { $$ = nil }
@@ -240,25 +270,25 @@ resources
 resource_list
     :
         { $$ = new(Resources) }
-    | resource_list THREADS '=' float_32 ','
+    | resource_list THREADS '=' resource_exp ','
         {
             n := NewAstNode($<loc>2)
             $1.ThreadNode = &n
-            $1.Threads = roundUpTo($4, 100)
+            $1.Threads, $1.ThreadsExp = resourceValue($4, 100)
             $$ = $1
         }
-    | resource_list MEM_GB '=' float_32 ','
+    | resource_list MEM_GB '=' resource_exp ','
         {
             n := NewAstNode($<loc>2)
             $1.MemNode = &n
-            $1.MemGB = roundUpTo($4, 1024)
+            $1.MemGB, $1.MemGBExp = resourceValue($4, 1024)
             $$ = $1
         }
-    | resource_list VMEM_GB '=' float_32 ','
+    | resource_list VMEM_GB '=' resource_exp ','
         {
             n := NewAstNode($<loc>2)
             $1.VMemNode = &n
-            $1.VMemGB = roundUpTo($4, 1024)
+            $1.VMemGB, $1.VMemGBExp = resourceValue($4, 1024)
             $$ = $1
         }
     | resource_list SPECIAL '=' LITSTRING ','
@@ -268,6 +298,13 @@ resource_list
             $1.Special = $<intern>4.unquote($4)
             $$ = $1
         }
+    | resource_list TIMEOUT '=' NUM_INT ','
+        {
+            n := NewAstNode($<loc>2)
+            $1.TimeoutNode = &n
+            $1.Timeout = parseInt($4)
+            $$ = $1
+        }
     | resource_list VOLATILE '=' STRICT ','
         {
             n := NewAstNode($<loc>2)
@@ -284,11 +321,66 @@ resource_list
         }
     ;
 
-float_32
-    : NUM_INT
-        { $$ = float32(parseInt($1)) }
-    | NUM_FLOAT
-        { $$ = parseFloat32($1) }
+resource_exp
+    : resource_term
+    | resource_exp '+' resource_term
+        { $$ = &FuncExp{
+            Node: NewAstNode($<loc>2),
+            Func: FuncAdd,
+            Args: []Exp{$1, $3},
+        } }
+    | resource_exp '-' resource_term
+        { $$ = &FuncExp{
+            Node: NewAstNode($<loc>2),
+            Func: FuncSub,
+            Args: []Exp{$1, $3},
+        } }
+    ;
+
+resource_term
+    : resource_factor
+    | resource_term '*' resource_factor
+        { $$ = &FuncExp{
+            Node: NewAstNode($<loc>2),
+            Func: FuncMul,
+            Args: []Exp{$1, $3},
+        } }
+    | resource_term '/' resource_factor
+        { $$ = &FuncExp{
+            Node: NewAstNode($<loc>2),
+            Func: FuncDiv,
+            Args: []Exp{$1, $3},
+        } }
+    ;
+
+resource_factor
+    : NUM_FLOAT
+        { $$ = &FloatExp{
+            valExp: valExp{Node: NewAstNode($<loc>1)},
+            Value: parseFloat($1),
+        } }
+    | NUM_INT
+        { $$ = &IntExp{
+            valExp: valExp{Node: NewAstNode($<loc>1)},
+            Value: parseInt($1),
+        } }
+    | ref_exp
+        { $$ = $1 }
+    | id '(' resource_exp_list ')'
+        { $$ = &FuncExp{
+            Node: NewAstNode($<loc>1),
+            Func: $<intern>1.Get($1),
+            Args: $3,
+        } }
+    | '(' resource_exp ')'
+        { $$ = $2 }
+    ;
+
+resource_exp_list
+    : resource_exp
+        { $$ = []Exp{$1} }
+    | resource_exp_list ',' resource_exp
+        { $$ = append($1, $3) }
     ;
 
 stage_retain
@@ -347,18 +439,37 @@ in_param_list
     ;
 
 in_param
-    : IN type_id id help ','
+    : IN type_id nonnull id help ','
         { $$ = &InParam{
             Node: NewAstNode($<loc>1),
             Tname: $2,
-            Id: $<intern>3.Get($3),
-            Help: unquote($4),
+            Id: $<intern>4.Get($4),
+            NonNull: $3,
+            Help: unquote($5),
         } }
-    | IN type_id id ','
+    | IN type_id nonnull id ','
         { $$ = &InParam{
             Node: NewAstNode($<loc>1),
             Tname: $2,
-            Id: $<intern>3.Get($3),
+            Id: $<intern>4.Get($4),
+            NonNull: $3,
+        } }
+    | IN type_id nonnull id '=' val_exp help ','
+        { $$ = &InParam{
+            Node: NewAstNode($<loc>1),
+            Tname: $2,
+            Id: $<intern>4.Get($4),
+            NonNull: $3,
+            Default: $6,
+            Help: unquote($7),
+        } }
+    | IN type_id nonnull id '=' val_exp ','
+        { $$ = &InParam{
+            Node: NewAstNode($<loc>1),
+            Tname: $2,
+            Id: $<intern>4.Get($4),
+            NonNull: $3,
+            Default: $6,
         } }
     ;
 
@@ -373,31 +484,34 @@ out_param_list
     ;
 
 out_param
-    : OUT type_id ','
+    : OUT type_id nonnull ','
         { $$ = &OutParam{
             StructMember: StructMember{
                 Node: NewAstNode($<loc>1),
                 Tname: $2,
+                NonNull: $3,
                 Id: defaultOutName,
             },
         } }
-    | OUT type_id help ','
+    | OUT type_id nonnull help ','
         { $$ = &OutParam{
             StructMember: StructMember{
                 Node: NewAstNode($<loc>1),
                 Tname: $2,
+                NonNull: $3,
                 Id: defaultOutName,
-                Help: unquote($3),
+                Help: unquote($4),
             },
         } }
-    | OUT type_id help outname ','
+    | OUT type_id nonnull help outname ','
         { $$ = &OutParam{
             StructMember: StructMember{
                 Node: NewAstNode($<loc>1),
                 Tname: $2,
+                NonNull: $3,
                 Id: defaultOutName,
-                OutName: $<intern>5.unquote($4),
-                Help: unquote($3),
+                OutName: $<intern>6.unquote($5),
+                Help: unquote($4),
             },
         } }
     | OUT struct_field
@@ -415,27 +529,43 @@ struct_field_list
         }
     ;
 
+enum_value_list
+    : LITSTRING ','
+        { $$ = []*EnumValue{{
+            Node: NewAstNode($<loc>1),
+            Value: $<intern>1.unquote($1),
+        }} }
+    | enum_value_list LITSTRING ','
+        { $$ = append($1, &EnumValue{
+            Node: NewAstNode($<loc>2),
+            Value: $<intern>2.unquote($2),
+        }) }
+    ;
+
 struct_field
-    : type_id id ','
+    : type_id nonnull id ','
         { $$ = &StructMember{
             Node: NewAstNode($<loc>1),
             Tname: $1,
-            Id: $<intern>2.Get($2),
+            Id: $<intern>3.Get($3),
+            NonNull: $2,
         } }
-    | type_id id help ','
+    | type_id nonnull id help ','
         { $$ = &StructMember{
             Node: NewAstNode($<loc>1),
             Tname: $1,
-            Id: $<intern>2.Get($2),
-            Help: unquote($3),
+            Id: $<intern>3.Get($3),
+            NonNull: $2,
+            Help: unquote($4),
         } }
-    | type_id id help outname ','
+    | type_id nonnull id help outname ','
         { $$ = &StructMember{
             Node: NewAstNode($<loc>1),
             Tname: $1,
-            Id: $<intern>2.Get($2),
-            OutName: $<intern>4.unquote($4),
-            Help: unquote($3),
+            Id: $<intern>3.Get($3),
+            NonNull: $2,
+            OutName: $<intern>5.unquote($5),
+            Help: unquote($4),
         } }
      ;
 
@@ -490,6 +620,13 @@ type_id
         } }
     ;
 
+nonnull
+    :
+        { $$ = false }
+    | '!'
+        { $$ = true }
+    ;
+
 src_lang
     : PY
     | EXEC
@@ -550,15 +687,15 @@ call_stm_list
     ;
 
 call_stm_begin
-    : CALL modifiers id
+    : CALL modifiers id_list
         { id := $<intern>3.Get($3)
           $$ = &CallStm{
             Node: NewAstNode($<loc>1),
             Modifiers: $2,
-            Id: id,
+            Id: LocalName(id),
             DecId: id,
         } }
-    | CALL modifiers id AS id
+    | CALL modifiers id_list AS id
         { $$ = &CallStm{
             Node: NewAstNode($<loc>1),
             Modifiers: $2,
@@ -799,6 +936,21 @@ exp
         { $$ = $1 }
     | ref_exp
         { $$ = $1 }
+    | ref_exp '?' exp ':' exp
+        { $$ = &CondExp{
+            Node: NewAstNode($<loc>1),
+            Condition: $1,
+            Then: $3,
+            Else: $5,
+        } }
+    | FSTRING
+        { $$ = parseInterpolation($<loc>1, $1, $<intern>1) }
+    | id '(' exp_list ')'
+        { $$ = &FuncExp{
+            Node: NewAstNode($<loc>1),
+            Func: $<intern>1.Get($1),
+            Args: $3,
+        } }
     ;
 
 val_exp
@@ -928,6 +1080,7 @@ id
     : ID
     | COMPILED
     | DISABLED
+    | ENUM
     | EXEC
     | FILETYPE
     | LOCAL
@@ -940,6 +1093,7 @@ id
     | STRICT
     | STRUCT
     | THREADS
+    | TIMEOUT
     | USING
     | VOLATILE
     ;
@@ -130,6 +130,18 @@ func addEdgeBindings(exp syntax.Exp, path string, set edgeBindingSet) {
 			// Skip disable, but keep value.
 			addEdgeBindings(exp.Value, p, set)
 			return syntax.SkipExp
+		case *syntax.CondExp:
+			if path != "" {
+				if p == "" {
+					p = path
+				} else {
+					p = path + "." + p
+				}
+			}
+			// Skip the condition, but keep both branches.
+			addEdgeBindings(exp.Then, p, set)
+			addEdgeBindings(exp.Else, p, set)
+			return syntax.SkipExp
 		case *syntax.RefExp:
 			if path != "" {
 				if p == "" {
@@ -30,6 +30,8 @@ type mmLexInfo struct {
 	intern *stringIntern
 	// True if the column number needs to be incremented.
 	incCol bool
+	// The id of the last token returned to the parser.
+	lastTok int
 }
 
 func (self *mmLexInfo) Loc() SourceLoc {
@@ -48,6 +50,12 @@ func (self *mmLexInfo) Lex(lval *mmSymType) int {
 
 		// Iterate through the regexps until one matches the head.
 		tokid, val := nextToken(head)
+		if (tokid == NUM_INT || tokid == NUM_FLOAT) &&
+			val[0] == '-' && endsOperand(self.lastTok) {
+			// A number can't directly follow an operand, so this must be
+			// subtraction, as in self.n-1.
+			tokid, val = '-', val[:1:1]
+		}
 		// Advance the cursor pos.
 		self.pos += len(val)
 		if self.incCol {
@@ -86,10 +94,25 @@ func (self *mmLexInfo) Lex(lval *mmSymType) int {
 		lval.global = self.global
 		lval.intern = self.intern
 
+		self.lastTok = tokid
 		return tokid
 	}
 }
 
+// endsOperand returns true if the token can be the end of an operand in an
+// arithmetic expression, that is a number, identifier, or closing
+// parenthesis.
+func endsOperand(tokid int) bool {
+	switch tokid {
+	case NUM_INT, NUM_FLOAT, ')',
+		ID, COMPILED, DISABLED, ENUM, EXEC, FILETYPE, LOCAL, MEM_GB,
+		VMEM_GB, PREFLIGHT, RETAIN, SPECIAL, SPLIT, STRICT, STRUCT,
+		THREADS, TIMEOUT, USING, VOLATILE:
+		return true
+	}
+	return false
+}
+
 func (self *mmLexInfo) getLine() []byte {
 	if self.pos >= len(self.src) {
 		return nil
@@ -659,7 +659,7 @@ func (err *InconsistentMapCallError) writeTo(w stringWriter) {
 	} else {
 		mustWriteString(w, "inconsistent split inputs in call to ")
 		mustWriteString(w, err.Call.DecId)
-		if err.Call.DecId != err.Call.Id {
+		if err.Call.IsAliased() {
 			mustWriteString(w, " as ")
 			mustWriteString(w, err.Call.Id)
 		}
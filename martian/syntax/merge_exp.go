@@ -305,6 +305,20 @@ func findMergeForkExpNode(v Exp, call *CallStm) *RefExp {
 			return m
 		}
 		return findMergeForkExpNode(v.Disabled, call)
+	case *CondExp:
+		if m := findMergeForkExpNode(v.Condition, call); m != nil {
+			return m
+		}
+		if m := findMergeForkExpNode(v.Then, call); m != nil {
+			return m
+		}
+		return findMergeForkExpNode(v.Else, call)
+	case *FuncExp:
+		for _, arg := range v.Args {
+			if m := findMergeForkExpNode(arg, call); m != nil {
+				return m
+			}
+		}
 	case *SplitExp:
 		if m := findMergeForkExpNode(v.Value, call); m != nil {
 			return m
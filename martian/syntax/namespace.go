@@ -0,0 +1,88 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+// Support for including files under a namespace.
+
+package syntax
+
+// addNamespace prefixes the names of the callables, structs, and enums
+// declared in the AST with the namespace, and updates references to them
+// so that they continue to refer to the same declarations.
+//
+// File types are not namespaced, since they are identified by their
+// extension rather than by a name which might collide.
+func (ast *Ast) addNamespace(ns string) {
+	names := make(map[string]string,
+		len(ast.Callables.List)+len(ast.StructTypes)+len(ast.EnumTypes))
+	qualify := func(id string) string {
+		q := ns + "." + id
+		names[id] = q
+		return q
+	}
+	for _, stage := range ast.Stages {
+		stage.Id = qualify(stage.Id)
+	}
+	for _, pipeline := range ast.Pipelines {
+		pipeline.Id = qualify(pipeline.Id)
+	}
+	for _, st := range ast.StructTypes {
+		st.Id = qualify(st.Id)
+	}
+	for _, et := range ast.EnumTypes {
+		et.Id = qualify(et.Id)
+	}
+	rename := func(t *TypeId) {
+		if q, ok := names[t.Tname]; ok {
+			t.Tname = q
+		}
+	}
+	renameIns := func(params *InParams) {
+		if params != nil {
+			for _, param := range params.List {
+				rename(&param.Tname)
+			}
+		}
+	}
+	renameOuts := func(params *OutParams) {
+		if params != nil {
+			for _, param := range params.List {
+				rename(&param.Tname)
+			}
+		}
+	}
+	for _, stage := range ast.Stages {
+		renameIns(stage.InParams)
+		renameOuts(stage.OutParams)
+		renameIns(stage.ChunkIns)
+		renameOuts(stage.ChunkOuts)
+	}
+	for _, pipeline := range ast.Pipelines {
+		renameIns(pipeline.InParams)
+		renameOuts(pipeline.OutParams)
+		for _, call := range pipeline.Calls {
+			if q, ok := names[call.DecId]; ok {
+				call.DecId = q
+			}
+		}
+	}
+	for _, st := range ast.StructTypes {
+		for _, member := range st.Members {
+			rename(&member.Tname)
+		}
+	}
+}
+
+// isIncludedFrom returns true if the file at the given path is the source
+// file or one of the files which included it, following the first include
+// of each file.
+func (src *SourceFile) isIncludedFrom(fullPath string) bool {
+	for f := src; f != nil; {
+		if f.FullPath == fullPath {
+			return true
+		}
+		if len(f.IncludedFrom) == 0 || f.IncludedFrom[0] == nil {
+			return false
+		}
+		f = f.IncludedFrom[0].File
+	}
+	return false
+}
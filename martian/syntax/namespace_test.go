@@ -0,0 +1,251 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package syntax
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeNamespaceFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name),
+			[]byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const namespaceLibSrc = `filetype txt;
+
+struct RESULT(
+    txt    data,
+    string name,
+)
+
+stage STAGE(
+    in  int    x,
+    out RESULT result,
+    src py     "stages/lib",
+)
+
+pipeline PIPE(
+    in  int    x,
+    out RESULT result,
+)
+{
+    call STAGE(
+        x = self.x,
+    )
+
+    return (
+        result = STAGE.result,
+    )
+}
+`
+
+const namespaceMainSrc = `@include "lib.mro" as lib
+@include "other.mro"
+
+pipeline TOP(
+    in  int        x,
+    out lib.RESULT result,
+    out RESULT     other,
+)
+{
+    call lib.PIPE(
+        x = self.x,
+    )
+
+    call STAGE(
+        x = self.x,
+    )
+
+    call lib.STAGE as LIB_STAGE(
+        x = self.x,
+    )
+
+    return (
+        result = PIPE.result,
+        other  = STAGE.result,
+    )
+}
+
+call TOP(
+    x = 1,
+)
+`
+
+func TestNamespacedInclude(t *testing.T) {
+	t.Parallel()
+	dir := writeNamespaceFiles(t, map[string]string{
+		"lib.mro":   namespaceLibSrc,
+		"other.mro": namespaceLibSrc,
+	})
+	postsrc, _, ast, err := ParseSourceBytes([]byte(namespaceMainSrc),
+		filepath.Join(dir, "main.mro"), []string{dir}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The expanded source is saved with pipestances, and must be possible
+	// to parse again without the included files.
+	if _, _, flat, err := ParseSourceBytes([]byte(postsrc),
+		"_mrosource", nil, false); err != nil {
+		t.Errorf("parsing expanded source: %v", err)
+	} else if flat.Callables.Table["lib.PIPE"] == nil {
+		t.Error("expected callable lib.PIPE in expanded source")
+	}
+	for _, id := range []string{"lib.STAGE", "lib.PIPE", "STAGE", "PIPE"} {
+		if ast.Callables.Table[id] == nil {
+			t.Errorf("expected callable %s", id)
+		}
+	}
+	if st, ok := ast.TypeTable.Get(TypeId{Tname: "lib.RESULT"}).(*StructType); !ok {
+		t.Error("expected struct lib.RESULT")
+	} else if st.Table["data"].Tname.Tname != "txt" {
+		t.Errorf("expected file type txt, got %s", st.Table["data"].Tname.Tname)
+	}
+	pipe := ast.Callables.Table["lib.PIPE"].(*Pipeline)
+	if pipe.Calls[0].DecId != "lib.STAGE" || pipe.Calls[0].Id != "STAGE" {
+		t.Errorf("expected call to lib.STAGE, got %s as %s",
+			pipe.Calls[0].DecId, pipe.Calls[0].Id)
+	}
+	top := ast.Callables.Table["TOP"].(*Pipeline)
+	if c := top.Calls[0]; c.Id != "PIPE" || c.IsAliased() {
+		t.Errorf("expected unaliased call PIPE, got %s", c.Id)
+	}
+	if c := top.Calls[2]; c.Id != "LIB_STAGE" || !c.IsAliased() {
+		t.Errorf("expected aliased call LIB_STAGE, got %s", c.Id)
+	}
+	if pipe.OutParams.Table["result"].Tname.Tname != "lib.RESULT" {
+		t.Errorf("expected lib.RESULT, got %s",
+			pipe.OutParams.Table["result"].Tname.Tname)
+	}
+}
+
+func TestNamespacedIncludeFormat(t *testing.T) {
+	t.Parallel()
+	ast, err := yaccParse([]byte(namespaceMainSrc), new(SourceFile),
+		makeStringIntern())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted := ast.format(true); formatted != namespaceMainSrc {
+		diffLines(namespaceMainSrc, formatted, t)
+	}
+}
+
+func TestNamespacedIncludeCycle(t *testing.T) {
+	t.Parallel()
+	dir := writeNamespaceFiles(t, map[string]string{
+		"a.mro": `@include "b.mro" as b
+`,
+		"b.mro": `@include "a.mro" as a
+`,
+	})
+	src := []byte(`@include "b.mro" as b
+`)
+	if _, _, _, err := ParseSourceBytes(src,
+		filepath.Join(dir, "a.mro"), []string{dir}, false); err == nil {
+		t.Error("expected an include cycle error")
+	}
+}
+
+func TestNamespacedIncludeFix(t *testing.T) {
+	t.Parallel()
+	dir := writeNamespaceFiles(t, map[string]string{
+		"lib.mro":   namespaceLibSrc,
+		"other.mro": namespaceLibSrc,
+		"extra.mro": "filetype bam;\n",
+	})
+	src := `@include "extra.mro"
+` + namespaceMainSrc
+	formatted, err := Format(src, filepath.Join(dir, "main.mro"),
+		true, []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted != namespaceMainSrc {
+		diffLines(namespaceMainSrc, formatted, t)
+	}
+}
+
+func TestNamespacedIncludeDiamond(t *testing.T) {
+	t.Parallel()
+	dir := writeNamespaceFiles(t, map[string]string{
+		"lib.mro": namespaceLibSrc,
+		"a.mro": `@include "lib.mro" as lib
+
+pipeline A(
+    in  int        x,
+    out lib.RESULT result,
+)
+{
+    call lib.PIPE(
+        x = self.x,
+    )
+
+    return (
+        result = PIPE.result,
+    )
+}
+`,
+		"b.mro": `@include "lib.mro" as lib
+
+pipeline B(
+    in  int        x,
+    out lib.RESULT result,
+)
+{
+    call lib.STAGE(
+        x = self.x,
+    )
+
+    return (
+        result = STAGE.result,
+    )
+}
+`,
+	})
+	src := []byte(`@include "a.mro"
+@include "b.mro"
+
+pipeline TOP(
+    in  int        x,
+    out lib.RESULT a,
+    out lib.RESULT b,
+)
+{
+    call A(
+        x = self.x,
+    )
+
+    call B(
+        x = self.x,
+    )
+
+    return (
+        a = A.result,
+        b = B.result,
+    )
+}
+`)
+	_, _, ast, err := ParseSourceBytes(src,
+		filepath.Join(dir, "main.mro"), []string{dir}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, stage := range ast.Stages {
+		if stage.Id == "lib.STAGE" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected lib.STAGE to be declared once, got %d", count)
+	}
+}
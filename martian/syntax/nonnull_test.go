@@ -0,0 +1,225 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package syntax
+
+import (
+	"strings"
+	"testing"
+)
+
+const nonNullPipelineSrc = `filetype fastq;
+
+struct READS(
+    fastq! r1,
+    fastq  r2,
+)
+
+stage CHECK(
+    out bool skip,
+    src py   "stages/check",
+)
+
+stage MAKE_READS(
+    in  int!   count,
+    out READS! reads,
+    src py     "stages/make_reads",
+)
+
+stage USE_READS(
+    in  READS! reads,
+    in  int    limit,
+    out int!   total,
+    src py     "stages/use_reads",
+)
+
+pipeline PIPE(
+    out int! total,
+)
+{
+    call CHECK()
+
+    call MAKE_READS(
+        count = 1,
+    )
+
+    call USE_READS(
+        reads = MAKE_READS.reads,
+        limit = null,
+    )
+
+    return (
+        total = USE_READS.total,
+    )
+}
+
+call PIPE()
+`
+
+func TestNonNullFormat(t *testing.T) {
+	t.Parallel()
+	ast := testGood(t, nonNullPipelineSrc)
+	if ast == nil {
+		return
+	}
+	if !ast.Callables.Table["USE_READS"].GetInParams().Table["reads"].NonNull {
+		t.Error("expected reads to be non-null")
+	}
+	if ast.Callables.Table["USE_READS"].GetInParams().Table["limit"].NonNull {
+		t.Error("expected limit to be nullable")
+	}
+	if formatted := ast.format(false); formatted != nonNullPipelineSrc {
+		diffLines(nonNullPipelineSrc, formatted, t)
+	}
+}
+
+func TestNonNullCompileErrors(t *testing.T) {
+	t.Parallel()
+	testBadCompile(t, strings.Replace(nonNullPipelineSrc,
+		"count = 1,", "count = null,", 1),
+		"NullBindingError: cannot bind null to non-null parameter 'count'")
+	testBadCompile(t, strings.Replace(nonNullPipelineSrc,
+		"in  int!   count,", "in  int!   count = null,", 1),
+		"NullBindingError: default value for non-null parameter 'count' cannot be null")
+	testBadCompile(t, `
+struct READS(
+    file! r1,
+)
+
+stage USE_READS(
+    in  READS reads,
+    src py    "stages/use_reads",
+)
+
+call USE_READS(
+    reads = {
+        r1: null,
+    },
+)
+`, "null value for non-null struct field r1")
+	testBadCompile(t, strings.NewReplacer(
+		"pipeline PIPE(\n", "pipeline PIPE(\n    in  bool flag,\n",
+		"count = 1,", "count = self.flag ? null : 1,",
+	).Replace(nonNullPipelineSrc),
+		"NullBindingError: cannot bind a value which may be null "+
+			"to non-null parameter 'count'")
+	testBadCompile(t, strings.NewReplacer(
+		"pipeline PIPE(\n", "pipeline PIPE(\n    in  bool flag,\n",
+		"count = 1,", "count = self.flag ? 1 : self.flag ? 2 : null,",
+	).Replace(nonNullPipelineSrc),
+		"NullBindingError: cannot bind a value which may be null "+
+			"to non-null parameter 'count'")
+}
+
+func TestNonNullEquals(t *testing.T) {
+	t.Parallel()
+	ast := testGood(t, nonNullPipelineSrc)
+	if ast == nil {
+		return
+	}
+	other := testGood(t, strings.NewReplacer(
+		"in  READS! reads,", "in  READS  reads,",
+		"out int!   total,", "out int    total,",
+	).Replace(nonNullPipelineSrc))
+	if other == nil {
+		return
+	}
+	stage := ast.Callables.Table["USE_READS"]
+	otherStage := other.Callables.Table["USE_READS"]
+	if !stage.GetInParams().Equals(stage.GetInParams()) {
+		t.Error("expected inputs to be equal")
+	}
+	if stage.GetInParams().Equals(otherStage.GetInParams()) {
+		t.Error("expected inputs with different nullability to differ")
+	}
+	if !stage.GetOutParams().Equals(stage.GetOutParams(), true) {
+		t.Error("expected outputs to be equal")
+	}
+	if stage.GetOutParams().Equals(otherStage.GetOutParams(), true) {
+		t.Error("expected outputs with different nullability to differ")
+	}
+}
+
+func TestNonNullResolve(t *testing.T) {
+	t.Parallel()
+	check := func(t *testing.T, src, expect string) {
+		t.Helper()
+		ast := testGood(t, src)
+		if ast == nil {
+			return
+		}
+		_, err := ast.MakePipelineCallGraph("", ast.Call)
+		if expect == "" {
+			if err != nil {
+				t.Error(err)
+			}
+		} else if err == nil {
+			t.Errorf("expected error %q", expect)
+		} else if !strings.Contains(err.Error(), expect) {
+			t.Errorf("expected %q, got %q", expect, err.Error())
+		}
+	}
+	t.Run("good", func(t *testing.T) {
+		check(t, nonNullPipelineSrc, "")
+	})
+	t.Run("disabled", func(t *testing.T) {
+		check(t, strings.Replace(nonNullPipelineSrc,
+			"        count = 1,\n    )",
+			"        count = 1,\n    ) using (\n        disabled = CHECK.skip,\n    )", 1),
+			"NullBindingError: non-null input reads of PIPE.USE_READS "+
+				"is bound to a value which is null if PIPE.CHECK.skip is true")
+	})
+	t.Run("both_disabled", func(t *testing.T) {
+		check(t, strings.Replace(strings.Replace(nonNullPipelineSrc,
+			"        count = 1,\n    )",
+			"        count = 1,\n    ) using (\n        disabled = CHECK.skip,\n    )", 1),
+			"        limit = null,\n    )",
+			"        limit = null,\n    ) using (\n        disabled = CHECK.skip,\n    )", 1),
+			"NullBindingError: non-null output total of PIPE")
+	})
+	t.Run("null_input", func(t *testing.T) {
+		check(t, strings.NewReplacer(
+			"pipeline PIPE(\n", "pipeline PIPE(\n    in  int  count,\n",
+			"count = 1,", "count = self.count,",
+			"call PIPE()\n", `pipeline OUTER(
+    out int total,
+)
+{
+    call PIPE(
+        count = null,
+    )
+
+    return (
+        total = PIPE.total,
+    )
+}
+
+call OUTER()
+`).Replace(nonNullPipelineSrc),
+			"NullBindingError: non-null input count of OUTER.PIPE.MAKE_READS is bound to null")
+	})
+	t.Run("cond_null", func(t *testing.T) {
+		check(t, strings.NewReplacer(
+			"pipeline PIPE(\n", "pipeline PIPE(\n    in  int  count,\n    in  bool flag,\n",
+			"count = 1,", "count = self.flag ? self.count : 1,",
+			"call PIPE()\n", `pipeline OUTER(
+    out int total,
+)
+{
+    call CHECK()
+
+    call PIPE(
+        count = null,
+        flag  = CHECK.skip,
+    )
+
+    return (
+        total = PIPE.total,
+    )
+}
+
+call OUTER()
+`).Replace(nonNullPipelineSrc),
+			"NullBindingError: non-null input count of OUTER.PIPE.MAKE_READS "+
+				"is bound to a conditional value which may be null")
+	})
+}
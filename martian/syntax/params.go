@@ -39,6 +39,10 @@ type (
 		GetHelp() string
 		GetOutName() string
 		IsFile() FileKind
+
+		// IsNonNull returns true if the parameter was declared with a
+		// trailing ! on its type, meaning it may not be null.
+		IsNonNull() bool
 	}
 
 	Param interface {
@@ -53,6 +57,13 @@ type (
 		Id     string
 		Help   string
 		Isfile FileKind
+
+		// True if the parameter may not be null.
+		NonNull bool
+
+		// The value to use if no argument is supplied for this parameter,
+		// or nil if an argument is required.
+		Default ValExp
 	}
 
 	OutParam struct {
@@ -115,11 +126,15 @@ func (s *InParam) GetId() string        { return s.Id }
 func (s *InParam) GetHelp() string      { return s.Help }
 func (s *InParam) GetOutName() string   { return "" }
 func (s *InParam) IsFile() FileKind     { return s.Isfile }
+func (s *InParam) IsNonNull() bool      { return s.NonNull }
 func (s *InParam) setIsFile(b FileKind) { s.Isfile = b }
 
 func (s *InParam) inheritComments() bool { return false }
 func (s *InParam) getSubnodes() []AstNodable {
-	return nil
+	if s.Default == nil {
+		return nil
+	}
+	return []AstNodable{s.Default}
 }
 
 func (s *OutParam) getNode() *AstNode { return &s.Node }
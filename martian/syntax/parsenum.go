@@ -87,6 +87,19 @@ func roundUpTo(value float32, granularity float64) float32 {
 	return 0
 }
 
+// resourceValue returns the value of a numeric literal in a stage resource
+// declaration, rounded to the given granularity, or the expression itself
+// if it must be computed at runtime.
+func resourceValue(exp Exp, granularity float64) (float32, Exp) {
+	switch exp := exp.(type) {
+	case *IntExp:
+		return roundUpTo(float32(exp.Value), granularity), nil
+	case *FloatExp:
+		return roundUpTo(float32(exp.Value), granularity), nil
+	}
+	return 0, exp
+}
+
 func unhex(c byte) byte {
 	switch {
 	case '0' <= c && c <= '9':
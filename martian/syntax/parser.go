@@ -299,6 +299,30 @@ func (parser *Parser) getIncludes(srcFile *SourceFile, includes []*Include, incP
 					innerError: fmt.Errorf("%s includes itself", srcFile.FullPath),
 					loc:        inc.Node.Loc,
 				})
+			} else if inc.As != "" {
+				key := includeKey(absPath, inc.As)
+				if iSrcFile := processedIncludes[key]; iSrcFile != nil {
+					// Already included under the same namespace elsewhere.
+					iSrcFile.IncludedFrom = append(iSrcFile.IncludedFrom,
+						&inc.Node.Loc)
+					continue
+				}
+				iast, err := parser.getNamespacedInclude(srcFile, inc, absPath,
+					incPaths[:len(incPaths)-1], processedIncludes)
+				// The last element of the array may have been overwritten.
+				// Restore it.
+				incPaths[len(incPaths)-1] = srcDir
+				errs = append(errs, err)
+				if iast != nil {
+					if iasts == nil {
+						iasts = iast
+					} else {
+						if err := iast.merge(iasts); err != nil {
+							errs = append(errs, err)
+						}
+						iasts = iast
+					}
+				}
 			} else if iSrcFile := processedIncludes[absPath]; iSrcFile != nil {
 				iSrcFile.IncludedFrom = append(iSrcFile.IncludedFrom, &inc.Node.Loc)
 				if err := srcFile.checkIncludes(absPath, &inc.Node.Loc); err != nil {
@@ -341,6 +365,52 @@ func (parser *Parser) getIncludes(srcFile *SourceFile, includes []*Include, incP
 	return iasts, errs.If()
 }
 
+// getNamespacedInclude parses a file which is included under a namespace.
+//
+// Unlike other includes, the file is parsed even if it was already included
+// without a namespace or under a different one, since its declarations get
+// different names.  It is only parsed once for each namespace.  Files which
+// were already included without a namespace before this one are not parsed
+// again, so declarations from them are shared rather than namespaced.
+func (parser *Parser) getNamespacedInclude(srcFile *SourceFile, inc *Include,
+	absPath string, incPaths []string,
+	processedIncludes map[string]*SourceFile) (*Ast, error) {
+	if srcFile.isIncludedFrom(absPath) {
+		return nil, &wrapError{
+			innerError: fmt.Errorf("%s includes itself", inc.Value),
+			loc:        inc.Node.Loc,
+		}
+	}
+	iSrcFile := &SourceFile{
+		FileName:     inc.Value,
+		FullPath:     absPath,
+		IncludedFrom: []*SourceLoc{&inc.Node.Loc},
+		namespace:    inc.As,
+	}
+	b, err := ioutil.ReadFile(iSrcFile.FullPath)
+	if err != nil {
+		return nil, &wrapError{
+			innerError: err,
+			loc:        inc.Node.Loc,
+		}
+	}
+	processedIncludes[includeKey(absPath, inc.As)] = iSrcFile
+	scope := make(map[string]*SourceFile, len(processedIncludes)+1)
+	for k, v := range processedIncludes {
+		// Namespaced includes within this file are nested in this
+		// namespace, so they do not match ones from outside of it.
+		if v.namespace == "" {
+			scope[k] = v
+		}
+	}
+	scope[absPath] = iSrcFile
+	iast, err := parser.parseSource(b, iSrcFile, incPaths, scope)
+	if iast != nil {
+		iast.addNamespace(inc.As)
+	}
+	return iast, err
+}
+
 // Get the mropath-relative and absolute paths for a file name,
 // which may or may not be an absolute file name.
 func IncludeFilePath(filename string, mroPaths []string) (rel, abs string, err error) {
@@ -930,6 +930,173 @@ pipeline SQ_PIPE(
 	}
 }
 
+func TestCondExp(t *testing.T) {
+	t.Parallel()
+	if ast := testGood(t, `
+stage SQUARE(
+    in  int value,
+    out int square,
+    src py  "stages/square",
+)
+
+pipeline SQ_PIPE(
+    in  bool square,
+    in  bool negate,
+    in  int  value,
+    out int  result,
+)
+{
+    call SQUARE(
+        value = self.negate ? self.value : 0,
+    )
+
+    return (
+        result = self.square ? SQUARE.square : self.negate ? 0 : self.value,
+    )
+}
+`); ast != nil {
+		exp, ok := ast.Pipelines[0].Ret.Bindings.List[0].Exp.(*CondExp)
+		if !ok {
+			t.Fatalf("expected conditional, got %T",
+				ast.Pipelines[0].Ret.Bindings.List[0].Exp)
+		}
+		if _, ok := exp.Else.(*CondExp); !ok {
+			t.Errorf("expected nested conditional, got %T", exp.Else)
+		}
+		if s := exp.GoString(); s != "self.square ? SQUARE.square : self.negate ? 0 : self.value" {
+			t.Errorf("incorrect expression %s", s)
+		}
+	}
+	testBadGrammar(t, `
+pipeline SQ_PIPE(
+    in  int value,
+    out int result,
+)
+{
+    return (
+        result = true ? self.value : 0,
+    )
+}
+`)
+}
+
+func TestInParamDefault(t *testing.T) {
+	t.Parallel()
+	if ast := testGood(t, `
+stage SQUARE(
+    in  int  value,
+    in  int  threads = 4    "number of threads",
+    in  bool negate  = false,
+    out int  square,
+    src py   "stages/square",
+)
+
+pipeline SQ_PIPE(
+    in  int value = 2,
+    out int result,
+)
+{
+    call SQUARE(
+        value = self.value,
+    )
+
+    return (
+        result = SQUARE.square,
+    )
+}
+`); ast != nil {
+		params := ast.Stages[0].InParams
+		if params.List[0].Default != nil {
+			t.Errorf("expected no default, got %v", params.List[0].Default)
+		}
+		if d, ok := params.List[1].Default.(*IntExp); !ok || d.Value != 4 {
+			t.Errorf("expected default 4, got %v", params.List[1].Default)
+		}
+		if h := params.List[1].Help; h != "number of threads" {
+			t.Errorf("incorrect help %q", h)
+		}
+		if d, ok := params.List[2].Default.(*BoolExp); !ok || d.Value {
+			t.Errorf("expected default false, got %v", params.List[2].Default)
+		}
+	}
+	testBadGrammar(t, `
+stage SQUARE(
+    in  int value = self.x,
+    src py  "stages/square",
+)
+`)
+}
+
+func TestFuncExp(t *testing.T) {
+	t.Parallel()
+	src := `stage ALIGN(
+    in  string sample,
+    in  path   reads,
+    in  int    lane,
+    out bam    aligned,
+    src py     "stages/align",
+)
+
+pipeline ALIGN_PIPE(
+    in  string sample_id,
+    in  path   outdir,
+    in  int    lane,
+    out bam    aligned,
+)
+{
+    call ALIGN(
+        sample = f"{self.sample_id}_L{self.lane}_{{R1\\\"}}",
+        reads  = path_join(self.outdir, "reads", f"{self.sample_id}.fastq"),
+        lane   = self.lane,
+    )
+
+    return (
+        aligned = ALIGN.aligned,
+    )
+}
+`
+	ast := testGood(t, "filetype bam;\n\n"+src)
+	if ast == nil {
+		return
+	}
+	bindings := ast.Pipelines[0].Calls[0].Bindings.Table
+	exp, ok := bindings["sample"].Exp.(*FuncExp)
+	if !ok {
+		t.Fatalf("expected function, got %T", bindings["sample"].Exp)
+	}
+	if exp.Func != FuncInterpolate {
+		t.Errorf("expected %s, got %s", FuncInterpolate, exp.Func)
+	}
+	if len(exp.Args) != 4 {
+		t.Errorf("expected 4 arguments, got %d", len(exp.Args))
+	} else if s, ok := exp.Args[3].(*StringExp); !ok {
+		t.Errorf("expected string, got %T", exp.Args[3])
+	} else if s.Value != `_{R1\"}` {
+		t.Errorf("incorrect literal %q", s.Value)
+	}
+	if s := bindings["reads"].Exp.GoString(); s != `path_join(self.outdir, "reads", f"{self.sample_id}.fastq")` {
+		t.Errorf("incorrect expression %s", s)
+	}
+	if formatted := ast.format(false); formatted != "filetype bam;\n\n"+src {
+		diffLines("filetype bam;\n\n"+src, formatted, t)
+	}
+	testBadGrammar(t, `
+call ALIGN(
+    sample = f"{self.sample_id",
+)
+`)
+	testBadGrammar(t, `
+call ALIGN(
+    sample = f"{self.sample_id}}",
+)
+`)
+	testBadGrammar(t, `
+call ALIGN(
+    sample = f"{ self.sample_id }",
+)
+`)
+}
+
 // Tests that preflights accept pipeline input values.
 func TestPreflightDepends(t *testing.T) {
 	t.Parallel()
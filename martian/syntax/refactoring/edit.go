@@ -5,6 +5,7 @@ package refactoring
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/martian-lang/martian/martian/syntax"
 )
@@ -41,6 +42,26 @@ func (e editSet) Apply(ast *syntax.Ast) (int, error) {
 	return count, errs.If()
 }
 
+// sameName returns true if the two declaration names are the same, ignoring
+// namespaces.
+//
+// Edits computed on an AST in which a file was included under a namespace
+// may be applied to an AST for that file by itself, where its declarations
+// are not namespaced.  Edits therefore also check the defining file.
+func sameName(id, other string) bool {
+	return syntax.LocalName(id) == syntax.LocalName(other)
+}
+
+// withLocalName replaces the unqualified part of a possibly-namespaced
+// name.
+func withLocalName(id, name string) string {
+	name = syntax.LocalName(name)
+	if i := strings.LastIndexByte(id, '.'); i >= 0 {
+		return id[:i+1] + name
+	}
+	return name
+}
+
 // A Matcher is used to determine whether an edit applies to a given Ast.
 type matcher func(*syntax.Ast) bool
 
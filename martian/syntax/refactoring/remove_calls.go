@@ -166,7 +166,7 @@ type removeCall struct {
 // The AST is not required to have been compiled.
 func (e removeCall) Apply(ast *syntax.Ast) (int, error) {
 	for _, pipe := range ast.Pipelines {
-		if pipe.Id == e.Pipeline.Id &&
+		if sameName(pipe.Id, e.Pipeline.Id) &&
 			pipe.Node.File().FullPath == e.Pipeline.Node.File().FullPath {
 			return e.remove(pipe)
 		}
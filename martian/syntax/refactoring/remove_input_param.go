@@ -23,7 +23,7 @@ type removeCallableInput struct {
 func (e removeCallableInput) Apply(ast *syntax.Ast) (int, error) {
 	count := 0
 	for _, target := range ast.Callables.List {
-		if target.GetId() == e.Callable.GetId() &&
+		if sameName(target.GetId(), e.Callable.GetId()) &&
 			target.File().FullPath == e.Callable.File().FullPath {
 			e.remove(target.GetInParams())
 			count++
@@ -72,7 +72,7 @@ func (e removeCallInput) Apply(ast *syntax.Ast) (int, error) {
 		return 0, nil
 	}
 	for _, pipe := range ast.Pipelines {
-		if pipe.Id == e.Pipeline.Id &&
+		if sameName(pipe.Id, e.Pipeline.Id) &&
 			pipe.Node.File().FullPath == e.Pipeline.Node.File().FullPath {
 			for _, call := range pipe.Calls {
 				if call.Id == e.Call.Id {
@@ -79,7 +79,7 @@ type (
 func (e removePipelineRetain) Apply(ast *syntax.Ast) (int, error) {
 	count := 0
 	for _, target := range ast.Pipelines {
-		if target.GetId() == e.Pipeline.Id &&
+		if sameName(target.GetId(), e.Pipeline.Id) &&
 			target.File().FullPath == e.Pipeline.File().FullPath {
 			e.remove(target)
 			count++
@@ -116,7 +116,7 @@ func (e removePipelineRetain) remove(target *syntax.Pipeline) {
 func (e removeStageOutput) Apply(ast *syntax.Ast) (int, error) {
 	count := 0
 	for _, target := range ast.Stages {
-		if target.Id == e.Stage.Id &&
+		if sameName(target.Id, e.Stage.Id) &&
 			target.File().FullPath == e.Stage.File().FullPath {
 			count += e.removeOutputParam(target.OutParams)
 			count += e.removeRetain(target)
@@ -173,7 +173,7 @@ func (e removeStageOutput) removeRetain(stage *syntax.Stage) int {
 // The AST is not required to have been compiled.
 func (e removeMod) Apply(ast *syntax.Ast) (int, error) {
 	for _, pipe := range ast.Pipelines {
-		if pipe.Id == e.Pipeline.Id &&
+		if sameName(pipe.Id, e.Pipeline.Id) &&
 			pipe.Node.File().FullPath == e.Pipeline.Node.File().FullPath {
 			for _, call := range pipe.Calls {
 				if call.Id == e.Call.Id {
@@ -219,7 +219,7 @@ func (e editBinding) Apply(ast *syntax.Ast) (int, error) {
 		return e.apply(ast.Call.Bindings.List), nil
 	}
 	for _, pipe := range ast.Pipelines {
-		if pipe.Id == e.Pipeline.Id &&
+		if sameName(pipe.Id, e.Pipeline.Id) &&
 			pipe.Node.File().FullPath == e.Pipeline.Node.File().FullPath {
 			if e.Call != nil {
 				return e.applyToCalls(pipe.Calls), nil
@@ -491,6 +491,44 @@ func removeRefFromExp(exp syntax.Exp,
 		ee := *exp
 		ee.Value = m
 		return &ee
+	case *syntax.CondExp:
+		e := removeRefFromExp(exp.Else, pipe, callable, param)
+		c := removeRefFromExp(exp.Condition, pipe, callable, param)
+		if _, ok := c.(*syntax.NullExp); ok {
+			// A null condition always selects the else branch.
+			return e
+		}
+		t := removeRefFromExp(exp.Then, pipe, callable, param)
+		if c == exp.Condition && t == exp.Then && e == exp.Else {
+			return exp
+		}
+		ee := *exp
+		ee.Condition = c
+		ee.Then = t
+		ee.Else = e
+		return &ee
+	case *syntax.FuncExp:
+		args := make([]syntax.Exp, 0, len(exp.Args))
+		change := false
+		for _, v := range exp.Args {
+			e := removeRefFromExp(v, pipe, callable, param)
+			if _, ok := e.(*syntax.NullExp); ok {
+				// A null argument makes the result null.
+				n := new(syntax.NullExp)
+				n.Node = exp.Node
+				return n
+			}
+			args = append(args, e)
+			if e != v {
+				change = true
+			}
+		}
+		if !change {
+			return exp
+		}
+		ee := *exp
+		ee.Args = args
+		return &ee
 	}
 	return exp
 }
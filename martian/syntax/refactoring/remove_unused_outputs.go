@@ -182,7 +182,7 @@ type removePipelineOutput struct {
 
 func (e removePipelineOutput) Apply(ast *syntax.Ast) (int, error) {
 	for _, pipe := range ast.Pipelines {
-		if pipe.Id == e.Pipeline.Id &&
+		if sameName(pipe.Id, e.Pipeline.Id) &&
 			pipe.File().FullPath == e.Pipeline.File().FullPath {
 			return e.removeParam(pipe) + e.removeRet(pipe), nil
 		}
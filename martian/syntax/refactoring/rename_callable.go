@@ -11,7 +11,7 @@ import (
 
 func RenameCallable(callable syntax.Callable,
 	newName string, asts []*syntax.Ast) Edit {
-	if callable.GetId() == newName {
+	if syntax.LocalName(callable.GetId()) == syntax.LocalName(newName) {
 		return nil
 	}
 	modified := make(map[decId]struct{}, 2*len(asts))
@@ -41,8 +41,8 @@ func RenameCallable(callable syntax.Callable,
 		// Fix up top-level call if needed.
 		if ast.Call != nil && ast.Call.DecId == callable.GetId() {
 			id := ast.Call.Id
-			if id == ast.Call.DecId {
-				id = newName
+			if !ast.Call.IsAliased() {
+				id = syntax.LocalName(newName)
 			}
 			edits = append(edits, renameCallEdit{
 				File:  syntax.DefiningFile(ast.Call),
@@ -61,9 +61,10 @@ func RenameCallable(callable syntax.Callable,
 func renameCallsToCallable(callable syntax.Callable,
 	newName string, pipe *syntax.Pipeline, edits editSet) editSet {
 	newIds := make(map[string]string)
+	newId := syntax.LocalName(newName)
 	for _, call := range pipe.Calls {
 		if call.DecId == callable.GetId() {
-			if call.DecId != call.Id || pipe.Callables.Table[newName] != nil {
+			if call.IsAliased() || pipe.Callables.Table[newId] != nil {
 				// Either the call was already aliased or changing the name
 				// would cause a collision.
 				edits = append(edits, renameCallEdit{
@@ -74,12 +75,12 @@ func renameCallsToCallable(callable syntax.Callable,
 					DecId:    newName,
 				})
 			} else {
-				newIds[call.Id] = newName
+				newIds[call.Id] = newId
 				edits = append(edits, renameCallEdit{
 					Pipeline: pipe,
 					File:     syntax.DefiningFile(call),
 					OldId:    call.Id,
-					Id:       newName,
+					Id:       newId,
 					DecId:    newName,
 				})
 			}
@@ -214,6 +215,34 @@ func updateRefInExp(exp syntax.Exp, kind syntax.ExpKind,
 		ee := *exp
 		ee.Value = m
 		return &ee
+	case *syntax.CondExp:
+		c := updateRefInExp(exp.Condition, kind, callId, oldName, newName)
+		t := updateRefInExp(exp.Then, kind, callId, oldName, newName)
+		e := updateRefInExp(exp.Else, kind, callId, oldName, newName)
+		if c == exp.Condition && t == exp.Then && e == exp.Else {
+			return exp
+		}
+		ee := *exp
+		ee.Condition = c
+		ee.Then = t
+		ee.Else = e
+		return &ee
+	case *syntax.FuncExp:
+		args := make([]syntax.Exp, 0, len(exp.Args))
+		change := false
+		for _, v := range exp.Args {
+			e := updateRefInExp(v, kind, callId, oldName, newName)
+			args = append(args, e)
+			if e != v {
+				change = true
+			}
+		}
+		if !change {
+			return exp
+		}
+		ee := *exp
+		ee.Args = args
+		return &ee
 	}
 	return exp
 }
@@ -236,21 +265,22 @@ type (
 
 func (e renameCallableEdit) Apply(ast *syntax.Ast) (int, error) {
 	for _, callable := range ast.Callables.List {
-		if callable.GetId() == e.Callable &&
+		if sameName(callable.GetId(), e.Callable) &&
 			syntax.DefiningFile(callable) == e.File {
+			newName := withLocalName(callable.GetId(), e.NewName)
 			if ast.Callables.Table != nil {
-				c, ok := ast.Callables.Table[e.Callable]
+				c, ok := ast.Callables.Table[callable.GetId()]
 				if ok {
-					delete(ast.Callables.Table, e.Callable)
-					ast.Callables.Table[e.NewName] = c
+					delete(ast.Callables.Table, callable.GetId())
+					ast.Callables.Table[newName] = c
 				}
 			}
 			switch c := callable.(type) {
 			case *syntax.Pipeline:
-				c.Id = e.NewName
+				c.Id = newName
 				return 1, nil
 			case *syntax.Stage:
-				c.Id = e.NewName
+				c.Id = newName
 				return 1, nil
 			default:
 				return 0, fmt.Errorf("unexpected callable type %T", callable)
@@ -269,20 +299,20 @@ func (e renameCallEdit) Apply(ast *syntax.Ast) (int, error) {
 			syntax.DefiningFile(ast.Call) != e.File {
 			return 0, nil
 		}
-		ast.Call.DecId = e.DecId
+		ast.Call.DecId = withLocalName(ast.Call.DecId, e.DecId)
 		ast.Call.Id = e.Id
 		return 1, nil
 	}
 	edits := 0
 	for _, p := range ast.Pipelines {
-		if p.Id != e.Pipeline.Id ||
+		if !sameName(p.Id, e.Pipeline.Id) ||
 			syntax.DefiningFile(p) != syntax.DefiningFile(e.Pipeline) {
 			continue
 		}
 		for _, call := range p.Calls {
 			if call.Id == e.OldId {
 				call.Id = e.Id
-				call.DecId = e.DecId
+				call.DecId = withLocalName(call.DecId, e.DecId)
 				edits++
 			}
 		}
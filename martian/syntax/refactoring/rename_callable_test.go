@@ -3,7 +3,10 @@
 package refactoring
 
 import (
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/martian-lang/martian/martian/syntax"
@@ -218,3 +221,83 @@ call NEWNAME(
 		diff(t, expected, s)
 	}
 }
+
+func TestRenameNamespacedCallable(t *testing.T) {
+	var parser syntax.Parser
+	dir := t.TempDir()
+	const libSrc = `stage OLDNAME(
+    in  int foo,
+    out int bar,
+    src comp "none",
+)
+
+pipeline INNER(
+    in  int foo,
+    out int bar,
+)
+{
+    call OLDNAME(
+        foo = self.foo,
+    )
+
+    return (
+        bar = OLDNAME.bar,
+    )
+}
+`
+	const src = `@include "lib.mro" as lib
+
+pipeline OUTER(
+    in  int foo,
+    out int bar,
+)
+{
+    call lib.OLDNAME(
+        foo = self.foo,
+    )
+
+    return (
+        bar = OLDNAME.bar,
+    )
+}
+`
+	libFile := filepath.Join(dir, "lib.mro")
+	if err := os.WriteFile(libFile, []byte(libSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "outer.mro")
+	_, _, ast, err := parser.ParseSourceBytes([]byte(src), file,
+		[]string{dir}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit := RenameCallable(ast.Callables.Table["lib.OLDNAME"],
+		"NEWNAME", []*syntax.Ast{ast})
+	if edit == nil {
+		t.Fatal("Expected non-nil edit")
+	}
+	fmtAst, err := parser.UncheckedParse([]byte(src), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := edit.Apply(fmtAst); err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.NewReplacer(
+		"lib.OLDNAME", "lib.NEWNAME",
+		"OLDNAME.", "NEWNAME.").Replace(src)
+	if s := fmtAst.Format(); s != expected {
+		diff(t, expected, s)
+	}
+	libAst, err := parser.UncheckedParse([]byte(libSrc), libFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := edit.Apply(libAst); err != nil {
+		t.Fatal(err)
+	}
+	expected = strings.ReplaceAll(libSrc, "OLDNAME", "NEWNAME")
+	if s := libAst.Format(); s != expected {
+		diff(t, expected, s)
+	}
+}
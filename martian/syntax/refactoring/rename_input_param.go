@@ -134,7 +134,7 @@ type (
 func (e renameCallableInputEdit) Apply(ast *syntax.Ast) (int, error) {
 	count := 0
 	for _, callable := range ast.Callables.List {
-		if callable.GetId() == e.Callable.GetId() &&
+		if sameName(callable.GetId(), e.Callable.GetId()) &&
 			syntax.DefiningFile(callable) == syntax.DefiningFile(e.Callable) {
 			count += e.applyIns(callable.GetInParams())
 			if pipe, ok := callable.(*syntax.Pipeline); ok &&
@@ -145,12 +145,34 @@ func (e renameCallableInputEdit) Apply(ast *syntax.Ast) (int, error) {
 						count++
 					}
 				}
+			} else if stage, ok := callable.(*syntax.Stage); ok &&
+				stage != nil && stage.Resources != nil {
+				count += e.applyResources(stage.Resources)
 			}
 		}
 	}
 	return count, nil
 }
 
+// applyResources updates references in resource expressions.
+func (e renameCallableInputEdit) applyResources(res *syntax.Resources) int {
+	count := 0
+	for _, exp := range [...]*syntax.Exp{
+		&res.ThreadsExp,
+		&res.MemGBExp,
+		&res.VMemGBExp,
+	} {
+		if *exp != nil {
+			if n := updateRefInExp(*exp, syntax.KindSelf, "",
+				e.OldParam, e.NewParam); n != *exp {
+				*exp = n
+				count++
+			}
+		}
+	}
+	return count
+}
+
 func (e renameCallableInputEdit) applyIns(params *syntax.InParams) int {
 	if params == nil {
 		return 0
@@ -184,7 +206,7 @@ func (e renameCallParamEdit) Apply(ast *syntax.Ast) (int, error) {
 	}
 	edits := 0
 	for _, p := range ast.Pipelines {
-		if p.Id != e.Pipeline.Id ||
+		if !sameName(p.Id, e.Pipeline.Id) ||
 			syntax.DefiningFile(p) != syntax.DefiningFile(e.Pipeline) {
 			continue
 		}
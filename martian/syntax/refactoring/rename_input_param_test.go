@@ -18,6 +18,8 @@ stage FOO(
     in  file bar,
     out file foo,
     src comp "none",
+) using (
+    mem_gb = self.foo * 2,
 )
 
 pipeline PIPE(
@@ -85,8 +87,8 @@ call PIPE(
 	}
 	if c, err := edit.Apply(fmtAst); err != nil {
 		t.Fatal(err)
-	} else if c != 2 {
-		t.Errorf("%d != 2", c)
+	} else if c != 3 {
+		t.Errorf("%d != 3", c)
 	}
 	edit = RenameInput(ast.Callables.Table["PIPE"],
 		"disble", "disable", []*syntax.Ast{ast})
@@ -106,6 +108,8 @@ call PIPE(
     in  file bar,
     out file foo,
     src comp "none",
+) using (
+    mem_gb = self.fuzz * 2,
 )
 
 pipeline PIPE(
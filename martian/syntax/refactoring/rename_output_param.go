@@ -131,7 +131,7 @@ type (
 func (e renameCallableOutputEdit) Apply(ast *syntax.Ast) (int, error) {
 	count := 0
 	for _, callable := range ast.Callables.List {
-		if callable.GetId() == e.Callable.GetId() &&
+		if sameName(callable.GetId(), e.Callable.GetId()) &&
 			syntax.DefiningFile(callable) == syntax.DefiningFile(e.Callable) {
 			count += e.applyOuts(callable.GetOutParams())
 			if pipe, ok := callable.(*syntax.Pipeline); ok &&
@@ -178,7 +178,7 @@ func (e renameCallableOutputEdit) applyOuts(params *syntax.OutParams) int {
 func (e updatePipelineRetain) Apply(ast *syntax.Ast) (int, error) {
 	count := 0
 	for _, target := range ast.Pipelines {
-		if target.GetId() == e.Pipeline.Id &&
+		if sameName(target.GetId(), e.Pipeline.Id) &&
 			target.File().FullPath == e.Pipeline.File().FullPath {
 			count += e.update(target)
 		}
@@ -4,6 +4,7 @@ package syntax
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -455,3 +456,164 @@ func (s *NullExp) filter(_ Type, _ *TypeLookup) (Exp, error) {
 func (s *RefExp) filter(_ Type, _ *TypeLookup) (Exp, error) {
 	return s, nil
 }
+
+func (s *FuncExp) BindingPath(bindPath string,
+	forks map[*CallStm]CollectionIndex,
+	lookup *TypeLookup) (Exp, error) {
+	if bindPath != "" {
+		return s, &bindingError{
+			Msg: "cannot bind to " + bindPath + " within a string",
+		}
+	}
+	var args []Exp
+	for i, arg := range s.Args {
+		a, err := arg.BindingPath("", forks, lookup)
+		if err != nil {
+			return s, err
+		}
+		if a != arg && args == nil {
+			args = make([]Exp, len(s.Args))
+			copy(args, s.Args[:i])
+		}
+		if args != nil {
+			args[i] = a
+		}
+	}
+	return s.makeFuncExp(args)
+}
+
+func (s *FuncExp) resolveRefs(self, siblings map[string]*ResolvedBinding,
+	lookup *TypeLookup) (Exp, error) {
+	var args []Exp
+	for i, arg := range s.Args {
+		a, err := arg.resolveRefs(self, siblings, lookup)
+		if err != nil {
+			return s, &bindingError{
+				Msg: "argument " + strconv.Itoa(i+1) + " of " + s.Func,
+				Err: err,
+			}
+		}
+		if a != arg && args == nil {
+			args = make([]Exp, len(s.Args))
+			copy(args, s.Args[:i])
+		}
+		if args != nil {
+			args[i] = a
+		}
+	}
+	return s.makeFuncExp(args)
+}
+
+func (s *FuncExp) filter(Type, *TypeLookup) (Exp, error) {
+	return s, nil
+}
+
+// makeFuncExp returns the result of applying the function to the given
+// arguments if they are all known, or otherwise a function expression with
+// the given arguments.  A nil argument list means the arguments are
+// unchanged.
+func (s *FuncExp) makeFuncExp(args []Exp) (Exp, error) {
+	if args == nil {
+		args = s.Args
+	}
+	if s.isStatic(args) {
+		return s.evaluateStatic(args)
+	}
+	for _, arg := range args {
+		if _, ok := arg.(*NullExp); ok {
+			return &NullExp{valExp: valExp{Node: s.Node}}, nil
+		}
+	}
+	if s.Func == FuncInterpolate {
+		var err error
+		if args, err = s.foldLiterals(args); err != nil {
+			return s, err
+		}
+	}
+	if len(args) == 0 || &args[0] == &s.Args[0] {
+		return s, nil
+	}
+	return &FuncExp{
+		Node:     s.Node,
+		Func:     s.Func,
+		Args:     args,
+		argTypes: s.argTypes,
+	}, nil
+}
+
+// foldLiterals combines consecutive literal arguments to interpolate into
+// single string literals.
+func (s *FuncExp) foldLiterals(args []Exp) ([]Exp, error) {
+	folded := args[:0:0]
+	start := -1
+	changed := false
+	for i := 0; i <= len(args); i++ {
+		if i < len(args) && isStaticArgs(args[i:i+1]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			if _, ok := args[start].(*StringExp); ok && i == start+1 {
+				folded = append(folded, args[start])
+			} else {
+				v, err := s.evaluateStatic(args[start:i])
+				if err != nil {
+					return args, err
+				}
+				folded = append(folded, v)
+				changed = true
+			}
+			start = -1
+		}
+		if i < len(args) {
+			folded = append(folded, args[i])
+		}
+	}
+	if !changed {
+		return args, nil
+	}
+	return folded, nil
+}
+
+// isStaticArgs returns true if all of the given arguments are scalar
+// literals.
+func isStaticArgs(args []Exp) bool {
+	for _, arg := range args {
+		switch arg.(type) {
+		case *StringExp, *IntExp, *FloatExp, *BoolExp, *NullExp:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// evaluateStatic computes the result of applying the function to
+// arguments which are all scalar literals.
+func (s *FuncExp) evaluateStatic(args []Exp) (Exp, error) {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case *StringExp:
+			values[i] = arg.Value
+		case *IntExp:
+			values[i] = strconv.FormatInt(arg.Value, 10)
+		case *FloatExp:
+			values[i] = strconv.FormatFloat(arg.Value, 'g', -1, 64)
+		case *BoolExp:
+			values[i] = strconv.FormatBool(arg.Value)
+		case *NullExp:
+			return &NullExp{valExp: valExp{Node: s.Node}}, nil
+		default:
+			return s, fmt.Errorf("argument %d of %s is not a literal: %s",
+				i+1, s.Func, arg.GoString())
+		}
+	}
+	v, err := s.apply(values)
+	if err != nil {
+		return s, err
+	}
+	return v, nil
+}
@@ -95,7 +95,8 @@ func (pipe *CallGraphPipeline) makeChildNodes(prefix string, ast *Ast) error {
 
 func (node *CallGraphPipeline) resolve(siblings map[string]*ResolvedBinding,
 	mapped ForkRootList, lookup *TypeLookup) error {
-	if err := node.resolveInputs(siblings, mapped, lookup); err != nil {
+	if err := node.resolveInputs(node.pipeline.InParams,
+		siblings, mapped, lookup); err != nil {
 		return err
 	}
 	if node.isAlwaysDisabled() {
@@ -184,6 +185,19 @@ func (node *CallGraphPipeline) makeOutExp(
 			loc: node.pipeline.Ret.Node.Loc,
 		}
 	}
+	var errs ErrorList
+	for _, param := range node.pipeline.OutParams.List {
+		if err := checkNonNull(node.Fqid, "output", param,
+			outs[param.Id], node.Disable); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errs.If(); err != nil {
+		return nil, &wrapError{
+			innerError: err,
+			loc:        node.pipeline.Ret.Node.Loc,
+		}
+	}
 	value := make(map[string]Exp, len(outs))
 	for k, out := range outs {
 		value[k] = out.Exp
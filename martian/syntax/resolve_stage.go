@@ -170,7 +170,8 @@ func alwaysDisable(disable []Exp) []Exp {
 	return []Exp{&trueExp}
 }
 
-func (node *CallGraphStage) resolveInputs(siblings map[string]*ResolvedBinding,
+func (node *CallGraphStage) resolveInputs(params *InParams,
+	siblings map[string]*ResolvedBinding,
 	mapped ForkRootList,
 	lookup *TypeLookup) error {
 	var errs ErrorList
@@ -188,6 +189,12 @@ func (node *CallGraphStage) resolveInputs(siblings map[string]*ResolvedBinding,
 			Err: err,
 		})
 	}
+	if ins, err = params.resolveDefaults(ins, lookup); err != nil {
+		errs = append(errs, &bindingError{
+			Msg: node.Fqid,
+			Err: err,
+		})
+	}
 	if node.isEmptyMapping() {
 		node.Disable = alwaysDisable(disable)
 	} else {
@@ -219,6 +226,14 @@ func (node *CallGraphStage) resolveInputs(siblings map[string]*ResolvedBinding,
 			mapped = append(mapped, node)
 		}
 	}
+	if !node.isAlwaysDisabled() {
+		for _, param := range params.List {
+			if err := checkNonNull(node.Fqid, "input", param,
+				ins[param.Id], node.Disable); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
 	node.Inputs = ins
 	node.resolveForks(mapped, node)
 	if err := errs.If(); err != nil {
@@ -230,6 +245,56 @@ func (node *CallGraphStage) resolveInputs(siblings map[string]*ResolvedBinding,
 	return nil
 }
 
+// checkNonNull returns an error if the parameter is non-null and the binding
+// is null, or is a value which could be disabled, or is a conditional value
+// with a branch which could be either.  Values which can only be disabled by
+// a condition in disable are permitted, since in that case the consumer would
+// also be disabled.
+func checkNonNull(fqid, kind string, param StructMemberLike,
+	binding *ResolvedBinding, disable []Exp) error {
+	if !param.IsNonNull() || binding == nil {
+		return nil
+	}
+	if reason := nullReason(binding.Exp, disable); reason != "" {
+		return &bindingError{
+			Msg: "NullBindingError: non-null " + kind + " " +
+				param.GetId() + " of " + fqid + " is bound to " + reason,
+		}
+	}
+	return nil
+}
+
+// nullReason returns a description of how the expression could be null, or
+// an empty string if it cannot be.
+func nullReason(exp Exp, disable []Exp) string {
+	switch exp := exp.(type) {
+	case *NullExp:
+		return "null"
+	case *DisabledExp:
+		if !hasDisableCondition(disable, exp.Disabled) {
+			return "a value which is null if " +
+				exp.Disabled.GoString() + " is true"
+		}
+	case *CondExp:
+		if reason := nullReason(exp.Then, disable); reason != "" {
+			return "a conditional value which may be " + reason
+		}
+		if reason := nullReason(exp.Else, disable); reason != "" {
+			return "a conditional value which may be " + reason
+		}
+	}
+	return ""
+}
+
+func hasDisableCondition(disable []Exp, cond Exp) bool {
+	for _, d := range disable {
+		if d == cond || d.GoString() == cond.GoString() {
+			return true
+		}
+	}
+	return false
+}
+
 type sortedSplitList []*SplitExp
 
 func (arr sortedSplitList) Len() int {
@@ -412,7 +477,7 @@ func (node *CallGraphStage) resolveDisable(disable []Exp,
 
 func resolveDisableExp(r Exp, disable []Exp) ([]Exp, error) {
 	switch r := r.(type) {
-	case *RefExp:
+	case *RefExp, *CondExp:
 		for _, e := range disable {
 			if e == r {
 				return disable, nil
@@ -584,7 +649,8 @@ func resolveDisableMap(r Exp, v map[string]Exp, disable []Exp) ([]Exp, error) {
 func (node *CallGraphStage) resolve(siblings map[string]*ResolvedBinding,
 	mapped ForkRootList, lookup *TypeLookup) error {
 	var errs ErrorList
-	if err := node.resolveInputs(siblings, mapped, lookup); err != nil {
+	if err := node.resolveInputs(node.stage.InParams,
+		siblings, mapped, lookup); err != nil {
 		errs = append(errs, err)
 	}
 
@@ -687,7 +753,7 @@ func wrapDisabled(d, exp Exp, lookup *TypeLookup) (Exp, error) {
 	switch d := d.(type) {
 	case *DisabledExp:
 		return d.makeDisabledExp(d.Value, exp)
-	case *RefExp:
+	case *RefExp, *CondExp:
 		return &DisabledExp{
 			Disabled: d,
 			Value:    exp,
@@ -816,6 +882,14 @@ func findSplitCalls(exp Exp, result map[*CallStm]struct{}, onlyUnknown bool) {
 	case *DisabledExp:
 		findSplitCalls(exp.Value, result, onlyUnknown)
 		findSplitCalls(exp.Disabled, result, onlyUnknown)
+	case *CondExp:
+		findSplitCalls(exp.Condition, result, onlyUnknown)
+		findSplitCalls(exp.Then, result, onlyUnknown)
+		findSplitCalls(exp.Else, result, onlyUnknown)
+	case *FuncExp:
+		for _, v := range exp.Args {
+			findSplitCalls(v, result, onlyUnknown)
+		}
 	case *RefExp:
 		for c, i := range exp.Forks {
 			if i.IndexSource() != nil {
@@ -848,6 +922,14 @@ func findSplitsForCall(exp Exp, call *CallStm, result map[*SplitExp]struct{}) {
 	case *DisabledExp:
 		findSplitsForCall(exp.Value, call, result)
 		findSplitsForCall(exp.Disabled, call, result)
+	case *CondExp:
+		findSplitsForCall(exp.Condition, call, result)
+		findSplitsForCall(exp.Then, call, result)
+		findSplitsForCall(exp.Else, call, result)
+	case *FuncExp:
+		for _, v := range exp.Args {
+			findSplitsForCall(v, call, result)
+		}
 	}
 }
 
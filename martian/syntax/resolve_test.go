@@ -985,3 +985,276 @@ call VERY_DEEP_PIPELINE_PIPELINE4()
 		t.Errorf("Expected string too long error, got %q", err.Error())
 	}
 }
+
+func TestResolveCondExp(t *testing.T) {
+	t.Parallel()
+	ast := testGood(t, `
+stage CHOOSE(
+    out bool flag,
+    src py   "stages/choose",
+)
+
+stage PRODUCE(
+    out int value,
+    src py  "stages/produce",
+)
+
+stage USE(
+    in  int value,
+    out int result,
+    src py  "stages/use",
+)
+
+pipeline INNER(
+    in  bool flag,
+    in  int  a,
+    in  int  b,
+    out int  result,
+)
+{
+    call USE(
+        value = self.flag ? self.a : self.b,
+    )
+
+    return (
+        result = USE.result,
+    )
+}
+
+pipeline OUTER(
+    out int lit,
+    out int dyn,
+    out int same,
+)
+{
+    call CHOOSE()
+    call PRODUCE()
+
+    call INNER as LIT(
+        flag = false,
+        a    = PRODUCE.value,
+        b    = 2,
+    )
+
+    call INNER as DYN(
+        flag = CHOOSE.flag,
+        a    = PRODUCE.value,
+        b    = 3,
+    )
+
+    return (
+        lit  = LIT.result,
+        dyn  = CHOOSE.flag ? PRODUCE.value : DYN.result,
+        same = CHOOSE.flag ? 4 : 4,
+    )
+}
+
+call OUTER()
+`)
+	if ast == nil {
+		return
+	}
+	graph, err := ast.MakeCallGraph("", ast.Call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := graph.NodeClosure()
+	check := func(fqid, expect string) {
+		t.Helper()
+		if n := nodes[fqid]; n == nil {
+			t.Error("No node for " + fqid)
+		} else if v := n.ResolvedInputs()["value"].Exp.GoString(); v != expect {
+			t.Errorf("Incorrect input for %s: expected %s, got %s",
+				fqid, expect, v)
+		}
+	}
+	check("OUTER.LIT.USE", "2")
+	check("OUTER.DYN.USE", "OUTER.CHOOSE.flag ? OUTER.PRODUCE.value : 3")
+	result := FormatExp(graph.ResolvedOutputs().Exp, "")
+	expect := `{
+    dyn:  OUTER.CHOOSE.flag ? OUTER.PRODUCE.value : OUTER.DYN.USE.result,
+    lit:  OUTER.LIT.USE.result,
+    same: 4,
+}`
+	if result != expect {
+		diffLines(expect, result, t)
+	}
+}
+
+func TestResolveFuncExp(t *testing.T) {
+	t.Parallel()
+	ast := testGood(t, `
+stage PRODUCE(
+    out string sample,
+    src py     "stages/produce",
+)
+
+stage USE(
+    in  string name,
+    in  path   dir,
+    out string result,
+    src py     "stages/use",
+)
+
+pipeline INNER(
+    in  string sample,
+    in  path   outdir,
+    in  int    lane,
+    out string result,
+)
+{
+    call USE(
+        name = f"{self.sample}_L{self.lane}",
+        dir  = path_join(self.outdir, f"{self.sample}", "reads"),
+    )
+
+    return (
+        result = USE.result,
+    )
+}
+
+pipeline OUTER(
+    out string lit,
+    out string dyn,
+    out string none,
+)
+{
+    call PRODUCE()
+
+    call INNER as LIT(
+        sample = "s1",
+        outdir = "/data",
+        lane   = 2,
+    )
+
+    call INNER as DYN(
+        sample = PRODUCE.sample,
+        outdir = "/data",
+        lane   = 3,
+    )
+
+    call INNER as NONE(
+        sample = null,
+        outdir = "/data",
+        lane   = 4,
+    )
+
+    return (
+        lit  = LIT.result,
+        dyn  = f"{PRODUCE.sample}.{DYN.result}",
+        none = NONE.result,
+    )
+}
+
+call OUTER()
+`)
+	if ast == nil {
+		return
+	}
+	graph, err := ast.MakeCallGraph("", ast.Call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := graph.NodeClosure()
+	check := func(fqid, param, expect string) {
+		t.Helper()
+		if n := nodes[fqid]; n == nil {
+			t.Error("No node for " + fqid)
+		} else if v := n.ResolvedInputs()[param].Exp.GoString(); v != expect {
+			t.Errorf("Incorrect input %s for %s: expected %s, got %s",
+				param, fqid, expect, v)
+		}
+	}
+	check("OUTER.LIT.USE", "name", `"s1_L2"`)
+	check("OUTER.LIT.USE", "dir", `"/data/s1/reads"`)
+	check("OUTER.DYN.USE", "name", `f"{OUTER.PRODUCE.sample}_L3"`)
+	check("OUTER.DYN.USE", "dir",
+		`path_join("/data", f"{OUTER.PRODUCE.sample}", "reads")`)
+	check("OUTER.NONE.USE", "name", "null")
+	if b, err := nodes["OUTER.DYN.USE"].ResolvedInputs()["name"].MarshalJSON(); err != nil {
+		t.Error(err)
+	} else if s := string(b); s != `{"expression":{"__func__":"interpolate",`+
+		`"args":[{"__reference__":"OUTER.PRODUCE.sample"},"_L3"]},"type":"string"}` {
+		t.Errorf("Incorrect json %s", s)
+	}
+	result := FormatExp(graph.ResolvedOutputs().Exp, "")
+	expect := `{
+    dyn:  f"{OUTER.PRODUCE.sample}.{OUTER.DYN.USE.result}",
+    lit:  OUTER.LIT.USE.result,
+    none: OUTER.NONE.USE.result,
+}`
+	if result != expect {
+		diffLines(expect, result, t)
+	}
+}
+
+func TestResolveInParamDefault(t *testing.T) {
+	t.Parallel()
+	ast := testGood(t, `
+stage USE(
+    in  int value,
+    in  int threads = 4,
+    out int result,
+    src py  "stages/use",
+)
+
+pipeline INNER(
+    in  int value   = 1,
+    in  int threads = 2,
+    out int result,
+)
+{
+    call USE(
+        value   = self.value,
+        threads = self.threads,
+    )
+
+    call USE as USE_DEFAULT(
+        value = self.value,
+    )
+
+    return (
+        result = USE.result,
+    )
+}
+
+pipeline OUTER(
+    out int result,
+)
+{
+    call INNER(
+        threads = 3,
+    )
+
+    return (
+        result = INNER.result,
+    )
+}
+
+call OUTER()
+`)
+	if ast == nil {
+		return
+	}
+	graph, err := ast.MakeCallGraph("", ast.Call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := graph.NodeClosure()
+	check := func(fqid, param, expect string) {
+		t.Helper()
+		if n := nodes[fqid]; n == nil {
+			t.Error("No node for " + fqid)
+		} else if b := n.ResolvedInputs()[param]; b == nil {
+			t.Errorf("No input %s for %s", param, fqid)
+		} else if v := b.Exp.GoString(); v != expect {
+			t.Errorf("Incorrect input %s for %s: expected %s, got %s",
+				param, fqid, expect, v)
+		}
+	}
+	check("OUTER.INNER", "value", "1")
+	check("OUTER.INNER", "threads", "3")
+	check("OUTER.INNER.USE", "value", "1")
+	check("OUTER.INNER.USE", "threads", "3")
+	check("OUTER.INNER.USE_DEFAULT", "threads", "4")
+}
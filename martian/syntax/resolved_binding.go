@@ -103,6 +103,33 @@ func (bindings *BindStms) resolve(self, calls map[string]*ResolvedBinding,
 	return result, errs.If()
 }
 
+// resolveDefaults adds the default values for any parameters which were
+// not bound.
+func (params *InParams) resolveDefaults(result map[string]*ResolvedBinding,
+	lookup *TypeLookup) (map[string]*ResolvedBinding, error) {
+	var errs ErrorList
+	for _, param := range params.List {
+		if param.Default == nil {
+			continue
+		}
+		if _, ok := result[param.Id]; ok {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]*ResolvedBinding, len(params.List))
+		}
+		r, err := resolveExp(param.Default, param.Tname, nil, nil, lookup)
+		if err != nil {
+			errs = append(errs, &bindingError{
+				Msg: "BindingError: default for parameter " + param.Id,
+				Err: err,
+			})
+		}
+		result[param.Id] = r
+	}
+	return result, errs.If()
+}
+
 func resolveExp(exp Exp, tname TypeId, self, siblings map[string]*ResolvedBinding,
 	lookup *TypeLookup) (*ResolvedBinding, error) {
 	t := lookup.Get(tname)
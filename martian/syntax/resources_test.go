@@ -0,0 +1,188 @@
+// Copyright (c) 2020 10X Genomics, Inc. All rights reserved.
+
+package syntax
+
+import (
+	"strings"
+	"testing"
+)
+
+const resourceExpSrc = `filetype bam;
+
+struct READS(
+    bam    reads,
+    string name,
+)
+
+stage SORT(
+    in  bam   input,
+    in  READS reads,
+    in  bam[] others,
+    in  int   mem_per_thread,
+    in  float scale,
+    out bam   sorted,
+    src py    "stages/sort",
+) using (
+    mem_gb  = 2 + file_size(self.input) * self.scale / 1073741824,
+    threads = max(1, min(self.mem_per_thread, 8)),
+    vmem_gb = (self.mem_per_thread + 1) * (2 - self.scale - (1 - 2)),
+)
+`
+
+func TestResourceExpFormat(t *testing.T) {
+	t.Parallel()
+	ast := testGood(t, resourceExpSrc)
+	if ast == nil {
+		return
+	}
+	res := ast.Callables.Table["SORT"].(*Stage).Resources
+	if res.MemGBExp == nil || res.ThreadsExp == nil || res.VMemGBExp == nil {
+		t.Fatal("expected resource expressions")
+	}
+	if res.MemGB != 0 || res.Threads != 0 || res.VMemGB != 0 {
+		t.Error("expected static resource values to be unset")
+	}
+	if formatted := ast.format(false); formatted != resourceExpSrc {
+		diffLines(resourceExpSrc, formatted, t)
+	}
+}
+
+func TestResourceExpStatic(t *testing.T) {
+	t.Parallel()
+	ast := testGood(t, `stage SORT(
+    src py "stages/sort",
+) using (
+    mem_gb  = 1.5,
+    threads = -1,
+)
+`)
+	if ast == nil {
+		return
+	}
+	res := ast.Callables.Table["SORT"].(*Stage).Resources
+	if res.MemGBExp != nil || res.ThreadsExp != nil {
+		t.Error("expected static resources")
+	}
+	if res.MemGB != 1.5 {
+		t.Errorf("expected 1.5 GB, got %g", res.MemGB)
+	}
+	if res.Threads != -1 {
+		t.Errorf("expected -1 threads, got %g", res.Threads)
+	}
+}
+
+func TestResourceExpSubtraction(t *testing.T) {
+	t.Parallel()
+	ast := testGood(t, `stage SORT(
+    in  int n,
+    src py  "stages/sort",
+) using (
+    mem_gb  = self.n-1,
+    threads = 4-1.5,
+    vmem_gb = max(self.n, 2)-(1)-2*3,
+)
+`)
+	if ast == nil {
+		return
+	}
+	res := ast.Callables.Table["SORT"].(*Stage).Resources
+	for _, c := range [...]struct {
+		exp    Exp
+		expect string
+	}{
+		{res.MemGBExp, "self.n - 1"},
+		{res.ThreadsExp, "4 - 1.5"},
+		{res.VMemGBExp, "max(self.n, 2) - 1 - 2 * 3"},
+	} {
+		if c.exp == nil {
+			t.Errorf("expected %s, got nil", c.expect)
+		} else if s := c.exp.GoString(); s != c.expect {
+			t.Errorf("expected %s, got %s", c.expect, s)
+		}
+	}
+	// Negative literals still work where an operand is expected.
+	testGood(t, `stage SORT(
+    src py "stages/sort",
+) using (
+    threads = -1 * -2,
+    mem_gb  = (-1) - -2,
+)
+`)
+}
+
+func TestResourceExpEvaluate(t *testing.T) {
+	t.Parallel()
+	check := func(t *testing.T, fn string, args []string, expect string) {
+		t.Helper()
+		exp := FuncExp{Func: fn}
+		v, err := exp.applyNumeric(args)
+		if err != nil {
+			t.Error(err)
+		} else if s := v.GoString(); s != expect {
+			t.Errorf("%s%v: expected %s, got %s", fn, args, expect, s)
+		}
+	}
+	check(t, FuncAdd, []string{"1", "2"}, "3")
+	check(t, FuncSub, []string{"1", "2.5"}, "-1.5")
+	check(t, FuncMul, []string{"3", "4"}, "12")
+	check(t, FuncDiv, []string{"3", "4"}, "0.75")
+	check(t, FuncMin, []string{"3", "4", "-1"}, "-1")
+	check(t, FuncMax, []string{"3", "4.5", "1"}, "4.5")
+	exp := FuncExp{Func: FuncDiv}
+	if _, err := exp.applyNumeric([]string{"1", "0"}); err == nil {
+		t.Error("expected division by zero error")
+	}
+	if _, err := exp.applyNumeric([]string{"1", "x"}); err == nil {
+		t.Error("expected an error for a non-number")
+	}
+}
+
+func TestResourceExpErrors(t *testing.T) {
+	t.Parallel()
+	replace := func(old, new string) string {
+		if !strings.Contains(resourceExpSrc, old) {
+			t.Fatalf("%q not found", old)
+		}
+		return strings.Replace(resourceExpSrc, old, new, 1)
+	}
+	testBadCompile(t,
+		replace("self.mem_per_thread, 8", "self.missing, 8"),
+		"ScopeNameError: 'missing' is not an input parameter of stage 'SORT'")
+	testBadCompile(t,
+		replace("* self.scale /", "* self.reads /"),
+		"ResourceError: self.reads has type READS, which is not a number")
+	testBadCompile(t,
+		replace("file_size(self.input)", "file_size(self.scale)"),
+		"ResourceError: self.scale has type float, which does not contain files")
+	testBadCompile(t,
+		replace("file_size(self.input)", "file_size(self.reads.name)"),
+		"ResourceError: self.reads.name has type string, which does not contain files")
+	testBadCompile(t,
+		replace("max(1, min(self.mem_per_thread, 8))", "min(1)"),
+		"ResourceError: min requires at least 2 arguments")
+	testBadCompile(t,
+		replace("max(1, min(", "path_join(1, min("),
+		"ResourceError: cannot use path_join in stage resources")
+	testBadCompile(t,
+		replace("file_size(self.input)", "file_size(1)"),
+		"ResourceError: the argument to file_size must be an input parameter")
+	testGood(t, replace("file_size(self.input)", "file_size(self.reads)"))
+	testGood(t, replace("file_size(self.input)", "file_size(self.others)"))
+	testGood(t, replace("file_size(self.input)", "file_size(self.reads.reads)"))
+	testBadCompile(t, `stage SORT(
+    in  int n,
+    src py  "stages/sort",
+)
+
+pipeline PIPE(
+    in  int n,
+)
+{
+    call SORT(
+        n = max(self.n, 1),
+    )
+
+    return ()
+}
+`, "may only be used in stage resources")
+}
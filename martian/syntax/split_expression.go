@@ -144,6 +144,12 @@ func (exp *SplitExp) FindTypedRefs(list []*BoundReference,
 			return list, err
 		}
 		return val.Disabled.FindTypedRefs(list, &builtinBool, lookup)
+	case *CondExp:
+		tt, err := lookup.AddDim(t, exp.CallMode())
+		if err != nil {
+			return list, err
+		}
+		return val.FindTypedRefs(list, tt, lookup)
 	default:
 		return list, &wrapError{
 			innerError: &bindingError{
@@ -25,7 +25,9 @@ type (
 		OutName string
 		// The name by which this value is labeled when printing outputs
 		// to the console.
-		Help      string
+		Help string
+		// True if the value may not be null.
+		NonNull   bool
 		isComplex bool
 		isFile    FileKind
 	}
@@ -63,6 +65,7 @@ func (*StructMember) getSubnodes() []AstNodable { return nil }
 func (m *StructMember) GetTname() TypeId        { return m.Tname }
 func (m *StructMember) GetArrayDim() int        { return int(m.Tname.ArrayDim) }
 func (m *StructMember) GetHelp() string         { return m.Help }
+func (m *StructMember) IsNonNull() bool         { return m.NonNull }
 
 // Gets the name used to refer to this parameter in outputs.
 func (s *StructMember) GetOutName() string {
@@ -210,6 +213,8 @@ func (s *StructType) IsValidExpression(exp Exp, pipeline *Pipeline, ast *Ast) er
 		return isValidSplit(s, exp, pipeline, ast)
 	case *DisabledExp:
 		return s.IsValidExpression(exp.Value, pipeline, ast)
+	case *CondExp:
+		return isValidCond(s, exp, pipeline, ast)
 	case *NullExp:
 		return nil
 	case *MapExp:
@@ -219,6 +224,10 @@ func (s *StructType) IsValidExpression(exp Exp, pipeline *Pipeline, ast *Ast) er
 				errs = append(errs, &IncompatibleTypeError{
 					Message: "missing value for struct field " + member.Id,
 				})
+			} else if _, ok := v.(*NullExp); ok && member.NonNull {
+				errs = append(errs, &IncompatibleTypeError{
+					Message: "null value for non-null struct field " + member.Id,
+				})
 			} else if err := ast.TypeTable.Get(member.Tname).IsValidExpression(
 				v, pipeline, ast); err != nil {
 				errs = append(errs, &IncompatibleTypeError{
@@ -295,6 +304,11 @@ func (s *StructType) CheckEqual(other Type) error {
 							": differing inner array dimension"),
 					})
 				}
+			} else if om.NonNull != member.NonNull {
+				errs = append(errs, &IncompatibleTypeError{
+					Message: fmt.Sprint("field ", member.Id,
+						": differing nullability"),
+				})
 			} else if om.Help != member.Help {
 				errs = append(errs, &IncompatibleTypeError{
 					Message: fmt.Sprint("field ", member.Id,
@@ -342,6 +356,10 @@ func (s *StructType) IsValidJson(data json.RawMessage,
 			errs = append(errs, &IncompatibleTypeError{
 				Message: "missing key: " + member.Id,
 			})
+		} else if member.NonNull && isNullBytes(element) {
+			errs = append(errs, &IncompatibleTypeError{
+				Message: "null value for non-null key " + member.Id,
+			})
 		} else if err := t.IsValidJson(element, alarms, lookup); err != nil {
 			errs = append(errs, &IncompatibleTypeError{
 				Message: "key " + member.Id,
@@ -80,11 +80,12 @@ func keywordToken(b []byte) ([]byte, int) {
 	if len(b) > 0 {
 		r := b[0]
 		switch r {
-		case '(', ')',
-			'*',
-			',', '.',
+		case '!',
+			'(', ')',
+			'*', '+',
+			',', '.', '/',
 			':', ';',
-			'<', '=', '>',
+			'<', '=', '>', '?',
 			'[', ']',
 			'{', '}':
 			// Puctuation marks
@@ -102,7 +103,11 @@ func keywordToken(b []byte) ([]byte, int) {
 			if v, id := tokFloatRule(b); len(v) > 0 {
 				return v, id
 			}
-			return tokIntRule(b)
+			if v, id := tokIntRule(b); len(v) > 0 || r != '-' {
+				return v, id
+			}
+			// Subtraction operator.
+			return b[:1:1], int(r)
 		case '_':
 			return tokIdRule(b)
 
@@ -126,8 +131,18 @@ func keywordToken(b []byte) ([]byte, int) {
 			}
 			return bytesPrefixString(b, disabled), DISABLED
 		case 'e':
+			if v := bytesPrefixString(b, `enum`); len(v) > 0 {
+				return v, ENUM
+			}
 			return bytesPrefixString(b, abr_exec), EXEC
 		case 'f':
+			if len(b) > 1 && b[1] == '"' {
+				// No token other than an f-string starts with f"
+				if v, id := tokFStringRule(b); len(v) > 0 {
+					return v, id
+				}
+				return b[:1:1], INVALID
+			}
 			if v := bytesPrefixString(b, `false`); len(v) > 0 {
 				return v, FALSE
 			}
@@ -201,6 +216,9 @@ func keywordToken(b []byte) ([]byte, int) {
 			if v := bytesPrefixString(b, `threads`); len(v) > 0 {
 				return v, THREADS
 			}
+			if v := bytesPrefixString(b, `timeout`); len(v) > 0 {
+				return v, TIMEOUT
+			}
 			return bytesPrefixString(b, `true`), TRUE
 		case 'u':
 			return bytesPrefixString(b, `using`), USING
@@ -228,19 +246,34 @@ func regexpRule(exp string, tokid int) rule {
 	}
 }
 
+// Escape sequences permitted in string literals.
+const stringEscapeRe = `\\(?:` +
+	`[abfnrtv\\"]|` + // standard escapes
+	`[0-7]{3}|` + // octal-encoded ascii
+	`x[[:xdigit:]]{2}|` + // one-byte unicode
+	`u[[:xdigit:]]{4}|` + // two-byte unicode
+	`U[[:xdigit:]]{8}` + // four-byte unicode
+	`)`
+
 var (
 	// double-quoted strings with escaping.
 	tokStringRule = regexpRule(
 		`^"(?:[^\\"]|`+ // non-escape sequences
-			`\\(?:`+
-			`[abfnrtv\\"]|`+ // standard escapes
-			`[0-7]{3}|`+ // octal-encoded ascii
-			`x[[:xdigit:]]{2}|`+ // one-byte unicode
-			`u[[:xdigit:]]{4}|`+ // two-byte unicode
-			`U[[:xdigit:]]{8}`+ // four-byte unicode
-			`))*"`,
+			stringEscapeRe+
+			`)*"`,
 		LITSTRING,
 	)
+	// f-strings, which are double-quoted strings in which references such
+	// as {self.foo} are interpolated.  Literal braces are escaped by
+	// doubling them.
+	tokFStringRule = regexpRule(
+		`^f"(?:[^\\"{}]|`+ // non-escape sequences
+			stringEscapeRe+`|`+
+			`\{\{|\}\}|`+ // escaped braces
+			`\{\w+(?:\.\w+)*\}`+ // references
+			`)*"`,
+		FSTRING,
+	)
 	tokFloatRule = regexpRule(`^-?\d+(:?(?:\.\d+)?[eE][+-]?|\.)\d+\b`, NUM_FLOAT)
 	tokIntRule   = regexpRule(`^-?0*\d{1,19}\b`, NUM_INT)
 
@@ -32,6 +32,9 @@ var (
 	duplicateOfUserTypeError = IncompatibleTypeError{
 		Message: "type name conflicts with previously declared struct type",
 	}
+	duplicateOfEnumTypeError = IncompatibleTypeError{
+		Message: "type name conflicts with previously declared enum type",
+	}
 	userBaseTypeNameError = IncompatibleTypeError{
 		Message: "type name conflicts with a base type name",
 	}
@@ -45,6 +48,11 @@ func (lookup *TypeLookup) AddUserType(t *UserType) error {
 		switch existing := existing.(type) {
 		case *UserType:
 			return nil
+		case *EnumType:
+			return &wrapError{
+				innerError: &duplicateOfEnumTypeError,
+				loc:        existing.getNode().Loc,
+			}
 		case *BuiltinType:
 			// The parser should prevent this from ever happening
 			return &userBaseTypeNameError
@@ -70,6 +78,11 @@ func (lookup *TypeLookup) AddStructType(t *StructType) error {
 				innerError: &duplicateOfUserTypeError,
 				loc:        existing.getNode().Loc,
 			}
+		case *EnumType:
+			return &wrapError{
+				innerError: &duplicateOfEnumTypeError,
+				loc:        existing.getNode().Loc,
+			}
 		case *StructType:
 			if err := t.CheckEqual(existing); err != nil {
 				return &wrapError{
@@ -100,6 +113,47 @@ func (lookup *TypeLookup) AddStructType(t *StructType) error {
 	}
 }
 
+func (lookup *TypeLookup) AddEnumType(t *EnumType) error {
+	if existing, ok := lookup.baseTypes[t.TypeId()]; !ok {
+		lookup.baseTypes[t.TypeId()] = t
+		return nil
+	} else {
+		switch existing := existing.(type) {
+		case *UserType:
+			return &wrapError{
+				innerError: &duplicateOfUserTypeError,
+				loc:        existing.getNode().Loc,
+			}
+		case *EnumType:
+			if err := t.CheckEqual(existing); err != nil {
+				return &wrapError{
+					innerError: &IncompatibleTypeError{
+						Message: "name '" + t.Id +
+							"' conflicts with previously declared enum type",
+						Reason: &wrapError{
+							innerError: err,
+							loc:        t.Node.Loc,
+						},
+					},
+					loc: existing.Node.Loc,
+				}
+			} else {
+				return nil
+			}
+		case *BuiltinType:
+			// The parser should prevent this from ever happening
+			return &userBaseTypeNameError
+		case AstNodable:
+			return &wrapError{
+				innerError: &duplicateOfStructTypeError,
+				loc:        existing.getNode().Loc,
+			}
+		default:
+			panic(fmt.Sprintf("Unexpected type %T", existing))
+		}
+	}
+}
+
 // Freeze the type lookup so that it will no longer cache constructed types,
 // making it safe for concurrent access.
 func (lookup *TypeLookup) Freeze() {
@@ -177,6 +231,13 @@ func (lookup *TypeLookup) GetArray(t Type, dim int16) Type {
 	return lookup.Get(id)
 }
 
+// FieldType gets the type of the given field of a struct type, or of the
+// projection of that field through an array or map of structs.  The field
+// may be a dot-separated path through nested structs.
+func (lookup *TypeLookup) FieldType(id TypeId, field string) (TypeId, error) {
+	return fieldType(id, lookup, field)
+}
+
 func (lookup *TypeLookup) AddDim(t Type, mode CallMode) (Type, error) {
 	switch mode {
 	case ModeArrayCall:
@@ -102,6 +102,10 @@ func (s *UserType) IsValidExpression(exp Exp, pipeline *Pipeline, ast *Ast) erro
 		return isValidSplit(s, exp, pipeline, ast)
 	case *DisabledExp:
 		return s.IsValidExpression(exp.Value, pipeline, ast)
+	case *CondExp:
+		return isValidCond(s, exp, pipeline, ast)
+	case *FuncExp:
+		return isValidFunc(exp, pipeline, ast)
 	case *NullExp:
 		return nil
 	case *StringExp:
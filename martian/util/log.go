@@ -24,10 +24,14 @@ type StringWriter interface {
 type Logger struct {
 	stdoutWriter StringWriter
 	fileWriter   StringWriter
+	hookWriter   StringWriter
 	cache        bytes.Buffer
 }
 
 func (logger *Logger) Write(msg []byte) (int, error) {
+	if logger.hookWriter != nil {
+		logger.hookWriter.Write(msg)
+	}
 	if logger.fileWriter != nil {
 		return logger.fileWriter.Write(msg)
 	} else {
@@ -37,6 +41,9 @@ func (logger *Logger) Write(msg []byte) (int, error) {
 }
 
 func (logger *Logger) WriteString(msg string) (int, error) {
+	if logger.hookWriter != nil {
+		logger.hookWriter.WriteString(msg)
+	}
 	if logger.fileWriter != nil {
 		return logger.fileWriter.WriteString(msg)
 	} else {
@@ -195,6 +202,16 @@ func LogTeeWriter(writer StringWriter) {
 	}
 }
 
+// Sets up the logging methods to additionally send everything which is
+// logged to the given writer, for example to forward log messages to
+// clients of the web API.  Content which was logged before this call is
+// not forwarded.
+func LogHook(writer StringWriter) {
+	if logInit() {
+		LOGGER.hookWriter = writer
+	}
+}
+
 func formatInfo(w io.Writer, component string, format string, v ...interface{}) {
 	fmt.Fprintf(w, "%s [%s] %s\n", Timestamp(), component, fmt.Sprintf(format, v...))
 }
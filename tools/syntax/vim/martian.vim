@@ -11,7 +11,7 @@ syn keyword filetype  filetype nextgroup=parType skipwhite
 syn keyword parameter in out  nextgroup=parType skipwhite contained
 syn keyword src       src nextgroup=srctype skipwhite contained
 syn keyword srctype   py comp exe nextgroup=mroString contained skipwhite
-syn keyword restype   mem_gb vmem_gb threads special volatile nextgroup=assign contained skipwhite
+syn keyword restype   mem_gb vmem_gb threads timeout special volatile nextgroup=assign contained skipwhite
 syn keyword modifier  local preflight volatile nextgroup=modifier,callTarg skipwhite contained
 syn keyword boundMod  local preflight volatile disabled nextgroup=assign contained skipwhite
 syn keyword sweep     sweep nextgroup=sweepArray contained
@@ -19,6 +19,7 @@ syn keyword sweep     sweep nextgroup=sweepArray contained
 syn match   mapCall     'map\s\+call'   nextgroup=callTarg  skipwhite transparent contains=map,call
 syn keyword declaration pipeline stage nextgroup=pipeName  skipwhite
 syn keyword declaration struct nextgroup=structName        skipwhite
+syn keyword declaration enum   nextgroup=enumName          skipwhite
 syn keyword call        call   nextgroup=modifier,callTarg skipwhite
 syn keyword map         map    nextgroup=call              skipwhite contained
 syn keyword return      return nextgroup=callParams        skipwhite contained
@@ -45,6 +46,7 @@ syn match arrayDim '\[\]' nextgroup=arraydim,parName skipwhite contained
 syn match parName '_\?[A-Za-z][A-Za-z0-9_]*' nextgroup=dot,mroString skipwhite contained
 syn match pipeName '_\?[A-Za-z][A-Za-z0-9_]*' nextgroup=paramBlock contained
 syn match structName '_\?[A-Za-z][A-Za-z0-9_]*' nextgroup=fieldBlock contained
+syn match enumName '_\?[A-Za-z][A-Za-z0-9_]*' nextgroup=enumBlock contained
 syn match callTarg '_\?[A-Za-z][A-Za-z0-9_]*' nextgroup=as,callParams skipwhite contained
 syn match structLitMember '_\?[A-Za-z][A-Za-z0-9_]*\s*:' contains=parName,mapSep nextgroup=mroString,mroNull,mroBool,arrayLit,mapLit,self,parName skipwhite transparent contained
 
@@ -54,6 +56,7 @@ syn match mroNumber '\v<\d+(\.\d+)?>' contained skipwhite nextgroup=mapSep
 
 syn region paramBlock start="(" end=")" fold transparent nextgroup=split,using,retain,callBlock skipwhite skipnl contained contains=parameter,src
 syn region fieldBlock start="(" end=")" fold transparent skipwhite skipnl contained contains=parType
+syn region enumBlock  start="(" end=")" fold transparent skipwhite skipnl contained contains=mroString,commentLine
 syn region mapType    start="<" end=">" transparent nextgroup=arrayDim,parName skipwhite contained contains=parType
 syn region callParams start="(" end=")" fold transparent nextgroup=callUsing,retain skipwhite contained contains=assignment
 syn region resParams  start="(" end=")" fold transparent nextgroup=retain contained skipwhite skipnl contains=restype
@@ -96,6 +99,7 @@ hi def link srcType       Type
 
 hi def link pipeName      Type
 hi def link structName    Type
+hi def link enumName      Type
 hi def link callTarg      Type
 hi def link parName       Identifier
 